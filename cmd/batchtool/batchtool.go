@@ -0,0 +1,244 @@
+// Copyright 2021-2022, Offchain Labs, Inc.
+// For license information, see https://github.com/fogr/blob/master/LICENSE
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"os"
+	"strings"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/FOGRCC/fogr/cmd/util/confighelpers"
+	"github.com/FOGRCC/fogr/das"
+	"github.com/FOGRCC/fogr/fognode"
+	"github.com/FOGRCC/fogr/fogstate"
+)
+
+// batchtool decodes a sequencer batch for debugging, read from L1, from a file or from a DAS certificate.
+
+type BatchToolConfig struct {
+	Batch                uint64 `koanf:"batch"`
+	File                 string `koanf:"file"`
+	DASCertificate       string `koanf:"das-certificate"`
+	L1NodeURL            string `koanf:"l1-node-url"`
+	L1ConnectionAttempts int    `koanf:"l1-connection-attempts"`
+	SequencerInbox       string `koanf:"sequencer-inbox-address"`
+	FromBlock            int64  `koanf:"from-block"`
+	ToBlock              int64  `koanf:"to-block"`
+	DASURL               string `koanf:"das-url"`
+	ChainID              uint64 `koanf:"chain-id"`
+	FogosVersion         uint64 `koanf:"fogos-version"`
+	Output               string `koanf:"output"`
+}
+
+var DefaultBatchToolConfig = BatchToolConfig{
+	L1ConnectionAttempts: 3,
+	ToBlock:              -1,
+	Output:               "text",
+}
+
+func parseBatchToolConfig(args []string) (*BatchToolConfig, error) {
+	f := flag.NewFlagSet("batchtool", flag.ContinueOnError)
+	f.Uint64("batch", DefaultBatchToolConfig.Batch, "sequence number of the batch to decode")
+	f.String("file", DefaultBatchToolConfig.File, "if set, read the serialized batch (its 40 byte header followed by its data) as hex from this file instead of from L1")
+	f.String("das-certificate", DefaultBatchToolConfig.DASCertificate, "if set, decode the batch data this hex encoded DAS certificate refers to instead of reading a batch from L1 (requires das-url)")
+	f.String("l1-node-url", DefaultBatchToolConfig.L1NodeURL, "URL for L1 node to read the batch from")
+	f.Int("l1-connection-attempts", DefaultBatchToolConfig.L1ConnectionAttempts, "layer 1 RPC connection attempts (spaced out at least 1 second per attempt, 0 to retry infinitely)")
+	f.String("sequencer-inbox-address", DefaultBatchToolConfig.SequencerInbox, "L1 address of SequencerInbox contract")
+	f.Int64("from-block", DefaultBatchToolConfig.FromBlock, "first L1 block to search for the batch in")
+	f.Int64("to-block", DefaultBatchToolConfig.ToBlock, "last L1 block to search for the batch in (-1 for the latest block)")
+	f.String("das-url", DefaultBatchToolConfig.DASURL, "URL of a REST DAS endpoint to fetch the keyset and data of DAS batches from")
+	f.Uint64("chain-id", DefaultBatchToolConfig.ChainID, "L2 chain ID, used to decode the batch's transactions")
+	f.Uint64("fogos-version", DefaultBatchToolConfig.FogosVersion, "fogOS version of the chain when the batch was posted, which decides the formats it can be read in (0 for the latest)")
+	f.String("output", DefaultBatchToolConfig.Output, "output format (\"text\" or \"json\")")
+	k, err := confighelpers.BeginCommonParse(f, args)
+	if err != nil {
+		return nil, err
+	}
+
+	var config BatchToolConfig
+	if err := confighelpers.EndCommonParse(k, &config); err != nil {
+		return nil, err
+	}
+	if config.Output != "text" && config.Output != "json" {
+		return nil, fmt.Errorf("unknown output format %q", config.Output)
+	}
+	return &config, nil
+}
+
+type decodedSegment struct {
+	*fogstate.DecodedBatchSegment
+	Transactions      types.Transactions `json:"transactions,omitempty"`
+	TransactionsError string             `json:"transactionsError,omitempty"`
+}
+
+type decodedBatch struct {
+	SequenceNumber uint64 `json:"sequenceNumber"`
+	*fogstate.DecodedSequencerMessage
+	Segments []*decodedSegment `json:"segments"`
+}
+
+func main() {
+	config, err := parseBatchToolConfig(os.Args[1:])
+	if err != nil {
+		confighelpers.PrintErrorAndExit(err, printUsage)
+	}
+	if err := run(context.Background(), config); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func printUsage(progname string) {
+	fmt.Printf("\n")
+	fmt.Printf("Usage: %s --batch <number> --l1-node-url <url> --sequencer-inbox-address <address> [OPTIONS...]\n", progname)
+	fmt.Printf("       %s --file <path> [OPTIONS...]\n", progname)
+	fmt.Printf("       %s --das-certificate <hex> --das-url <url> [OPTIONS...]\n", progname)
+}
+
+func run(ctx context.Context, config *BatchToolConfig) error {
+	var dasReader fogstate.DataAvailabilityReader
+	if config.DASURL != "" {
+		client, err := das.NewRestfulDasClientFromURL(config.DASURL)
+		if err != nil {
+			return err
+		}
+		dasReader = client
+	}
+	data, err := readBatch(ctx, config)
+	if err != nil {
+		return err
+	}
+	if len(data) < 40 {
+		return errors.New("batch is missing its 40 byte header")
+	}
+	fogosVersion := config.FogosVersion
+	if fogosVersion == 0 {
+		fogosVersion = math.MaxUint64
+	}
+	message, err := fogstate.DecodeSequencerMessage(ctx, config.Batch, data, dasReader, fogosVersion)
+	if err != nil {
+		return err
+	}
+	batch := &decodedBatch{
+		SequenceNumber:          config.Batch,
+		DecodedSequencerMessage: message,
+		Segments:                []*decodedSegment{},
+	}
+	chainId := new(big.Int).SetUint64(config.ChainID)
+	for _, segment := range message.Segments {
+		decoded := &decodedSegment{DecodedBatchSegment: segment}
+		if segment.Message != nil {
+			decoded.Transactions, err = segment.Message.ParseL2Transactions(chainId, nil)
+			if err != nil {
+				decoded.TransactionsError = err.Error()
+			}
+		}
+		batch.Segments = append(batch.Segments, decoded)
+	}
+	if config.Output == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(batch)
+	}
+	printBatch(os.Stdout, batch, types.LatestSignerForChainID(chainId))
+	return nil
+}
+
+func readBatch(ctx context.Context, config *BatchToolConfig) ([]byte, error) {
+	if config.File != "" {
+		contents, err := os.ReadFile(config.File)
+		if err != nil {
+			return nil, err
+		}
+		return common.FromHex(strings.TrimSpace(string(contents))), nil
+	}
+	if config.DASCertificate != "" {
+		// The certificate is decoded as the data of a batch with an empty header.
+		return append(make([]byte, 40), common.FromHex(config.DASCertificate)...), nil
+	}
+	if config.L1NodeURL == "" || !common.IsHexAddress(config.SequencerInbox) {
+		return nil, errors.New("reading a batch from L1 requires --l1-node-url and --sequencer-inbox-address")
+	}
+	l1Client, err := das.GetL1Client(ctx, config.L1ConnectionAttempts, config.L1NodeURL)
+	if err != nil {
+		return nil, err
+	}
+	seqInbox, err := fognode.NewSequencerInbox(l1Client, common.HexToAddress(config.SequencerInbox), config.FromBlock)
+	if err != nil {
+		return nil, err
+	}
+	var toBlock *big.Int
+	if config.ToBlock >= 0 {
+		toBlock = big.NewInt(config.ToBlock)
+	}
+	batch, err := seqInbox.LookupBatch(ctx, config.Batch, big.NewInt(config.FromBlock), toBlock)
+	if err != nil {
+		return nil, err
+	}
+	return batch.Serialize(ctx, l1Client)
+}
+
+func printBatch(w io.Writer, batch *decodedBatch, signer types.Signer) {
+	fmt.Fprintf(w, "Batch %v\n", batch.SequenceNumber)
+	fmt.Fprintf(w, "  timestamps:         %v - %v\n", batch.MinTimestamp, batch.MaxTimestamp)
+	fmt.Fprintf(w, "  L1 blocks:          %v - %v\n", batch.MinL1Block, batch.MaxL1Block)
+	fmt.Fprintf(w, "  delayed messages:   %v after the batch\n", batch.AfterDelayedMessages)
+	if batch.HeaderByte != nil {
+		fmt.Fprintf(w, "  header byte:        %v (%v)\n", batch.HeaderByte, strings.Join(batch.HeaderFlags, ", "))
+	} else {
+		fmt.Fprintf(w, "  header byte:        none (empty batch)\n")
+	}
+	if batch.Dictionary != "" {
+		fmt.Fprintf(w, "  dictionary:         %v\n", batch.Dictionary)
+	}
+	if cert := batch.DASCertificate; cert != nil {
+		if cert.Error != "" {
+			fmt.Fprintf(w, "  DAS certificate:    invalid: %v\n", cert.Error)
+		} else {
+			fmt.Fprintf(w, "  DAS certificate:    version %v, data hash %v, timeout %v, signers mask %v\n", cert.Version, cert.DataHash, cert.Timeout, cert.SignersMask)
+			fmt.Fprintf(w, "  DAS keyset:         %v\n", cert.KeysetHash)
+			if cert.Keyset != nil {
+				fmt.Fprintf(w, "                      %v keys, %v assumed honest\n", len(cert.Keyset.PubKeys), cert.Keyset.AssumedHonest)
+			} else {
+				fmt.Fprintf(w, "                      unavailable: %v\n", cert.KeysetError)
+			}
+		}
+	}
+	fmt.Fprintf(w, "Segments (%v):\n", len(batch.Segments))
+	for _, segment := range batch.Segments {
+		fmt.Fprintf(w, "  #%v %v", segment.Index, segment.Kind)
+		if segment.Advance != 0 {
+			fmt.Fprintf(w, " +%v", segment.Advance)
+		}
+		fmt.Fprintf(w, " (timestamp %v, L1 block %v)\n", segment.Timestamp, segment.L1BlockNumber)
+		if segment.Error != "" {
+			fmt.Fprintf(w, "      error: %v\n", segment.Error)
+		}
+		if segment.TransactionsError != "" {
+			fmt.Fprintf(w, "      failed to parse transactions: %v\n", segment.TransactionsError)
+		}
+		for _, tx := range segment.Transactions {
+			from := "unknown sender"
+			if sender, err := types.Sender(signer, tx); err == nil {
+				from = sender.String()
+			}
+			to := "contract creation"
+			if tx.To() != nil {
+				to = tx.To().String()
+			}
+			fmt.Fprintf(w, "      tx %v type %v from %v to %v nonce %v value %v gas %v data %v bytes\n", tx.Hash(), tx.Type(), from, to, tx.Nonce(), tx.Value(), tx.Gas(), len(tx.Data()))
+		}
+	}
+}
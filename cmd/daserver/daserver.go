@@ -32,11 +32,13 @@ type DAServerConfig struct {
 	RPCAddr           string                              `koanf:"rpc-addr"`
 	RPCPort           uint64                              `koanf:"rpc-port"`
 	RPCServerTimeouts genericconf.HTTPServerTimeoutConfig `koanf:"rpc-server-timeouts"`
+	RPCRateLimit      das.RateLimitConfig                 `koanf:"rpc-rate-limit"`
 
 	EnableREST         bool                                `koanf:"enable-rest"`
 	RESTAddr           string                              `koanf:"rest-addr"`
 	RESTPort           uint64                              `koanf:"rest-port"`
 	RESTServerTimeouts genericconf.HTTPServerTimeoutConfig `koanf:"rest-server-timeouts"`
+	RESTRateLimit      das.RateLimitConfig                 `koanf:"rest-rate-limit"`
 
 	DAConf das.DataAvailabilityConfig `koanf:"data-availability"`
 
@@ -52,10 +54,12 @@ var DefaultDAServerConfig = DAServerConfig{
 	RPCAddr:            "localhost",
 	RPCPort:            9876,
 	RPCServerTimeouts:  genericconf.HTTPServerTimeoutConfigDefault,
+	RPCRateLimit:       das.DefaultRateLimitConfig,
 	EnableREST:         false,
 	RESTAddr:           "localhost",
 	RESTPort:           9877,
 	RESTServerTimeouts: genericconf.HTTPServerTimeoutConfigDefault,
+	RESTRateLimit:      das.DefaultRateLimitConfig,
 	DAConf:             das.DefaultDataAvailabilityConfig,
 	ConfConfig:         genericconf.ConfConfigDefault,
 	Metrics:            false,
@@ -80,11 +84,13 @@ func parseDAServer(args []string) (*DAServerConfig, error) {
 	f.String("rpc-addr", DefaultDAServerConfig.RPCAddr, "HTTP-RPC server listening interface")
 	f.Uint64("rpc-port", DefaultDAServerConfig.RPCPort, "HTTP-RPC server listening port")
 	genericconf.HTTPServerTimeoutConfigAddOptions("rpc-server-timeouts", f)
+	das.RateLimitConfigAddOptions("rpc-rate-limit", f)
 
 	f.Bool("enable-rest", DefaultDAServerConfig.EnableREST, "enable the REST server listening on rest-addr and rest-port")
 	f.String("rest-addr", DefaultDAServerConfig.RESTAddr, "REST server listening interface")
 	f.Uint64("rest-port", DefaultDAServerConfig.RESTPort, "REST server listening port")
 	genericconf.HTTPServerTimeoutConfigAddOptions("rest-server-timeouts", f)
+	das.RateLimitConfigAddOptions("rest-rate-limit", f)
 
 	f.Bool("metrics", DefaultDAServerConfig.Metrics, "enable metrics")
 	genericconf.MetricsServerAddOptions("metrics-server", f)
@@ -208,7 +214,7 @@ func startup() error {
 	if serverConfig.EnableRPC {
 		log.Info("Starting HTTP-RPC server", "addr", serverConfig.RPCAddr, "port", serverConfig.RPCPort, "revision", vcsRevision, "vcs.time", vcsTime)
 
-		rpcServer, err = das.StartDASRPCServer(ctx, serverConfig.RPCAddr, serverConfig.RPCPort, serverConfig.RPCServerTimeouts, daReader, daWriter, daHealthChecker)
+		rpcServer, err = das.StartDASRPCServer(ctx, serverConfig.RPCAddr, serverConfig.RPCPort, serverConfig.RPCServerTimeouts, serverConfig.RPCRateLimit, daReader, daWriter, daHealthChecker)
 		if err != nil {
 			return err
 		}
@@ -218,7 +224,7 @@ func startup() error {
 	if serverConfig.EnableREST {
 		log.Info("Starting REST server", "addr", serverConfig.RESTAddr, "port", serverConfig.RESTPort, "revision", vcsRevision, "vcs.time", vcsTime)
 
-		restServer, err = das.NewRestfulDasServer(serverConfig.RESTAddr, serverConfig.RESTPort, serverConfig.RESTServerTimeouts, daReader, daHealthChecker)
+		restServer, err = das.NewRestfulDasServer(serverConfig.RESTAddr, serverConfig.RESTPort, serverConfig.RESTServerTimeouts, serverConfig.RESTRateLimit, daReader, daHealthChecker)
 		if err != nil {
 			return err
 		}
@@ -12,12 +12,14 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"os"
 	"strings"
 	"time"
 
 	flag "github.com/spf13/pflag"
 
+	"github.com/FOGRCC/fogr/blsSignatures"
 	"github.com/FOGRCC/fogr/cmd/genericconf"
 	"github.com/FOGRCC/fogr/cmd/util"
 	"github.com/FOGRCC/fogr/fogstate"
@@ -34,7 +36,7 @@ import (
 func main() {
 	args := os.Args
 	if len(args) < 2 {
-		panic("Usage: datool [client|keygen|generatehash] ...")
+		panic("Usage: datool [client|keygen|generatehash|export|import|keyset] ...")
 	}
 
 	var err error
@@ -45,8 +47,14 @@ func main() {
 		err = startKeyGen(args[2:])
 	case "generatehash":
 		err = generateHash(args[2])
+	case "export":
+		err = startExport(args[2:])
+	case "import":
+		err = startImport(args[2:])
+	case "keyset":
+		err = startKeyset(args[2:])
 	default:
-		panic(fmt.Sprintf("Unknown tool '%s' specified, valid tools are 'client', 'keygen', 'generatehash'", args[1]))
+		panic(fmt.Sprintf("Unknown tool '%s' specified, valid tools are 'client', 'keygen', 'generatehash', 'export', 'import', 'keyset'", args[1]))
 	}
 	if err != nil {
 		panic(err)
@@ -313,3 +321,315 @@ func generateHash(message string) error {
 	fmt.Printf("Hex Encoded Data Hash: %s\n", hexutil.Encode(dastree.HashBytes([]byte(message))))
 	return nil
 }
+
+// datool export
+
+type ExportConfig struct {
+	Output          string                     `koanf:"output"`
+	FromBlock       uint64                     `koanf:"from-block"`
+	ToBlock         uint64                     `koanf:"to-block"`
+	L1BlocksPerRead uint64                     `koanf:"l1-blocks-per-read"`
+	RetentionPeriod time.Duration              `koanf:"retention-period"`
+	DAConf          das.DataAvailabilityConfig `koanf:"data-availability"`
+	ConfConfig      genericconf.ConfConfig     `koanf:"conf"`
+}
+
+func parseExportConfig(args []string) (*ExportConfig, error) {
+	f := flag.NewFlagSet("datool export", flag.ContinueOnError)
+	f.String("output", "", "file to write the archive to")
+	f.Uint64("from-block", 0, "when exporting from L1, the first L1 block to export batches from")
+	f.Uint64("to-block", 0, "when exporting from L1, the last L1 block to export batches from")
+	f.Uint64("l1-blocks-per-read", das.DefaultSyncToStorageConfig.L1BlocksPerRead, "when exporting from L1, max l1 blocks to read per query")
+	f.Duration("retention-period", time.Duration(math.MaxInt64), "when exporting from L1, period after each batch's max timestamp to retain its data (defaults to forever)")
+	das.DataAvailabilityConfigAddDaserverOptions("data-availability", f)
+	genericconf.ConfConfigAddOptions("conf", f)
+
+	k, err := confighelpers.BeginCommonParse(f, args)
+	if err != nil {
+		return nil, err
+	}
+
+	var config ExportConfig
+	if err := confighelpers.EndCommonParse(k, &config); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// startExport writes an archive of either everything in the configured storage, which must have
+// sync-from-storage-service enabled so it can be iterated over, or, if l1-node-url and
+// sequencer-inbox-address are set, the batches posted in an L1 block range.
+func startExport(args []string) error {
+	config, err := parseExportConfig(args)
+	if err != nil {
+		return err
+	}
+	if config.Output == "" {
+		return errors.New("--output must be specified")
+	}
+
+	ctx := context.Background()
+	var syncFromStorageServices []*das.IterableStorageService
+	var syncToStorageServices []das.StorageService
+	storageService, lifecycleManager, err := das.CreatePersistentStorageService(ctx, &config.DAConf, &syncFromStorageServices, &syncToStorageServices)
+	if err != nil {
+		return err
+	}
+	defer lifecycleManager.StopAndWaitUntil(time.Second)
+
+	output, err := os.Create(config.Output)
+	if err != nil {
+		return err
+	}
+	archive := das.NewArchiveWriter(output)
+	err = exportToArchive(ctx, config, storageService, lifecycleManager, syncFromStorageServices, archive)
+	if err == nil {
+		err = archive.Close()
+	}
+	if closeErr := output.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Exported %d entries to %s\n", archive.Len(), config.Output)
+	return nil
+}
+
+func exportToArchive(
+	ctx context.Context,
+	config *ExportConfig,
+	storageService das.StorageService,
+	lifecycleManager *das.LifecycleManager,
+	syncFromStorageServices []*das.IterableStorageService,
+	archive *das.ArchiveWriter,
+) error {
+	fromL1 := config.DAConf.L1NodeURL != "" && config.DAConf.L1NodeURL != "none" && config.DAConf.SequencerInboxAddress != ""
+	if fromL1 {
+		if config.ToBlock < config.FromBlock {
+			return errors.New("--to-block must not be before --from-block")
+		}
+		seqInboxAddress, err := das.OptionalAddressFromString(config.DAConf.SequencerInboxAddress)
+		if err != nil {
+			return err
+		}
+		if seqInboxAddress == nil {
+			return errors.New("--data-availability.sequencer-inbox-address must be set to export from L1")
+		}
+		l1Client, err := das.GetL1Client(ctx, config.DAConf.L1ConnectionAttempts, config.DAConf.L1NodeURL)
+		if err != nil {
+			return err
+		}
+
+		var dataSource das.DataAvailabilityServiceReader = storageService
+		if config.DAConf.RestfulClientAggregatorConfig.Enable {
+			restAgg, err := das.NewRestfulClientAggregator(ctx, &config.DAConf.RestfulClientAggregatorConfig)
+			if err != nil {
+				return err
+			}
+			restAgg.Start(ctx)
+			lifecycleManager.Register(restAgg)
+			dataSource = restAgg
+		}
+		if dataSource == nil {
+			return errors.New("a storage backend or --data-availability.rest-aggregator must be enabled to fetch batch data from")
+		}
+		return das.ExportBatchesFromL1(ctx, l1Client, *seqInboxAddress, dataSource, config.FromBlock, config.ToBlock, config.L1BlocksPerRead, config.RetentionPeriod, archive)
+	}
+	if len(syncFromStorageServices) != 1 {
+		return errors.New("exactly one storage backend must be enabled with sync-from-storage-service to export from storage")
+	}
+	return das.ExportIterableStorage(ctx, syncFromStorageServices[0], archive)
+}
+
+// datool import
+
+type ImportConfig struct {
+	Input      string                     `koanf:"input"`
+	DAConf     das.DataAvailabilityConfig `koanf:"data-availability"`
+	ConfConfig genericconf.ConfConfig     `koanf:"conf"`
+}
+
+func parseImportConfig(args []string) (*ImportConfig, error) {
+	f := flag.NewFlagSet("datool import", flag.ContinueOnError)
+	f.String("input", "", "archive file to import")
+	das.DataAvailabilityConfigAddDaserverOptions("data-availability", f)
+	genericconf.ConfConfigAddOptions("conf", f)
+
+	k, err := confighelpers.BeginCommonParse(f, args)
+	if err != nil {
+		return nil, err
+	}
+
+	var config ImportConfig
+	if err := confighelpers.EndCommonParse(k, &config); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+func startImport(args []string) error {
+	config, err := parseImportConfig(args)
+	if err != nil {
+		return err
+	}
+	if config.Input == "" {
+		return errors.New("--input must be specified")
+	}
+
+	ctx := context.Background()
+	var syncFromStorageServices []*das.IterableStorageService
+	var syncToStorageServices []das.StorageService
+	storageService, lifecycleManager, err := das.CreatePersistentStorageService(ctx, &config.DAConf, &syncFromStorageServices, &syncToStorageServices)
+	if err != nil {
+		return err
+	}
+	defer lifecycleManager.StopAndWaitUntil(time.Second)
+	if storageService == nil {
+		return errors.New("at least one storage backend must be enabled to import into")
+	}
+
+	input, err := os.Open(config.Input)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	index, err := das.ImportArchive(ctx, input, storageService)
+	if err != nil {
+		return err
+	}
+	if err := storageService.Sync(ctx); err != nil {
+		return err
+	}
+	fmt.Printf("Imported %d entries from %s\n", len(index.Entries), config.Input)
+	return nil
+}
+
+// datool keyset ...
+
+func startKeyset(args []string) error {
+	if len(args) == 0 {
+		return errors.New("datool keyset requires an argument, valid arguments are 'build' and 'invalidate'")
+	}
+	switch strings.ToLower(args[0]) {
+	case "build":
+		return startKeysetBuild(args[1:])
+	case "invalidate":
+		return startKeysetInvalidate(args[1:])
+	}
+	return fmt.Errorf("datool keyset '%s' not supported, valid arguments are 'build' and 'invalidate'", args[0])
+}
+
+// datool keyset build
+
+type KeysetBuildConfig struct {
+	PubKeys       []string               `koanf:"pubkeys"`
+	Backends      string                 `koanf:"backends"`
+	AssumedHonest uint64                 `koanf:"assumed-honest"`
+	ConfConfig    genericconf.ConfConfig `koanf:"conf"`
+}
+
+func parseKeysetBuildConfig(args []string) (*KeysetBuildConfig, error) {
+	f := flag.NewFlagSet("datool keyset build", flag.ContinueOnError)
+	f.StringSlice("pubkeys", []string{}, "committee members' BLS public keys in signers mask order, each either base64 encoded or the path to a public key file")
+	f.String("backends", "", "rpc-aggregator backends JSON to read the committee's public keys from, instead of --pubkeys")
+	f.Uint64("assumed-honest", 1, "number of committee members assumed to be honest")
+	genericconf.ConfConfigAddOptions("conf", f)
+
+	k, err := confighelpers.BeginCommonParse(f, args)
+	if err != nil {
+		return nil, err
+	}
+
+	var config KeysetBuildConfig
+	if err := confighelpers.EndCommonParse(k, &config); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+func startKeysetBuild(args []string) error {
+	config, err := parseKeysetBuildConfig(args)
+	if err != nil {
+		return err
+	}
+
+	var pubKeys []blsSignatures.PublicKey
+	if config.Backends != "" {
+		if len(config.PubKeys) != 0 {
+			return errors.New("only one of --pubkeys and --backends may be specified")
+		}
+		pubKeys, err = das.PubKeysFromBackends(config.Backends)
+		if err != nil {
+			return err
+		}
+	} else {
+		for _, encoded := range config.PubKeys {
+			var pubKey *blsSignatures.PublicKey
+			if _, statErr := os.Stat(encoded); statErr == nil {
+				pubKey, err = das.ReadPubKeyFromFile(encoded)
+			} else {
+				pubKey, err = das.DecodeBase64BLSPublicKey([]byte(encoded))
+			}
+			if err != nil {
+				return fmt.Errorf("invalid public key %s: %w", encoded, err)
+			}
+			pubKeys = append(pubKeys, *pubKey)
+		}
+	}
+
+	_, keysetBytes, keysetHash, err := das.BuildKeyset(pubKeys, config.AssumedHonest)
+	if err != nil {
+		return err
+	}
+	calldata, err := das.SetValidKeysetCalldata(keysetBytes)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Keyset: %s\n", hexutil.Encode(keysetBytes))
+	fmt.Printf("KeysetHash: %s\n", hexutil.Encode(keysetHash[:]))
+	fmt.Printf("SetValidKeysetCalldata: %s\n", hexutil.Encode(calldata))
+	return nil
+}
+
+// datool keyset invalidate
+
+type KeysetInvalidateConfig struct {
+	KeysetHash string                 `koanf:"keyset-hash"`
+	ConfConfig genericconf.ConfConfig `koanf:"conf"`
+}
+
+func parseKeysetInvalidateConfig(args []string) (*KeysetInvalidateConfig, error) {
+	f := flag.NewFlagSet("datool keyset invalidate", flag.ContinueOnError)
+	f.String("keyset-hash", "", "hex encoded hash of the keyset to retire")
+	genericconf.ConfConfigAddOptions("conf", f)
+
+	k, err := confighelpers.BeginCommonParse(f, args)
+	if err != nil {
+		return nil, err
+	}
+
+	var config KeysetInvalidateConfig
+	if err := confighelpers.EndCommonParse(k, &config); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+func startKeysetInvalidate(args []string) error {
+	config, err := parseKeysetInvalidateConfig(args)
+	if err != nil {
+		return err
+	}
+	keysetHash, err := hexutil.Decode(config.KeysetHash)
+	if err != nil || len(keysetHash) != 32 {
+		return fmt.Errorf("--keyset-hash must be a hex encoded 32 byte hash, got '%s'", config.KeysetHash)
+	}
+	calldata, err := das.InvalidateKeysetHashCalldata(common.BytesToHash(keysetHash))
+	if err != nil {
+		return err
+	}
+	fmt.Printf("InvalidateKeysetHashCalldata: %s\n", hexutil.Encode(calldata))
+	return nil
+}
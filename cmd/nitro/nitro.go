@@ -790,6 +790,11 @@ func ParseNode(ctx context.Context, args []string) (*NodeConfig, *genericconf.Wa
 		return nil, nil, nil, nil, nil, err
 	}
 
+	if nodeConfig.Node.BatchPoster.DisableDasFallbackStoreDataOnChain {
+		log.Warn("--node.batch-poster.disable-das-fallback-store-data-on-chain has been deprecated. Please use --node.batch-poster.das-fallback.enable=false instead.")
+		nodeConfig.Node.BatchPoster.DASFallback.Enable = false
+	}
+
 	// Don't pass around wallet contents with normal configuration
 	l1Wallet := nodeConfig.L1.Wallet
 	l2DevWallet := nodeConfig.L2.DevWallet
@@ -54,7 +54,9 @@ func (c WavmChainContext) GetHeader(hash common.Hash, num uint64) *types.Header
 	return header
 }
 
-type WavmInbox struct{}
+type WavmInbox struct {
+	lastBlockHeader *types.Header
+}
 
 func (i WavmInbox) PeekSequencerInbox() ([]byte, error) {
 	pos := wavmio.GetInboxPosition()
@@ -93,6 +95,27 @@ func (i WavmInbox) ReadDelayedInbox(seqNum uint64) (*fogos.L1IncomingMessage, er
 	})
 }
 
+func (i WavmInbox) GetBatchStartfogosVersion() (uint64, error) {
+	// Each earlier message of this sequencer message made a block, so walk back past them.
+	header := i.lastBlockHeader
+	for pos := wavmio.GetPositionWithinMessage(); pos > 0 && header != nil; pos-- {
+		if header.ParentHash == (common.Hash{}) {
+			// The sequencer message started before genesis
+			header = nil
+		} else {
+			header = getBlockHeaderByHash(header.ParentHash)
+		}
+	}
+	if header == nil {
+		return 0, nil
+	}
+	extraInfo, err := types.DeserializeHeaderExtraInformation(header)
+	if err != nil {
+		return 0, err
+	}
+	return extraInfo.fogOSFormatVersion, nil
+}
+
 type PreimageDASReader struct {
 }
 
@@ -165,7 +188,7 @@ func main() {
 		if dasEnabled {
 			dasReader = &PreimageDASReader{}
 		}
-		backend := WavmInbox{}
+		backend := WavmInbox{lastBlockHeader}
 		var keysetValidationMode = fogstate.KeysetPanicIfInvalid
 		if backend.GetPositionWithinMessage() > 0 {
 			keysetValidationMode = fogstate.KeysetDontValidate
@@ -33,12 +33,15 @@ type AggregatorConfig struct {
 	AssumedHonest int    `koanf:"assumed-honest"`
 	Backends      string `koanf:"backends"`
 	DumpKeyset    bool   `koanf:"dump-keyset"`
+
+	SignerHealth SignerHealthConfig `koanf:"signer-health"`
 }
 
 var DefaultAggregatorConfig = AggregatorConfig{
 	AssumedHonest: 0,
 	Backends:      "",
 	DumpKeyset:    false,
+	SignerHealth:  DefaultSignerHealthConfig,
 }
 
 var BatchToDasFailed = errors.New("unable to batch to DAS")
@@ -48,6 +51,7 @@ func AggregatorConfigAddOptions(prefix string, f *flag.FlagSet) {
 	f.Int(prefix+".assumed-honest", DefaultAggregatorConfig.AssumedHonest, "Number of assumed honest backends (H). If there are N backends, K=N+1-H valid responses are required to consider an Store request to be successful.")
 	f.String(prefix+".backends", DefaultAggregatorConfig.Backends, "JSON RPC backend configuration")
 	f.Bool(prefix+".dump-keyset", DefaultAggregatorConfig.DumpKeyset, "Dump the keyset encoded in hexadecimal for the backends string")
+	SignerHealthConfigAddOptions(prefix+".signer-health", f)
 }
 
 type Aggregator struct {
@@ -61,6 +65,7 @@ type Aggregator struct {
 	keysetHash                     [32]byte
 	keysetBytes                    []byte
 	bpVerifier                     *contracts.BatchPosterVerifier
+	health                         *signerHealthTracker
 }
 
 type ServiceDetails struct {
@@ -158,15 +163,17 @@ func NewAggregatorWithSeqInboxCaller(
 		bpVerifier = contracts.NewBatchPosterVerifier(seqInboxCaller)
 	}
 
+	maxAllowedServiceStoreFailures := config.AggregatorConfig.AssumedHonest - 1
 	return &Aggregator{
 		config:                         config.AggregatorConfig,
 		services:                       services,
 		requestTimeout:                 config.RequestTimeout,
 		requiredServicesForStore:       len(services) + 1 - config.AggregatorConfig.AssumedHonest,
-		maxAllowedServiceStoreFailures: config.AggregatorConfig.AssumedHonest - 1,
+		maxAllowedServiceStoreFailures: maxAllowedServiceStoreFailures,
 		keysetHash:                     keysetHash,
 		keysetBytes:                    ksBuf.Bytes(),
 		bpVerifier:                     bpVerifier,
+		health:                         newSignerHealthTracker(config.AggregatorConfig.SignerHealth, services, maxAllowedServiceStoreFailures),
 	}, nil
 }
 
@@ -190,6 +197,13 @@ type storeResponse struct {
 // If Store gets not enough successful responses by the time its context is canceled
 // (eg via TimeoutWrapper) then it also returns an error.
 //
+// Each backend's responses feed its rolling health statistics. Backends with a
+// high recent error rate or latency get the shorter SignerHealth.DegradedTimeout
+// instead of the full RequestTimeout, so a chronically failing committee member
+// doesn't delay every Store by its whole timeout. At most AssumedHonest-1 backends
+// are ever given the shorter deadline, and they still get the full deadline once
+// every SignerHealth.RecoveryProbeInterval.
+//
 // If Sequencer Inbox contract details are provided when a das.Aggregator is
 // constructed, calls to Store(...) will try to verify the passed-in data's signature
 // is from the batch poster. If the contract details are not provided, then the
@@ -213,13 +227,18 @@ func (a *Aggregator) Store(ctx context.Context, message []byte, timeout uint64,
 	responses := make(chan storeResponse, len(a.services))
 
 	expectedHash := dastree.Hash(message)
-	for _, d := range a.services {
-		go func(ctx context.Context, d ServiceDetails) {
-			storeCtx, cancel := context.WithTimeout(ctx, a.requestTimeout)
+	for i, d := range a.services {
+		go func(ctx context.Context, i int, d ServiceDetails) {
+			storeCtx, cancel := context.WithTimeout(ctx, a.health.timeout(i, a.requestTimeout))
 			const metricBase string = "fogr/das/rpc/aggregator/store"
 			var metricWithServiceName = metricBase + "/" + d.metricName
 			defer cancel()
+			start := time.Now()
 			incFailureMetric := func() {
+				// A canceled Store says nothing about the backend's health.
+				if ctx.Err() == nil {
+					a.health.record(i, time.Since(start), true)
+				}
 				metrics.GetOrRegisterCounter(metricWithServiceName+"/error/total", nil).Inc(1)
 				metrics.GetOrRegisterCounter(metricBase+"/error/all/total", nil).Inc(1)
 			}
@@ -267,10 +286,11 @@ func (a *Aggregator) Store(ctx context.Context, message []byte, timeout uint64,
 				return
 			}
 
+			a.health.record(i, time.Since(start), false)
 			metrics.GetOrRegisterCounter(metricWithServiceName+"/success/total", nil).Inc(1)
 			metrics.GetOrRegisterCounter(metricBase+"/success/all/total", nil).Inc(1)
 			responses <- storeResponse{d, cert.Sig, nil}
-		}(ctx, d)
+		}(ctx, i, d)
 	}
 
 	var aggCert fogstate.DataAvailabilityCertificate
@@ -357,6 +377,11 @@ func (a *Aggregator) Store(ctx context.Context, message []byte, timeout uint64,
 	return &aggCert, nil
 }
 
+// SignerHealth returns the rolling health statistics of each backend.
+func (a *Aggregator) SignerHealth() []SignerHealth {
+	return a.health.snapshot(a.services)
+}
+
 func (a *Aggregator) String() string {
 	var b bytes.Buffer
 	b.WriteString("das.Aggregator{")
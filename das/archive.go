@@ -0,0 +1,347 @@
+// Copyright 2022, Offchain Labs, Inc.
+// For license information, see https://github.com/fogr/blob/master/LICENSE
+
+package das
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"math/big"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/FOGRCC/fogr/das/dastree"
+	"github.com/FOGRCC/fogr/fogstate"
+	"github.com/FOGRCC/fogr/fogutil"
+	"github.com/FOGRCC/fogr/solgen/go/bridgegen"
+	"github.com/FOGRCC/fogr/util/fogmath"
+)
+
+// A DAS archive is a tar file holding one "data/<hash>" entry per preimage, with the expiration
+// time of each recorded in its PAX header, followed by an "index.json" entry listing every
+// preimage and a SHA-256 checksum over their contents in archive order. Archives are written and
+// read in a single streaming pass, so they can be piped between machines.
+
+const archiveVersion = 1
+const archiveIndexName = "index.json"
+const archiveDataPrefix = "data/"
+const archiveExpirationRecord = "FOGR.das.expiration"
+
+type ArchiveEntry struct {
+	Hash       common.Hash `json:"hash"`
+	Expiration uint64      `json:"expiration"`
+	Size       uint64      `json:"size"`
+}
+
+type ArchiveIndex struct {
+	Version int            `json:"version"`
+	Entries []ArchiveEntry `json:"entries"`
+	SHA256  string         `json:"sha256"`
+}
+
+type ArchiveWriter struct {
+	tarWriter *tar.Writer
+	hasher    hash.Hash
+	index     ArchiveIndex
+	seen      map[common.Hash]bool
+}
+
+func NewArchiveWriter(w io.Writer) *ArchiveWriter {
+	return &ArchiveWriter{
+		tarWriter: tar.NewWriter(w),
+		hasher:    sha256.New(),
+		index:     ArchiveIndex{Version: archiveVersion, Entries: []ArchiveEntry{}},
+		seen:      make(map[common.Hash]bool),
+	}
+}
+
+// Add appends a preimage to the archive, ignoring preimages that were already added.
+func (a *ArchiveWriter) Add(data []byte, expiration uint64) error {
+	key := dastree.Hash(data)
+	if a.seen[key] {
+		return nil
+	}
+	header := &tar.Header{
+		Typeflag:   tar.TypeReg,
+		Name:       archiveDataPrefix + EncodeStorageServiceKey(key),
+		Mode:       0o600,
+		Size:       int64(len(data)),
+		ModTime:    time.Unix(0, 0),
+		Format:     tar.FormatPAX,
+		PAXRecords: map[string]string{archiveExpirationRecord: strconv.FormatUint(expiration, 10)},
+	}
+	if err := a.tarWriter.WriteHeader(header); err != nil {
+		return err
+	}
+	if _, err := a.tarWriter.Write(data); err != nil {
+		return err
+	}
+	a.hasher.Write(data)
+	a.seen[key] = true
+	a.index.Entries = append(a.index.Entries, ArchiveEntry{key, expiration, uint64(len(data))})
+	return nil
+}
+
+func (a *ArchiveWriter) Len() int {
+	return len(a.index.Entries)
+}
+
+// Close writes the index and finishes the archive. It doesn't close the underlying writer.
+func (a *ArchiveWriter) Close() error {
+	a.index.SHA256 = hex.EncodeToString(a.hasher.Sum(nil))
+	indexBytes, err := json.MarshalIndent(a.index, "", "  ")
+	if err != nil {
+		return err
+	}
+	header := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     archiveIndexName,
+		Mode:     0o600,
+		Size:     int64(len(indexBytes)),
+		ModTime:  time.Unix(0, 0),
+		Format:   tar.FormatPAX,
+	}
+	if err := a.tarWriter.WriteHeader(header); err != nil {
+		return err
+	}
+	if _, err := a.tarWriter.Write(indexBytes); err != nil {
+		return err
+	}
+	return a.tarWriter.Close()
+}
+
+// ReadArchive streams the preimages in an archive to handle, checking each against its hash as it is read.
+// Once the whole archive is read it is checked against its index, and the index is returned.
+func ReadArchive(r io.Reader, handle func(data []byte, expiration uint64) error) (*ArchiveIndex, error) {
+	tarReader := tar.NewReader(r)
+	hasher := sha256.New()
+	read := []ArchiveEntry{}
+	var index *ArchiveIndex
+	for {
+		header, err := tarReader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if index != nil {
+			return nil, fmt.Errorf("unexpected archive entry %v after the index", header.Name)
+		}
+		contents, err := io.ReadAll(tarReader)
+		if err != nil {
+			return nil, err
+		}
+		if header.Name == archiveIndexName {
+			index = &ArchiveIndex{}
+			if err := json.Unmarshal(contents, index); err != nil {
+				return nil, fmt.Errorf("invalid archive index: %w", err)
+			}
+			continue
+		}
+		if !strings.HasPrefix(header.Name, archiveDataPrefix) {
+			return nil, fmt.Errorf("unexpected archive entry %v", header.Name)
+		}
+		key, err := DecodeStorageServiceKey(strings.TrimPrefix(header.Name, archiveDataPrefix))
+		if err != nil {
+			return nil, fmt.Errorf("invalid archive entry %v: %w", header.Name, err)
+		}
+		if !dastree.ValidHash(key, contents) {
+			return nil, fmt.Errorf("archive entry %v doesn't match its hash", header.Name)
+		}
+		expiration, err := strconv.ParseUint(header.PAXRecords[archiveExpirationRecord], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid expiration for archive entry %v: %w", header.Name, err)
+		}
+		hasher.Write(contents)
+		read = append(read, ArchiveEntry{key, expiration, uint64(len(contents))})
+		if err := handle(contents, expiration); err != nil {
+			return nil, err
+		}
+	}
+
+	if index == nil {
+		return nil, errors.New("archive has no index, it may be truncated")
+	}
+	if index.Version != archiveVersion {
+		return nil, fmt.Errorf("unsupported archive version %v", index.Version)
+	}
+	if len(index.Entries) != len(read) {
+		return nil, fmt.Errorf("archive index lists %v entries but %v were read", len(index.Entries), len(read))
+	}
+	for i, entry := range index.Entries {
+		if entry != read[i] {
+			return nil, fmt.Errorf("archive index entry %v doesn't match the archive contents", entry.Hash)
+		}
+	}
+	checksum, err := hex.DecodeString(index.SHA256)
+	if err != nil || !bytes.Equal(checksum, hasher.Sum(nil)) {
+		return nil, errors.New("archive checksum mismatch")
+	}
+	return index, nil
+}
+
+// ImportArchive stores every preimage in the archive into storageService. The whole archive is
+// checked against its index before anything is stored, so a truncated or corrupted archive leaves
+// storageService untouched. If r can't seek back to the start, the archive is spooled to a temporary
+// file while it's checked.
+func ImportArchive(ctx context.Context, r io.Reader, storageService StorageService) (*ArchiveIndex, error) {
+	seeker, ok := r.(io.ReadSeeker)
+	if !ok {
+		spool, err := os.CreateTemp("", "das-archive-")
+		if err != nil {
+			return nil, err
+		}
+		defer func() {
+			spool.Close()
+			os.Remove(spool.Name())
+		}()
+		r = io.TeeReader(r, spool)
+		seeker = spool
+	}
+	start, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = ReadArchive(r, func([]byte, uint64) error {
+		return ctx.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return ReadArchive(seeker, func(data []byte, expiration uint64) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return storageService.Put(ctx, data, expiration)
+	})
+}
+
+// ExportIterableStorage adds every preimage stored in source to the archive.
+func ExportIterableStorage(ctx context.Context, source *IterableStorageService, archive *ArchiveWriter) error {
+	end := source.End(ctx)
+	if (end == common.Hash{}) {
+		return nil
+	}
+	hash := source.DefaultBegin()
+	for hash != end {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		hash = source.Next(ctx, hash)
+		if (hash == common.Hash{}) {
+			return errors.New("storage iteration ended before reaching its end")
+		}
+		data, err := source.GetByHash(ctx, hash)
+		if errors.Is(err, ErrNotFound) {
+			// The data may have expired and been removed since it was iterated over.
+			log.Warn("Skipping missing data during export", "hash", hash)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		expiration, err := source.GetExpirationTime(ctx, hash)
+		if err != nil {
+			return err
+		}
+		if err := archive.Add(data, expiration); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ExportBatchesFromL1 adds the preimages of all DAS batches posted to the sequencer inbox in the
+// L1 block range [fromBlock, toBlock] to the archive. Preimages are fetched from dataSource, and
+// are given an expiration of the batch's max timestamp plus the retention period.
+func ExportBatchesFromL1(
+	ctx context.Context,
+	l1Client fogutil.L1Interface,
+	inboxAddr common.Address,
+	dataSource fogstate.DataAvailabilityReader,
+	fromBlock, toBlock, blocksPerRead uint64,
+	retentionPeriod time.Duration,
+	archive *ArchiveWriter,
+) error {
+	inboxContract, err := bridgegen.NewSequencerInbox(inboxAddr, l1Client)
+	if err != nil {
+		return err
+	}
+	// make sure any Keysets missing from dataSource will fetched from the L1 chain
+	dataSource, err = NewChainFetchReader(dataSource, l1Client, inboxAddr)
+	if err != nil {
+		return err
+	}
+	if blocksPerRead == 0 {
+		blocksPerRead = DefaultSyncToStorageConfig.L1BlocksPerRead
+	}
+	for low := fromBlock; low <= toBlock; low += blocksPerRead {
+		high := fogmath.MinInt(low+blocksPerRead-1, toBlock)
+		query := ethereum.FilterQuery{
+			FromBlock: new(big.Int).SetUint64(low),
+			ToBlock:   new(big.Int).SetUint64(high),
+			Addresses: []common.Address{inboxAddr},
+			Topics:    [][]common.Hash{{BatchDeliveredID}},
+		}
+		logs, err := l1Client.FilterLogs(ctx, query)
+		if err != nil {
+			return err
+		}
+		for _, deliveredLog := range logs {
+			deliveredEvent, err := inboxContract.ParseSequencerBatchDelivered(deliveredLog)
+			if err != nil {
+				return err
+			}
+			data, err := FindDASDataFromLog(ctx, inboxContract, deliveredEvent, inboxAddr, l1Client, deliveredLog)
+			if err != nil {
+				return err
+			}
+			if data == nil {
+				continue
+			}
+			preimages, err := RecoverDASPreimages(ctx, deliveredEvent, data, dataSource)
+			if err != nil {
+				return fmt.Errorf("failed to recover batch %v: %w", deliveredEvent.BatchSequenceNumber, err)
+			}
+			expiration := fogmath.SaturatingUAdd(deliveredEvent.TimeBounds.MaxTimestamp, uint64(retentionPeriod.Seconds()))
+			// Add the preimages in a fixed order so the same batches always produce the same archive.
+			keys := make([]common.Hash, 0, len(preimages))
+			for key := range preimages {
+				keys = append(keys, key)
+			}
+			sort.Slice(keys, func(i, j int) bool {
+				return bytes.Compare(keys[i].Bytes(), keys[j].Bytes()) < 0
+			})
+			for _, key := range keys {
+				if err := archive.Add(preimages[key], expiration); err != nil {
+					return err
+				}
+			}
+		}
+		log.Info("Exported DAS batches", "fromBlock", low, "toBlock", high, "entries", archive.Len())
+		if high == toBlock {
+			break
+		}
+	}
+	return nil
+}
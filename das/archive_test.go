@@ -0,0 +1,112 @@
+// Copyright 2022, Offchain Labs, Inc.
+// For license information, see https://github.com/fogr/blob/master/LICENSE
+
+package das
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/FOGRCC/fogr/das/dastree"
+	"github.com/FOGRCC/fogr/util/testhelpers"
+)
+
+func TestArchiveExportImport(t *testing.T) {
+	ctx := context.Background()
+	source := NewIterableStorageService(ConvertStorageServiceToIterationCompatibleStorageService(NewMemoryBackedStorageService(ctx)))
+
+	values := [][]byte{
+		[]byte("first value"),
+		testhelpers.RandomizeSlice(make([]byte, 2*dastree.BinSize+5)),
+		[]byte("third value"),
+	}
+	expiration := uint64(time.Now().Add(time.Hour).Unix())
+	for i, value := range values {
+		Require(t, source.Put(ctx, value, expiration+uint64(i)))
+	}
+
+	var archive bytes.Buffer
+	writer := NewArchiveWriter(&archive)
+	Require(t, ExportIterableStorage(ctx, source, writer))
+	Require(t, writer.Close())
+	if writer.Len() != len(values) {
+		Fail(t, "expected", len(values), "archive entries but got", writer.Len())
+	}
+
+	dest := NewMemoryBackedStorageService(ctx)
+	index, err := ImportArchive(ctx, bytes.NewReader(archive.Bytes()), dest)
+	Require(t, err)
+	for i, value := range values {
+		entry := index.Entries[i]
+		if entry.Hash != dastree.Hash(value) || entry.Expiration != expiration+uint64(i) || entry.Size != uint64(len(value)) {
+			Fail(t, "unexpected archive index entry", entry)
+		}
+		res, err := dest.GetByHash(ctx, dastree.Hash(value))
+		Require(t, err)
+		if !bytes.Equal(res, value) {
+			Fail(t, "imported data doesn't match")
+		}
+	}
+}
+
+func TestArchiveDetectsCorruption(t *testing.T) {
+	ctx := context.Background()
+	value := []byte("some batch data that will be corrupted")
+
+	var archive bytes.Buffer
+	writer := NewArchiveWriter(&archive)
+	Require(t, writer.Add(value, 0))
+	Require(t, writer.Close())
+
+	corrupted := bytes.Replace(archive.Bytes(), []byte("corrupted"), []byte("CORRUPTED"), 1)
+	_, err := ImportArchive(ctx, bytes.NewReader(corrupted), NewMemoryBackedStorageService(ctx))
+	if err == nil {
+		Fail(t, "expected corrupted archive to be rejected")
+	}
+
+	truncated := archive.Bytes()[:archive.Len()/2]
+	_, err = ImportArchive(ctx, bytes.NewReader(truncated), NewMemoryBackedStorageService(ctx))
+	if err == nil {
+		Fail(t, "expected truncated archive to be rejected")
+	}
+}
+
+func TestArchiveImportChecksBeforeStoring(t *testing.T) {
+	ctx := context.Background()
+	first := []byte("first value")
+	second := testhelpers.RandomizeSlice(make([]byte, 4096))
+
+	var archive bytes.Buffer
+	writer := NewArchiveWriter(&archive)
+	Require(t, writer.Add(first, 0))
+	Require(t, writer.Add(second, 0))
+	Require(t, writer.Close())
+
+	// The first entry is intact, but the archive is cut off before its index.
+	truncated := archive.Bytes()[:archive.Len()-2048]
+	for _, r := range []io.Reader{bytes.NewReader(truncated), struct{ io.Reader }{bytes.NewReader(truncated)}} {
+		dest := NewMemoryBackedStorageService(ctx)
+		if _, err := ImportArchive(ctx, r, dest); err == nil {
+			Fail(t, "expected truncated archive to be rejected")
+		}
+		if _, err := dest.GetByHash(ctx, dastree.Hash(first)); !errors.Is(err, ErrNotFound) {
+			Fail(t, "data was stored from a truncated archive", err)
+		}
+	}
+
+	// Archives that can't be read twice are spooled to disk, and import the same way.
+	dest := NewMemoryBackedStorageService(ctx)
+	_, err := ImportArchive(ctx, struct{ io.Reader }{bytes.NewReader(archive.Bytes())}, dest)
+	Require(t, err)
+	for _, value := range [][]byte{first, second} {
+		res, err := dest.GetByHash(ctx, dastree.Hash(value))
+		Require(t, err)
+		if !bytes.Equal(res, value) {
+			Fail(t, "imported data doesn't match")
+		}
+	}
+}
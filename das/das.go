@@ -48,6 +48,9 @@ type DataAvailabilityConfig struct {
 	S3StorageServiceConfig   S3StorageServiceConfig   `koanf:"s3-storage"`
 	IpfsStorageServiceConfig IpfsStorageServiceConfig `koanf:"ipfs-storage"`
 	RegularSyncStorageConfig RegularSyncStorageConfig `koanf:"regular-sync-storage"`
+	ErasureStorageConfig     ErasureStorageConfig     `koanf:"erasure-storage"`
+	GossipSyncConfig         GossipSyncConfig         `koanf:"gossip-sync"`
+	TieredStorageConfig      TieredStorageConfig      `koanf:"tiered-storage"`
 
 	KeyConfig KeyConfig `koanf:"key"`
 
@@ -67,6 +70,9 @@ var DefaultDataAvailabilityConfig = DataAvailabilityConfig{
 	RequestTimeout:                5 * time.Second,
 	Enable:                        false,
 	RestfulClientAggregatorConfig: DefaultRestfulClientAggregatorConfig,
+	ErasureStorageConfig:          DefaultErasureStorageConfig,
+	GossipSyncConfig:              DefaultGossipSyncConfig,
+	TieredStorageConfig:           DefaultTieredStorageConfig,
 	L1ConnectionAttempts:          15,
 	PanicOnError:                  false,
 }
@@ -116,6 +122,9 @@ func dataAvailabilityConfigAddOptions(prefix string, f *flag.FlagSet, r role) {
 		LocalFileStorageConfigAddOptions(prefix+".local-file-storage", f)
 		S3ConfigAddOptions(prefix+".s3-storage", f)
 		RegularSyncStorageConfigAddOptions(prefix+".regular-sync-storage", f)
+		ErasureStorageConfigAddOptions(prefix+".erasure-storage", f)
+		GossipSyncConfigAddOptions(prefix+".gossip-sync", f)
+		TieredStorageConfigAddOptions(prefix+".tiered-storage", f)
 
 		// Key config for storage
 		KeyConfigAddOptions(prefix+".key", f)
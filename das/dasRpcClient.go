@@ -4,6 +4,7 @@
 package das
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"time"
@@ -19,8 +20,9 @@ import (
 )
 
 type DASRPCClient struct { // implements DataAvailabilityService
-	clnt *rpc.Client
-	url  string
+	clnt   *rpc.Client
+	url    string
+	signer []byte
 }
 
 func NewDASRPCClient(target string) (*DASRPCClient, error) {
@@ -34,13 +36,31 @@ func NewDASRPCClient(target string) (*DASRPCClient, error) {
 	}, nil
 }
 
+// NewDASRPCClientForSigner creates a client that expects certificates signed with pubKey. If the
+// server signs with several keys while rotating them, the signature from pubKey is used.
+func NewDASRPCClientForSigner(target string, pubKey blsSignatures.PublicKey) (*DASRPCClient, error) {
+	client, err := NewDASRPCClient(target)
+	if err != nil {
+		return nil, err
+	}
+	client.signer = blsSignatures.PublicKeyToBytes(pubKey)
+	return client, nil
+}
+
 func (c *DASRPCClient) Store(ctx context.Context, message []byte, timeout uint64, reqSig []byte) (*fogstate.DataAvailabilityCertificate, error) {
 	log.Trace("das.DASRPCClient.Store(...)", "message", pretty.FirstFewBytes(message), "timeout", time.Unix(int64(timeout), 0), "sig", pretty.FirstFewBytes(reqSig), "this", *c)
 	var ret StoreResult
 	if err := c.clnt.CallContext(ctx, &ret, "das_store", hexutil.Bytes(message), hexutil.Uint64(timeout), hexutil.Bytes(reqSig)); err != nil {
 		return nil, err
 	}
-	respSig, err := blsSignatures.SignatureFromBytes(ret.Sig)
+	sigBytes := ret.Sig
+	for _, extraSig := range ret.ExtraSigs {
+		if c.signer != nil && bytes.Equal(extraSig.PubKey, c.signer) {
+			sigBytes = extraSig.Sig
+			break
+		}
+	}
+	respSig, err := blsSignatures.SignatureFromBytes(sigBytes)
 	if err != nil {
 		return nil, err
 	}
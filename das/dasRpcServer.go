@@ -18,6 +18,7 @@ import (
 
 	"github.com/FOGRCC/fogr/blsSignatures"
 	"github.com/FOGRCC/fogr/cmd/genericconf"
+	"github.com/FOGRCC/fogr/fogstate"
 	"github.com/FOGRCC/fogr/util/pretty"
 )
 
@@ -35,15 +36,25 @@ type DASRPCServer struct {
 	daHealthChecker DataAvailabilityServiceHealthChecker
 }
 
-func StartDASRPCServer(ctx context.Context, addr string, portNum uint64, rpcServerTimeouts genericconf.HTTPServerTimeoutConfig, daReader DataAvailabilityServiceReader, daWriter DataAvailabilityServiceWriter, daHealthChecker DataAvailabilityServiceHealthChecker) (*http.Server, error) {
+func StartDASRPCServer(ctx context.Context, addr string, portNum uint64, rpcServerTimeouts genericconf.HTTPServerTimeoutConfig, rateLimitConfig RateLimitConfig, daReader DataAvailabilityServiceReader, daWriter DataAvailabilityServiceWriter, daHealthChecker DataAvailabilityServiceHealthChecker) (*http.Server, error) {
+	rateLimiter, err := NewRateLimiter(rateLimitConfig, "fogr/das/rpc")
+	if err != nil {
+		return nil, err
+	}
 	listener, err := net.Listen("tcp", fmt.Sprintf("%s:%d", addr, portNum))
 	if err != nil {
 		return nil, err
 	}
-	return StartDASRPCServerOnListener(ctx, listener, rpcServerTimeouts, daReader, daWriter, daHealthChecker)
+	return StartDASRPCServerOnListenerWithRateLimiter(ctx, listener, rpcServerTimeouts, rateLimiter, daReader, daWriter, daHealthChecker)
 }
 
 func StartDASRPCServerOnListener(ctx context.Context, listener net.Listener, rpcServerTimeouts genericconf.HTTPServerTimeoutConfig, daReader DataAvailabilityServiceReader, daWriter DataAvailabilityServiceWriter, daHealthChecker DataAvailabilityServiceHealthChecker) (*http.Server, error) {
+	return StartDASRPCServerOnListenerWithRateLimiter(ctx, listener, rpcServerTimeouts, nil, daReader, daWriter, daHealthChecker)
+}
+
+// StartDASRPCServerOnListenerWithRateLimiter starts a server that rejects requests over the rateLimiter's
+// limits with a JSON-RPC error and a 429 status. A nil rateLimiter doesn't limit requests.
+func StartDASRPCServerOnListenerWithRateLimiter(ctx context.Context, listener net.Listener, rpcServerTimeouts genericconf.HTTPServerTimeoutConfig, rateLimiter *RateLimiter, daReader DataAvailabilityServiceReader, daWriter DataAvailabilityServiceWriter, daHealthChecker DataAvailabilityServiceHealthChecker) (*http.Server, error) {
 	rpcServer := rpc.NewServer()
 	err := rpcServer.RegisterName("das", &DASRPCServer{
 		daReader:        daReader,
@@ -55,7 +66,7 @@ func StartDASRPCServerOnListener(ctx context.Context, listener net.Listener, rpc
 	}
 
 	srv := &http.Server{
-		Handler:           rpcServer,
+		Handler:           rateLimiter.wrap(rpcServer, writeJSONRPCRateLimitError),
 		ReadTimeout:       rpcServerTimeouts.ReadTimeout,
 		ReadHeaderTimeout: rpcServerTimeouts.ReadHeaderTimeout,
 		WriteTimeout:      rpcServerTimeouts.WriteTimeout,
@@ -82,6 +93,19 @@ type StoreResult struct {
 	KeysetHash  hexutil.Bytes  `json:"keysetHash,omitempty"`
 	Sig         hexutil.Bytes  `json:"sig,omitempty"`
 	Version     hexutil.Uint64 `json:"version,omitempty"`
+
+	// Signatures made with the DAS's extra keys while it rotates keys.
+	ExtraSigs []StoreResultSig `json:"extraSigs,omitempty"`
+}
+
+type StoreResultSig struct {
+	PubKey hexutil.Bytes `json:"pubKey"`
+	Sig    hexutil.Bytes `json:"sig"`
+}
+
+// extraSigner is implemented by DAS writers that can sign certificates with more than one key.
+type extraSigner interface {
+	ExtraSignatures(c *fogstate.DataAvailabilityCertificate) ([]KeyedSignature, error)
 }
 
 func (serv *DASRPCServer) Store(ctx context.Context, message hexutil.Bytes, timeout hexutil.Uint64, sig hexutil.Bytes) (*StoreResult, error) {
@@ -102,6 +126,19 @@ func (serv *DASRPCServer) Store(ctx context.Context, message hexutil.Bytes, time
 	if err != nil {
 		return nil, err
 	}
+	var extraSigs []StoreResultSig
+	if signer, ok := serv.daWriter.(extraSigner); ok {
+		keyedSigs, err := signer.ExtraSignatures(cert)
+		if err != nil {
+			return nil, err
+		}
+		for _, keyedSig := range keyedSigs {
+			extraSigs = append(extraSigs, StoreResultSig{
+				PubKey: blsSignatures.PublicKeyToBytes(keyedSig.PubKey),
+				Sig:    blsSignatures.SignatureToBytes(keyedSig.Sig),
+			})
+		}
+	}
 	rpcStoreStoredBytesGauge.Inc(int64(len(message)))
 	success = true
 	return &StoreResult{
@@ -111,6 +148,7 @@ func (serv *DASRPCServer) Store(ctx context.Context, message hexutil.Bytes, time
 		SignersMask: hexutil.Uint64(cert.SignersMask),
 		Sig:         blsSignatures.SignatureToBytes(cert.Sig),
 		Version:     hexutil.Uint64(cert.Version),
+		ExtraSigs:   extraSigs,
 	}, nil
 }
 
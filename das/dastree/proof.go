@@ -0,0 +1,113 @@
+// Copyright 2022, Offchain Labs, Inc.
+// For license information, see https://github.com/fogr/blob/master/LICENSE
+
+package dastree
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/FOGRCC/fogr/util/fogmath"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// A ProofNode is a sibling on the path from a leaf to the root of a dastree,
+// along with the number of preimage bytes under it.
+type ProofNode struct {
+	Hash bytes32 `json:"hash"`
+	Size uint32  `json:"size"`
+	Left bool    `json:"left"` // whether the sibling is hashed to the left of the path
+}
+
+func leafHash(bin []byte) bytes32 {
+	return crypto.Keccak256Hash(append([]byte{LeafByte}, crypto.Keccak256(bin)...))
+}
+
+func parentHash(first, other node) node {
+	sizeUnder := first.size + other.size
+	dataUnder := fogmath.ConcatByteSlices(first.hash.Bytes(), other.hash.Bytes(), fogmath.Uint32ToBytes(sizeUnder))
+	return node{crypto.Keccak256Hash(append([]byte{NodeByte}, dataUnder...)), sizeUnder}
+}
+
+// BinCount returns the number of 64kB bins a preimage of the given size is split into.
+func BinCount(size int) int {
+	if size == 0 {
+		return 1
+	}
+	return (size + BinSize - 1) / BinSize
+}
+
+// Prove returns the bin'th 64kB bin of the preimage along with the sibling hashes, ordered
+// from the leaf upwards, needed to recompute the preimage's dastree root from it.
+func Prove(preimage []byte, bin int) ([]byte, []ProofNode, error) {
+	if bin < 0 || bin >= BinCount(len(preimage)) {
+		return nil, nil, fmt.Errorf("bin %v out of range for preimage of size %v", bin, len(preimage))
+	}
+	length := uint32(len(preimage))
+	leaves := []node{}
+	for start := uint32(0); start < length || len(leaves) == 0; start += BinSize {
+		end := fogmath.MinInt(start+BinSize, length)
+		leaves = append(leaves, node{leafHash(preimage[start:end]), end - start})
+	}
+
+	proof := []ProofNode{}
+	position := bin
+	layer := leaves
+	for len(layer) > 1 {
+		prior := len(layer)
+		after := prior/2 + prior%2
+		paired := make([]node, after)
+		for i := 0; i < prior-1; i += 2 {
+			paired[i/2] = parentHash(layer[i], layer[i+1])
+		}
+		if prior%2 == 1 {
+			paired[after-1] = layer[prior-1]
+		}
+
+		if position%2 == 1 {
+			sibling := layer[position-1]
+			proof = append(proof, ProofNode{sibling.hash, sibling.size, true})
+		} else if position+1 < prior {
+			sibling := layer[position+1]
+			proof = append(proof, ProofNode{sibling.hash, sibling.size, false})
+		}
+		// otherwise this is the odd one out, and bubbles up to the next layer unchanged
+		position /= 2
+		layer = paired
+	}
+
+	start := uint32(bin) * BinSize
+	end := fogmath.MinInt(start+BinSize, length)
+	return preimage[start:end], proof, nil
+}
+
+// VerifyProof checks that the bin is part of the preimage under root, returning the offset of the
+// bin in the preimage and the preimage's total size.
+func VerifyProof(root bytes32, bin []byte, proof []ProofNode) (uint32, uint32, error) {
+	if len(bin) > BinSize {
+		return 0, 0, fmt.Errorf("bin of size %v is larger than %v", len(bin), BinSize)
+	}
+	current := node{leafHash(bin), uint32(len(bin))}
+	offset := uint32(0)
+	for _, sibling := range proof {
+		if sibling.Size == 0 {
+			return 0, 0, errors.New("proof contains an empty sibling")
+		}
+		if current.size+sibling.Size < current.size {
+			return 0, 0, errors.New("proof sizes overflow")
+		}
+		if sibling.Left {
+			offset += sibling.Size
+			current = parentHash(node{sibling.Hash, sibling.Size}, current)
+		} else {
+			current = parentHash(current, node{sibling.Hash, sibling.Size})
+		}
+	}
+	if fogmath.FlipBit(current.hash, 0) != root {
+		return 0, 0, errors.New("proof doesn't match root")
+	}
+	if offset%BinSize != 0 || (len(bin) < BinSize && offset+uint32(len(bin)) != current.size) {
+		return 0, 0, errors.New("proof places bin at a non-canonical position")
+	}
+	return offset, current.size, nil
+}
@@ -0,0 +1,61 @@
+// Copyright 2022, Offchain Labs, Inc.
+// For license information, see https://github.com/fogr/blob/master/LICENSE
+
+package dastree
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+
+	"github.com/FOGRCC/fogr/util/testhelpers"
+)
+
+func TestProofs(t *testing.T) {
+	sizes := []int{0, 1, BinSize - 1, BinSize, BinSize + 1, 3 * BinSize, 5*BinSize + 7}
+	for i := 0; i < 16; i++ {
+		sizes = append(sizes, rand.Intn(12*BinSize))
+	}
+	for _, size := range sizes {
+		preimage := testhelpers.RandomizeSlice(make([]byte, size))
+		root := Hash(preimage)
+		for bin := 0; bin < BinCount(size); bin++ {
+			chunk, proof, err := Prove(preimage, bin)
+			Require(t, err, size, bin)
+			offset, total, err := VerifyProof(root, chunk, proof)
+			Require(t, err, size, bin)
+			if offset != uint32(bin*BinSize) || total != uint32(size) {
+				Fail(t, "unexpected offset or size", offset, total, size, bin)
+			}
+			if !bytes.Equal(chunk, preimage[offset:offset+uint32(len(chunk))]) {
+				Fail(t, "proved chunk doesn't match preimage", size, bin)
+			}
+		}
+		if _, _, err := Prove(preimage, BinCount(size)); err == nil {
+			Fail(t, "expected out of range bin to fail", size)
+		}
+	}
+}
+
+func TestProofTampering(t *testing.T) {
+	preimage := testhelpers.RandomizeSlice(make([]byte, 4*BinSize+100))
+	root := Hash(preimage)
+	chunk, proof, err := Prove(preimage, 2)
+	Require(t, err)
+
+	tamperedChunk := append([]byte{}, chunk...)
+	tamperedChunk[0] ^= 1
+	if _, _, err := VerifyProof(root, tamperedChunk, proof); err == nil {
+		Fail(t, "tampered chunk verified")
+	}
+
+	tamperedProof := append([]ProofNode{}, proof...)
+	tamperedProof[0].Size++
+	if _, _, err := VerifyProof(root, chunk, tamperedProof); err == nil {
+		Fail(t, "tampered proof verified")
+	}
+
+	if _, _, err := VerifyProof(Hash([]byte("other")), chunk, proof); err == nil {
+		Fail(t, "proof verified against the wrong root")
+	}
+}
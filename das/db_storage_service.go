@@ -6,6 +6,7 @@ package das
 import (
 	"bytes"
 	"context"
+	"encoding/binary"
 	"errors"
 	"time"
 
@@ -20,19 +21,23 @@ import (
 )
 
 type LocalDBStorageConfig struct {
-	Enable                  bool   `koanf:"enable"`
-	DataDir                 string `koanf:"data-dir"`
-	DiscardAfterTimeout     bool   `koanf:"discard-after-timeout"`
-	SyncFromStorageServices bool   `koanf:"sync-from-storage-service"`
-	SyncToStorageServices   bool   `koanf:"sync-to-storage-service"`
+	Enable                  bool                `koanf:"enable"`
+	DataDir                 string              `koanf:"data-dir"`
+	DiscardAfterTimeout     bool                `koanf:"discard-after-timeout"`
+	ExpirySweeper           ExpirySweeperConfig `koanf:"expiry-sweeper"`
+	SyncFromStorageServices bool                `koanf:"sync-from-storage-service"`
+	SyncToStorageServices   bool                `koanf:"sync-to-storage-service"`
 }
 
-var DefaultLocalDBStorageConfig = LocalDBStorageConfig{}
+var DefaultLocalDBStorageConfig = LocalDBStorageConfig{
+	ExpirySweeper: DefaultExpirySweeperConfig,
+}
 
 func LocalDBStorageConfigAddOptions(prefix string, f *flag.FlagSet) {
 	f.Bool(prefix+".enable", DefaultLocalDBStorageConfig.Enable, "enable storage/retrieval of sequencer batch data from a database on the local filesystem")
 	f.String(prefix+".data-dir", DefaultLocalDBStorageConfig.DataDir, "directory in which to store the database")
 	f.Bool(prefix+".discard-after-timeout", DefaultLocalDBStorageConfig.DiscardAfterTimeout, "discard data after its expiry timeout")
+	ExpirySweeperConfigAddOptions(prefix+".expiry-sweeper", f)
 	f.Bool(prefix+".sync-from-storage-service", DefaultLocalDBStorageConfig.SyncFromStorageServices, "enable db storage to be used as a source for regular sync storage")
 	f.Bool(prefix+".sync-to-storage-service", DefaultLocalDBStorageConfig.SyncToStorageServices, "enable db storage to be used as a sink for regular sync storage")
 }
@@ -44,16 +49,33 @@ type DBStorageService struct {
 	stopWaiter          stopwaiter.StopWaiterSafe
 }
 
-func NewDBStorageService(ctx context.Context, dirPath string, discardAfterTimeout bool) (StorageService, error) {
-	db, err := badger.Open(badger.DefaultOptions(dirPath))
+// Expiration times of stored data are indexed under dbExpiryIndexPrefix followed by the big-endian
+// expiration and the key, so that iterating over the prefix yields them in expiration order.
+// The latest expiration requested for each key is kept under dbExpiryLatestPrefix.
+var dbExpiryIndexPrefix = []byte("expiry_index_")
+var dbExpiryLatestPrefix = []byte("expiry_latest_")
+
+func dbExpiryIndexKey(expiration uint64, key common.Hash) []byte {
+	indexKey := make([]byte, len(dbExpiryIndexPrefix)+8, len(dbExpiryIndexPrefix)+8+32)
+	copy(indexKey, dbExpiryIndexPrefix)
+	binary.BigEndian.PutUint64(indexKey[len(dbExpiryIndexPrefix):], expiration)
+	return append(indexKey, key.Bytes()...)
+}
+
+func dbExpiryLatestKey(key common.Hash) []byte {
+	return append(append([]byte{}, dbExpiryLatestPrefix...), key.Bytes()...)
+}
+
+func NewDBStorageService(ctx context.Context, config LocalDBStorageConfig) (StorageService, error) {
+	db, err := badger.Open(badger.DefaultOptions(config.DataDir))
 	if err != nil {
 		return nil, err
 	}
 
 	ret := &DBStorageService{
 		db:                  db,
-		discardAfterTimeout: discardAfterTimeout,
-		dirPath:             dirPath,
+		discardAfterTimeout: config.DiscardAfterTimeout,
+		dirPath:             config.DataDir,
 	}
 	if err := ret.stopWaiter.Start(ctx, ret); err != nil {
 		return nil, err
@@ -84,6 +106,12 @@ func NewDBStorageService(ctx context.Context, dirPath string, discardAfterTimeou
 	if err != nil {
 		return nil, err
 	}
+	if config.DiscardAfterTimeout {
+		sweeper := newExpirySweeper(config.ExpirySweeper, ret)
+		if err := ret.stopWaiter.CallIteratively(sweeper.sweep); err != nil {
+			return nil, err
+		}
+	}
 
 	return ret, nil
 }
@@ -110,13 +138,38 @@ func (dbs *DBStorageService) GetByHash(ctx context.Context, key common.Hash) ([]
 
 func (dbs *DBStorageService) Put(ctx context.Context, data []byte, timeout uint64) error {
 	logPut("das.DBStorageService.Put", data, timeout, dbs)
+	return dbs.putWithExpiration(ctx, dastree.Hash(data), data, timeout)
+}
 
+func (dbs *DBStorageService) putWithExpiration(ctx context.Context, key common.Hash, value []byte, timeout uint64) error {
 	return dbs.db.Update(func(txn *badger.Txn) error {
-		e := badger.NewEntry(dastree.HashBytes(data), data)
-		if dbs.discardAfterTimeout {
-			e = e.WithTTL(time.Until(time.Unix(int64(timeout), 0)))
+		if err := txn.SetEntry(badger.NewEntry(key.Bytes(), value)); err != nil {
+			return err
 		}
-		return txn.SetEntry(e)
+		if !dbs.discardAfterTimeout || !shouldIndex(timeout) {
+			return nil
+		}
+		// Expired data is deleted by the expiry sweeper once its grace period has also passed.
+		latestKey := dbExpiryLatestKey(key)
+		item, err := txn.Get(latestKey)
+		if err == nil {
+			var stillLive bool
+			err = item.Value(func(val []byte) error {
+				stillLive = len(val) == 8 && binary.BigEndian.Uint64(val) >= timeout
+				return nil
+			})
+			if err != nil || stillLive {
+				return err
+			}
+		} else if !errors.Is(err, badger.ErrKeyNotFound) {
+			return err
+		}
+		var expiration [8]byte
+		binary.BigEndian.PutUint64(expiration[:], timeout)
+		if err := txn.Set(latestKey, expiration[:]); err != nil {
+			return err
+		}
+		return txn.Set(dbExpiryIndexKey(timeout, key), []byte{})
 	})
 }
 
@@ -149,7 +202,8 @@ func (dbs *DBStorageService) String() string {
 
 func (dbs *DBStorageService) HealthCheck(ctx context.Context) error {
 	testData := []byte("Test-Data")
-	err := dbs.Put(ctx, testData, uint64(time.Now().Add(time.Minute).Unix()))
+	// Written without an expiration so the health check doesn't add to the expiry index.
+	err := dbs.putKeyValue(ctx, dastree.Hash(testData), testData)
 	if err != nil {
 		return err
 	}
@@ -162,3 +216,199 @@ func (dbs *DBStorageService) HealthCheck(ctx context.Context) error {
 	}
 	return nil
 }
+
+func (dbs *DBStorageService) expiredEntries(cutoff uint64, limit int) ([]expiryEntry, error) {
+	entries := []expiryEntry{}
+	err := dbs.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+		opts.Prefix = dbExpiryIndexPrefix
+		it := txn.NewIterator(opts)
+		defer it.Close()
+		for it.Rewind(); it.Valid() && len(entries) < limit; it.Next() {
+			indexKey := it.Item().Key()[len(dbExpiryIndexPrefix):]
+			if len(indexKey) != 8+32 {
+				log.Warn("Unexpected key in DAS expiry index", "key", it.Item().Key())
+				continue
+			}
+			expiration := binary.BigEndian.Uint64(indexKey)
+			if expiration > cutoff {
+				break
+			}
+			entries = append(entries, expiryEntry{expiration, common.BytesToHash(indexKey[8:])})
+		}
+		return nil
+	})
+	return entries, err
+}
+
+func (dbs *DBStorageService) deleteExpired(entries []expiryEntry) (uint64, error) {
+	var reclaimed uint64
+	txn := dbs.db.NewTransaction(true)
+	defer func() {
+		txn.Discard()
+	}()
+	for _, entry := range entries {
+		size, err := deleteExpiredEntry(txn, entry)
+		if errors.Is(err, badger.ErrTxnTooBig) {
+			// Commit what fits and delete the entry in a new transaction
+			if err := txn.Commit(); err != nil {
+				return reclaimed, err
+			}
+			txn = dbs.db.NewTransaction(true)
+			size, err = deleteExpiredEntry(txn, entry)
+		}
+		if err != nil {
+			return reclaimed, err
+		}
+		reclaimed += size
+	}
+	if err := txn.Commit(); err != nil {
+		return reclaimed, err
+	}
+	return reclaimed, nil
+}
+
+// deleteExpiredEntry deletes an expired entry's index key, and its data unless it was stored again with a later expiration.
+// It returns the size of the data deleted.
+func deleteExpiredEntry(txn *badger.Txn, entry expiryEntry) (uint64, error) {
+	var reclaimed uint64
+	latestKey := dbExpiryLatestKey(entry.key)
+	var stillLive bool
+	item, err := txn.Get(latestKey)
+	if err == nil {
+		err = item.Value(func(val []byte) error {
+			stillLive = len(val) == 8 && binary.BigEndian.Uint64(val) > entry.expiration
+			return nil
+		})
+		if err != nil {
+			return 0, err
+		}
+	} else if !errors.Is(err, badger.ErrKeyNotFound) {
+		return 0, err
+	}
+	if !stillLive {
+		item, err := txn.Get(entry.key.Bytes())
+		if err == nil {
+			reclaimed = uint64(item.ValueSize())
+			if err := txn.Delete(entry.key.Bytes()); err != nil {
+				return 0, err
+			}
+		} else if !errors.Is(err, badger.ErrKeyNotFound) {
+			return 0, err
+		}
+		if err := txn.Delete(latestKey); err != nil {
+			return 0, err
+		}
+	}
+	if err := txn.Delete(dbExpiryIndexKey(entry.expiration, entry.key)); err != nil {
+		return 0, err
+	}
+	return reclaimed, nil
+}
+
+// When the database is the hot tier of a TieredStorageService, the time each key was stored is
+// indexed under dbTierIndexPrefix followed by the big-endian store time and the key, with the
+// data's expiration and whether the cold tier already has a copy as the value. The latest store
+// time of each key is kept under dbTierLatestPrefix.
+var dbTierIndexPrefix = []byte("tier_index_")
+var dbTierLatestPrefix = []byte("tier_latest_")
+
+func dbTierIndexKey(storedAt uint64, key common.Hash) []byte {
+	indexKey := make([]byte, len(dbTierIndexPrefix)+8, len(dbTierIndexPrefix)+8+32)
+	copy(indexKey, dbTierIndexPrefix)
+	binary.BigEndian.PutUint64(indexKey[len(dbTierIndexPrefix):], storedAt)
+	return append(indexKey, key.Bytes()...)
+}
+
+func dbTierLatestKey(key common.Hash) []byte {
+	return append(append([]byte{}, dbTierLatestPrefix...), key.Bytes()...)
+}
+
+func (dbs *DBStorageService) putHot(ctx context.Context, entry hotTierEntry, data []byte) error {
+	return dbs.db.Update(func(txn *badger.Txn) error {
+		if err := txn.SetEntry(badger.NewEntry(entry.key.Bytes(), data)); err != nil {
+			return err
+		}
+		var storedAt [8]byte
+		binary.BigEndian.PutUint64(storedAt[:], entry.storedAt)
+		if err := txn.Set(dbTierLatestKey(entry.key), storedAt[:]); err != nil {
+			return err
+		}
+		value := make([]byte, 9)
+		binary.BigEndian.PutUint64(value, entry.expiration)
+		if entry.inCold {
+			value[8] = 1
+		}
+		return txn.Set(dbTierIndexKey(entry.storedAt, entry.key), value)
+	})
+}
+
+func (dbs *DBStorageService) demotionCandidates(ctx context.Context, cutoff uint64, limit int) ([]hotTierEntry, error) {
+	entries := []hotTierEntry{}
+	err := dbs.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = dbTierIndexPrefix
+		it := txn.NewIterator(opts)
+		defer it.Close()
+		for it.Rewind(); it.Valid() && len(entries) < limit; it.Next() {
+			indexKey := it.Item().Key()[len(dbTierIndexPrefix):]
+			if len(indexKey) != 8+32 {
+				log.Warn("Unexpected key in DAS tier index", "key", it.Item().Key())
+				continue
+			}
+			storedAt := binary.BigEndian.Uint64(indexKey)
+			if storedAt > cutoff {
+				break
+			}
+			entry := hotTierEntry{storedAt: storedAt, key: common.BytesToHash(indexKey[8:])}
+			err := it.Item().Value(func(val []byte) error {
+				if len(val) != 9 {
+					return errors.New("invalid DAS tier index entry")
+				}
+				entry.expiration = binary.BigEndian.Uint64(val)
+				entry.inCold = val[8] == 1
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			entries = append(entries, entry)
+		}
+		return nil
+	})
+	return entries, err
+}
+
+func (dbs *DBStorageService) removeHot(ctx context.Context, entries []hotTierEntry) error {
+	return dbs.db.Update(func(txn *badger.Txn) error {
+		for _, entry := range entries {
+			latestKey := dbTierLatestKey(entry.key)
+			var storedAgain bool
+			item, err := txn.Get(latestKey)
+			if err == nil {
+				err = item.Value(func(val []byte) error {
+					storedAgain = len(val) == 8 && binary.BigEndian.Uint64(val) > entry.storedAt
+					return nil
+				})
+				if err != nil {
+					return err
+				}
+			} else if !errors.Is(err, badger.ErrKeyNotFound) {
+				return err
+			}
+			if !storedAgain {
+				if err := txn.Delete(entry.key.Bytes()); err != nil {
+					return err
+				}
+				if err := txn.Delete(latestKey); err != nil {
+					return err
+				}
+			}
+			if err := txn.Delete(dbTierIndexKey(entry.storedAt, entry.key)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,214 @@
+// Copyright 2022, Offchain Labs, Inc.
+// For license information, see https://github.com/fogr/blob/master/LICENSE
+
+package erasure
+
+import (
+	"errors"
+	"fmt"
+)
+
+// MaxShards is the largest number of shards a systematic Reed-Solomon code over GF(2^8) can have.
+const MaxShards = 256
+
+var ErrTooFewShards = errors.New("too few shards to reconstruct data")
+
+// Coder is a systematic Reed-Solomon erasure coder over GF(2^8).
+// Data is split into k data shards, and n-k parity shards are computed such that
+// the original data can be recovered from any k of the n shards.
+type Coder struct {
+	dataShards   int
+	parityShards int
+	matrix       [][]byte // n x k, the top k rows form the identity matrix
+}
+
+func NewCoder(dataShards, parityShards int) (*Coder, error) {
+	if dataShards <= 0 {
+		return nil, fmt.Errorf("invalid number of data shards %v", dataShards)
+	}
+	if parityShards < 0 {
+		return nil, fmt.Errorf("invalid number of parity shards %v", parityShards)
+	}
+	if dataShards+parityShards > MaxShards {
+		return nil, fmt.Errorf("too many shards %v, the maximum is %v", dataShards+parityShards, MaxShards)
+	}
+	matrix, err := buildEncodingMatrix(dataShards+parityShards, dataShards)
+	if err != nil {
+		return nil, err
+	}
+	return &Coder{
+		dataShards:   dataShards,
+		parityShards: parityShards,
+		matrix:       matrix,
+	}, nil
+}
+
+func (c *Coder) DataShards() int {
+	return c.dataShards
+}
+
+func (c *Coder) ParityShards() int {
+	return c.parityShards
+}
+
+func (c *Coder) TotalShards() int {
+	return c.dataShards + c.parityShards
+}
+
+// ShardSize returns the size of each shard produced when encoding size bytes of data.
+func (c *Coder) ShardSize(size int) int {
+	return (size + c.dataShards - 1) / c.dataShards
+}
+
+// Encode splits data into k equally sized (zero padded) data shards followed by n-k parity shards.
+func (c *Coder) Encode(data []byte) [][]byte {
+	shardSize := c.ShardSize(len(data))
+	padded := make([]byte, shardSize*c.TotalShards())
+	copy(padded, data)
+
+	shards := make([][]byte, c.TotalShards())
+	for i := range shards {
+		shards[i] = padded[i*shardSize : (i+1)*shardSize]
+	}
+	for i := 0; i < c.parityShards; i++ {
+		row := c.matrix[c.dataShards+i]
+		parity := shards[c.dataShards+i]
+		for j := 0; j < c.dataShards; j++ {
+			mulAddSlice(row[j], shards[j], parity)
+		}
+	}
+	return shards
+}
+
+// Decode reconstructs the original size bytes of data from the shards.
+// Missing shards must be nil, and at least k shards must be present.
+func (c *Coder) Decode(shards [][]byte, size int) ([]byte, error) {
+	if len(shards) != c.TotalShards() {
+		return nil, fmt.Errorf("expected %v shards but got %v", c.TotalShards(), len(shards))
+	}
+	shardSize := c.ShardSize(size)
+	present := make([]int, 0, c.dataShards)
+	for i, shard := range shards {
+		if shard == nil {
+			continue
+		}
+		if len(shard) != shardSize {
+			return nil, fmt.Errorf("shard %v has size %v but expected %v", i, len(shard), shardSize)
+		}
+		if len(present) < c.dataShards {
+			present = append(present, i)
+		}
+	}
+	if len(present) < c.dataShards {
+		return nil, ErrTooFewShards
+	}
+
+	data := make([]byte, shardSize*c.dataShards)
+	allData := true
+	for i, index := range present {
+		if index != i {
+			allData = false
+			break
+		}
+	}
+	if allData {
+		for i := 0; i < c.dataShards; i++ {
+			copy(data[i*shardSize:], shards[i])
+		}
+		return data[:size], nil
+	}
+
+	sub := make([][]byte, c.dataShards)
+	for i, index := range present {
+		sub[i] = append([]byte{}, c.matrix[index]...)
+	}
+	inverse, err := invertMatrix(sub)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < c.dataShards; i++ {
+		out := data[i*shardSize : (i+1)*shardSize]
+		for j, index := range present {
+			mulAddSlice(inverse[i][j], shards[index], out)
+		}
+	}
+	return data[:size], nil
+}
+
+// buildEncodingMatrix creates an n x k Vandermonde matrix and normalizes it so the top k rows form
+// the identity, which makes the code systematic while keeping every k x k submatrix invertible.
+func buildEncodingMatrix(rows, cols int) ([][]byte, error) {
+	vandermonde := make([][]byte, rows)
+	for r := 0; r < rows; r++ {
+		vandermonde[r] = make([]byte, cols)
+		for c := 0; c < cols; c++ {
+			vandermonde[r][c] = gfExp(byte(r), c)
+		}
+	}
+	top := make([][]byte, cols)
+	for r := 0; r < cols; r++ {
+		top[r] = append([]byte{}, vandermonde[r]...)
+	}
+	topInverse, err := invertMatrix(top)
+	if err != nil {
+		return nil, err
+	}
+	return multiplyMatrices(vandermonde, topInverse), nil
+}
+
+func multiplyMatrices(left, right [][]byte) [][]byte {
+	result := make([][]byte, len(left))
+	for r := range left {
+		result[r] = make([]byte, len(right[0]))
+		for c := range right[0] {
+			var value byte
+			for i := range right {
+				value ^= gfMul(left[r][i], right[i][c])
+			}
+			result[r][c] = value
+		}
+	}
+	return result
+}
+
+// invertMatrix inverts a square matrix using Gauss-Jordan elimination. The input is destroyed.
+func invertMatrix(matrix [][]byte) ([][]byte, error) {
+	size := len(matrix)
+	inverse := make([][]byte, size)
+	for i := range inverse {
+		inverse[i] = make([]byte, size)
+		inverse[i][i] = 1
+	}
+	for col := 0; col < size; col++ {
+		if matrix[col][col] == 0 {
+			swapped := false
+			for r := col + 1; r < size; r++ {
+				if matrix[r][col] != 0 {
+					matrix[col], matrix[r] = matrix[r], matrix[col]
+					inverse[col], inverse[r] = inverse[r], inverse[col]
+					swapped = true
+					break
+				}
+			}
+			if !swapped {
+				return nil, errors.New("matrix is singular")
+			}
+		}
+		scale := gfInverse(matrix[col][col])
+		for c := 0; c < size; c++ {
+			matrix[col][c] = gfMul(matrix[col][c], scale)
+			inverse[col][c] = gfMul(inverse[col][c], scale)
+		}
+		for r := 0; r < size; r++ {
+			if r == col || matrix[r][col] == 0 {
+				continue
+			}
+			factor := matrix[r][col]
+			for c := 0; c < size; c++ {
+				matrix[r][c] ^= gfMul(factor, matrix[col][c])
+				inverse[r][c] ^= gfMul(factor, inverse[col][c])
+			}
+		}
+	}
+	return inverse, nil
+}
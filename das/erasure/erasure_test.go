@@ -0,0 +1,69 @@
+// Copyright 2022, Offchain Labs, Inc.
+// For license information, see https://github.com/fogr/blob/master/LICENSE
+
+package erasure
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"testing"
+)
+
+func TestReconstructFromAnySubset(t *testing.T) {
+	for _, params := range [][2]int{{1, 0}, {1, 2}, {2, 1}, {3, 2}, {4, 4}, {10, 4}} {
+		coder, err := NewCoder(params[0], params[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, size := range []int{0, 1, 7, 64, 1000, 65537} {
+			data := make([]byte, size)
+			rand.Read(data)
+			shards := coder.Encode(data)
+			if len(shards) != coder.TotalShards() {
+				t.Fatal("wrong number of shards", len(shards))
+			}
+
+			for trial := 0; trial < 8; trial++ {
+				damaged := make([][]byte, len(shards))
+				copy(damaged, shards)
+				for _, drop := range rand.Perm(len(shards))[:coder.ParityShards()] {
+					damaged[drop] = nil
+				}
+				decoded, err := coder.Decode(damaged, size)
+				if err != nil {
+					t.Fatal(params, size, err)
+				}
+				if !bytes.Equal(decoded, data) {
+					t.Fatal("decoded data differs", params, size)
+				}
+			}
+		}
+	}
+}
+
+func TestTooFewShards(t *testing.T) {
+	coder, err := NewCoder(3, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := []byte("the quick brown fox jumps over the lazy dog")
+	shards := coder.Encode(data)
+	shards[0], shards[2], shards[4] = nil, nil, nil
+	_, err = coder.Decode(shards, len(data))
+	if !errors.Is(err, ErrTooFewShards) {
+		t.Fatal("expected ErrTooFewShards but got", err)
+	}
+}
+
+func TestInvalidParameters(t *testing.T) {
+	if _, err := NewCoder(0, 1); err == nil {
+		t.Fatal("expected error for zero data shards")
+	}
+	if _, err := NewCoder(1, -1); err == nil {
+		t.Fatal("expected error for negative parity shards")
+	}
+	if _, err := NewCoder(200, 57); err == nil {
+		t.Fatal("expected error for too many shards")
+	}
+}
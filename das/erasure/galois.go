@@ -0,0 +1,67 @@
+// Copyright 2022, Offchain Labs, Inc.
+// For license information, see https://github.com/fogr/blob/master/LICENSE
+
+package erasure
+
+// Arithmetic over GF(2^8) using the primitive polynomial x^8 + x^4 + x^3 + x^2 + 1.
+const generatorPolynomial = 0x11d
+
+var logTable [256]byte
+var expTable [510]byte
+
+func init() {
+	x := 1
+	for i := 0; i < 255; i++ {
+		expTable[i] = byte(x)
+		expTable[i+255] = byte(x)
+		logTable[x] = byte(i)
+		x <<= 1
+		if x&0x100 != 0 {
+			x ^= generatorPolynomial
+		}
+	}
+}
+
+func gfMul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return expTable[int(logTable[a])+int(logTable[b])]
+}
+
+func gfInverse(a byte) byte {
+	if a == 0 {
+		panic("zero has no inverse in GF(2^8)")
+	}
+	return expTable[255-int(logTable[a])]
+}
+
+// gfExp raises a to the n-th power.
+func gfExp(a byte, n int) byte {
+	if n == 0 {
+		return 1
+	}
+	if a == 0 {
+		return 0
+	}
+	return expTable[(int(logTable[a])*n)%255]
+}
+
+// mulAddSlice computes out[i] ^= c * in[i] for each byte of in.
+func mulAddSlice(c byte, in, out []byte) {
+	if c == 0 {
+		return
+	}
+	if c == 1 {
+		for i, v := range in {
+			out[i] ^= v
+		}
+		return
+	}
+	logC := int(logTable[c])
+	for i, v := range in {
+		if v != 0 {
+			out[i] ^= expTable[logC+int(logTable[v])]
+		}
+	}
+}
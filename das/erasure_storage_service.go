@@ -0,0 +1,338 @@
+// Copyright 2022, Offchain Labs, Inc.
+// For license information, see https://github.com/fogr/blob/master/LICENSE
+
+package das
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/FOGRCC/fogr/das/dastree"
+	"github.com/FOGRCC/fogr/das/erasure"
+	"github.com/FOGRCC/fogr/fogstate"
+	"github.com/FOGRCC/fogr/util/pretty"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	flag "github.com/spf13/pflag"
+)
+
+type ErasureStorageConfig struct {
+	Enable                  bool     `koanf:"enable"`
+	DataShards              int      `koanf:"data-shards"`
+	LocalFileDataDirs       []string `koanf:"local-file-data-dirs"`
+	SyncFromStorageServices bool     `koanf:"sync-from-storage-service"`
+	SyncToStorageServices   bool     `koanf:"sync-to-storage-service"`
+}
+
+var DefaultErasureStorageConfig = ErasureStorageConfig{
+	DataShards: 2,
+}
+
+func ErasureStorageConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Bool(prefix+".enable", DefaultErasureStorageConfig.Enable, "enable erasure coding of sequencer batch data across the enabled local-db, local-file and s3 storage backends instead of replicating it to each of them")
+	f.Int(prefix+".data-shards", DefaultErasureStorageConfig.DataShards, "number of shards needed to reconstruct the data (k); the remaining backends each hold a parity shard")
+	f.StringSlice(prefix+".local-file-data-dirs", DefaultErasureStorageConfig.LocalFileDataDirs, "additional local data directories to each hold one shard, typically on separate disks")
+	f.Bool(prefix+".sync-from-storage-service", DefaultErasureStorageConfig.SyncFromStorageServices, "enable erasure coded storage to be used as a source for regular sync storage")
+	f.Bool(prefix+".sync-to-storage-service", DefaultErasureStorageConfig.SyncToStorageServices, "enable erasure coded storage to be used as a sink for regular sync storage")
+}
+
+const erasureShardKeyPrefix = "erasure_shard_key_prefix_"
+const erasureShardVersion = byte(0)
+
+// version byte, data shards, total shards, shard index, data size, shard hash
+const erasureShardHeaderSize = 1 + 2 + 2 + 2 + 8 + 32
+
+// erasureShardBackend is a StorageService that can hold erasure shards. Shards are stored under
+// their own keys, so the backend needs to be told when they expire rather than deriving it from the data.
+type erasureShardBackend interface {
+	IterationCompatibleStorageService
+	putWithExpiration(ctx context.Context, key common.Hash, value []byte, expirationTime uint64) error
+}
+
+// ErasureStorageService splits each value into k-of-n Reed-Solomon shards, storing one shard
+// in each of n inner StorageServices. The value can be rebuilt from any k of them, which gives
+// durability against the loss of n-k backends at a fraction of the cost of full replication.
+type ErasureStorageService struct {
+	innerServices []erasureShardBackend
+	coder         *erasure.Coder
+}
+
+func NewErasureStorageService(services []StorageService, dataShards int) (*ErasureStorageService, error) {
+	if dataShards > len(services) {
+		return nil, fmt.Errorf("erasure storage needs at least %v backends but only %v are enabled", dataShards, len(services))
+	}
+	innerServices := make([]erasureShardBackend, 0, len(services))
+	for _, serv := range services {
+		inner, ok := serv.(erasureShardBackend)
+		if !ok {
+			return nil, fmt.Errorf("%v can't be used as an erasure storage backend", serv)
+		}
+		innerServices = append(innerServices, inner)
+	}
+	coder, err := erasure.NewCoder(dataShards, len(services)-dataShards)
+	if err != nil {
+		return nil, err
+	}
+	return &ErasureStorageService{innerServices, coder}, nil
+}
+
+func erasureShardKey(key common.Hash, index int) common.Hash {
+	return dastree.Hash([]byte(erasureShardKeyPrefix + strconv.Itoa(index) + "_" + EncodeStorageServiceKey(key)))
+}
+
+func (e *ErasureStorageService) encodeShard(index int, size int, shard []byte) []byte {
+	buf := make([]byte, erasureShardHeaderSize, erasureShardHeaderSize+len(shard))
+	buf[0] = erasureShardVersion
+	binary.BigEndian.PutUint16(buf[1:], uint16(e.coder.DataShards()))
+	binary.BigEndian.PutUint16(buf[3:], uint16(e.coder.TotalShards()))
+	binary.BigEndian.PutUint16(buf[5:], uint16(index))
+	binary.BigEndian.PutUint64(buf[7:], uint64(size))
+	copy(buf[15:], crypto.Keccak256(shard))
+	return append(buf, shard...)
+}
+
+// decodeShard checks a stored shard against this service's layout and returns the data size and shard contents.
+func (e *ErasureStorageService) decodeShard(index int, data []byte) (int, []byte, error) {
+	if len(data) < erasureShardHeaderSize {
+		return 0, nil, errors.New("erasure shard is too short")
+	}
+	if data[0] != erasureShardVersion {
+		return 0, nil, fmt.Errorf("unknown erasure shard version %v", data[0])
+	}
+	dataShards := int(binary.BigEndian.Uint16(data[1:]))
+	totalShards := int(binary.BigEndian.Uint16(data[3:]))
+	if dataShards != e.coder.DataShards() || totalShards != e.coder.TotalShards() {
+		return 0, nil, fmt.Errorf("erasure shard was written as %v-of-%v but storage is configured as %v-of-%v", dataShards, totalShards, e.coder.DataShards(), e.coder.TotalShards())
+	}
+	if int(binary.BigEndian.Uint16(data[5:])) != index {
+		return 0, nil, fmt.Errorf("erasure shard has index %v but expected %v", binary.BigEndian.Uint16(data[5:]), index)
+	}
+	size := binary.BigEndian.Uint64(data[7:])
+	shard := data[erasureShardHeaderSize:]
+	if !bytes.Equal(crypto.Keccak256(shard), data[15:erasureShardHeaderSize]) {
+		return 0, nil, errors.New("erasure shard is corrupted")
+	}
+	if uint64(e.coder.ShardSize(int(size))) != uint64(len(shard)) {
+		return 0, nil, errors.New("erasure shard has inconsistent size")
+	}
+	return int(size), shard, nil
+}
+
+type erasureShardResponse struct {
+	index int
+	size  int
+	shard []byte
+	err   error
+}
+
+func (e *ErasureStorageService) GetByHash(ctx context.Context, key common.Hash) ([]byte, error) {
+	log.Trace("das.ErasureStorageService.GetByHash", "key", pretty.PrettyHash(key), "this", e)
+	subCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	responsesExpected := len(e.innerServices)
+	resultChan := make(chan erasureShardResponse, responsesExpected)
+	for i, serv := range e.innerServices {
+		go func(index int, s StorageService) {
+			data, err := s.GetByHash(subCtx, erasureShardKey(key, index))
+			if err != nil {
+				resultChan <- erasureShardResponse{index: index, err: err}
+				return
+			}
+			size, shard, err := e.decodeShard(index, data)
+			resultChan <- erasureShardResponse{index, size, shard, err}
+		}(i, serv)
+	}
+
+	shards := make([][]byte, len(e.innerServices))
+	shardDataSizes := make([]int, len(e.innerServices))
+	sizes := make(map[int]int)
+	var anyError error
+	for responsesExpected > 0 {
+		select {
+		case resp := <-resultChan:
+			responsesExpected--
+			if resp.err != nil {
+				if !errors.Is(resp.err, ErrNotFound) {
+					log.Warn("Failed to read erasure shard", "key", pretty.PrettyHash(key), "index", resp.index, "err", resp.err)
+				}
+				anyError = resp.err
+				continue
+			}
+			shards[resp.index] = resp.shard
+			shardDataSizes[resp.index] = resp.size
+			sizes[resp.size]++
+			if sizes[resp.size] < e.coder.DataShards() {
+				continue
+			}
+			if result, ok := e.decodeVerified(key, shards, shardDataSizes, resp.index); ok {
+				return result, nil
+			}
+			log.Warn("Erasure shards don't reconstruct the expected data", "key", pretty.PrettyHash(key), "index", resp.index)
+			anyError = errors.New("no combination of erasure shards matches the key")
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if anyError == nil || errors.Is(anyError, ErrNotFound) {
+		return nil, ErrNotFound
+	}
+	return nil, fmt.Errorf("too few erasure shards available for %v: %w", pretty.PrettyHash(key), anyError)
+}
+
+// decodeVerified reconstructs the data from k of the shards that agree on the data size with the
+// shard at index, and checks the result against key. A shard can be intact by its own hash and still
+// be wrong, e.g. if a backend returned a shard written for different data, so every combination
+// including the new shard is tried. Combinations without it were already tried as earlier shards arrived.
+func (e *ErasureStorageService) decodeVerified(key common.Hash, shards [][]byte, shardDataSizes []int, index int) ([]byte, bool) {
+	size := shardDataSizes[index]
+	var others []int
+	for i, shard := range shards {
+		if i != index && shard != nil && shardDataSizes[i] == size {
+			others = append(others, i)
+		}
+	}
+	needed := e.coder.DataShards() - 1
+	chosen := make([]int, needed)
+	var try func(start, depth int) ([]byte, bool)
+	try = func(start, depth int) ([]byte, bool) {
+		if depth == needed {
+			candidates := make([][]byte, len(shards))
+			candidates[index] = shards[index]
+			for _, i := range chosen {
+				candidates[i] = shards[i]
+			}
+			result, err := e.coder.Decode(candidates, size)
+			if err != nil || !dastree.ValidHash(key, result) {
+				return nil, false
+			}
+			return result, true
+		}
+		for i := start; i <= len(others)-(needed-depth); i++ {
+			chosen[depth] = others[i]
+			if result, ok := try(i+1, depth+1); ok {
+				return result, true
+			}
+		}
+		return nil, false
+	}
+	return try(0, 0)
+}
+
+func (e *ErasureStorageService) Put(ctx context.Context, data []byte, expirationTime uint64) error {
+	logPut("das.ErasureStorageService.Store", data, expirationTime, e)
+	// Each shard expires along with the data, so the backends' expiry sweepers clean it up.
+	return e.putShards(ctx, dastree.Hash(data), data, func(s erasureShardBackend, shardKey common.Hash, shard []byte) error {
+		return s.putWithExpiration(ctx, shardKey, shard, expirationTime)
+	})
+}
+
+func (e *ErasureStorageService) putKeyValue(ctx context.Context, key common.Hash, value []byte) error {
+	return e.putShards(ctx, key, value, func(s erasureShardBackend, shardKey common.Hash, shard []byte) error {
+		return s.putKeyValue(ctx, shardKey, shard)
+	})
+}
+
+// putShards writes a shard of value to each backend. The write succeeds as long as no more backends
+// failed than there are parity shards, since the value can still be reconstructed from the rest.
+func (e *ErasureStorageService) putShards(ctx context.Context, key common.Hash, value []byte, put func(erasureShardBackend, common.Hash, []byte) error) error {
+	shards := e.coder.Encode(value)
+	var wg sync.WaitGroup
+	var errorMutex sync.Mutex
+	var anyError error
+	failures := 0
+	wg.Add(len(e.innerServices))
+	for i, serv := range e.innerServices {
+		go func(index int, s erasureShardBackend) {
+			defer wg.Done()
+			err := put(s, erasureShardKey(key, index), e.encodeShard(index, len(value), shards[index]))
+			if err != nil {
+				log.Warn("Failed to write erasure shard", "key", pretty.PrettyHash(key), "index", index, "backend", s, "err", err)
+				errorMutex.Lock()
+				failures++
+				anyError = err
+				errorMutex.Unlock()
+			}
+		}(i, serv)
+	}
+	wg.Wait()
+	if failures > e.coder.ParityShards() {
+		return fmt.Errorf("failed to write %v of %v erasure shards for %v: %w", failures, len(e.innerServices), pretty.PrettyHash(key), anyError)
+	}
+	return nil
+}
+
+func (e *ErasureStorageService) Sync(ctx context.Context) error {
+	var anyError error
+	for _, serv := range e.innerServices {
+		if err := serv.Sync(ctx); err != nil {
+			anyError = err
+		}
+	}
+	return anyError
+}
+
+func (e *ErasureStorageService) Close(ctx context.Context) error {
+	var anyError error
+	for _, serv := range e.innerServices {
+		if err := serv.Close(ctx); err != nil {
+			anyError = err
+		}
+	}
+	return anyError
+}
+
+func (e *ErasureStorageService) ExpirationPolicy(ctx context.Context) (fogstate.ExpirationPolicy, error) {
+	// Data needs k of its shards to survive, so the whole service can only promise
+	// what its shortest-lived backend promises.
+	res := fogstate.KeepForever
+	for _, serv := range e.innerServices {
+		expirationPolicy, err := serv.ExpirationPolicy(ctx)
+		if err != nil {
+			return -1, err
+		}
+		switch expirationPolicy {
+		case fogstate.KeepForever:
+		case fogstate.DiscardAfterArchiveTimeout:
+			if res == fogstate.KeepForever {
+				res = fogstate.DiscardAfterArchiveTimeout
+			}
+		case fogstate.DiscardAfterDataTimeout:
+			res = fogstate.DiscardAfterDataTimeout
+		default:
+			return -1, errors.New("unknown expiration policy")
+		}
+	}
+	return res, nil
+}
+
+func (e *ErasureStorageService) String() string {
+	str := fmt.Sprintf("ErasureStorageService(%v-of-%v,", e.coder.DataShards(), e.coder.TotalShards())
+	for _, serv := range e.innerServices {
+		str = str + serv.String() + ","
+	}
+	return str + ")"
+}
+
+func (e *ErasureStorageService) HealthCheck(ctx context.Context) error {
+	// The service stays available as long as enough backends are healthy to reconstruct data.
+	unhealthy := 0
+	var anyError error
+	for _, serv := range e.innerServices {
+		if err := serv.HealthCheck(ctx); err != nil {
+			log.Warn("Erasure storage backend is unhealthy", "backend", serv, "err", err)
+			unhealthy++
+			anyError = err
+		}
+	}
+	if unhealthy > e.coder.ParityShards() {
+		return fmt.Errorf("%v of %v erasure storage backends are unhealthy: %w", unhealthy, len(e.innerServices), anyError)
+	}
+	return nil
+}
@@ -0,0 +1,261 @@
+// Copyright 2022, Offchain Labs, Inc.
+// For license information, see https://github.com/fogr/blob/master/LICENSE
+
+package das
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/FOGRCC/fogr/das/dastree"
+	"github.com/FOGRCC/fogr/util/testhelpers"
+)
+
+func TestErasureStorageService(t *testing.T) {
+	ctx := context.Background()
+	timeout := uint64(time.Now().Add(time.Hour).Unix())
+	services := []StorageService{}
+	for i := 0; i < 5; i++ {
+		services = append(services, NewMemoryBackedStorageService(ctx))
+	}
+	erasureService, err := NewErasureStorageService(services, 3)
+	Require(t, err)
+
+	val1 := testhelpers.RandomizeSlice(make([]byte, 3*dastree.BinSize+17))
+	key1 := dastree.Hash(val1)
+	key2 := dastree.Hash(append(val1, 0))
+
+	_, err = erasureService.GetByHash(ctx, key1)
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatal(err)
+	}
+
+	err = erasureService.Put(ctx, val1, timeout)
+	Require(t, err)
+
+	_, err = erasureService.GetByHash(ctx, key2)
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatal(err)
+	}
+	val, err := erasureService.GetByHash(ctx, key1)
+	Require(t, err)
+	if !bytes.Equal(val, val1) {
+		Fail(t, "erasure storage returned wrong data")
+	}
+
+	// No backend holds a full copy of the data.
+	for _, serv := range services {
+		_, err = serv.GetByHash(ctx, key1)
+		if !errors.Is(err, ErrNotFound) {
+			t.Fatal(err)
+		}
+	}
+
+	// Losing two of the five backends still leaves enough shards.
+	Require(t, services[0].Close(ctx))
+	Require(t, services[3].Close(ctx))
+	val, err = erasureService.GetByHash(ctx, key1)
+	Require(t, err)
+	if !bytes.Equal(val, val1) {
+		Fail(t, "erasure storage returned wrong data after losing backends")
+	}
+
+	// Losing a third does not.
+	Require(t, services[4].Close(ctx))
+	_, err = erasureService.GetByHash(ctx, key1)
+	if err == nil {
+		Fail(t, "expected an error with too few shards available")
+	}
+}
+
+func TestErasureStorageServiceCorruptShard(t *testing.T) {
+	ctx := context.Background()
+	timeout := uint64(time.Now().Add(time.Hour).Unix())
+	services := []StorageService{}
+	for i := 0; i < 3; i++ {
+		services = append(services, NewMemoryBackedStorageService(ctx))
+	}
+	erasureService, err := NewErasureStorageService(services, 2)
+	Require(t, err)
+
+	val1 := []byte("The first value")
+	key1 := dastree.Hash(val1)
+	Require(t, erasureService.Put(ctx, val1, timeout))
+
+	memory, ok := services[1].(*MemoryBackedStorageService)
+	if !ok {
+		Fail(t, "expected a MemoryBackedStorageService")
+	}
+	shardKey := erasureShardKey(key1, 1)
+	shard, err := memory.GetByHash(ctx, shardKey)
+	Require(t, err)
+	corrupted := append([]byte{}, shard...)
+	corrupted[len(corrupted)-1] ^= 0xff
+	Require(t, memory.putKeyValue(ctx, shardKey, corrupted))
+
+	val, err := erasureService.GetByHash(ctx, key1)
+	Require(t, err)
+	if !bytes.Equal(val, val1) {
+		Fail(t, "erasure storage returned wrong data with a corrupted shard")
+	}
+}
+
+func TestErasureStorageServiceTooFewBackends(t *testing.T) {
+	ctx := context.Background()
+	services := []StorageService{NewMemoryBackedStorageService(ctx)}
+	_, err := NewErasureStorageService(services, 2)
+	if err == nil {
+		Fail(t, "expected an error when there are fewer backends than data shards")
+	}
+}
+
+func TestErasureStorageServiceMismatchedShard(t *testing.T) {
+	ctx := context.Background()
+	timeout := uint64(time.Now().Add(time.Hour).Unix())
+	services := []StorageService{}
+	for i := 0; i < 3; i++ {
+		services = append(services, NewMemoryBackedStorageService(ctx))
+	}
+	erasureService, err := NewErasureStorageService(services, 2)
+	Require(t, err)
+
+	val1 := []byte("The first value")
+	val2 := []byte("The other value")
+	key1 := dastree.Hash(val1)
+	Require(t, erasureService.Put(ctx, val1, timeout))
+	Require(t, erasureService.Put(ctx, val2, timeout))
+
+	// The shard for the other value is intact by its own hash, so only the reconstruction shows it's wrong.
+	memory, ok := services[1].(*MemoryBackedStorageService)
+	if !ok {
+		Fail(t, "expected a MemoryBackedStorageService")
+	}
+	otherShard, err := memory.GetByHash(ctx, erasureShardKey(dastree.Hash(val2), 1))
+	Require(t, err)
+	Require(t, memory.putKeyValue(ctx, erasureShardKey(key1, 1), otherShard))
+
+	val, err := erasureService.GetByHash(ctx, key1)
+	Require(t, err)
+	if !bytes.Equal(val, val1) {
+		Fail(t, "erasure storage returned wrong data with a mismatched shard")
+	}
+
+	// With only the mismatched shard and one good one left, no combination matches.
+	Require(t, services[2].Close(ctx))
+	_, err = erasureService.GetByHash(ctx, key1)
+	if err == nil {
+		Fail(t, "expected an error when the shards don't reconstruct the data")
+	}
+}
+
+func TestErasureStorageServiceFailedBackends(t *testing.T) {
+	ctx := context.Background()
+	timeout := uint64(time.Now().Add(time.Hour).Unix())
+	services := []StorageService{}
+	for i := 0; i < 4; i++ {
+		services = append(services, NewMemoryBackedStorageService(ctx))
+	}
+	erasureService, err := NewErasureStorageService(services, 2)
+	Require(t, err)
+
+	// Two parity shards mean two backends can fail without losing the write.
+	Require(t, services[0].Close(ctx))
+	Require(t, services[2].Close(ctx))
+	val1 := []byte("The first value")
+	Require(t, erasureService.Put(ctx, val1, timeout))
+	val, err := erasureService.GetByHash(ctx, dastree.Hash(val1))
+	Require(t, err)
+	if !bytes.Equal(val, val1) {
+		Fail(t, "erasure storage returned wrong data after a partial write")
+	}
+
+	Require(t, services[3].Close(ctx))
+	if erasureService.Put(ctx, []byte("The second value"), timeout) == nil {
+		Fail(t, "expected an error when too many backends fail")
+	}
+}
+
+func TestErasureStorageServiceExpiry(t *testing.T) {
+	ctx := context.Background()
+	services := []StorageService{}
+	for i := 0; i < 3; i++ {
+		service, err := NewLocalFileStorageService(ctx, LocalFileStorageConfig{
+			DataDir:             t.TempDir(),
+			DiscardAfterTimeout: true,
+			ExpirySweeper:       DefaultExpirySweeperConfig,
+		})
+		Require(t, err)
+		defer func() {
+			Require(t, service.Close(ctx))
+		}()
+		services = append(services, service)
+	}
+	erasureService, err := NewErasureStorageService(services, 2)
+	Require(t, err)
+
+	val1 := []byte("The first value")
+	timeout := uint64(time.Now().Add(time.Hour).Unix())
+	Require(t, erasureService.Put(ctx, val1, timeout))
+
+	// Each backend indexes its shard so its sweeper discards it along with the data.
+	for i, serv := range services {
+		index, ok := serv.(expiryIndex)
+		if !ok {
+			Fail(t, "storage service doesn't support expiry sweeping")
+		}
+		entries, err := index.expiredEntries(timeout, 100)
+		Require(t, err)
+		if len(entries) != 1 || entries[0].key != erasureShardKey(dastree.Hash(val1), i) || entries[0].expiration != timeout {
+			Fail(t, "unexpected expiry index entries", entries)
+		}
+	}
+}
+
+func TestErasureStorageServiceExtraDirsExpiry(t *testing.T) {
+	ctx := context.Background()
+	extraDirs := []string{t.TempDir(), t.TempDir()}
+	config := &DataAvailabilityConfig{
+		LocalFileStorageConfig: LocalFileStorageConfig{
+			Enable:              true,
+			DataDir:             t.TempDir(),
+			DiscardAfterTimeout: true,
+			ExpirySweeper: ExpirySweeperConfig{
+				Interval:    10 * time.Millisecond,
+				GracePeriod: 0,
+				BatchSize:   100,
+			},
+		},
+		ErasureStorageConfig: ErasureStorageConfig{
+			Enable:            true,
+			DataShards:        2,
+			LocalFileDataDirs: extraDirs,
+		},
+	}
+	storageService, lifecycleManager, err := CreatePersistentStorageService(ctx, config, nil, nil)
+	Require(t, err)
+	defer lifecycleManager.StopAndWaitUntil(time.Second)
+
+	expired := []byte("expired value")
+	Require(t, storageService.Put(ctx, expired, uint64(time.Now().Add(-time.Hour).Unix())))
+
+	// The primary directory holds shard 0, and the extra directories hold the others.
+	for i, dataDir := range extraDirs {
+		reader, err := NewLocalFileStorageService(ctx, LocalFileStorageConfig{DataDir: dataDir})
+		Require(t, err)
+		shardKey := erasureShardKey(dastree.Hash(expired), i+1)
+		for start := time.Now(); ; time.Sleep(10 * time.Millisecond) {
+			_, err = reader.GetByHash(ctx, shardKey)
+			if errors.Is(err, ErrNotFound) {
+				break
+			}
+			Require(t, err)
+			if time.Since(start) > 5*time.Second {
+				Fail(t, "shard in extra directory", dataDir, "wasn't swept")
+			}
+		}
+		Require(t, reader.Close(ctx))
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright 2022, Offchain Labs, Inc.
+// For license information, see https://github.com/fogr/blob/master/LICENSE
+
+package das
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	flag "github.com/spf13/pflag"
+
+	"github.com/FOGRCC/fogr/fogstate"
+)
+
+var (
+	expirySweptEntriesCounter    = metrics.NewRegisteredCounter("fogr/das/expiry/swept/entries", nil)
+	expiryReclaimedBytesCounter  = metrics.NewRegisteredCounter("fogr/das/expiry/reclaimed/bytes", nil)
+	expirySweepFailureCounter    = metrics.NewRegisteredCounter("fogr/das/expiry/failure", nil)
+	expirySweepDurationHistogram = metrics.NewRegisteredHistogram("fogr/das/expiry/duration", nil, metrics.NewBoundedHistogramSample())
+)
+
+type ExpirySweeperConfig struct {
+	Interval    time.Duration `koanf:"interval"`
+	GracePeriod time.Duration `koanf:"grace-period"`
+	BatchSize   int           `koanf:"batch-size"`
+}
+
+var DefaultExpirySweeperConfig = ExpirySweeperConfig{
+	Interval:    time.Hour,
+	GracePeriod: 24 * time.Hour,
+	BatchSize:   1000,
+}
+
+func ExpirySweeperConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Duration(prefix+".interval", DefaultExpirySweeperConfig.Interval, "interval between sweeps for expired data")
+	f.Duration(prefix+".grace-period", DefaultExpirySweeperConfig.GracePeriod, "how long to keep data past its expiry timeout before deleting it")
+	f.Int(prefix+".batch-size", DefaultExpirySweeperConfig.BatchSize, "maximum number of expired entries to delete at once")
+}
+
+type expiryEntry struct {
+	expiration uint64
+	key        common.Hash
+}
+
+// An expiryIndex is implemented by storage backends that can find and delete their expired data.
+type expiryIndex interface {
+	// expiredEntries returns up to limit index entries with an expiration at or before cutoff, oldest first.
+	expiredEntries(cutoff uint64, limit int) ([]expiryEntry, error)
+	// deleteExpired removes the index entries, and the data they refer to unless it was
+	// stored again with a later expiration. It returns the number of data bytes reclaimed.
+	deleteExpired(entries []expiryEntry) (uint64, error)
+	ExpirationPolicy(ctx context.Context) (fogstate.ExpirationPolicy, error)
+	fmt.Stringer
+}
+
+// An expirySweeper periodically deletes data whose expiry timeout plus a grace period has passed.
+// It is run by the storage service that owns the index, on that service's StopWaiter.
+type expirySweeper struct {
+	config ExpirySweeperConfig
+	index  expiryIndex
+}
+
+func newExpirySweeper(config ExpirySweeperConfig, index expiryIndex) *expirySweeper {
+	return &expirySweeper{config, index}
+}
+
+// shouldIndex returns whether data with this expiration time can ever be swept.
+func shouldIndex(expiration uint64) bool {
+	return expiration != math.MaxUint64
+}
+
+func (s *expirySweeper) sweep(ctx context.Context) time.Duration {
+	policy, err := s.index.ExpirationPolicy(ctx)
+	if err != nil {
+		log.Warn("Failed to get expiration policy for expiry sweep", "storage", s.index, "err", err)
+		return s.config.Interval
+	}
+	if policy == fogstate.KeepForever {
+		return s.config.Interval
+	}
+
+	start := time.Now()
+	grace := uint64(s.config.GracePeriod.Seconds())
+	now := uint64(start.Unix())
+	if now < grace {
+		return s.config.Interval
+	}
+	cutoff := now - grace
+	batchSize := s.config.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultExpirySweeperConfig.BatchSize
+	}
+
+	var swept int
+	var reclaimed uint64
+	for ctx.Err() == nil {
+		entries, err := s.index.expiredEntries(cutoff, batchSize)
+		if err != nil {
+			expirySweepFailureCounter.Inc(1)
+			log.Warn("Failed to find expired data", "storage", s.index, "err", err)
+			break
+		}
+		if len(entries) == 0 {
+			break
+		}
+		bytes, err := s.index.deleteExpired(entries)
+		reclaimed += bytes
+		expiryReclaimedBytesCounter.Inc(int64(bytes))
+		if err != nil {
+			expirySweepFailureCounter.Inc(1)
+			log.Warn("Failed to delete expired data", "storage", s.index, "err", err)
+			break
+		}
+		swept += len(entries)
+		expirySweptEntriesCounter.Inc(int64(len(entries)))
+		if len(entries) < batchSize {
+			break
+		}
+	}
+	expirySweepDurationHistogram.Update(time.Since(start).Nanoseconds())
+	if swept > 0 {
+		log.Info("Swept expired data", "storage", s.index, "entries", swept, "reclaimedBytes", reclaimed, "elapsed", time.Since(start))
+	}
+	return s.config.Interval
+}
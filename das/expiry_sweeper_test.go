@@ -0,0 +1,120 @@
+// Copyright 2022, Offchain Labs, Inc.
+// For license information, see https://github.com/fogr/blob/master/LICENSE
+
+package das
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/FOGRCC/fogr/das/dastree"
+)
+
+type sweepableStorageService interface {
+	StorageService
+	expiryIndex
+}
+
+func testExpirySweeper(t *testing.T, storageService StorageService) {
+	ctx := context.Background()
+	defer func() {
+		Require(t, storageService.Close(ctx))
+	}()
+	service, ok := storageService.(sweepableStorageService)
+	if !ok {
+		Fail(t, "storage service doesn't support expiry sweeping")
+	}
+
+	now := time.Now()
+	expired := []byte("expired value")
+	inGracePeriod := []byte("value in its grace period")
+	extended := []byte("value stored again with a later expiration")
+	live := []byte("live value")
+
+	Require(t, service.Put(ctx, expired, uint64(now.Add(-2*time.Hour).Unix())))
+	Require(t, service.Put(ctx, inGracePeriod, uint64(now.Add(-time.Minute).Unix())))
+	Require(t, service.Put(ctx, extended, uint64(now.Add(-2*time.Hour).Unix())))
+	Require(t, service.Put(ctx, extended, uint64(now.Add(time.Hour).Unix())))
+	Require(t, service.Put(ctx, live, uint64(now.Add(time.Hour).Unix())))
+
+	sweeper := newExpirySweeper(ExpirySweeperConfig{
+		Interval:    time.Hour,
+		GracePeriod: time.Hour,
+		BatchSize:   1,
+	}, service)
+	sweeper.sweep(ctx)
+
+	_, err := service.GetByHash(ctx, dastree.Hash(expired))
+	if !errors.Is(err, ErrNotFound) {
+		Fail(t, "expired data wasn't swept", err)
+	}
+	for _, value := range [][]byte{inGracePeriod, extended, live} {
+		res, err := service.GetByHash(ctx, dastree.Hash(value))
+		Require(t, err, string(value))
+		if !bytes.Equal(res, value) {
+			Fail(t, "unexpected data for", string(value))
+		}
+	}
+
+	entries, err := service.expiredEntries(uint64(now.Unix()), 100)
+	Require(t, err)
+	if len(entries) != 1 || entries[0].key != dastree.Hash(inGracePeriod) {
+		Fail(t, "unexpected expiry index entries", entries)
+	}
+
+	// Health checks store their test data without an expiration, so they don't grow the index.
+	Require(t, service.HealthCheck(ctx))
+	entries, err = service.expiredEntries(uint64(now.Add(2*time.Hour).Unix()), 100)
+	Require(t, err)
+	if len(entries) != 3 {
+		Fail(t, "unexpected expiry index entries after health check", entries)
+	}
+}
+
+func TestLocalFileExpirySweeper(t *testing.T) {
+	ctx := context.Background()
+	storageService, err := NewLocalFileStorageService(ctx, LocalFileStorageConfig{
+		DataDir:             t.TempDir(),
+		DiscardAfterTimeout: true,
+		ExpirySweeper:       DefaultExpirySweeperConfig,
+	})
+	Require(t, err)
+	testExpirySweeper(t, storageService)
+}
+
+func TestDBExpirySweeper(t *testing.T) {
+	ctx := context.Background()
+	storageService, err := NewDBStorageService(ctx, LocalDBStorageConfig{
+		DataDir:             t.TempDir(),
+		DiscardAfterTimeout: true,
+		ExpirySweeper:       DefaultExpirySweeperConfig,
+	})
+	Require(t, err)
+	testExpirySweeper(t, storageService)
+}
+
+func TestExpirySweeperKeepForever(t *testing.T) {
+	ctx := context.Background()
+	storageService, err := NewLocalFileStorageService(ctx, LocalFileStorageConfig{
+		DataDir: t.TempDir(),
+	})
+	Require(t, err)
+	defer func() {
+		Require(t, storageService.Close(ctx))
+	}()
+
+	expired := []byte("expired value")
+	Require(t, storageService.Put(ctx, expired, uint64(time.Now().Add(-48*time.Hour).Unix())))
+
+	service, ok := storageService.(sweepableStorageService)
+	if !ok {
+		Fail(t, "storage service doesn't support expiry sweeping")
+	}
+	newExpirySweeper(DefaultExpirySweeperConfig, service).sweep(ctx)
+
+	_, err = storageService.GetByHash(ctx, dastree.Hash(expired))
+	Require(t, err, "data was swept despite KeepForever policy")
+}
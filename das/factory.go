@@ -11,6 +11,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 
+	"github.com/FOGRCC/fogr/cmd/genericconf"
 	"github.com/FOGRCC/fogr/fogutil"
 	"github.com/FOGRCC/fogr/solgen/go/bridgegen"
 	"github.com/FOGRCC/fogr/util/headerreader"
@@ -19,16 +20,26 @@ import (
 
 // CreatePersistentStorageService creates any storage services that persist to files, database, cloud storage,
 // and group them together into a RedundantStorage instance if there is more than one.
+// If erasure storage is enabled, the file, database and cloud storage services each hold
+// one erasure coded shard instead of a full copy of the data.
 func CreatePersistentStorageService(
 	ctx context.Context,
 	config *DataAvailabilityConfig,
 	syncFromStorageServices *[]*IterableStorageService,
 	syncToStorageServices *[]StorageService,
 ) (StorageService, *LifecycleManager, error) {
+	if config.ErasureStorageConfig.Enable {
+		if config.LocalDBStorageConfig.SyncFromStorageServices || config.LocalDBStorageConfig.SyncToStorageServices ||
+			config.LocalFileStorageConfig.SyncFromStorageServices || config.LocalFileStorageConfig.SyncToStorageServices ||
+			config.S3StorageServiceConfig.SyncFromStorageServices || config.S3StorageServiceConfig.SyncToStorageServices {
+			return nil, nil, errors.New("--data-availability.erasure-storage is enabled, so regular sync storage must be configured under erasure-storage rather than on its backends")
+		}
+	}
+
 	storageServices := make([]StorageService, 0, 10)
 	var lifecycleManager LifecycleManager
 	if config.LocalDBStorageConfig.Enable {
-		s, err := NewDBStorageService(ctx, config.LocalDBStorageConfig.DataDir, config.LocalDBStorageConfig.DiscardAfterTimeout)
+		s, err := NewDBStorageService(ctx, config.LocalDBStorageConfig)
 		if err != nil {
 			return nil, nil, err
 		}
@@ -45,7 +56,7 @@ func CreatePersistentStorageService(
 	}
 
 	if config.LocalFileStorageConfig.Enable {
-		s, err := NewLocalFileStorageService(config.LocalFileStorageConfig.DataDir)
+		s, err := NewLocalFileStorageService(ctx, config.LocalFileStorageConfig)
 		if err != nil {
 			return nil, nil, err
 		}
@@ -78,6 +89,36 @@ func CreatePersistentStorageService(
 		storageServices = append(storageServices, s)
 	}
 
+	if config.ErasureStorageConfig.Enable {
+		shardServices := storageServices
+		for _, dataDir := range config.ErasureStorageConfig.LocalFileDataDirs {
+			// Shards in the extra directories expire like those in the primary directory.
+			shardConfig := config.LocalFileStorageConfig
+			shardConfig.DataDir = dataDir
+			s, err := NewLocalFileStorageService(ctx, shardConfig)
+			if err != nil {
+				return nil, nil, err
+			}
+			lifecycleManager.Register(s)
+			shardServices = append(shardServices, s)
+		}
+		erasureStorageService, err := NewErasureStorageService(shardServices, config.ErasureStorageConfig.DataShards)
+		if err != nil {
+			return nil, nil, err
+		}
+		lifecycleManager.Register(erasureStorageService)
+		var s StorageService = erasureStorageService
+		if config.ErasureStorageConfig.SyncFromStorageServices {
+			iterableStorageService := NewIterableStorageService(ConvertStorageServiceToIterationCompatibleStorageService(s))
+			*syncFromStorageServices = append(*syncFromStorageServices, iterableStorageService)
+			s = iterableStorageService
+		}
+		if config.ErasureStorageConfig.SyncToStorageServices {
+			*syncToStorageServices = append(*syncToStorageServices, s)
+		}
+		storageServices = []StorageService{s}
+	}
+
 	if config.IpfsStorageServiceConfig.Enable {
 		s, err := NewIpfsStorageService(ctx, config.IpfsStorageServiceConfig)
 		if err != nil {
@@ -145,37 +186,37 @@ func CreateBatchPosterDAS(
 	dataSigner signature.DataSignerFunc,
 	l1Reader fogutil.L1Interface,
 	sequencerInboxAddr common.Address,
-) (DataAvailabilityServiceWriter, DataAvailabilityServiceReader, *LifecycleManager, error) {
+) (DataAvailabilityServiceWriter, *Aggregator, DataAvailabilityServiceReader, *LifecycleManager, error) {
 	if !config.Enable {
-		return nil, nil, nil, nil
+		return nil, nil, nil, nil, nil
 	}
 
 	// Check config requirements
 	if !config.AggregatorConfig.Enable || !config.RestfulClientAggregatorConfig.Enable {
-		return nil, nil, nil, errors.New("--node.data-availabilty.rpc-aggregator.enable and rest-aggregator.enable must be set when running a Batch Poster in AnyTrust mode")
+		return nil, nil, nil, nil, errors.New("--node.data-availabilty.rpc-aggregator.enable and rest-aggregator.enable must be set when running a Batch Poster in AnyTrust mode")
 	}
 
 	if config.IpfsStorageServiceConfig.Enable {
-		return nil, nil, nil, errors.New("--node.data-availability.ipfs-storage.enable may not be set when running a FOGR AnyTrust node in Batch Poster mode")
+		return nil, nil, nil, nil, errors.New("--node.data-availability.ipfs-storage.enable may not be set when running a FOGR AnyTrust node in Batch Poster mode")
 	}
 	// Done checking config requirements
 
-	var daWriter DataAvailabilityServiceWriter
-	daWriter, err := NewRPCAggregator(ctx, *config)
+	aggregator, err := NewRPCAggregator(ctx, *config)
 	if err != nil {
-		return nil, nil, nil, err
+		return nil, nil, nil, nil, err
 	}
+	var daWriter DataAvailabilityServiceWriter = aggregator
 	if dataSigner != nil {
 		// In some tests the batch poster does not sign Store requests
 		daWriter, err = NewStoreSigningDAS(daWriter, dataSigner)
 		if err != nil {
-			return nil, nil, nil, err
+			return nil, nil, nil, nil, err
 		}
 	}
 
 	restAgg, err := NewRestfulClientAggregator(ctx, &config.RestfulClientAggregatorConfig)
 	if err != nil {
-		return nil, nil, nil, err
+		return nil, nil, nil, nil, err
 	}
 	restAgg.Start(ctx)
 	var lifecycleManager LifecycleManager
@@ -183,10 +224,10 @@ func CreateBatchPosterDAS(
 	var daReader DataAvailabilityServiceReader = restAgg
 	daReader, err = NewChainFetchReader(daReader, l1Reader, sequencerInboxAddr)
 	if err != nil {
-		return nil, nil, nil, err
+		return nil, nil, nil, nil, err
 	}
 
-	return daWriter, daReader, &lifecycleManager, nil
+	return daWriter, aggregator, daReader, &lifecycleManager, nil
 }
 
 func CreateDAComponentsForDaserver(
@@ -203,8 +244,9 @@ func CreateDAComponentsForDaserver(
 	if !config.LocalDBStorageConfig.Enable &&
 		!config.LocalFileStorageConfig.Enable &&
 		!config.S3StorageServiceConfig.Enable &&
-		!config.IpfsStorageServiceConfig.Enable {
-		return nil, nil, nil, nil, errors.New("At least one of --data-availability.(local-db-storage|local-file-storage|s3-storage|ipfs-storage) must be enabled.")
+		!config.IpfsStorageServiceConfig.Enable &&
+		!config.ErasureStorageConfig.Enable {
+		return nil, nil, nil, nil, errors.New("At least one of --data-availability.(local-db-storage|local-file-storage|s3-storage|ipfs-storage|erasure-storage) must be enabled.")
 	}
 	// Done checking config requirements
 
@@ -215,11 +257,31 @@ func CreateDAComponentsForDaserver(
 		return nil, nil, nil, nil, err
 	}
 
+	if config.TieredStorageConfig.Enable {
+		// The persistent storage becomes the cold tier.
+		tieredStorageService, err := NewTieredStorageService(ctx, config.TieredStorageConfig, storageService)
+		if err != nil {
+			return nil, nil, nil, nil, err
+		}
+		dasLifecycleManager.Register(tieredStorageService)
+		storageService = tieredStorageService
+	}
+
 	storageService, err = WrapStorageWithCache(ctx, config, storageService, &syncFromStorageServices, &syncToStorageServices, dasLifecycleManager)
 	if err != nil {
 		return nil, nil, nil, nil, err
 	}
 
+	if config.GossipSyncConfig.Enable {
+		gossip, err := NewGossipSync(config.GossipSyncConfig, storageService, genericconf.HTTPServerTimeoutConfigDefault)
+		if err != nil {
+			return nil, nil, nil, nil, err
+		}
+		dasLifecycleManager.Register(gossip)
+		gossip.Start(ctx)
+		storageService = NewGossipStorageService(storageService, gossip)
+	}
+
 	// The REST aggregator is used as the fallback if requested data is not present
 	// in the storage service.
 	if config.RestfulClientAggregatorConfig.Enable {
@@ -284,12 +346,17 @@ func CreateDAComponentsForDaserver(
 		if err != nil {
 			return nil, nil, nil, nil, err
 		}
+		extraPrivKeys, err := config.KeyConfig.ExtraBLSPrivKeys()
+		if err != nil {
+			return nil, nil, nil, nil, err
+		}
 
 		daWriter, err = NewSignAfterStoreDASWriterWithSeqInboxCaller(
 			privKey,
 			seqInboxCaller,
 			storageService,
 			config.ExtraSignatureCheckingPublicKey,
+			extraPrivKeys...,
 		)
 		if err != nil {
 			return nil, nil, nil, nil, err
@@ -0,0 +1,447 @@
+// Copyright 2022, Offchain Labs, Inc.
+// For license information, see https://github.com/fogr/blob/master/LICENSE
+
+package das
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+
+	"github.com/FOGRCC/fogr/cmd/genericconf"
+	"github.com/FOGRCC/fogr/das/dastree"
+	"github.com/FOGRCC/fogr/fogstate"
+	"github.com/FOGRCC/fogr/util/containers"
+	"github.com/FOGRCC/fogr/util/stopwaiter"
+)
+
+var (
+	gossipAnnouncedCounter       = metrics.NewRegisteredCounter("fogr/das/gossip/announced", nil)
+	gossipReceivedCounter        = metrics.NewRegisteredCounter("fogr/das/gossip/received", nil)
+	gossipFetchedCounter         = metrics.NewRegisteredCounter("fogr/das/gossip/fetched", nil)
+	gossipFetchFailureCounter    = metrics.NewRegisteredCounter("fogr/das/gossip/fetch/failure", nil)
+	gossipAnnounceFailureCounter = metrics.NewRegisteredCounter("fogr/das/gossip/announce/failure", nil)
+	gossipRejectedCounter        = metrics.NewRegisteredCounter("fogr/das/gossip/rejected", nil)
+	gossipRateLimitedCounter     = metrics.NewRegisteredCounter("fogr/das/gossip/ratelimited", nil)
+)
+
+type GossipSyncConfig struct {
+	Enable                bool          `koanf:"enable"`
+	Addr                  string        `koanf:"addr"`
+	Port                  uint64        `koanf:"port"`
+	URL                   string        `koanf:"url"`
+	Peers                 []string      `koanf:"peers"`
+	Secret                string        `koanf:"secret"`
+	AnnounceInterval      time.Duration `koanf:"announce-interval"`
+	RequestTimeout        time.Duration `koanf:"request-timeout"`
+	MaxFetchQueue         int           `koanf:"max-fetch-queue"`
+	AnnouncedCache        int           `koanf:"announced-cache"`
+	MaxRetentionPeriod    time.Duration `koanf:"max-retention-period"`
+	MaxAnnouncedPerSecond float64       `koanf:"max-announced-per-second"`
+	MaxAnnouncedBurst     int           `koanf:"max-announced-burst"`
+}
+
+var DefaultGossipSyncConfig = GossipSyncConfig{
+	Addr:                  "localhost",
+	Port:                  9878,
+	AnnounceInterval:      500 * time.Millisecond,
+	RequestTimeout:        5 * time.Second,
+	MaxFetchQueue:         10000,
+	AnnouncedCache:        100000,
+	MaxRetentionPeriod:    time.Hour * 24 * 15,
+	MaxAnnouncedPerSecond: 100,
+	MaxAnnouncedBurst:     1000,
+}
+
+func GossipSyncConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Bool(prefix+".enable", DefaultGossipSyncConfig.Enable, "enable announcing newly stored data to peer mirrors and pulling data they announce")
+	f.String(prefix+".addr", DefaultGossipSyncConfig.Addr, "gossip server listening interface")
+	f.Uint64(prefix+".port", DefaultGossipSyncConfig.Port, "gossip server listening port")
+	f.String(prefix+".url", DefaultGossipSyncConfig.URL, "URL at which peers can reach this mirror's gossip server, sent along with announcements")
+	f.StringSlice(prefix+".peers", DefaultGossipSyncConfig.Peers, "URLs of the gossip servers of peer mirrors; announcements are only accepted from these peers")
+	f.String(prefix+".secret", DefaultGossipSyncConfig.Secret, "secret shared by all peer mirrors, used to authenticate announcements")
+	f.Duration(prefix+".announce-interval", DefaultGossipSyncConfig.AnnounceInterval, "interval at which newly stored hashes are announced to peers")
+	f.Duration(prefix+".request-timeout", DefaultGossipSyncConfig.RequestTimeout, "timeout for announcing to and fetching from peers")
+	f.Int(prefix+".max-fetch-queue", DefaultGossipSyncConfig.MaxFetchQueue, "maximum number of announced hashes waiting to be fetched; further announcements are dropped until the queue drains")
+	f.Int(prefix+".announced-cache", DefaultGossipSyncConfig.AnnouncedCache, "number of recently announced hashes to remember, to avoid announcing the same data twice")
+	f.Duration(prefix+".max-retention-period", DefaultGossipSyncConfig.MaxRetentionPeriod, "maximum period to retain data fetched from peers; later expirations announced by peers are shortened to this")
+	f.Float64(prefix+".max-announced-per-second", DefaultGossipSyncConfig.MaxAnnouncedPerSecond, "sustained number of announced hashes per second accepted from each peer")
+	f.Int(prefix+".max-announced-burst", DefaultGossipSyncConfig.MaxAnnouncedBurst, "number of announced hashes accepted from each peer in a burst")
+}
+
+type GossipEntry struct {
+	Hash       common.Hash `json:"hash"`
+	Expiration uint64      `json:"expiration"`
+}
+
+type GossipAnnouncement struct {
+	From    string        `json:"from"`
+	Entries []GossipEntry `json:"entries"`
+}
+
+type gossipFetch struct {
+	peer  string
+	entry GossipEntry
+}
+
+const gossipAnnouncePath = "/gossip/announce"
+const gossipGetByHashPath = "/gossip/get-by-hash/"
+const gossipSignatureHeader = "X-Gossip-Signature"
+
+// GossipSync keeps a set of DAS mirrors in sync by having each announce the hashes of newly stored
+// data to its peers. A mirror receiving an announcement for data it doesn't have pulls the preimage
+// from the announcing peer, checks it against its hash, and stores it, which in turn announces it
+// to the mirror's own peers. Since data is only announced once it is newly stored, announcements
+// stop spreading once every mirror has the data.
+//
+// Announcements are authenticated with an HMAC of their body under a secret shared by the peers,
+// are rate limited per peer, and can't ask a mirror to keep data longer than its MaxRetentionPeriod.
+type GossipSync struct {
+	stopwaiter.StopWaiter
+	config   GossipSyncConfig
+	storage  StorageService
+	peers    map[string]*tokenBucket
+	client   *http.Client
+	server   *http.Server
+	listener net.Listener
+
+	pendingMutex sync.Mutex
+	pending      []GossipEntry
+	announced    *containers.LruCache[common.Hash, bool]
+	fetchQueue   chan gossipFetch
+	limitMutex   sync.Mutex
+}
+
+func NewGossipSync(config GossipSyncConfig, storage StorageService, serverTimeouts genericconf.HTTPServerTimeoutConfig) (*GossipSync, error) {
+	listener, err := net.Listen("tcp", fmt.Sprintf("%s:%d", config.Addr, config.Port))
+	if err != nil {
+		return nil, err
+	}
+	return NewGossipSyncOnListener(listener, config, storage, serverTimeouts)
+}
+
+func NewGossipSyncOnListener(listener net.Listener, config GossipSyncConfig, storage StorageService, serverTimeouts genericconf.HTTPServerTimeoutConfig) (*GossipSync, error) {
+	if config.URL == "" {
+		return nil, errors.New("gossip-sync.url must be set so that peers can fetch announced data")
+	}
+	if config.Secret == "" {
+		return nil, errors.New("gossip-sync.secret must be set so that announcements can be authenticated")
+	}
+	if config.MaxFetchQueue <= 0 {
+		return nil, errors.New("gossip-sync.max-fetch-queue must be positive")
+	}
+	if config.MaxAnnouncedPerSecond <= 0 || config.MaxAnnouncedBurst <= 0 {
+		return nil, errors.New("gossip-sync.max-announced-per-second and gossip-sync.max-announced-burst must be positive")
+	}
+	peers := make(map[string]*tokenBucket)
+	for _, peer := range config.Peers {
+		peers[strings.TrimSuffix(peer, "/")] = &tokenBucket{
+			tokens:    float64(config.MaxAnnouncedBurst),
+			lastCheck: time.Now(),
+			rate:      config.MaxAnnouncedPerSecond,
+			burst:     float64(config.MaxAnnouncedBurst),
+		}
+	}
+	g := &GossipSync{
+		config:     config,
+		storage:    storage,
+		peers:      peers,
+		client:     &http.Client{Timeout: config.RequestTimeout},
+		listener:   listener,
+		announced:  containers.NewLruCache[common.Hash, bool](config.AnnouncedCache),
+		fetchQueue: make(chan gossipFetch, config.MaxFetchQueue),
+	}
+	g.server = &http.Server{
+		Handler:           g,
+		ReadTimeout:       serverTimeouts.ReadTimeout,
+		ReadHeaderTimeout: serverTimeouts.ReadHeaderTimeout,
+		WriteTimeout:      serverTimeouts.WriteTimeout,
+		IdleTimeout:       serverTimeouts.IdleTimeout,
+	}
+	return g, nil
+}
+
+func (g *GossipSync) Start(ctx context.Context) {
+	g.StopWaiter.Start(ctx, g)
+	g.LaunchThread(func(ctx context.Context) {
+		err := g.server.Serve(g.listener)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error("Gossip server exited", "err", err)
+		}
+	})
+	g.LaunchThread(func(ctx context.Context) {
+		<-ctx.Done()
+		_ = g.server.Close()
+	})
+	g.CallIteratively(g.flushAnnouncements)
+	g.LaunchThread(g.fetchLoop)
+}
+
+func (g *GossipSync) StopAndWait() {
+	g.StopWaiter.StopAndWait()
+}
+
+func (g *GossipSync) Close(ctx context.Context) error {
+	g.StopAndWait()
+	return nil
+}
+
+func (g *GossipSync) String() string {
+	return fmt.Sprintf("GossipSync{url:%s, peers:%v}", g.config.URL, g.config.Peers)
+}
+
+// announce queues a newly stored hash to be announced to peers.
+func (g *GossipSync) announce(hash common.Hash, expiration uint64) {
+	g.pendingMutex.Lock()
+	defer g.pendingMutex.Unlock()
+	if g.announced.Contains(hash) {
+		return
+	}
+	g.announced.Add(hash, true)
+	g.pending = append(g.pending, GossipEntry{hash, expiration})
+}
+
+func (g *GossipSync) flushAnnouncements(ctx context.Context) time.Duration {
+	g.pendingMutex.Lock()
+	entries := g.pending
+	g.pending = nil
+	g.pendingMutex.Unlock()
+	if len(entries) == 0 {
+		return g.config.AnnounceInterval
+	}
+
+	body, err := json.Marshal(GossipAnnouncement{From: g.config.URL, Entries: entries})
+	if err != nil {
+		log.Error("Failed to encode gossip announcement", "err", err)
+		return g.config.AnnounceInterval
+	}
+	var wg sync.WaitGroup
+	for peer := range g.peers {
+		wg.Add(1)
+		go func(peer string) {
+			defer wg.Done()
+			if err := g.postAnnouncement(ctx, peer, body); err != nil {
+				gossipAnnounceFailureCounter.Inc(1)
+				log.Warn("Failed to announce to gossip peer", "peer", peer, "entries", len(entries), "err", err)
+			}
+		}(peer)
+	}
+	wg.Wait()
+	gossipAnnouncedCounter.Inc(int64(len(entries)))
+	return g.config.AnnounceInterval
+}
+
+func (g *GossipSync) postAnnouncement(ctx context.Context, peer string, body []byte) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, peer+gossipAnnouncePath, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(gossipSignatureHeader, hex.EncodeToString(g.sign(body)))
+	res, err := g.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP error with status %d returned by peer: %s", res.StatusCode, http.StatusText(res.StatusCode))
+	}
+	return nil
+}
+
+func (g *GossipSync) fetchLoop(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case fetch := <-g.fetchQueue:
+			if err := g.fetch(ctx, fetch); err != nil {
+				gossipFetchFailureCounter.Inc(1)
+				log.Warn("Failed to fetch announced data from gossip peer", "peer", fetch.peer, "hash", fetch.entry.Hash, "err", err)
+			}
+		}
+	}
+}
+
+func (g *GossipSync) fetch(ctx context.Context, fetch gossipFetch) error {
+	if fetch.entry.Expiration <= uint64(time.Now().Unix()) {
+		return nil
+	}
+	if _, err := g.storage.GetByHash(ctx, fetch.entry.Hash); err == nil {
+		return nil
+	}
+	url := fetch.peer + gossipGetByHashPath + EncodeStorageServiceKey(fetch.entry.Hash)
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	res, err := g.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP error with status %d returned by peer: %s", res.StatusCode, http.StatusText(res.StatusCode))
+	}
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	if !dastree.ValidHash(fetch.entry.Hash, data) {
+		return fogstate.ErrHashMismatch
+	}
+	if err := g.storage.Put(ctx, data, fetch.entry.Expiration); err != nil {
+		return err
+	}
+	gossipFetchedCounter.Inc(1)
+	g.announce(fetch.entry.Hash, fetch.entry.Expiration)
+	return nil
+}
+
+func (g *GossipSync) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	requestPath := path.Clean(r.URL.Path)
+	switch {
+	case requestPath == gossipAnnouncePath && r.Method == http.MethodPost:
+		g.announceHandler(w, r)
+	case strings.HasPrefix(requestPath, gossipGetByHashPath) && r.Method == http.MethodGet:
+		g.getByHashHandler(w, r, requestPath)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+func (g *GossipSync) sign(body []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(g.config.Secret))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
+
+// takeAnnounced returns how many of count announced hashes the peer's rate limit allows.
+func (g *GossipSync) takeAnnounced(bucket *tokenBucket, count int) int {
+	g.limitMutex.Lock()
+	defer g.limitMutex.Unlock()
+	now := time.Now()
+	for i := 0; i < count; i++ {
+		if !bucket.take(now) {
+			return i
+		}
+	}
+	return count
+}
+
+func (g *GossipSync) announceHandler(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, 16*1024*1024))
+	if err != nil {
+		log.Warn("Failed to read gossip announcement", "remoteAddr", r.RemoteAddr, "err", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	signature, err := hex.DecodeString(r.Header.Get(gossipSignatureHeader))
+	if err != nil || !hmac.Equal(signature, g.sign(body)) {
+		gossipRejectedCounter.Inc(1)
+		log.Warn("Ignoring unauthenticated gossip announcement", "remoteAddr", r.RemoteAddr)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	var announcement GossipAnnouncement
+	if err := json.Unmarshal(body, &announcement); err != nil {
+		log.Warn("Failed to decode gossip announcement", "remoteAddr", r.RemoteAddr, "err", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	peer := strings.TrimSuffix(announcement.From, "/")
+	bucket, ok := g.peers[peer]
+	if !ok {
+		gossipRejectedCounter.Inc(1)
+		log.Warn("Ignoring gossip announcement from unknown peer", "from", announcement.From, "remoteAddr", r.RemoteAddr)
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	gossipReceivedCounter.Inc(int64(len(announcement.Entries)))
+	entries := announcement.Entries
+	if allowed := g.takeAnnounced(bucket, len(entries)); allowed < len(entries) {
+		// The regular sync mechanisms will pick up anything dropped here.
+		gossipRateLimitedCounter.Inc(int64(len(entries) - allowed))
+		log.Warn("Gossip peer is over its rate limit, dropping announcements", "peer", peer, "dropped", len(entries)-allowed)
+		entries = entries[:allowed]
+	}
+	maxExpiration := uint64(time.Now().Add(g.config.MaxRetentionPeriod).Unix())
+	for _, entry := range entries {
+		if entry.Expiration > maxExpiration {
+			entry.Expiration = maxExpiration
+		}
+		select {
+		case g.fetchQueue <- gossipFetch{peer, entry}:
+		default:
+			// The regular sync mechanisms will pick up anything dropped here.
+			log.Warn("Gossip fetch queue full, dropping announcement", "peer", peer, "hash", entry.Hash)
+		}
+	}
+	if len(entries) < len(announcement.Entries) {
+		w.Header().Set("Retry-After", "1")
+		w.WriteHeader(http.StatusTooManyRequests)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (g *GossipSync) getByHashHandler(w http.ResponseWriter, r *http.Request, requestPath string) {
+	hash, err := DecodeStorageServiceKey(strings.TrimPrefix(requestPath, gossipGetByHashPath))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	data, err := g.storage.GetByHash(r.Context(), hash)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	if _, err := w.Write(data); err != nil {
+		log.Warn("Failed writing gossip response", "hash", hash, "err", err)
+	}
+}
+
+// GossipStorageService announces data newly Put into the underlying StorageService to peers.
+type GossipStorageService struct {
+	StorageService
+	gossip *GossipSync
+}
+
+func NewGossipStorageService(storageService StorageService, gossip *GossipSync) *GossipStorageService {
+	return &GossipStorageService{storageService, gossip}
+}
+
+func (s *GossipStorageService) Put(ctx context.Context, data []byte, expiration uint64) error {
+	if err := s.StorageService.Put(ctx, data, expiration); err != nil {
+		return err
+	}
+	s.gossip.announce(dastree.Hash(data), expiration)
+	return nil
+}
+
+func (s *GossipStorageService) String() string {
+	return fmt.Sprintf("GossipStorageService(%v)", s.StorageService)
+}
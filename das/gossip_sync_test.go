@@ -0,0 +1,185 @@
+// Copyright 2022, Offchain Labs, Inc.
+// For license information, see https://github.com/fogr/blob/master/LICENSE
+
+package das
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/FOGRCC/fogr/cmd/genericconf"
+	"github.com/FOGRCC/fogr/das/dastree"
+	"github.com/FOGRCC/fogr/util/testhelpers"
+)
+
+func TestGossipSync(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Mirrors are connected in a line, so data stored on the first has to be relayed by the
+	// second to reach the third.
+	const numMirrors = 3
+	listeners := make([]net.Listener, numMirrors)
+	urls := make([]string, numMirrors)
+	for i := range listeners {
+		var err error
+		listeners[i], err = net.Listen("tcp", "localhost:0")
+		Require(t, err)
+		urls[i] = "http://" + listeners[i].Addr().String()
+	}
+	storages := make([]StorageService, numMirrors)
+	mirrors := make([]*GossipStorageService, numMirrors)
+	for i := range mirrors {
+		config := DefaultGossipSyncConfig
+		config.Enable = true
+		config.URL = urls[i]
+		config.Secret = "test secret"
+		config.AnnounceInterval = 20 * time.Millisecond
+		config.Peers = nil
+		if i > 0 {
+			config.Peers = append(config.Peers, urls[i-1])
+		}
+		if i < numMirrors-1 {
+			config.Peers = append(config.Peers, urls[i+1])
+		}
+		storages[i] = NewMemoryBackedStorageService(ctx)
+		gossip, err := NewGossipSyncOnListener(listeners[i], config, storages[i], genericconf.HTTPServerTimeoutConfigDefault)
+		Require(t, err)
+		gossip.Start(ctx)
+		defer gossip.StopAndWait()
+		mirrors[i] = NewGossipStorageService(storages[i], gossip)
+	}
+
+	data := testhelpers.RandomizeSlice(make([]byte, 3*dastree.BinSize))
+	expiration := uint64(time.Now().Add(time.Hour).Unix())
+	Require(t, mirrors[0].Put(ctx, data, expiration))
+
+	for i := 1; i < numMirrors; i++ {
+		var res []byte
+		var err error
+		for start := time.Now(); time.Since(start) < 10*time.Second; time.Sleep(20 * time.Millisecond) {
+			res, err = storages[i].GetByHash(ctx, dastree.Hash(data))
+			if err == nil {
+				break
+			}
+		}
+		Require(t, err, "mirror", i, "never received the data")
+		if !bytes.Equal(res, data) {
+			Fail(t, "mirror", i, "received the wrong data")
+		}
+	}
+
+	// Announcements without a valid signature are rejected, even if they claim to be from a peer.
+	postAnnouncement := func(announcement GossipAnnouncement, secret string) int {
+		body, err := json.Marshal(announcement)
+		Require(t, err)
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(body)
+		request, err := http.NewRequestWithContext(ctx, http.MethodPost, urls[0]+gossipAnnouncePath, bytes.NewReader(body))
+		Require(t, err)
+		request.Header.Set(gossipSignatureHeader, hex.EncodeToString(mac.Sum(nil)))
+		res, err := http.DefaultClient.Do(request)
+		Require(t, err)
+		res.Body.Close()
+		return res.StatusCode
+	}
+	if status := postAnnouncement(GossipAnnouncement{From: urls[1]}, "wrong secret"); status != http.StatusUnauthorized {
+		Fail(t, "expected an unauthenticated announcement to be rejected, got status", status)
+	}
+
+	// Announcements from outside the configured peers are rejected.
+	if status := postAnnouncement(GossipAnnouncement{From: "http://localhost:1"}, "test secret"); status != http.StatusForbidden {
+		Fail(t, "expected an announcement from an unknown peer to be rejected, got status", status)
+	}
+
+	// Announcing more hashes than a peer's burst allows is rate limited.
+	entries := make([]GossipEntry, DefaultGossipSyncConfig.MaxAnnouncedBurst+1)
+	for i := range entries {
+		entries[i] = GossipEntry{Hash: dastree.Hash([]byte{byte(i), byte(i >> 8)}), Expiration: 1}
+	}
+	if status := postAnnouncement(GossipAnnouncement{From: urls[1], Entries: entries}, "test secret"); status != http.StatusTooManyRequests {
+		Fail(t, "expected announcements over the rate limit to be rejected, got status", status)
+	}
+}
+
+func TestGossipSyncClampsExpiration(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The peer keeps data forever, but this mirror only keeps what it fetches for its own retention period.
+	peerStorage := NewMemoryBackedStorageService(ctx)
+	data := []byte("data announced with a distant expiration")
+	Require(t, peerStorage.Put(ctx, data, math.MaxUint64))
+	peer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		res, err := peerStorage.GetByHash(r.Context(), dastree.Hash(data))
+		Require(t, err)
+		_, err = w.Write(res)
+		Require(t, err)
+	}))
+	defer peer.Close()
+
+	listener, err := net.Listen("tcp", "localhost:0")
+	Require(t, err)
+	config := DefaultGossipSyncConfig
+	config.Enable = true
+	config.URL = "http://" + listener.Addr().String()
+	config.Peers = []string{peer.URL}
+	config.Secret = "test secret"
+	config.MaxRetentionPeriod = time.Hour
+	storage := &expirationRecordingStorage{StorageService: NewMemoryBackedStorageService(ctx)}
+	gossip, err := NewGossipSyncOnListener(listener, config, storage, genericconf.HTTPServerTimeoutConfigDefault)
+	Require(t, err)
+	gossip.Start(ctx)
+	defer gossip.StopAndWait()
+
+	body, err := json.Marshal(GossipAnnouncement{From: peer.URL, Entries: []GossipEntry{{dastree.Hash(data), math.MaxUint64}}})
+	Require(t, err)
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, config.URL+gossipAnnouncePath, bytes.NewReader(body))
+	Require(t, err)
+	request.Header.Set(gossipSignatureHeader, hex.EncodeToString(gossip.sign(body)))
+	res, err := http.DefaultClient.Do(request)
+	Require(t, err)
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		Fail(t, "announcement was rejected with status", res.StatusCode)
+	}
+
+	for start := time.Now(); time.Since(start) < 10*time.Second; time.Sleep(20 * time.Millisecond) {
+		if storage.lastExpiration() != 0 {
+			break
+		}
+	}
+	if expiration := storage.lastExpiration(); expiration == 0 || expiration > uint64(time.Now().Add(time.Hour).Unix()) {
+		Fail(t, "fetched data wasn't stored with a clamped expiration", expiration)
+	}
+}
+
+type expirationRecordingStorage struct {
+	StorageService
+	mutex      sync.Mutex
+	expiration uint64
+}
+
+func (s *expirationRecordingStorage) Put(ctx context.Context, data []byte, expiration uint64) error {
+	s.mutex.Lock()
+	s.expiration = expiration
+	s.mutex.Unlock()
+	return s.StorageService.Put(ctx, data, expiration)
+}
+
+func (s *expirationRecordingStorage) lastExpiration() uint64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.expiration
+}
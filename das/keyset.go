@@ -0,0 +1,95 @@
+// Copyright 2022, Offchain Labs, Inc.
+// For license information, see https://github.com/fogr/blob/master/LICENSE
+
+package das
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/bits"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/FOGRCC/fogr/blsSignatures"
+	"github.com/FOGRCC/fogr/fogstate"
+	"github.com/FOGRCC/fogr/solgen/go/bridgegen"
+)
+
+// BuildKeyset creates the keyset for a committee whose members sign with pubKeys, where the i'th
+// key belongs to the member with signers mask 1<<i. It returns the keyset along with its
+// serialization and hash.
+func BuildKeyset(pubKeys []blsSignatures.PublicKey, assumedHonest uint64) (*fogstate.DataAvailabilityKeyset, []byte, common.Hash, error) {
+	if len(pubKeys) == 0 || len(pubKeys) > 64 {
+		return nil, nil, common.Hash{}, fmt.Errorf("a keyset must have between 1 and 64 keys, got %d", len(pubKeys))
+	}
+	if assumedHonest == 0 || assumedHonest > uint64(len(pubKeys)) {
+		return nil, nil, common.Hash{}, fmt.Errorf("assumed honest must be between 1 and the number of keys (%d), got %d", len(pubKeys), assumedHonest)
+	}
+	keyset := &fogstate.DataAvailabilityKeyset{
+		AssumedHonest: assumedHonest,
+		PubKeys:       pubKeys,
+	}
+	ksBuf := bytes.NewBuffer([]byte{})
+	if err := keyset.Serialize(ksBuf); err != nil {
+		return nil, nil, common.Hash{}, err
+	}
+	keysetHash, err := keyset.Hash()
+	if err != nil {
+		return nil, nil, common.Hash{}, err
+	}
+	return keyset, ksBuf.Bytes(), keysetHash, nil
+}
+
+// PubKeysFromBackends returns the public keys in an rpc-aggregator backends configuration, ordered by signers mask.
+func PubKeysFromBackends(backends string) ([]blsSignatures.PublicKey, error) {
+	var cs []BackendConfig
+	if err := json.Unmarshal([]byte(backends), &cs); err != nil {
+		return nil, err
+	}
+	var aggSignersMask uint64
+	for _, b := range cs {
+		if bits.OnesCount64(b.SignerMask) != 1 {
+			return nil, fmt.Errorf("backend %s has invalid signersMask %X", b.URL, b.SignerMask)
+		}
+		aggSignersMask |= b.SignerMask
+	}
+	if bits.OnesCount64(aggSignersMask) != len(cs) {
+		return nil, errors.New("at least two backends share a signersMask")
+	}
+	if aggSignersMask&(aggSignersMask+1) != 0 {
+		return nil, errors.New("backend signersMasks must be consecutive bits starting from 1")
+	}
+	sort.Slice(cs, func(i, j int) bool { return cs[i].SignerMask < cs[j].SignerMask })
+
+	pubKeys := []blsSignatures.PublicKey{}
+	for _, b := range cs {
+		pubKey, err := DecodeBase64BLSPublicKey([]byte(b.PubKeyBase64Encoded))
+		if err != nil {
+			return nil, fmt.Errorf("invalid pubkey for backend %s: %w", b.URL, err)
+		}
+		pubKeys = append(pubKeys, *pubKey)
+	}
+	return pubKeys, nil
+}
+
+// SetValidKeysetCalldata returns the calldata for a SequencerInbox setValidKeyset call registering the serialized keyset.
+func SetValidKeysetCalldata(keysetBytes []byte) ([]byte, error) {
+	inboxAbi, err := bridgegen.SequencerInboxMetaData.GetAbi()
+	if err != nil {
+		return nil, err
+	}
+	return inboxAbi.Pack("setValidKeyset", keysetBytes)
+}
+
+// InvalidateKeysetHashCalldata returns the calldata for a SequencerInbox invalidateKeysetHash call,
+// which retires a keyset once a rotation away from it is complete.
+func InvalidateKeysetHashCalldata(keysetHash common.Hash) ([]byte, error) {
+	inboxAbi, err := bridgegen.SequencerInboxMetaData.GetAbi()
+	if err != nil {
+		return nil, err
+	}
+	return inboxAbi.Pack("invalidateKeysetHash", keysetHash)
+}
@@ -0,0 +1,58 @@
+// Copyright 2022, Offchain Labs, Inc.
+// For license information, see https://github.com/fogr/blob/master/LICENSE
+
+package das
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/FOGRCC/fogr/blsSignatures"
+	"github.com/FOGRCC/fogr/fogstate"
+)
+
+func TestBuildKeyset(t *testing.T) {
+	var pubKeys []blsSignatures.PublicKey
+	var backends []BackendConfig
+	for i := 0; i < 3; i++ {
+		pubKey, _, err := blsSignatures.GenerateKeys()
+		Require(t, err)
+		pubKeys = append(pubKeys, pubKey)
+		backends = append([]BackendConfig{{
+			URL:                 "http://localhost",
+			PubKeyBase64Encoded: blsPubToBase64(&pubKey),
+			SignerMask:          1 << i,
+		}}, backends...)
+	}
+
+	_, keysetBytes, keysetHash, err := BuildKeyset(pubKeys, 2)
+	Require(t, err)
+	keyset, err := fogstate.DeserializeKeyset(bytes.NewReader(keysetBytes), false)
+	Require(t, err)
+	if keyset.AssumedHonest != 2 || len(keyset.PubKeys) != 3 {
+		Fail(t, "unexpected deserialized keyset", keyset)
+	}
+	hash, err := keyset.Hash()
+	Require(t, err)
+	if hash != keysetHash {
+		Fail(t, "keyset hash mismatch")
+	}
+
+	backendsJson, err := json.Marshal(backends)
+	Require(t, err)
+	backendPubKeys, err := PubKeysFromBackends(string(backendsJson))
+	Require(t, err)
+	_, _, backendsKeysetHash, err := BuildKeyset(backendPubKeys, 2)
+	Require(t, err)
+	if backendsKeysetHash != keysetHash {
+		Fail(t, "keyset from backends wasn't ordered by signers mask")
+	}
+
+	if _, _, _, err := BuildKeyset(pubKeys, 0); err == nil {
+		Fail(t, "expected keyset with no assumed honest members to be rejected")
+	}
+	if _, _, _, err := BuildKeyset(pubKeys, 4); err == nil {
+		Fail(t, "expected keyset with more assumed honest members than keys to be rejected")
+	}
+}
@@ -7,14 +7,19 @@ import (
 	"bytes"
 	"context"
 	"encoding/base32"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"os"
-	"time"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/FOGRCC/fogr/das/dastree"
 	"github.com/FOGRCC/fogr/fogstate"
 	"github.com/FOGRCC/fogr/util/pretty"
+	"github.com/FOGRCC/fogr/util/stopwaiter"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/log"
 	flag "github.com/spf13/pflag"
@@ -22,32 +27,75 @@ import (
 )
 
 type LocalFileStorageConfig struct {
-	Enable                  bool   `koanf:"enable"`
-	DataDir                 string `koanf:"data-dir"`
-	SyncFromStorageServices bool   `koanf:"sync-from-storage-service"`
-	SyncToStorageServices   bool   `koanf:"sync-to-storage-service"`
+	Enable                  bool                `koanf:"enable"`
+	DataDir                 string              `koanf:"data-dir"`
+	DiscardAfterTimeout     bool                `koanf:"discard-after-timeout"`
+	ExpirySweeper           ExpirySweeperConfig `koanf:"expiry-sweeper"`
+	SyncFromStorageServices bool                `koanf:"sync-from-storage-service"`
+	SyncToStorageServices   bool                `koanf:"sync-to-storage-service"`
 }
 
 var DefaultLocalFileStorageConfig = LocalFileStorageConfig{
-	DataDir: "",
+	DataDir:       "",
+	ExpirySweeper: DefaultExpirySweeperConfig,
 }
 
 func LocalFileStorageConfigAddOptions(prefix string, f *flag.FlagSet) {
 	f.Bool(prefix+".enable", DefaultLocalFileStorageConfig.Enable, "enable storage/retrieval of sequencer batch data from a directory of files, one per batch")
 	f.String(prefix+".data-dir", DefaultLocalFileStorageConfig.DataDir, "local data directory")
+	f.Bool(prefix+".discard-after-timeout", DefaultLocalFileStorageConfig.DiscardAfterTimeout, "discard data after its expiry timeout")
+	ExpirySweeperConfigAddOptions(prefix+".expiry-sweeper", f)
 	f.Bool(prefix+".sync-from-storage-service", DefaultLocalFileStorageConfig.SyncFromStorageServices, "enable local storage to be used as a source for regular sync storage")
 	f.Bool(prefix+".sync-to-storage-service", DefaultLocalFileStorageConfig.SyncToStorageServices, "enable local storage to be used as a sink for regular sync storage")
 }
 
+// Expiration times of stored data are indexed by files named <expiration>_<key> in expiryIndexDir.
+// The files are grouped into one subdirectory per hour of expiration, named by the start of the hour,
+// so the sweeper only has to list the oldest hours rather than the whole index. The latest expiration
+// requested for each key is kept in expiryLatestDir, so data stored again with a later expiration is
+// not deleted by the sweep of an earlier index entry.
+const localFileExpiryIndexDir = "expiry-index"
+const localFileExpiryLatestDir = "expiry-latest"
+const localFileExpiryBucketSeconds = 60 * 60
+
+func localFileExpiryBucket(expiration uint64) string {
+	return fmt.Sprintf("%016x", expiration-expiration%localFileExpiryBucketSeconds)
+}
+
+func localFileExpiryIndexName(entry expiryEntry) string {
+	return filepath.Join(localFileExpiryIndexDir, localFileExpiryBucket(entry.expiration), fmt.Sprintf("%016x_%s", entry.expiration, EncodeStorageServiceKey(entry.key)))
+}
+
 type LocalFileStorageService struct {
-	dataDir string
+	dataDir             string
+	discardAfterTimeout bool
+	stopWaiter          stopwaiter.StopWaiterSafe
 }
 
-func NewLocalFileStorageService(dataDir string) (StorageService, error) {
+func NewLocalFileStorageService(ctx context.Context, config LocalFileStorageConfig) (StorageService, error) {
+	dataDir := config.DataDir
 	if unix.Access(dataDir, unix.W_OK|unix.R_OK) != nil {
 		return nil, fmt.Errorf("couldn't start LocalFileStorageService, directory '%s' must be readable and writeable", dataDir)
 	}
-	return &LocalFileStorageService{dataDir: dataDir}, nil
+	s := &LocalFileStorageService{
+		dataDir:             dataDir,
+		discardAfterTimeout: config.DiscardAfterTimeout,
+	}
+	if err := s.stopWaiter.Start(ctx, s); err != nil {
+		return nil, err
+	}
+	if config.DiscardAfterTimeout {
+		for _, dir := range []string{localFileExpiryIndexDir, localFileExpiryLatestDir} {
+			if err := os.MkdirAll(filepath.Join(dataDir, dir), 0o700); err != nil {
+				return nil, err
+			}
+		}
+		sweeper := newExpirySweeper(config.ExpirySweeper, s)
+		if err := s.stopWaiter.CallIteratively(sweeper.sweep); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
 }
 
 func (s *LocalFileStorageService) GetByHash(ctx context.Context, key common.Hash) ([]byte, error) {
@@ -71,29 +119,17 @@ func (s *LocalFileStorageService) GetByHash(ctx context.Context, key common.Hash
 
 func (s *LocalFileStorageService) Put(ctx context.Context, data []byte, timeout uint64) error {
 	logPut("das.LocalFileStorageService.Store", data, timeout, s)
-	fileName := EncodeStorageServiceKey(dastree.Hash(data))
-	finalPath := s.dataDir + "/" + fileName
+	return s.putWithExpiration(ctx, dastree.Hash(data), data, timeout)
+}
 
-	// Use a temp file and rename to achieve atomic writes.
-	f, err := os.CreateTemp(s.dataDir, fileName)
-	if err != nil {
-		return err
-	}
-	err = f.Chmod(0o600)
-	if err != nil {
-		return err
-	}
-	_, err = f.Write(data)
-	if err != nil {
+func (s *LocalFileStorageService) putWithExpiration(ctx context.Context, key common.Hash, value []byte, timeout uint64) error {
+	if err := s.putKeyValue(ctx, key, value); err != nil {
 		return err
 	}
-	err = f.Close()
-	if err != nil {
-		return err
+	if s.discardAfterTimeout && shouldIndex(timeout) {
+		return s.recordExpiration(key, timeout)
 	}
-
-	return os.Rename(f.Name(), finalPath)
-
+	return nil
 }
 
 func (s *LocalFileStorageService) putKeyValue(ctx context.Context, key common.Hash, value []byte) error {
@@ -127,10 +163,13 @@ func (s *LocalFileStorageService) Sync(ctx context.Context) error {
 }
 
 func (s *LocalFileStorageService) Close(ctx context.Context) error {
-	return nil
+	return s.stopWaiter.StopAndWait()
 }
 
 func (s *LocalFileStorageService) ExpirationPolicy(ctx context.Context) (fogstate.ExpirationPolicy, error) {
+	if s.discardAfterTimeout {
+		return fogstate.DiscardAfterDataTimeout, nil
+	}
 	return fogstate.KeepForever, nil
 }
 
@@ -140,7 +179,8 @@ func (s *LocalFileStorageService) String() string {
 
 func (s *LocalFileStorageService) HealthCheck(ctx context.Context) error {
 	testData := []byte("Test-Data")
-	err := s.Put(ctx, testData, uint64(time.Now().Add(time.Minute).Unix()))
+	// Written without an expiration so the health check doesn't add to the expiry index.
+	err := s.putKeyValue(ctx, dastree.Hash(testData), testData)
 	if err != nil {
 		return err
 	}
@@ -153,3 +193,134 @@ func (s *LocalFileStorageService) HealthCheck(ctx context.Context) error {
 	}
 	return nil
 }
+
+func (s *LocalFileStorageService) recordExpiration(key common.Hash, expiration uint64) error {
+	encodedKey := EncodeStorageServiceKey(key)
+	latestPath := filepath.Join(s.dataDir, localFileExpiryLatestDir, encodedKey)
+	if latest, err := os.ReadFile(latestPath); err == nil && len(latest) == 8 && binary.BigEndian.Uint64(latest) >= expiration {
+		return nil
+	}
+	var expirationBytes [8]byte
+	binary.BigEndian.PutUint64(expirationBytes[:], expiration)
+	if err := os.WriteFile(latestPath, expirationBytes[:], 0o600); err != nil {
+		return err
+	}
+	bucketDir := filepath.Join(s.dataDir, localFileExpiryIndexDir, localFileExpiryBucket(expiration))
+	indexPath := filepath.Join(s.dataDir, localFileExpiryIndexName(expiryEntry{expiration, key}))
+	for {
+		if err := os.MkdirAll(bucketDir, 0o700); err != nil {
+			return err
+		}
+		// The sweeper may remove the bucket between creating it and writing to it if it's already expired.
+		err := os.WriteFile(indexPath, nil, 0o600)
+		if !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+}
+
+func readDirNames(path string) ([]string, error) {
+	dir, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer dir.Close()
+	names, err := dir.Readdirnames(-1)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (s *LocalFileStorageService) expiredEntries(cutoff uint64, limit int) ([]expiryEntry, error) {
+	indexDir := filepath.Join(s.dataDir, localFileExpiryIndexDir)
+	buckets, err := readDirNames(indexDir)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []expiryEntry{}
+	for _, bucket := range buckets {
+		bucketStart, err := strconv.ParseUint(bucket, 16, 64)
+		if err != nil {
+			log.Warn("Unexpected file in expiry index", "name", bucket)
+			continue
+		}
+		if len(entries) >= limit || bucketStart > cutoff {
+			break
+		}
+		names, err := readDirNames(filepath.Join(indexDir, bucket))
+		if err != nil {
+			return nil, err
+		}
+		if len(names) == 0 {
+			// Buckets are removed once swept, unless an entry was added concurrently.
+			if err := os.Remove(filepath.Join(indexDir, bucket)); err != nil && !errors.Is(err, unix.ENOTEMPTY) && !errors.Is(err, os.ErrNotExist) {
+				log.Warn("Failed to remove empty expiry index bucket", "name", bucket, "err", err)
+			}
+			continue
+		}
+		for _, name := range names {
+			if len(entries) >= limit {
+				break
+			}
+			parts := strings.SplitN(name, "_", 2)
+			if len(parts) != 2 {
+				log.Warn("Unexpected file in expiry index", "name", name)
+				continue
+			}
+			expiration, err := strconv.ParseUint(parts[0], 16, 64)
+			if err != nil {
+				log.Warn("Unexpected file in expiry index", "name", name)
+				continue
+			}
+			if expiration > cutoff {
+				return entries, nil
+			}
+			key, err := DecodeStorageServiceKey(parts[1])
+			if err != nil {
+				log.Warn("Unexpected file in expiry index", "name", name)
+				continue
+			}
+			entries = append(entries, expiryEntry{expiration, key})
+		}
+	}
+	return entries, nil
+}
+
+func (s *LocalFileStorageService) deleteExpired(entries []expiryEntry) (uint64, error) {
+	var reclaimed uint64
+	for _, entry := range entries {
+		encodedKey := EncodeStorageServiceKey(entry.key)
+		latestPath := filepath.Join(s.dataDir, localFileExpiryLatestDir, encodedKey)
+		latest, err := os.ReadFile(latestPath)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return reclaimed, err
+		}
+		stillLive := err == nil && len(latest) == 8 && binary.BigEndian.Uint64(latest) > entry.expiration
+		if !stillLive {
+			for _, name := range []string{encodedKey, base32.StdEncoding.EncodeToString(entry.key.Bytes())} {
+				pathname := filepath.Join(s.dataDir, name)
+				info, err := os.Stat(pathname)
+				if errors.Is(err, os.ErrNotExist) {
+					continue
+				}
+				if err != nil {
+					return reclaimed, err
+				}
+				if err := os.Remove(pathname); err != nil {
+					return reclaimed, err
+				}
+				reclaimed += uint64(info.Size())
+			}
+			if err := os.Remove(latestPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return reclaimed, err
+			}
+		}
+		if err := os.Remove(filepath.Join(s.dataDir, localFileExpiryIndexName(entry))); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return reclaimed, err
+		}
+	}
+	return reclaimed, nil
+}
@@ -0,0 +1,314 @@
+// Copyright 2022, Offchain Labs, Inc.
+// For license information, see https://github.com/fogr/blob/master/LICENSE
+
+package das
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+type RateLimitConfig struct {
+	Enable            bool     `koanf:"enable"`
+	RequestsPerSecond float64  `koanf:"requests-per-second"`
+	Burst             int      `koanf:"burst"`
+	APIKeyHeader      string   `koanf:"api-key-header"`
+	APIKeysFile       string   `koanf:"api-keys-file"`
+	ClientIPHeader    string   `koanf:"client-ip-header"`
+	TrustedProxies    []string `koanf:"trusted-proxies"`
+}
+
+var DefaultRateLimitConfig = RateLimitConfig{
+	Enable:            false,
+	RequestsPerSecond: 10,
+	Burst:             20,
+	APIKeyHeader:      "X-Api-Key",
+	APIKeysFile:       "",
+	ClientIPHeader:    "",
+	TrustedProxies:    nil,
+}
+
+func RateLimitConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Bool(prefix+".enable", DefaultRateLimitConfig.Enable, "enable per-client rate limiting")
+	f.Float64(prefix+".requests-per-second", DefaultRateLimitConfig.RequestsPerSecond, "sustained requests per second allowed from each client IP without an API key")
+	f.Int(prefix+".burst", DefaultRateLimitConfig.Burst, "number of requests a client IP without an API key may make in a burst")
+	f.String(prefix+".api-key-header", DefaultRateLimitConfig.APIKeyHeader, "HTTP header clients can send an API key in")
+	f.String(prefix+".api-keys-file", DefaultRateLimitConfig.APIKeysFile, "JSON file listing API keys and their quotas, as [{\"key\": ..., \"name\": ..., \"requests-per-second\": ..., \"burst\": ...}]; a key with requests-per-second of 0 is unlimited")
+	f.String(prefix+".client-ip-header", DefaultRateLimitConfig.ClientIPHeader, "HTTP header holding the client IP when behind a trusted proxy (e.g. X-Forwarded-For); if empty the connection's remote address is used")
+	f.StringSlice(prefix+".trusted-proxies", DefaultRateLimitConfig.TrustedProxies, "IPs or CIDR ranges of proxies whose client-ip-header is trusted; the header is ignored on connections from anywhere else")
+}
+
+type APIKeyQuota struct {
+	Key               string  `json:"key"`
+	Name              string  `json:"name"`
+	RequestsPerSecond float64 `json:"requests-per-second"`
+	Burst             int     `json:"burst"`
+}
+
+func ReadAPIKeysFile(path string) (map[string]APIKeyQuota, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var quotas []APIKeyQuota
+	if err := json.Unmarshal(contents, &quotas); err != nil {
+		return nil, fmt.Errorf("invalid API keys file %s: %w", path, err)
+	}
+	keys := make(map[string]APIKeyQuota)
+	for _, quota := range quotas {
+		if quota.Key == "" {
+			return nil, fmt.Errorf("API keys file %s has an entry with no key", path)
+		}
+		if _, ok := keys[quota.Key]; ok {
+			return nil, fmt.Errorf("API keys file %s has a duplicate key for %s", path, quota.Name)
+		}
+		keys[quota.Key] = quota
+	}
+	return keys, nil
+}
+
+type tokenBucket struct {
+	tokens    float64
+	lastCheck time.Time
+	rate      float64
+	burst     float64
+}
+
+func (b *tokenBucket) take(now time.Time) bool {
+	b.tokens += now.Sub(b.lastCheck).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.lastCheck = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// full reports whether the bucket would have refilled by now, in which case forgetting it is harmless.
+func (b *tokenBucket) full(now time.Time) bool {
+	return b.tokens+now.Sub(b.lastCheck).Seconds()*b.rate >= b.burst
+}
+
+const rateLimitCleanupInterval = time.Minute
+
+type rateLimitResult int
+
+const (
+	rateLimitAllowed rateLimitResult = iota
+	rateLimitExceeded
+	rateLimitUnknownKey
+)
+
+// RateLimiter applies a token bucket rate limit to each client of an HTTP server. Clients that
+// send a known API key are limited by that key's quota, and other clients by their IP.
+type RateLimiter struct {
+	config         RateLimitConfig
+	keys           map[string]APIKeyQuota
+	trustedProxies []*net.IPNet
+	mutex          sync.Mutex
+	buckets        map[string]*tokenBucket
+	lastCleanup    time.Time
+
+	rejectedCounter   metrics.Counter
+	unknownKeyCounter metrics.Counter
+}
+
+// NewRateLimiter returns nil if rate limiting isn't enabled.
+func NewRateLimiter(config RateLimitConfig, metricBase string) (*RateLimiter, error) {
+	if !config.Enable {
+		return nil, nil
+	}
+	if config.RequestsPerSecond <= 0 || config.Burst <= 0 {
+		return nil, fmt.Errorf("rate limit requests-per-second and burst must be positive, got %v and %v", config.RequestsPerSecond, config.Burst)
+	}
+	keys := make(map[string]APIKeyQuota)
+	if config.APIKeysFile != "" {
+		var err error
+		keys, err = ReadAPIKeysFile(config.APIKeysFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if config.ClientIPHeader != "" && len(config.TrustedProxies) == 0 {
+		return nil, errors.New("rate limit client-ip-header requires trusted-proxies to be set")
+	}
+	trustedProxies, err := parseTrustedProxies(config.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+	return &RateLimiter{
+		config:            config,
+		keys:              keys,
+		trustedProxies:    trustedProxies,
+		buckets:           make(map[string]*tokenBucket),
+		lastCleanup:       time.Now(),
+		rejectedCounter:   metrics.GetOrRegisterCounter(metricBase+"/ratelimit/rejected", nil),
+		unknownKeyCounter: metrics.GetOrRegisterCounter(metricBase+"/ratelimit/unknownkey", nil),
+	}, nil
+}
+
+func parseTrustedProxies(proxies []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(proxies))
+	for _, proxy := range proxies {
+		if ip := net.ParseIP(proxy); ip != nil {
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %v, expected an IP or CIDR range", proxy)
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets, nil
+}
+
+func (l *RateLimiter) trustedProxy(address string) bool {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return false
+	}
+	for _, ipNet := range l.trustedProxies {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP returns the IP that the request is rate limited by. The client IP header is only used
+// if the connection comes from a trusted proxy. Each proxy appends the address it received the
+// request from to X-Forwarded-For style headers, so the hops are walked from the right, and the
+// first one that isn't a trusted proxy is the client. Anything to its left could be forged.
+func (l *RateLimiter) clientIP(r *http.Request) string {
+	remoteIP, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		remoteIP = r.RemoteAddr
+	}
+	if l.config.ClientIPHeader == "" || !l.trustedProxy(remoteIP) {
+		return remoteIP
+	}
+	clientIP := remoteIP
+	headers := r.Header.Values(l.config.ClientIPHeader)
+	for i := len(headers) - 1; i >= 0; i-- {
+		hops := strings.Split(headers[i], ",")
+		for j := len(hops) - 1; j >= 0; j-- {
+			hop := strings.TrimSpace(hops[j])
+			if net.ParseIP(hop) == nil {
+				// A malformed hop can't be attributed to anyone, so stop at the last hop we trust.
+				return clientIP
+			}
+			clientIP = hop
+			if !l.trustedProxy(hop) {
+				return clientIP
+			}
+		}
+	}
+	return clientIP
+}
+
+func (l *RateLimiter) check(r *http.Request) rateLimitResult {
+	bucketKey := "ip:" + l.clientIP(r)
+	rate, burst := l.config.RequestsPerSecond, l.config.Burst
+	if apiKey := r.Header.Get(l.config.APIKeyHeader); apiKey != "" {
+		quota, ok := l.keys[apiKey]
+		if !ok {
+			l.unknownKeyCounter.Inc(1)
+			return rateLimitUnknownKey
+		}
+		if quota.RequestsPerSecond <= 0 {
+			return rateLimitAllowed
+		}
+		bucketKey = "key:" + quota.Key
+		rate, burst = quota.RequestsPerSecond, quota.Burst
+		if burst <= 0 {
+			burst = 1
+		}
+	}
+
+	now := time.Now()
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if now.Sub(l.lastCleanup) > rateLimitCleanupInterval {
+		for key, bucket := range l.buckets {
+			if bucket.full(now) {
+				delete(l.buckets, key)
+			}
+		}
+		l.lastCleanup = now
+	}
+	bucket, ok := l.buckets[bucketKey]
+	if !ok {
+		bucket = &tokenBucket{tokens: float64(burst), lastCheck: now, rate: rate, burst: float64(burst)}
+		l.buckets[bucketKey] = bucket
+	}
+	if !bucket.take(now) {
+		l.rejectedCounter.Inc(1)
+		return rateLimitExceeded
+	}
+	return rateLimitAllowed
+}
+
+func writeRESTRateLimitError(w http.ResponseWriter, result rateLimitResult) {
+	if result == rateLimitUnknownKey {
+		http.Error(w, "unknown API key", http.StatusUnauthorized)
+		return
+	}
+	w.Header().Set("Retry-After", "1")
+	http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+}
+
+const (
+	jsonRPCRateLimitedCode  = -32005
+	jsonRPCUnauthorizedCode = -32001
+)
+
+func writeJSONRPCRateLimitError(w http.ResponseWriter, result rateLimitResult) {
+	status, code, message := http.StatusTooManyRequests, jsonRPCRateLimitedCode, "rate limit exceeded"
+	if result == rateLimitUnknownKey {
+		status, code, message = http.StatusUnauthorized, jsonRPCUnauthorizedCode, "unknown API key"
+	} else {
+		w.Header().Set("Retry-After", "1")
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, err := fmt.Fprintf(w, `{"jsonrpc":"2.0","id":null,"error":{"code":%d,"message":%q}}`, code, message)
+	if err != nil {
+		log.Debug("Failed writing rate limit response", "err", err)
+	}
+}
+
+// wrap returns a handler that rejects requests over the limit with reject, and passes the rest to next.
+// A nil RateLimiter doesn't limit anything.
+func (l *RateLimiter) wrap(next http.Handler, reject func(http.ResponseWriter, rateLimitResult)) http.Handler {
+	if l == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if result := l.check(r); result != rateLimitAllowed {
+			log.Debug("Rejected rate limited request", "remoteAddr", r.RemoteAddr, "path", r.URL.Path, "result", result)
+			reject(w, result)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
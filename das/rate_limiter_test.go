@@ -0,0 +1,147 @@
+// Copyright 2022, Offchain Labs, Inc.
+// For license information, see https://github.com/fogr/blob/master/LICENSE
+
+package das
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/FOGRCC/fogr/cmd/genericconf"
+)
+
+func testRateLimitConfig(t *testing.T) RateLimitConfig {
+	keysFile := filepath.Join(t.TempDir(), "keys.json")
+	err := os.WriteFile(keysFile, []byte(`[
+		{"key": "limited", "name": "limited client", "requests-per-second": 0.001, "burst": 3},
+		{"key": "unlimited", "name": "trusted mirror", "requests-per-second": 0}
+	]`), 0o600)
+	Require(t, err)
+	config := DefaultRateLimitConfig
+	config.Enable = true
+	config.RequestsPerSecond = 0.001
+	config.Burst = 2
+	config.APIKeysFile = keysFile
+	return config
+}
+
+func getWithAPIKey(t *testing.T, url string, apiKey string) int {
+	request, err := http.NewRequest(http.MethodGet, url, nil)
+	Require(t, err)
+	if apiKey != "" {
+		request.Header.Set(DefaultRateLimitConfig.APIKeyHeader, apiKey)
+	}
+	res, err := http.DefaultClient.Do(request)
+	Require(t, err)
+	res.Body.Close()
+	return res.StatusCode
+}
+
+func TestRestfulServerRateLimit(t *testing.T) {
+	ctx := context.Background()
+	rateLimiter, err := NewRateLimiter(testRateLimitConfig(t), "fogr/das/rest/test")
+	Require(t, err)
+	listener, err := net.Listen("tcp", "localhost:0")
+	Require(t, err)
+	storage := NewMemoryBackedStorageService(ctx)
+	server, err := NewRestfulDasServerOnListenerWithRateLimiter(listener, genericconf.HTTPServerTimeoutConfigDefault, rateLimiter, storage, storage)
+	Require(t, err)
+	defer func() {
+		Require(t, server.Shutdown())
+	}()
+	url := "http://" + listener.Addr().String() + healthRequestPath
+
+	for i := 0; i < 2; i++ {
+		if status := getWithAPIKey(t, url, ""); status != http.StatusOK {
+			Fail(t, "request within the IP burst failed with status", status)
+		}
+	}
+	if status := getWithAPIKey(t, url, ""); status != http.StatusTooManyRequests {
+		Fail(t, "request over the IP limit got status", status)
+	}
+
+	// API keys get their own quota, independent of the IP they come from.
+	for i := 0; i < 3; i++ {
+		if status := getWithAPIKey(t, url, "limited"); status != http.StatusOK {
+			Fail(t, "request within the API key burst failed with status", status)
+		}
+	}
+	if status := getWithAPIKey(t, url, "limited"); status != http.StatusTooManyRequests {
+		Fail(t, "request over the API key limit got status", status)
+	}
+	for i := 0; i < 10; i++ {
+		if status := getWithAPIKey(t, url, "unlimited"); status != http.StatusOK {
+			Fail(t, "request with an unlimited API key failed with status", status)
+		}
+	}
+	if status := getWithAPIKey(t, url, "bogus"); status != http.StatusUnauthorized {
+		Fail(t, "request with an unknown API key got status", status)
+	}
+}
+
+func TestRPCServerRateLimit(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	config := testRateLimitConfig(t)
+	config.Burst = 1
+	rateLimiter, err := NewRateLimiter(config, "fogr/das/rpc/test")
+	Require(t, err)
+	listener, err := net.Listen("tcp", "localhost:0")
+	Require(t, err)
+	storage := NewMemoryBackedStorageService(ctx)
+	_, err = StartDASRPCServerOnListenerWithRateLimiter(ctx, listener, genericconf.HTTPServerTimeoutConfigDefault, rateLimiter, storage, nil, storage)
+	Require(t, err)
+
+	client, err := NewDASRPCClient("http://" + listener.Addr().String())
+	Require(t, err)
+	Require(t, client.HealthCheck(ctx))
+	err = client.HealthCheck(ctx)
+	if err == nil || !strings.Contains(err.Error(), "rate limit exceeded") {
+		Fail(t, "expected a rate limit error, got", err)
+	}
+}
+
+func TestRateLimiterClientIP(t *testing.T) {
+	config := DefaultRateLimitConfig
+	config.Enable = true
+	config.ClientIPHeader = "X-Forwarded-For"
+	config.TrustedProxies = []string{"10.0.0.1", "192.168.0.0/16"}
+	rateLimiter, err := NewRateLimiter(config, "fogr/das/clientip/test")
+	Require(t, err)
+
+	for _, test := range []struct {
+		remoteAddr string
+		forwarded  []string
+		expected   string
+	}{
+		// The header is ignored unless the connection comes from a trusted proxy.
+		{"1.2.3.4:5678", []string{"5.6.7.8"}, "1.2.3.4"},
+		{"10.0.0.1:5678", nil, "10.0.0.1"},
+		{"10.0.0.1:5678", []string{"5.6.7.8"}, "5.6.7.8"},
+		// Hops added by trusted proxies are skipped, and anything left of the client could be forged.
+		{"10.0.0.1:5678", []string{"9.9.9.9, 5.6.7.8, 192.168.1.1"}, "5.6.7.8"},
+		{"10.0.0.1:5678", []string{"9.9.9.9", "5.6.7.8, 192.168.1.1"}, "5.6.7.8"},
+		{"10.0.0.1:5678", []string{"192.168.1.2, 192.168.1.1"}, "192.168.1.2"},
+		{"10.0.0.1:5678", []string{"5.6.7.8, not-an-ip"}, "10.0.0.1"},
+	} {
+		request, err := http.NewRequest(http.MethodGet, "http://localhost", nil)
+		Require(t, err)
+		request.RemoteAddr = test.remoteAddr
+		for _, forwarded := range test.forwarded {
+			request.Header.Add(config.ClientIPHeader, forwarded)
+		}
+		if ip := rateLimiter.clientIP(request); ip != test.expected {
+			Fail(t, "expected client IP", test.expected, "for", test.remoteAddr, test.forwarded, "but got", ip)
+		}
+	}
+
+	config.TrustedProxies = nil
+	if _, err := NewRateLimiter(config, "fogr/das/clientip/test"); err == nil {
+		Fail(t, "expected client-ip-header without trusted-proxies to be rejected")
+	}
+}
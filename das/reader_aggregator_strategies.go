@@ -5,12 +5,19 @@ package das
 
 import (
 	"errors"
+	"fmt"
+	"math"
 	"math/rand"
 	"sort"
+	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/metrics"
 
 	"github.com/FOGRCC/fogr/fogstate"
+	"github.com/FOGRCC/fogr/util/metricsutil"
 )
 
 var ErrNoReadersResponded = errors.New("no DAS readers responded successfully")
@@ -80,6 +87,192 @@ func (s *simpleExploreExploitStrategy) newInstance() aggregatorStrategyInstance
 	return &basicStrategyInstance{readerSets: readerSets}
 }
 
+// Latency Percentile Strategy
+// Readers are tried one at a time, ranked by their p50 latency weighted inversely by their success
+// rate, with samples decaying by age. If a reader hasn't responded by its p95 latency the request
+// is hedged by also trying the next reader. Readers without enough recent samples to rank are
+// tried first, but only waited on as long as the best ranked reader's p95.
+type latencyPercentileStrategy struct {
+	halfLife        time.Duration
+	minHedgeDelay   time.Duration
+	maxHedgeDelay   time.Duration
+	minSampleWeight float64
+
+	sync.RWMutex
+	readers []fogstate.DataAvailabilityReader
+	delays  []time.Duration
+
+	// Only accessed by update, which is called from a single goroutine.
+	readerMetrics map[fogstate.DataAvailabilityReader]*latencyPercentileReaderMetrics
+}
+
+type latencyPercentileReaderMetrics struct {
+	p50Gauge         metrics.Gauge
+	p95Gauge         metrics.Gauge
+	successRateGauge metrics.GaugeFloat64
+}
+
+func newLatencyPercentileStrategy(config LatencyPercentileStrategyConfig, maxHedgeDelay time.Duration) *latencyPercentileStrategy {
+	return &latencyPercentileStrategy{
+		halfLife:        config.HalfLife,
+		minHedgeDelay:   config.MinHedgeDelay,
+		maxHedgeDelay:   maxHedgeDelay,
+		minSampleWeight: config.MinSampleWeight,
+		readerMetrics:   make(map[fogstate.DataAvailabilityReader]*latencyPercentileReaderMetrics),
+	}
+}
+
+type readerLatencySummary struct {
+	p50         time.Duration
+	p95         time.Duration
+	successRate float64
+	weight      float64
+}
+
+func (s readerLatencySummary) score() time.Duration {
+	if s.successRate == 0 {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration(float64(s.p50) / s.successRate)
+}
+
+// summarizeReaderStats computes latency percentiles and the success rate of a reader, with each
+// sample's weight halving every halfLife.
+func summarizeReaderStats(stats readerStats, now time.Time, halfLife time.Duration) readerLatencySummary {
+	type weightedLatency struct {
+		latency time.Duration
+		weight  float64
+	}
+	var summary readerLatencySummary
+	var successes []weightedLatency
+	var successWeight float64
+	for _, stat := range stats {
+		weight := 1.0
+		if halfLife > 0 && !stat.at.IsZero() && now.After(stat.at) {
+			weight = math.Pow(0.5, float64(now.Sub(stat.at))/float64(halfLife))
+		}
+		summary.weight += weight
+		if stat.success {
+			successes = append(successes, weightedLatency{stat.latency, weight})
+			successWeight += weight
+		}
+	}
+	if summary.weight == 0 {
+		return summary
+	}
+	summary.successRate = successWeight / summary.weight
+	sort.Slice(successes, func(i, j int) bool { return successes[i].latency < successes[j].latency })
+	percentile := func(p float64) time.Duration {
+		var cumulative float64
+		for _, sample := range successes {
+			cumulative += sample.weight
+			if cumulative >= p*successWeight {
+				return sample.latency
+			}
+		}
+		if len(successes) == 0 {
+			return 0
+		}
+		return successes[len(successes)-1].latency
+	}
+	summary.p50 = percentile(0.5)
+	summary.p95 = percentile(0.95)
+	return summary
+}
+
+func (s *latencyPercentileStrategy) clampHedgeDelay(delay time.Duration) time.Duration {
+	if delay < s.minHedgeDelay {
+		delay = s.minHedgeDelay
+	}
+	if delay > s.maxHedgeDelay {
+		delay = s.maxHedgeDelay
+	}
+	return delay
+}
+
+func (s *latencyPercentileStrategy) update(readers []fogstate.DataAvailabilityReader, stats map[fogstate.DataAvailabilityReader]readerStats) {
+	now := time.Now()
+	var unranked, ranked []fogstate.DataAvailabilityReader
+	summaries := make(map[fogstate.DataAvailabilityReader]readerLatencySummary)
+	for _, reader := range readers {
+		summary := summarizeReaderStats(stats[reader], now, s.halfLife)
+		summaries[reader] = summary
+		s.updateMetrics(reader, summary)
+		if summary.weight < s.minSampleWeight || summary.weight == 0 {
+			unranked = append(unranked, reader)
+		} else {
+			ranked = append(ranked, reader)
+		}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		a, b := summaries[ranked[i]], summaries[ranked[j]]
+		if a.score() != b.score() {
+			return a.score() < b.score()
+		}
+		return a.p95 < b.p95
+	})
+
+	unrankedDelay := s.maxHedgeDelay
+	if len(ranked) > 0 && summaries[ranked[0]].successRate > 0 {
+		unrankedDelay = s.clampHedgeDelay(summaries[ranked[0]].p95)
+	}
+	newReaders := make([]fogstate.DataAvailabilityReader, 0, len(readers))
+	delays := make([]time.Duration, 0, len(readers))
+	for _, reader := range unranked {
+		newReaders = append(newReaders, reader)
+		delays = append(delays, unrankedDelay)
+	}
+	for _, reader := range ranked {
+		delay := s.maxHedgeDelay
+		if summary := summaries[reader]; summary.successRate > 0 {
+			delay = s.clampHedgeDelay(summary.p95)
+		}
+		newReaders = append(newReaders, reader)
+		delays = append(delays, delay)
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	s.readers = newReaders
+	s.delays = delays
+}
+
+func readerMetricName(reader fogstate.DataAvailabilityReader) string {
+	name := fmt.Sprint(reader)
+	if client, ok := reader.(*RestfulDasClient); ok {
+		name = strings.TrimPrefix(strings.TrimPrefix(client.url, "https://"), "http://")
+	}
+	return metricsutil.CanonicalizeMetricName(name)
+}
+
+func (s *latencyPercentileStrategy) updateMetrics(reader fogstate.DataAvailabilityReader, summary readerLatencySummary) {
+	m, ok := s.readerMetrics[reader]
+	if !ok {
+		metricBase := "fogr/das/restaggregator/reader/" + readerMetricName(reader)
+		m = &latencyPercentileReaderMetrics{
+			p50Gauge:         metrics.GetOrRegisterGauge(metricBase+"/latency/p50", nil),
+			p95Gauge:         metrics.GetOrRegisterGauge(metricBase+"/latency/p95", nil),
+			successRateGauge: metrics.GetOrRegisterGaugeFloat64(metricBase+"/successrate", nil),
+		}
+		s.readerMetrics[reader] = m
+	}
+	m.p50Gauge.Update(summary.p50.Milliseconds())
+	m.p95Gauge.Update(summary.p95.Milliseconds())
+	m.successRateGauge.Update(summary.successRate)
+}
+
+func (s *latencyPercentileStrategy) newInstance() aggregatorStrategyInstance {
+	s.RLock()
+	defer s.RUnlock()
+
+	si := &hedgingStrategyInstance{delay: s.maxHedgeDelay}
+	for i, reader := range s.readers {
+		si.readerSets = append(si.readerSets, []fogstate.DataAvailabilityReader{reader})
+		si.delays = append(si.delays, s.delays[i])
+	}
+	return si
+}
+
 // Sequential Strategy for Testing
 type testingSequentialStrategy struct {
 	abstractAggregatorStrategy
@@ -102,6 +295,31 @@ type aggregatorStrategyInstance interface {
 	nextReaders() []fogstate.DataAvailabilityReader
 }
 
+// A hedgingAggregatorStrategyInstance decides how long to wait for the readers it last returned
+// before also trying the next ones, instead of always waiting WaitBeforeTryNext.
+type hedgingAggregatorStrategyInstance interface {
+	aggregatorStrategyInstance
+	hedgeDelay() time.Duration
+}
+
+type hedgingStrategyInstance struct {
+	basicStrategyInstance
+	delays []time.Duration
+	delay  time.Duration
+}
+
+func (si *hedgingStrategyInstance) nextReaders() []fogstate.DataAvailabilityReader {
+	if len(si.delays) != 0 {
+		si.delay = si.delays[0]
+		si.delays = si.delays[1:]
+	}
+	return si.basicStrategyInstance.nextReaders()
+}
+
+func (si *hedgingStrategyInstance) hedgeDelay() time.Duration {
+	return si.delay
+}
+
 type basicStrategyInstance struct {
 	readerSets [][]fogstate.DataAvailabilityReader
 }
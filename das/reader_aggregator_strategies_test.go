@@ -34,27 +34,27 @@ func TestDAS_SimpleExploreExploit(t *testing.T) {
 	readers := []fogstate.DataAvailabilityReader{&dummyReader{0}, &dummyReader{1}, &dummyReader{2}, &dummyReader{3}, &dummyReader{4}, &dummyReader{5}}
 	stats := make(map[fogstate.DataAvailabilityReader]readerStats)
 	stats[readers[0]] = []readerStat{ // weighted avg 10s
-		{10 * time.Second, true},
+		{latency: 10 * time.Second, success: true},
 	}
 	stats[readers[1]] = []readerStat{ // weighted avg 5s
-		{6 * time.Second, true},
-		{4 * time.Second, true},
+		{latency: 6 * time.Second, success: true},
+		{latency: 4 * time.Second, success: true},
 	}
 	stats[readers[2]] = []readerStat{ // weighted avg 3 / (1/2) = 6s
-		{3 * time.Second, true},
-		{3 * time.Second, false},
+		{latency: 3 * time.Second, success: true},
+		{latency: 3 * time.Second, success: false},
 	}
 	stats[readers[3]] = []readerStat{ // weighted avg max int
-		{1 * time.Second, false},
-		{1 * time.Second, false},
+		{latency: 1 * time.Second, success: false},
+		{latency: 1 * time.Second, success: false},
 	}
 	stats[readers[4]] = []readerStat{ // weighted avg 3 / (1/3) = 9s
-		{3 * time.Second, true},
-		{3 * time.Second, false},
-		{3 * time.Second, false},
+		{latency: 3 * time.Second, success: true},
+		{latency: 3 * time.Second, success: false},
+		{latency: 3 * time.Second, success: false},
 	}
 	stats[readers[5]] = []readerStat{ // weighted avg 8s
-		{8 * time.Second, true},
+		{latency: 8 * time.Second, success: true},
 	}
 
 	expectedOrdering := []fogstate.DataAvailabilityReader{readers[1], readers[2], readers[5], readers[4], readers[0], readers[3]}
@@ -114,3 +114,64 @@ func TestDAS_SimpleExploreExploit(t *testing.T) {
 	}
 
 }
+
+func TestDAS_LatencyPercentile(t *testing.T) {
+	readers := []fogstate.DataAvailabilityReader{&dummyReader{0}, &dummyReader{1}, &dummyReader{2}, &dummyReader{3}, &dummyReader{4}}
+	now := time.Now()
+	samples := func(at time.Time, success bool, latencies ...time.Duration) readerStats {
+		var stats readerStats
+		for _, latency := range latencies {
+			stats = append(stats, readerStat{latency: latency, success: success, at: at})
+		}
+		return stats
+	}
+	stats := make(map[fogstate.DataAvailabilityReader]readerStats)
+	// p50 100ms, but a long tail
+	stats[readers[0]] = samples(now, true, 100*time.Millisecond, 100*time.Millisecond, 100*time.Millisecond, 900*time.Millisecond)
+	// p50 200ms, p95 300ms
+	stats[readers[1]] = samples(now, true, 200*time.Millisecond, 200*time.Millisecond, 300*time.Millisecond)
+	// p50 100ms, but only succeeds a quarter of the time
+	stats[readers[2]] = append(samples(now, true, 100*time.Millisecond), samples(now, false, time.Second, time.Second, time.Second)...)
+	// Only samples from long ago, so it needs to be measured again
+	stats[readers[3]] = samples(now.Add(-24*time.Hour), true, 10*time.Millisecond, 10*time.Millisecond)
+	// Never succeeds
+	stats[readers[4]] = samples(now, false, time.Second)
+
+	strategy := newLatencyPercentileStrategy(LatencyPercentileStrategyConfig{
+		HalfLife:        time.Hour,
+		MinHedgeDelay:   150 * time.Millisecond,
+		MinSampleWeight: 1,
+	}, 2*time.Second)
+	strategy.update(readers, stats)
+
+	expected := []struct {
+		reader int
+		delay  time.Duration
+	}{
+		{3, 900 * time.Millisecond}, // unranked, waited on as long as the best reader's p95
+		{0, 900 * time.Millisecond},
+		{1, 300 * time.Millisecond},
+		{2, 150 * time.Millisecond}, // p95 clamped to the minimum hedge delay
+		{4, 2 * time.Second},
+	}
+	si := strategy.newInstance()
+	hedging, ok := si.(hedgingAggregatorStrategyInstance)
+	if !ok {
+		Fail(t, "latency percentile strategy instance doesn't hedge")
+	}
+	for _, e := range expected {
+		next := si.nextReaders()
+		if len(next) != 1 {
+			Fail(t, "expected one reader at a time, got", len(next))
+		}
+		if next[0].(*dummyReader).int != e.reader {
+			Fail(t, fmt.Sprintf("expected reader %d, was %d", e.reader, next[0].(*dummyReader).int))
+		}
+		if hedging.hedgeDelay() != e.delay {
+			Fail(t, fmt.Sprintf("expected hedge delay %v for reader %d, was %v", e.delay, e.reader, hedging.hedgeDelay()))
+		}
+	}
+	if len(si.nextReaders()) != 0 {
+		Fail(t, "expected no more readers")
+	}
+}
@@ -71,6 +71,41 @@ func (c *RestfulDasClient) GetByHash(ctx context.Context, hash common.Hash) ([]b
 	return decodedBytes, nil
 }
 
+// GetChunk fetches the index'th 64kB bin of the tree-hashed preimage under hash, checking its
+// Merkle path to the root. It returns the bin along with the total size of the preimage.
+func (c *RestfulDasClient) GetChunk(ctx context.Context, hash common.Hash, index uint32) ([]byte, uint32, error) {
+	url := fmt.Sprintf("%s%s%s/%d", c.url, getChunkRequestPath, EncodeStorageServiceKey(hash), index)
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	res, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, 0, fmt.Errorf("HTTP error with status %d returned by server: %s", res.StatusCode, http.StatusText(res.StatusCode))
+	}
+
+	var response RestfulDasServerChunkResponse
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+		return nil, 0, err
+	}
+	chunk, err := base64.StdEncoding.DecodeString(response.Chunk)
+	if err != nil {
+		return nil, 0, err
+	}
+	offset, size, err := dastree.VerifyProof(hash, chunk, response.Proof)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w: %v", fogstate.ErrHashMismatch, err)
+	}
+	if uint64(offset) != uint64(index)*uint64(dastree.BinSize) {
+		return nil, 0, fmt.Errorf("%w: server returned chunk at offset %v instead of chunk %v", fogstate.ErrHashMismatch, offset, index)
+	}
+	return chunk, size, nil
+}
+
 func (c *RestfulDasClient) HealthCheck(ctx context.Context) error {
 	res, err := http.Get(c.url + healthRequestPath)
 	if err != nil {
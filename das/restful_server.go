@@ -11,10 +11,12 @@ import (
 	"net"
 	"net/http"
 	"path"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/FOGRCC/fogr/cmd/genericconf"
+	"github.com/FOGRCC/fogr/das/dastree"
 	"github.com/FOGRCC/fogr/fogstate"
 	"github.com/FOGRCC/fogr/util/pretty"
 	"github.com/ethereum/go-ethereum/common"
@@ -28,6 +30,12 @@ var (
 	restGetByHashFailureGauge       = metrics.NewRegisteredGauge("fogr/das/rest/getbyhash/failure", nil)
 	restGetByHashReturnedBytesGauge = metrics.NewRegisteredGauge("fogr/das/rest/getbyhash/bytes", nil)
 	restGetByHashDurationHistogram  = metrics.NewRegisteredHistogram("fogr/das/rest/getbyhash/duration", nil, metrics.NewBoundedHistogramSample())
+
+	restGetChunkRequestGauge       = metrics.NewRegisteredGauge("fogr/das/rest/getchunk/requests", nil)
+	restGetChunkSuccessGauge       = metrics.NewRegisteredGauge("fogr/das/rest/getchunk/success", nil)
+	restGetChunkFailureGauge       = metrics.NewRegisteredGauge("fogr/das/rest/getchunk/failure", nil)
+	restGetChunkReturnedBytesGauge = metrics.NewRegisteredGauge("fogr/das/rest/getchunk/bytes", nil)
+	restGetChunkDurationHistogram  = metrics.NewRegisteredHistogram("fogr/das/rest/getchunk/duration", nil, metrics.NewBoundedHistogramSample())
 )
 
 type RestfulDasServer struct {
@@ -38,15 +46,25 @@ type RestfulDasServer struct {
 	httpServerError      error
 }
 
-func NewRestfulDasServer(address string, port uint64, restServerTimeouts genericconf.HTTPServerTimeoutConfig, daReader fogstate.DataAvailabilityReader, daHealthChecker DataAvailabilityServiceHealthChecker) (*RestfulDasServer, error) {
+func NewRestfulDasServer(address string, port uint64, restServerTimeouts genericconf.HTTPServerTimeoutConfig, rateLimitConfig RateLimitConfig, daReader fogstate.DataAvailabilityReader, daHealthChecker DataAvailabilityServiceHealthChecker) (*RestfulDasServer, error) {
+	rateLimiter, err := NewRateLimiter(rateLimitConfig, "fogr/das/rest")
+	if err != nil {
+		return nil, err
+	}
 	listener, err := net.Listen("tcp", fmt.Sprintf("%s:%d", address, port))
 	if err != nil {
 		return nil, err
 	}
-	return NewRestfulDasServerOnListener(listener, restServerTimeouts, daReader, daHealthChecker)
+	return NewRestfulDasServerOnListenerWithRateLimiter(listener, restServerTimeouts, rateLimiter, daReader, daHealthChecker)
 }
 
 func NewRestfulDasServerOnListener(listener net.Listener, restServerTimeouts genericconf.HTTPServerTimeoutConfig, daReader fogstate.DataAvailabilityReader, daHealthChecker DataAvailabilityServiceHealthChecker) (*RestfulDasServer, error) {
+	return NewRestfulDasServerOnListenerWithRateLimiter(listener, restServerTimeouts, nil, daReader, daHealthChecker)
+}
+
+// NewRestfulDasServerOnListenerWithRateLimiter creates a server that rejects requests over the rateLimiter's
+// limits with a 429 status. A nil rateLimiter doesn't limit requests.
+func NewRestfulDasServerOnListenerWithRateLimiter(listener net.Listener, restServerTimeouts genericconf.HTTPServerTimeoutConfig, rateLimiter *RateLimiter, daReader fogstate.DataAvailabilityReader, daHealthChecker DataAvailabilityServiceHealthChecker) (*RestfulDasServer, error) {
 
 	ret := &RestfulDasServer{
 		daReader:             daReader,
@@ -55,7 +73,7 @@ func NewRestfulDasServerOnListener(listener net.Listener, restServerTimeouts gen
 	}
 
 	ret.server = &http.Server{
-		Handler:           ret,
+		Handler:           rateLimiter.wrap(ret, writeRESTRateLimitError),
 		ReadTimeout:       restServerTimeouts.ReadTimeout,
 		ReadHeaderTimeout: restServerTimeouts.ReadHeaderTimeout,
 		WriteTimeout:      restServerTimeouts.WriteTimeout,
@@ -78,6 +96,13 @@ type RestfulDasServerResponse struct {
 	ExpirationPolicy string `json:"expirationPolicy,omitempty"`
 }
 
+// RestfulDasServerChunkResponse holds one 64kB bin of a tree-hashed preimage,
+// along with its dastree Merkle path to the root.
+type RestfulDasServerChunkResponse struct {
+	Chunk string              `json:"chunk"`
+	Proof []dastree.ProofNode `json:"proof"`
+}
+
 var cacheControlKey = http.CanonicalHeaderKey("cache-control")
 
 const cacheControlValueDefault = "public, max-age=1"                                 // cache for up to 1 second (Used to reduce DOS possibility)
@@ -85,6 +110,7 @@ const cacheControlValueForSuccessfulGetByHash = "public, max-age=2419200, immuta
 const healthRequestPath = "/health"
 const expirationPolicyRequestPath = "/expiration-policy/"
 const getByHashRequestPath = "/get-by-hash/"
+const getChunkRequestPath = "/get-chunk/"
 
 func (rds *RestfulDasServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	w.Header()[cacheControlKey] = []string{cacheControlValueDefault}
@@ -97,6 +123,8 @@ func (rds *RestfulDasServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		rds.ExpirationPolicyHandler(w, r, requestPath)
 	case strings.HasPrefix(requestPath, getByHashRequestPath):
 		rds.GetByHashHandler(w, r, requestPath)
+	case strings.HasPrefix(requestPath, getChunkRequestPath):
+		rds.GetChunkHandler(w, r, requestPath)
 	default:
 		log.Warn("Unknown requestPath", "requestPath", requestPath)
 		w.WriteHeader(http.StatusBadRequest)
@@ -187,6 +215,75 @@ func (rds *RestfulDasServer) GetByHashHandler(w http.ResponseWriter, r *http.Req
 	success = true
 }
 
+// GetChunkHandler serves requests of the form /get-chunk/<hash>/<index>, returning the index'th
+// 64kB bin of the preimage along with a proof of its inclusion under the hash.
+func (rds *RestfulDasServer) GetChunkHandler(w http.ResponseWriter, r *http.Request, requestPath string) {
+	log.Debug("Got request", "requestPath", requestPath)
+	restGetChunkRequestGauge.Inc(1)
+	start := time.Now()
+	success := false
+	defer func() {
+		if success {
+			restGetChunkSuccessGauge.Inc(1)
+		} else {
+			restGetChunkFailureGauge.Inc(1)
+		}
+		restGetChunkDurationHistogram.Update(time.Since(start).Nanoseconds())
+	}()
+
+	parts := strings.Split(strings.TrimPrefix(requestPath, getChunkRequestPath), "/")
+	if len(parts) != 2 {
+		log.Warn("Expected a hash and chunk index", "path", requestPath)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	hash, err := DecodeStorageServiceKey(parts[0])
+	if err != nil {
+		log.Warn("Failed to decode hex-encoded hash", "path", requestPath, "err", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	index, err := strconv.ParseUint(parts[1], 10, 32)
+	if err != nil {
+		log.Warn("Failed to parse chunk index", "path", requestPath, "err", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	preimage, err := rds.daReader.GetByHash(r.Context(), hash)
+	if err != nil {
+		log.Warn("Unable to find data", "path", requestPath, "err", err, "remoteAddr", r.RemoteAddr)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if dastree.Hash(preimage) != hash {
+		// Flat hashed data from before dastree can't be proven in chunks.
+		log.Warn("Data is not tree hashed", "path", requestPath)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	chunk, proof, err := dastree.Prove(preimage, int(index))
+	if err != nil {
+		log.Warn("Unable to prove chunk", "path", requestPath, "err", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	response := RestfulDasServerChunkResponse{
+		Chunk: base64.StdEncoding.EncodeToString(chunk),
+		Proof: proof,
+	}
+	restGetChunkReturnedBytesGauge.Inc(int64(len(response.Chunk)))
+
+	w.Header()[cacheControlKey] = []string{cacheControlValueForSuccessfulGetByHash}
+	err = json.NewEncoder(w).Encode(response)
+	if err != nil {
+		log.Warn("Failed encoding and writing response", "path", requestPath, "err", err)
+		return
+	}
+	success = true
+}
+
 func (rds *RestfulDasServer) GetServerExitedChan() <-chan interface{} { // channel will close when server terminates
 	return rds.httpServerExitedChan
 }
@@ -15,6 +15,7 @@ import (
 
 	"github.com/FOGRCC/fogr/cmd/genericconf"
 	"github.com/FOGRCC/fogr/das/dastree"
+	"github.com/FOGRCC/fogr/util/testhelpers"
 )
 
 const LocalServerAddressForTest = "localhost"
@@ -68,3 +69,48 @@ func TestRestfulClientServer(t *testing.T) {
 	err = server.Shutdown()
 	Require(t, err)
 }
+
+func TestRestfulClientServerChunks(t *testing.T) {
+	initTest(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	storage := NewMemoryBackedStorageService(ctx)
+	data := testhelpers.RandomizeSlice(make([]byte, 3*dastree.BinSize+17))
+	dataHash := dastree.Hash(data)
+
+	server, port, err := NewRestfulDasServerOnRandomPort(LocalServerAddressForTest, storage)
+	Require(t, err)
+
+	err = storage.Put(ctx, data, uint64(time.Now().Add(time.Hour).Unix()))
+	Require(t, err)
+
+	time.Sleep(100 * time.Millisecond)
+
+	client := NewRestfulDasClient("http", LocalServerAddressForTest, port)
+	for index := uint32(0); index < uint32(dastree.BinCount(len(data))); index++ {
+		chunk, size, err := client.GetChunk(ctx, dataHash, index)
+		Require(t, err)
+		if size != uint32(len(data)) {
+			Fail(t, "unexpected preimage size", size)
+		}
+		start := index * dastree.BinSize
+		if !bytes.Equal(chunk, data[start:start+uint32(len(chunk))]) {
+			Fail(t, "returned chunk doesn't match", index)
+		}
+	}
+
+	_, _, err = client.GetChunk(ctx, dataHash, uint32(dastree.BinCount(len(data))))
+	if err == nil || !strings.Contains(err.Error(), "400") {
+		Fail(t, "Expected a 400 error for an out of range chunk")
+	}
+
+	_, _, err = client.GetChunk(ctx, dastree.Hash([]byte("absent data")), 0)
+	if err == nil || !strings.Contains(err.Error(), "404") {
+		Fail(t, "Expected a 404 error")
+	}
+
+	err = server.Shutdown()
+	Require(t, err)
+}
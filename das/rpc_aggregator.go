@@ -61,12 +61,12 @@ func setUpServices(config DataAvailabilityConfig) ([]ServiceDetails, error) {
 		}
 		metricName := metricsutil.CanonicalizeMetricName(url.Hostname())
 
-		service, err := NewDASRPCClient(b.URL)
+		pubKey, err := DecodeBase64BLSPublicKey([]byte(b.PubKeyBase64Encoded))
 		if err != nil {
 			return nil, err
 		}
 
-		pubKey, err := DecodeBase64BLSPublicKey([]byte(b.PubKeyBase64Encoded))
+		service, err := NewDASRPCClientForSigner(b.URL, *pubKey)
 		if err != nil {
 			return nil, err
 		}
@@ -98,3 +98,65 @@ func TestRPC(t *testing.T) {
 		testhelpers.FailImpl(t, "failed to getByHash correct message")
 	}
 }
+
+func TestRPCKeyRotation(t *testing.T) {
+	ctx := context.Background()
+	lis, err := net.Listen("tcp", "localhost:0")
+	testhelpers.RequireImpl(t, err)
+	oldPubkey, oldPrivKey, err := GenerateAndStoreKeys(t.TempDir())
+	testhelpers.RequireImpl(t, err)
+	newKeyDir := t.TempDir()
+	newPubkey, _, err := GenerateAndStoreKeys(newKeyDir)
+	testhelpers.RequireImpl(t, err)
+
+	keyConfig := KeyConfig{
+		PrivKey:      base64.StdEncoding.EncodeToString(blsSignatures.PrivateKeyToBytes(*oldPrivKey)),
+		ExtraKeyDirs: []string{newKeyDir},
+	}
+	privKey, err := keyConfig.BLSPrivKey()
+	testhelpers.RequireImpl(t, err)
+	extraPrivKeys, err := keyConfig.ExtraBLSPrivKeys()
+	testhelpers.RequireImpl(t, err)
+
+	storageService := NewMemoryBackedStorageService(ctx)
+	localDas, err := NewSignAfterStoreDASWriterWithSeqInboxCaller(privKey, nil, storageService, "", extraPrivKeys...)
+	testhelpers.RequireImpl(t, err)
+	dasServer, err := StartDASRPCServerOnListener(ctx, lis, genericconf.HTTPServerTimeoutConfigDefault, storageService, localDas, storageService)
+	testhelpers.RequireImpl(t, err)
+	defer func() {
+		if err := dasServer.Shutdown(ctx); err != nil {
+			panic(err)
+		}
+	}()
+
+	// Batch posters configured with either the old or the new key get certificates they can verify.
+	for _, pubkey := range []*blsSignatures.PublicKey{oldPubkey, newPubkey} {
+		backendsJsonByte, err := json.Marshal([]BackendConfig{{
+			URL:                 "http://" + lis.Addr().String(),
+			PubKeyBase64Encoded: blsPubToBase64(pubkey),
+			SignerMask:          1,
+		}})
+		testhelpers.RequireImpl(t, err)
+		aggConf := DataAvailabilityConfig{
+			AggregatorConfig: AggregatorConfig{
+				AssumedHonest: 1,
+				Backends:      string(backendsJsonByte),
+			},
+			RequestTimeout: 5 * time.Second,
+		}
+		rpcAgg, err := NewRPCAggregatorWithSeqInboxCaller(aggConf, nil)
+		testhelpers.RequireImpl(t, err)
+
+		cert, err := rpcAgg.Store(ctx, testhelpers.RandomizeSlice(make([]byte, 100)), 0, nil)
+		testhelpers.RequireImpl(t, err)
+
+		pubKeys, err := PubKeysFromBackends(string(backendsJsonByte))
+		testhelpers.RequireImpl(t, err)
+		keyset, _, keysetHash, err := BuildKeyset(pubKeys, 1)
+		testhelpers.RequireImpl(t, err)
+		if cert.KeysetHash != keysetHash {
+			testhelpers.FailImpl(t, "certificate has unexpected keyset hash")
+		}
+		testhelpers.RequireImpl(t, keyset.VerifySignature(cert.SignersMask, cert.SerializeSignableFields(), cert.Sig))
+	}
+}
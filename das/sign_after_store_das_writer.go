@@ -27,8 +27,10 @@ import (
 )
 
 type KeyConfig struct {
-	KeyDir  string `koanf:"key-dir"`
-	PrivKey string `koanf:"priv-key"`
+	KeyDir        string   `koanf:"key-dir"`
+	PrivKey       string   `koanf:"priv-key"`
+	ExtraKeyDirs  []string `koanf:"extra-key-dirs"`
+	ExtraPrivKeys []string `koanf:"extra-priv-keys"`
 }
 
 func (c *KeyConfig) BLSPrivKey() (blsSignatures.PrivateKey, error) {
@@ -37,11 +39,8 @@ func (c *KeyConfig) BLSPrivKey() (blsSignatures.PrivateKey, error) {
 		privKeyBytes = []byte(c.PrivKey)
 	} else if len(c.KeyDir) != 0 {
 		var err error
-		privKeyBytes, err = os.ReadFile(c.KeyDir + "/" + DefaultPrivKeyFilename)
+		privKeyBytes, err = readBLSPrivKeyFile(c.KeyDir)
 		if err != nil {
-			if os.IsNotExist(err) {
-				return nil, fmt.Errorf("required BLS keypair did not exist at %s", c.KeyDir)
-			}
 			return nil, err
 		}
 	} else {
@@ -54,11 +53,55 @@ func (c *KeyConfig) BLSPrivKey() (blsSignatures.PrivateKey, error) {
 	return privKey, nil
 }
 
+// ExtraBLSPrivKeys returns the keys a DAS signs with in addition to its main key, such as the
+// key it is rotating to or away from.
+func (c *KeyConfig) ExtraBLSPrivKeys() ([]blsSignatures.PrivateKey, error) {
+	privKeys := []blsSignatures.PrivateKey{}
+	for _, encoded := range c.ExtraPrivKeys {
+		privKey, err := DecodeBase64BLSPrivateKey([]byte(encoded))
+		if err != nil {
+			return nil, errors.Wrap(err, "'extra-priv-keys' was invalid")
+		}
+		privKeys = append(privKeys, privKey)
+	}
+	for _, dir := range c.ExtraKeyDirs {
+		privKeyBytes, err := readBLSPrivKeyFile(dir)
+		if err != nil {
+			return nil, err
+		}
+		privKey, err := DecodeBase64BLSPrivateKey(privKeyBytes)
+		if err != nil {
+			return nil, errors.Wrapf(err, "BLS private key in %s was invalid", dir)
+		}
+		privKeys = append(privKeys, privKey)
+	}
+	return privKeys, nil
+}
+
+func readBLSPrivKeyFile(keyDir string) ([]byte, error) {
+	privKeyBytes, err := os.ReadFile(keyDir + "/" + DefaultPrivKeyFilename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("required BLS keypair did not exist at %s", keyDir)
+		}
+		return nil, err
+	}
+	return privKeyBytes, nil
+}
+
 var DefaultKeyConfig = KeyConfig{}
 
 func KeyConfigAddOptions(prefix string, f *flag.FlagSet) {
 	f.String(prefix+".key-dir", DefaultKeyConfig.KeyDir, fmt.Sprintf("the directory to read the bls keypair ('%s' and '%s') from; if using any of the DAS storage types exactly one of key-dir or priv-key must be specified", DefaultPubKeyFilename, DefaultPrivKeyFilename))
 	f.String(prefix+".priv-key", DefaultKeyConfig.PrivKey, "the base64 BLS private key to use for signing DAS certificates; if using any of the DAS storage types exactly one of key-dir or priv-key must be specified")
+	f.StringSlice(prefix+".extra-key-dirs", DefaultKeyConfig.ExtraKeyDirs, "directories to read additional bls keypairs from; certificates are also signed with these keys, which allows rotating keys without downtime")
+	f.StringSlice(prefix+".extra-priv-keys", DefaultKeyConfig.ExtraPrivKeys, "additional base64 BLS private keys to sign DAS certificates with, which allows rotating keys without downtime")
+}
+
+// KeyedSignature is a signature over a certificate's signable fields made with one of a DAS's extra keys.
+type KeyedSignature struct {
+	PubKey blsSignatures.PublicKey
+	Sig    blsSignatures.Signature
 }
 
 // SignAfterStoreDASWriter provides DAS signature functionality over a StorageService
@@ -72,9 +115,15 @@ func KeyConfigAddOptions(prefix string, f *flag.FlagSet) {
 // constructed, calls to Store(...) will try to verify the passed-in data's signature
 // is from the batch poster. If the contract details are not provided, then the
 // signature is not checked, which is useful for testing.
+//
+// Certificates are signed with the main BLS key. If extra keys are configured, ExtraSignatures
+// signs them with those too, which lets a committee member serve both its old and new keysets
+// while a key rotation is in progress.
 type SignAfterStoreDASWriter struct {
 	privKey        blsSignatures.PrivateKey
 	pubKey         *blsSignatures.PublicKey
+	extraPrivKeys  []blsSignatures.PrivateKey
+	extraPubKeys   []blsSignatures.PublicKey
 	keysetHash     [32]byte
 	keysetBytes    []byte
 	storageService StorageService
@@ -90,8 +139,12 @@ func NewSignAfterStoreDASWriter(ctx context.Context, config DataAvailabilityConf
 	if err != nil {
 		return nil, err
 	}
+	extraPrivKeys, err := config.KeyConfig.ExtraBLSPrivKeys()
+	if err != nil {
+		return nil, err
+	}
 	if config.L1NodeURL == "none" {
-		return NewSignAfterStoreDASWriterWithSeqInboxCaller(privKey, nil, storageService, config.ExtraSignatureCheckingPublicKey)
+		return NewSignAfterStoreDASWriterWithSeqInboxCaller(privKey, nil, storageService, config.ExtraSignatureCheckingPublicKey, extraPrivKeys...)
 	}
 	l1client, err := GetL1Client(ctx, config.L1ConnectionAttempts, config.L1NodeURL)
 	if err != nil {
@@ -102,14 +155,14 @@ func NewSignAfterStoreDASWriter(ctx context.Context, config DataAvailabilityConf
 		return nil, err
 	}
 	if seqInboxAddress == nil {
-		return NewSignAfterStoreDASWriterWithSeqInboxCaller(privKey, nil, storageService, config.ExtraSignatureCheckingPublicKey)
+		return NewSignAfterStoreDASWriterWithSeqInboxCaller(privKey, nil, storageService, config.ExtraSignatureCheckingPublicKey, extraPrivKeys...)
 	}
 
 	seqInboxCaller, err := bridgegen.NewSequencerInboxCaller(*seqInboxAddress, l1client)
 	if err != nil {
 		return nil, err
 	}
-	return NewSignAfterStoreDASWriterWithSeqInboxCaller(privKey, seqInboxCaller, storageService, config.ExtraSignatureCheckingPublicKey)
+	return NewSignAfterStoreDASWriterWithSeqInboxCaller(privKey, seqInboxCaller, storageService, config.ExtraSignatureCheckingPublicKey, extraPrivKeys...)
 }
 
 func NewSignAfterStoreDASWriterWithSeqInboxCaller(
@@ -117,11 +170,20 @@ func NewSignAfterStoreDASWriterWithSeqInboxCaller(
 	seqInboxCaller *bridgegen.SequencerInboxCaller,
 	storageService StorageService,
 	extraSignatureCheckingPublicKey string,
+	extraPrivKeys ...blsSignatures.PrivateKey,
 ) (*SignAfterStoreDASWriter, error) {
 	publicKey, err := blsSignatures.PublicKeyFromPrivateKey(privKey)
 	if err != nil {
 		return nil, err
 	}
+	extraPubKeys := []blsSignatures.PublicKey{}
+	for _, extraPrivKey := range extraPrivKeys {
+		extraPubKey, err := blsSignatures.PublicKeyFromPrivateKey(extraPrivKey)
+		if err != nil {
+			return nil, err
+		}
+		extraPubKeys = append(extraPubKeys, extraPubKey)
+	}
 
 	keyset := &fogstate.DataAvailabilityKeyset{
 		AssumedHonest: 1,
@@ -171,6 +233,8 @@ func NewSignAfterStoreDASWriterWithSeqInboxCaller(
 	return &SignAfterStoreDASWriter{
 		privKey:         privKey,
 		pubKey:          &publicKey,
+		extraPrivKeys:   extraPrivKeys,
+		extraPubKeys:    extraPubKeys,
 		keysetHash:      ksHash,
 		keysetBytes:     ksBuf.Bytes(),
 		storageService:  storageService,
@@ -229,6 +293,20 @@ func (d *SignAfterStoreDASWriter) Store(
 	return c, nil
 }
 
+// ExtraSignatures signs the certificate's signable fields with each of the extra keys.
+func (d *SignAfterStoreDASWriter) ExtraSignatures(c *fogstate.DataAvailabilityCertificate) ([]KeyedSignature, error) {
+	fields := c.SerializeSignableFields()
+	sigs := []KeyedSignature{}
+	for i, privKey := range d.extraPrivKeys {
+		sig, err := blsSignatures.SignMessage(privKey, fields)
+		if err != nil {
+			return nil, err
+		}
+		sigs = append(sigs, KeyedSignature{d.extraPubKeys[i], sig})
+	}
+	return sigs, nil
+}
+
 func (d *SignAfterStoreDASWriter) String() string {
 	return fmt.Sprintf("SignAfterStoreDASWriter{%v}", hexutil.Encode(blsSignatures.PublicKeyToBytes(*d.pubKey)))
 }
@@ -0,0 +1,209 @@
+// Copyright 2022, Offchain Labs, Inc.
+// For license information, see https://github.com/fogr/blob/master/LICENSE
+
+package das
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+type SignerHealthConfig struct {
+	Enable                bool          `koanf:"enable"`
+	DegradedTimeout       time.Duration `koanf:"degraded-timeout"`
+	DegradedErrorRate     float64       `koanf:"degraded-error-rate"`
+	DegradedLatency       time.Duration `koanf:"degraded-latency"`
+	RecoveryProbeInterval time.Duration `koanf:"recovery-probe-interval"`
+	MinSamples            uint64        `koanf:"min-samples"`
+	Decay                 float64       `koanf:"decay"`
+}
+
+var DefaultSignerHealthConfig = SignerHealthConfig{
+	Enable:                true,
+	DegradedTimeout:       time.Second,
+	DegradedErrorRate:     0.5,
+	DegradedLatency:       3 * time.Second,
+	RecoveryProbeInterval: time.Minute,
+	MinSamples:            5,
+	Decay:                 0.1,
+}
+
+func SignerHealthConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Bool(prefix+".enable", DefaultSignerHealthConfig.Enable, "give committee members with a high recent error rate a shorter Store deadline")
+	f.Duration(prefix+".degraded-timeout", DefaultSignerHealthConfig.DegradedTimeout, "Store deadline for committee members considered degraded")
+	f.Float64(prefix+".degraded-error-rate", DefaultSignerHealthConfig.DegradedErrorRate, "recent error rate (0 to 1) at which a committee member is considered degraded")
+	f.Duration(prefix+".degraded-latency", DefaultSignerHealthConfig.DegradedLatency, "recent Store latency at which a committee member is considered degraded (0 to only consider the error rate)")
+	f.Duration(prefix+".recovery-probe-interval", DefaultSignerHealthConfig.RecoveryProbeInterval, "how often a degraded committee member is given the full Store deadline, so it can show it has recovered (0 to disable)")
+	f.Uint64(prefix+".min-samples", DefaultSignerHealthConfig.MinSamples, "number of Store requests a committee member must have received before it can be considered degraded")
+	f.Float64(prefix+".decay", DefaultSignerHealthConfig.Decay, "weight (0 to 1) of each new Store response in a committee member's rolling error rate and latency")
+}
+
+// SignerHealth is a snapshot of the rolling statistics for one committee member.
+type SignerHealth struct {
+	Service     string  `json:"service"`
+	SignersMask uint64  `json:"signersMask"`
+	Requests    uint64  `json:"requests"`
+	Failures    uint64  `json:"failures"`
+	ErrorRate   float64 `json:"errorRate"`
+	LatencyMs   float64 `json:"latencyMs"`
+	Score       float64 `json:"score"`
+	Degraded    bool    `json:"degraded"`
+}
+
+type signerStats struct {
+	requests  uint64
+	failures  uint64
+	errorRate float64
+	latencyMs float64
+	lastProbe time.Time
+
+	scoreGauge    metrics.GaugeFloat64
+	latencyGauge  metrics.GaugeFloat64
+	degradedGauge metrics.Gauge
+}
+
+// signerHealthTracker keeps exponentially decaying error rates and latencies for each backend
+// of an Aggregator, and decides which of them are degraded enough to get a shorter deadline.
+type signerHealthTracker struct {
+	config      SignerHealthConfig
+	maxDegraded int
+
+	mutex    sync.Mutex
+	stats    []signerStats
+	degraded []bool
+}
+
+func newSignerHealthTracker(config SignerHealthConfig, services []ServiceDetails, maxDegraded int) *signerHealthTracker {
+	if config.Decay <= 0 || config.Decay > 1 {
+		config.Decay = DefaultSignerHealthConfig.Decay
+	}
+	if maxDegraded < 0 {
+		maxDegraded = 0
+	}
+	stats := make([]signerStats, len(services))
+	for i, d := range services {
+		metricBase := "fogr/das/rpc/aggregator/health/" + d.metricName
+		stats[i] = signerStats{
+			scoreGauge:    metrics.GetOrRegisterGaugeFloat64(metricBase+"/score", nil),
+			latencyGauge:  metrics.GetOrRegisterGaugeFloat64(metricBase+"/latency_ms", nil),
+			degradedGauge: metrics.GetOrRegisterGauge(metricBase+"/degraded", nil),
+		}
+		stats[i].scoreGauge.Update(1)
+	}
+	return &signerHealthTracker{
+		config:      config,
+		maxDegraded: maxDegraded,
+		stats:       stats,
+		degraded:    make([]bool, len(services)),
+	}
+}
+
+func (h *signerHealthTracker) record(index int, latency time.Duration, failed bool) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	s := &h.stats[index]
+	sample := 0.0
+	if failed {
+		sample = 1
+		s.failures++
+	}
+	latencyMs := float64(latency) / float64(time.Millisecond)
+	if s.requests == 0 {
+		s.errorRate = sample
+		s.latencyMs = latencyMs
+	} else {
+		s.errorRate += h.config.Decay * (sample - s.errorRate)
+		s.latencyMs += h.config.Decay * (latencyMs - s.latencyMs)
+	}
+	s.requests++
+	s.scoreGauge.Update(1 - s.errorRate)
+	s.latencyGauge.Update(s.latencyMs)
+	h.updateDegraded()
+}
+
+func (h *signerHealthTracker) slow(s *signerStats) bool {
+	return h.config.DegradedLatency > 0 && s.latencyMs >= float64(h.config.DegradedLatency)/float64(time.Millisecond)
+}
+
+// updateDegraded marks the members with the worst error rates or latencies above the thresholds as
+// degraded. No more members are degraded than the quorum can afford to lose, so that a shortened
+// deadline can't by itself be the reason a Store fails. Must be called with the mutex held.
+func (h *signerHealthTracker) updateDegraded() {
+	candidates := []int{}
+	for i := range h.stats {
+		s := &h.stats[i]
+		if h.config.Enable && s.requests >= h.config.MinSamples && (s.errorRate >= h.config.DegradedErrorRate || h.slow(s)) {
+			candidates = append(candidates, i)
+		}
+	}
+	sort.SliceStable(candidates, func(a, b int) bool {
+		statsA, statsB := h.stats[candidates[a]], h.stats[candidates[b]]
+		if statsA.errorRate != statsB.errorRate {
+			return statsA.errorRate > statsB.errorRate
+		}
+		return statsA.latencyMs > statsB.latencyMs
+	})
+	if len(candidates) > h.maxDegraded {
+		candidates = candidates[:h.maxDegraded]
+	}
+	wasDegraded := append([]bool{}, h.degraded...)
+	for i := range h.degraded {
+		h.degraded[i] = false
+	}
+	for _, i := range candidates {
+		h.degraded[i] = true
+		if !wasDegraded[i] {
+			// The first recovery probe is due one interval after the member is degraded.
+			h.stats[i].lastProbe = time.Now()
+		}
+	}
+	for i, s := range h.stats {
+		if h.degraded[i] {
+			s.degradedGauge.Update(1)
+		} else {
+			s.degradedGauge.Update(0)
+		}
+	}
+}
+
+// timeout returns the Store deadline for the index'th backend. A degraded backend is still given
+// the full deadline once every RecoveryProbeInterval, since a slow backend could otherwise never
+// respond in time to show that it has recovered.
+func (h *signerHealthTracker) timeout(index int, requestTimeout time.Duration) time.Duration {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if !h.degraded[index] || h.config.DegradedTimeout <= 0 || h.config.DegradedTimeout >= requestTimeout {
+		return requestTimeout
+	}
+	s := &h.stats[index]
+	if h.config.RecoveryProbeInterval > 0 && time.Since(s.lastProbe) >= h.config.RecoveryProbeInterval {
+		s.lastProbe = time.Now()
+		return requestTimeout
+	}
+	return h.config.DegradedTimeout
+}
+
+func (h *signerHealthTracker) snapshot(services []ServiceDetails) []SignerHealth {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	health := make([]SignerHealth, len(services))
+	for i, d := range services {
+		s := h.stats[i]
+		health[i] = SignerHealth{
+			Service:     d.service.String(),
+			SignersMask: d.signersMask,
+			Requests:    s.requests,
+			Failures:    s.failures,
+			ErrorRate:   s.errorRate,
+			LatencyMs:   s.latencyMs,
+			Score:       1 - s.errorRate,
+			Degraded:    h.degraded[i],
+		}
+	}
+	return health
+}
@@ -0,0 +1,160 @@
+// Copyright 2022, Offchain Labs, Inc.
+// For license information, see https://github.com/fogr/blob/master/LICENSE
+
+package das
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/FOGRCC/fogr/blsSignatures"
+)
+
+type alwaysFail struct{}
+
+func (alwaysFail) shouldFail() failureType {
+	return immediateError
+}
+
+func TestSignerHealthTracker(t *testing.T) {
+	services := make([]ServiceDetails, 4)
+	for i := range services {
+		services[i] = ServiceDetails{signersMask: 1 << i, metricName: "health_test" + strconv.Itoa(i)}
+	}
+	config := SignerHealthConfig{
+		Enable:            true,
+		DegradedTimeout:   100 * time.Millisecond,
+		DegradedErrorRate: 0.5,
+		MinSamples:        3,
+		Decay:             0.5,
+	}
+	requestTimeout := 5 * time.Second
+	health := newSignerHealthTracker(config, services, 1)
+
+	for i := 0; i < 4; i++ {
+		health.record(0, time.Millisecond, true)
+		health.record(1, time.Millisecond, i%2 == 0)
+		health.record(2, time.Millisecond, false)
+		health.record(3, time.Millisecond, false)
+	}
+	if health.timeout(0, requestTimeout) != config.DegradedTimeout {
+		Fail(t, "failing member wasn't degraded")
+	}
+	// member 1 is also above the error rate threshold, but only one member may be degraded
+	if health.timeout(1, requestTimeout) != requestTimeout || health.timeout(2, requestTimeout) != requestTimeout {
+		Fail(t, "more members were degraded than allowed")
+	}
+
+	for i := 0; i < 4; i++ {
+		health.record(0, time.Millisecond, false)
+	}
+	if health.timeout(0, requestTimeout) != requestTimeout {
+		Fail(t, "recovered member is still degraded")
+	}
+
+	snapshot := health.snapshot(services)
+	if snapshot[0].Requests != 8 || snapshot[0].Failures != 4 || snapshot[3].Score != 1 {
+		Fail(t, "unexpected health snapshot", snapshot)
+	}
+}
+
+func TestSignerHealthLatencyAndRecoveryProbe(t *testing.T) {
+	services := make([]ServiceDetails, 3)
+	for i := range services {
+		services[i] = ServiceDetails{signersMask: 1 << i, metricName: "health_probe_test" + strconv.Itoa(i)}
+	}
+	config := SignerHealthConfig{
+		Enable:                true,
+		DegradedTimeout:       100 * time.Millisecond,
+		DegradedErrorRate:     0.5,
+		DegradedLatency:       time.Second,
+		RecoveryProbeInterval: 50 * time.Millisecond,
+		MinSamples:            3,
+		Decay:                 0.5,
+	}
+	requestTimeout := 5 * time.Second
+	health := newSignerHealthTracker(config, services, 1)
+
+	// Member 0 never fails but is slow.
+	for i := 0; i < 3; i++ {
+		health.record(0, 2*time.Second, false)
+		health.record(1, time.Millisecond, false)
+		health.record(2, time.Millisecond, false)
+	}
+	if health.timeout(0, requestTimeout) != config.DegradedTimeout {
+		Fail(t, "slow member wasn't degraded")
+	}
+	if health.timeout(1, requestTimeout) != requestTimeout {
+		Fail(t, "fast member was degraded")
+	}
+
+	// Once the probe interval has passed, the degraded member gets one request with the full deadline.
+	time.Sleep(config.RecoveryProbeInterval)
+	if health.timeout(0, requestTimeout) != requestTimeout {
+		Fail(t, "degraded member wasn't probed with the full deadline")
+	}
+	if health.timeout(0, requestTimeout) != config.DegradedTimeout {
+		Fail(t, "degraded member was probed twice in one interval")
+	}
+	for i := 0; i < 4; i++ {
+		health.record(0, time.Millisecond, false)
+	}
+	if health.timeout(0, requestTimeout) != requestTimeout {
+		Fail(t, "recovered member is still degraded")
+	}
+}
+
+func TestDAS_AggregatorDegradesFailingSigner(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var backends []ServiceDetails
+	for i := 0; i < 3; i++ {
+		privKey, err := blsSignatures.GeneratePrivKeyString()
+		Require(t, err)
+		config := DataAvailabilityConfig{
+			Enable:    true,
+			KeyConfig: KeyConfig{PrivKey: privKey},
+			L1NodeURL: "none",
+		}
+		das, err := NewSignAfterStoreDASWriter(ctx, config, NewMemoryBackedStorageService(ctx))
+		Require(t, err)
+		var writer DataAvailabilityServiceWriter = das
+		if i == 0 {
+			writer = &WrapStore{t, alwaysFail{}, das}
+		}
+		details, err := NewServiceDetails(writer, *das.pubKey, uint64(1<<i), "degrade_test"+strconv.Itoa(i))
+		Require(t, err)
+		backends = append(backends, *details)
+	}
+
+	healthConfig := DefaultSignerHealthConfig
+	healthConfig.MinSamples = 2
+	aggregator, err := NewAggregator(ctx, DataAvailabilityConfig{
+		AggregatorConfig: AggregatorConfig{AssumedHonest: 2, SignerHealth: healthConfig},
+		L1NodeURL:        "none",
+		RequestTimeout:   5 * time.Second,
+	}, backends)
+	Require(t, err)
+
+	for i := 0; i < 3; i++ {
+		_, err := aggregator.Store(ctx, []byte("message "+strconv.Itoa(i)), 0, []byte{})
+		Require(t, err)
+	}
+
+	// Responses from backends can be recorded after Store returns.
+	for start := time.Now(); time.Since(start) < 5*time.Second; time.Sleep(10 * time.Millisecond) {
+		if aggregator.SignerHealth()[0].Requests == 3 {
+			break
+		}
+	}
+	health := aggregator.SignerHealth()
+	if !health[0].Degraded || health[0].Failures != 3 {
+		Fail(t, "failing signer wasn't degraded", health[0])
+	}
+	if health[1].Degraded || health[2].Degraded || health[1].Score != 1 {
+		Fail(t, "healthy signers have unexpected health", health[1], health[2])
+	}
+}
@@ -18,6 +18,7 @@ import (
 	"github.com/FOGRCC/fogr/util/stopwaiter"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
 	flag "github.com/spf13/pflag"
 )
 
@@ -34,6 +35,7 @@ type RestfulClientAggregatorConfig struct {
 	WaitBeforeTryNext                  time.Duration                      `koanf:"wait-before-try-next"`
 	MaxPerEndpointStats                int                                `koanf:"max-per-endpoint-stats"`
 	SimpleExploreExploitStrategyConfig SimpleExploreExploitStrategyConfig `koanf:"simple-explore-exploit-strategy"`
+	LatencyPercentileStrategyConfig    LatencyPercentileStrategyConfig    `koanf:"latency-percentile-strategy"`
 	SyncToStorageConfig                SyncToStorageConfig                `koanf:"sync-to-storage"`
 }
 
@@ -46,6 +48,7 @@ var DefaultRestfulClientAggregatorConfig = RestfulClientAggregatorConfig{
 	WaitBeforeTryNext:                  2 * time.Second,
 	MaxPerEndpointStats:                20,
 	SimpleExploreExploitStrategyConfig: DefaultSimpleExploreExploitStrategyConfig,
+	LatencyPercentileStrategyConfig:    DefaultLatencyPercentileStrategyConfig,
 	SyncToStorageConfig:                DefaultSyncToStorageConfig,
 }
 
@@ -59,16 +62,29 @@ var DefaultSimpleExploreExploitStrategyConfig = SimpleExploreExploitStrategyConf
 	ExploitIterations: 1000,
 }
 
+type LatencyPercentileStrategyConfig struct {
+	HalfLife        time.Duration `koanf:"half-life"`
+	MinHedgeDelay   time.Duration `koanf:"min-hedge-delay"`
+	MinSampleWeight float64       `koanf:"min-sample-weight"`
+}
+
+var DefaultLatencyPercentileStrategyConfig = LatencyPercentileStrategyConfig{
+	HalfLife:        10 * time.Minute,
+	MinHedgeDelay:   50 * time.Millisecond,
+	MinSampleWeight: 1,
+}
+
 func RestfulClientAggregatorConfigAddOptions(prefix string, f *flag.FlagSet) {
 	f.Bool(prefix+".enable", DefaultRestfulClientAggregatorConfig.Enable, "enable retrieval of sequencer batch data from a list of remote REST endpoints; if other DAS storage types are enabled, this mode is used as a fallback")
 	f.StringSlice(prefix+".urls", DefaultRestfulClientAggregatorConfig.Urls, "list of URLs including 'http://' or 'https://' prefixes and port numbers to REST DAS endpoints; additive with the online-url-list option")
 	f.String(prefix+".online-url-list", DefaultRestfulClientAggregatorConfig.OnlineUrlList, "a URL to a list of URLs of REST das endpoints that is checked at startup; additive with the url option")
 	f.Duration(prefix+".online-url-list-fetch-interval", DefaultRestfulClientAggregatorConfig.OnlineUrlListFetchInterval, "time interval to periodically fetch url list from online-url-list")
-	f.String(prefix+".strategy", DefaultRestfulClientAggregatorConfig.Strategy, "strategy to use to determine order and parallelism of calling REST endpoint URLs; valid options are 'simple-explore-exploit'")
+	f.String(prefix+".strategy", DefaultRestfulClientAggregatorConfig.Strategy, "strategy to use to determine order and parallelism of calling REST endpoint URLs; valid options are 'simple-explore-exploit' and 'latency-percentile'")
 	f.Duration(prefix+".strategy-update-interval", DefaultRestfulClientAggregatorConfig.StrategyUpdateInterval, "how frequently to update the strategy with endpoint latency and error rate data")
 	f.Duration(prefix+".wait-before-try-next", DefaultRestfulClientAggregatorConfig.WaitBeforeTryNext, "time to wait until trying the next set of REST endpoints while waiting for a response; the next set of REST endpoints is determined by the strategy selected")
 	f.Int(prefix+".max-per-endpoint-stats", DefaultRestfulClientAggregatorConfig.MaxPerEndpointStats, "number of stats entries (latency and success rate) to keep for each REST endpoint; controls whether strategy is faster or slower to respond to changing conditions")
 	SimpleExploreExploitStrategyConfigAddOptions(prefix+".simple-explore-exploit-strategy", f)
+	LatencyPercentileStrategyConfigAddOptions(prefix+".latency-percentile-strategy", f)
 	SyncToStorageConfigAddOptions(prefix+".sync-to-storage", f)
 }
 
@@ -77,6 +93,12 @@ func SimpleExploreExploitStrategyConfigAddOptions(prefix string, f *flag.FlagSet
 	f.Int(prefix+".exploit-iterations", DefaultSimpleExploreExploitStrategyConfig.ExploitIterations, "number of consecutive GetByHash calls to the aggregator where each call will cause it to select from REST endpoints in order of best latency and success rate, before switching to explore mode")
 }
 
+func LatencyPercentileStrategyConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Duration(prefix+".half-life", DefaultLatencyPercentileStrategyConfig.HalfLife, "age at which a latency and success rate sample for a REST endpoint counts half as much as a new one")
+	f.Duration(prefix+".min-hedge-delay", DefaultLatencyPercentileStrategyConfig.MinHedgeDelay, "minimum time to wait for a REST endpoint before also trying the next one; the wait is otherwise the endpoint's p95 latency, up to wait-before-try-next")
+	f.Float64(prefix+".min-sample-weight", DefaultLatencyPercentileStrategyConfig.MinSampleWeight, "total decayed weight of samples a REST endpoint needs to be ranked; endpoints with less are tried first to measure them")
+}
+
 func NewRestfulClientAggregator(ctx context.Context, config *RestfulClientAggregatorConfig) (*SimpleDASReaderAggregator, error) {
 	a := SimpleDASReaderAggregator{
 		config: config,
@@ -123,6 +145,8 @@ func NewRestfulClientAggregator(ctx context.Context, config *RestfulClientAggreg
 			exploreIterations: uint32(config.SimpleExploreExploitStrategyConfig.ExploreIterations),
 			exploitIterations: uint32(config.SimpleExploreExploitStrategyConfig.ExploitIterations),
 		}
+	case "latency-percentile":
+		a.strategy = newLatencyPercentileStrategy(config.LatencyPercentileStrategyConfig, config.WaitBeforeTryNext)
 	case "testing-sequential":
 		a.strategy = &testingSequentialStrategy{}
 	default:
@@ -132,6 +156,8 @@ func NewRestfulClientAggregator(ctx context.Context, config *RestfulClientAggreg
 	return &a, nil
 }
 
+var restAggregatorHedgedCounter = metrics.NewRegisteredCounter("fogr/das/restaggregator/hedged", nil)
+
 type readerStats []readerStat
 
 // Return the mean latency, weighted inversely by the ratio of successes : total attempts
@@ -156,6 +182,7 @@ func (s *readerStats) successRatioWeightedMeanLatency() time.Duration {
 type readerStat struct {
 	latency time.Duration
 	success bool
+	at      time.Time
 }
 
 type readerStatMessage struct {
@@ -194,7 +221,12 @@ func (a *SimpleDASReaderAggregator) GetByHash(ctx context.Context, hash common.H
 
 	go func() {
 		si := a.strategy.newInstance()
+		hedging, isHedging := si.(hedgingAggregatorStrategyInstance)
 		for readers := si.nextReaders(); len(readers) != 0 && subCtx.Err() == nil; readers = si.nextReaders() {
+			waitBeforeTryNext := a.config.WaitBeforeTryNext
+			if isHedging {
+				waitBeforeTryNext = hedging.hedgeDelay()
+			}
 			wg := sync.WaitGroup{}
 			waitChan := make(chan interface{})
 			for _, reader := range readers {
@@ -217,7 +249,10 @@ func (a *SimpleDASReaderAggregator) GetByHash(ctx context.Context, hash common.H
 			select {
 			case <-subCtx.Done():
 				return
-			case <-time.After(a.config.WaitBeforeTryNext):
+			case <-time.After(waitBeforeTryNext):
+				if isHedging {
+					restAggregatorHedgedCounter.Inc(1)
+				}
 			case <-waitChan:
 				// Yield to give the collector a chance to run in case a request succeeded
 				time.Sleep(10 * time.Millisecond)
@@ -257,7 +292,8 @@ func (a *SimpleDASReaderAggregator) tryGetByHash(
 			err = fmt.Errorf("SimpleDASReaderAggregator got result from reader(%v) not matching hash", reader)
 		}
 	}
-	stat.latency = time.Since(start)
+	stat.at = time.Now()
+	stat.latency = stat.at.Sub(start)
 
 	select {
 	case a.statMessages <- stat:
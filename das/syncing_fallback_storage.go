@@ -203,16 +203,8 @@ func (s *l1SyncService) processBatchDelivered(ctx context.Context, batchDelivere
 		return nil
 	}
 
-	header := make([]byte, 40)
-	binary.BigEndian.PutUint64(header[:8], deliveredEvent.TimeBounds.MinTimestamp)
-	binary.BigEndian.PutUint64(header[8:16], deliveredEvent.TimeBounds.MaxTimestamp)
-	binary.BigEndian.PutUint64(header[16:24], deliveredEvent.TimeBounds.MinBlockNumber)
-	binary.BigEndian.PutUint64(header[24:32], deliveredEvent.TimeBounds.MaxBlockNumber)
-	binary.BigEndian.PutUint64(header[32:40], deliveredEvent.AfterDelayedMessagesRead.Uint64())
-
-	data = append(header, data...)
-	preimages := make(map[common.Hash][]byte)
-	if _, err = fogstate.RecoverPayloadFromDasBatch(ctx, deliveredEvent.BatchSequenceNumber.Uint64(), data, s.dataSource, preimages, fogstate.KeysetValidate); err != nil {
+	preimages, err := RecoverDASPreimages(ctx, deliveredEvent, data, s.dataSource)
+	if err != nil {
 		log.Error("recover payload failed", "txhash", batchDeliveredLog.TxHash, "data", data)
 		return err
 	}
@@ -295,6 +287,29 @@ func FindDASDataFromLog(
 	return data, nil
 }
 
+// RecoverDASPreimages fetches all the preimages making up a DAS batch, as found by FindDASDataFromLog,
+// from dataSource. The preimages are returned keyed by their hash.
+func RecoverDASPreimages(
+	ctx context.Context,
+	deliveredEvent *bridgegen.SequencerInboxSequencerBatchDelivered,
+	data []byte,
+	dataSource fogstate.DataAvailabilityReader,
+) (map[common.Hash][]byte, error) {
+	header := make([]byte, 40)
+	binary.BigEndian.PutUint64(header[:8], deliveredEvent.TimeBounds.MinTimestamp)
+	binary.BigEndian.PutUint64(header[8:16], deliveredEvent.TimeBounds.MaxTimestamp)
+	binary.BigEndian.PutUint64(header[16:24], deliveredEvent.TimeBounds.MinBlockNumber)
+	binary.BigEndian.PutUint64(header[24:32], deliveredEvent.TimeBounds.MaxBlockNumber)
+	binary.BigEndian.PutUint64(header[32:40], deliveredEvent.AfterDelayedMessagesRead.Uint64())
+
+	data = append(header, data...)
+	preimages := make(map[common.Hash][]byte)
+	if _, err := fogstate.RecoverPayloadFromDasBatch(ctx, deliveredEvent.BatchSequenceNumber.Uint64(), data, dataSource, preimages, fogstate.KeysetValidate); err != nil {
+		return nil, err
+	}
+	return preimages, nil
+}
+
 func (s *l1SyncService) processBlockRange(ctx context.Context, lowerBound, higherBound uint64) error {
 	query := ethereum.FilterQuery{
 		FromBlock: new(big.Int).SetUint64(lowerBound),
@@ -0,0 +1,453 @@
+// Copyright 2022, Offchain Labs, Inc.
+// For license information, see https://github.com/fogr/blob/master/LICENSE
+
+package das
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/go-redis/redis/v8"
+	flag "github.com/spf13/pflag"
+
+	"github.com/FOGRCC/fogr/das/dastree"
+	"github.com/FOGRCC/fogr/fogstate"
+	"github.com/FOGRCC/fogr/util/containers"
+	"github.com/FOGRCC/fogr/util/pretty"
+	"github.com/FOGRCC/fogr/util/redisutil"
+	"github.com/FOGRCC/fogr/util/stopwaiter"
+)
+
+var (
+	tieredHotHitCounter           = metrics.NewRegisteredCounter("fogr/das/tiered/hot/hit", nil)
+	tieredColdHitCounter          = metrics.NewRegisteredCounter("fogr/das/tiered/cold/hit", nil)
+	tieredDemotedCounter          = metrics.NewRegisteredCounter("fogr/das/tiered/demoted", nil)
+	tieredPromotedCounter         = metrics.NewRegisteredCounter("fogr/das/tiered/promoted", nil)
+	tieredDemoteFailure           = metrics.NewRegisteredCounter("fogr/das/tiered/demote/failure", nil)
+	tieredDemoteDurationHistogram = metrics.NewRegisteredHistogram("fogr/das/tiered/demote/duration", nil, metrics.NewBoundedHistogramSample())
+)
+
+const (
+	hotTierLocalDB = "local-db"
+	hotTierRedis   = "redis"
+)
+
+type TieredStorageConfig struct {
+	Enable            bool          `koanf:"enable"`
+	HotTier           string        `koanf:"hot-tier"`
+	DataDir           string        `koanf:"data-dir"`
+	RedisUrl          string        `koanf:"redis-url"`
+	RedisKeyConfig    string        `koanf:"redis-key-config"`
+	DemoteAfter       time.Duration `koanf:"demote-after"`
+	DemoteInterval    time.Duration `koanf:"demote-interval"`
+	DemoteBatchSize   int           `koanf:"demote-batch-size"`
+	PromoteAfterReads int           `koanf:"promote-after-reads"`
+	ReadCountCache    int           `koanf:"read-count-cache"`
+}
+
+var DefaultTieredStorageConfig = TieredStorageConfig{
+	HotTier:           hotTierLocalDB,
+	DemoteAfter:       24 * time.Hour,
+	DemoteInterval:    time.Minute,
+	DemoteBatchSize:   1000,
+	PromoteAfterReads: 3,
+	ReadCountCache:    10000,
+}
+
+func TieredStorageConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Bool(prefix+".enable", DefaultTieredStorageConfig.Enable, "store new data in a fast hot tier and move it to the other configured storage (the cold tier) once it is old enough")
+	f.String(prefix+".hot-tier", DefaultTieredStorageConfig.HotTier, "storage to use as the hot tier, either local-db or redis; in-memory caches can't be the hot tier since data would be lost on restart before it is demoted, but local-cache can still be enabled in front of the tiered storage")
+	f.String(prefix+".data-dir", DefaultTieredStorageConfig.DataDir, "directory in which to store the hot tier database when hot-tier is local-db")
+	f.String(prefix+".redis-url", DefaultTieredStorageConfig.RedisUrl, "Redis url when hot-tier is redis")
+	f.String(prefix+".redis-key-config", DefaultTieredStorageConfig.RedisKeyConfig, "Redis key used to sign hot tier data when hot-tier is redis")
+	f.Duration(prefix+".demote-after", DefaultTieredStorageConfig.DemoteAfter, "how long data stays in the hot tier before it is moved to the cold tier")
+	f.Duration(prefix+".demote-interval", DefaultTieredStorageConfig.DemoteInterval, "interval between checks for hot tier data old enough to demote")
+	f.Int(prefix+".demote-batch-size", DefaultTieredStorageConfig.DemoteBatchSize, "maximum number of entries to demote at once")
+	f.Int(prefix+".promote-after-reads", DefaultTieredStorageConfig.PromoteAfterReads, "number of reads from the cold tier after which data is copied back into the hot tier (0 to never promote)")
+	f.Int(prefix+".read-count-cache", DefaultTieredStorageConfig.ReadCountCache, "number of cold tier keys to count reads of for promotion")
+}
+
+// A hotTierEntry records when data was stored in the hot tier. Data promoted from the cold tier
+// is inCold, so demoting it only needs to delete it from the hot tier.
+type hotTierEntry struct {
+	storedAt   uint64 // unix milliseconds
+	key        common.Hash
+	expiration uint64
+	inCold     bool
+}
+
+// A hotTier is a storage backend that can act as the hot tier of a TieredStorageService.
+// It must be durable, since data isn't in the cold tier until it is demoted.
+type hotTier interface {
+	StorageService
+	putHot(ctx context.Context, entry hotTierEntry, data []byte) error
+	// demotionCandidates returns up to limit entries stored at or before cutoff, oldest first.
+	demotionCandidates(ctx context.Context, cutoff uint64, limit int) ([]hotTierEntry, error)
+	// removeHot deletes the entries from the index, and their data unless it was stored again later.
+	removeHot(ctx context.Context, entries []hotTierEntry) error
+}
+
+// TieredStorageService stores new data in a hot tier, where recently posted batches are cheap
+// to read, and moves it to the cold tier once it's older than DemoteAfter. Data read from the
+// cold tier PromoteAfterReads times is copied back into the hot tier until it is demoted again.
+type TieredStorageService struct {
+	stopwaiter.StopWaiterSafe
+	config TieredStorageConfig
+	hot    hotTier
+	cold   StorageService
+
+	readCountsMutex sync.Mutex
+	readCounts      *containers.LruCache[common.Hash, int]
+}
+
+func NewTieredStorageService(ctx context.Context, config TieredStorageConfig, cold StorageService) (*TieredStorageService, error) {
+	var hot hotTier
+	switch config.HotTier {
+	case hotTierLocalDB:
+		if config.DataDir == "" {
+			return nil, errors.New("--data-availability.tiered-storage.data-dir must be set when the hot tier is local-db")
+		}
+		s, err := NewDBStorageService(ctx, LocalDBStorageConfig{Enable: true, DataDir: config.DataDir})
+		if err != nil {
+			return nil, err
+		}
+		hot = s.(*DBStorageService)
+	case hotTierRedis:
+		s, err := newRedisHotTier(config.RedisUrl, config.RedisKeyConfig)
+		if err != nil {
+			return nil, err
+		}
+		hot = s
+	default:
+		return nil, fmt.Errorf("unknown tiered storage hot tier %q, must be local-db or redis", config.HotTier)
+	}
+	return newTieredStorageService(ctx, config, hot, cold)
+}
+
+func newTieredStorageService(ctx context.Context, config TieredStorageConfig, hot hotTier, cold StorageService) (*TieredStorageService, error) {
+	if config.DemoteBatchSize <= 0 {
+		config.DemoteBatchSize = DefaultTieredStorageConfig.DemoteBatchSize
+	}
+	readCountCache := config.ReadCountCache
+	if readCountCache <= 0 {
+		readCountCache = 1
+	}
+	ts := &TieredStorageService{
+		config:     config,
+		hot:        hot,
+		cold:       cold,
+		readCounts: containers.NewLruCache[common.Hash, int](readCountCache),
+	}
+	if err := ts.StopWaiterSafe.Start(ctx, ts); err != nil {
+		return nil, err
+	}
+	if err := ts.CallIteratively(func(ctx context.Context) time.Duration {
+		ts.demote(ctx, time.Now())
+		return ts.config.DemoteInterval
+	}); err != nil {
+		return nil, err
+	}
+	return ts, nil
+}
+
+// demote moves everything stored in the hot tier more than DemoteAfter before now to the cold tier.
+func (ts *TieredStorageService) demote(ctx context.Context, now time.Time) {
+	start := time.Now()
+	cutoff := uint64(now.Add(-ts.config.DemoteAfter).UnixMilli())
+	var demoted int
+batches:
+	for ctx.Err() == nil {
+		entries, err := ts.hot.demotionCandidates(ctx, cutoff, ts.config.DemoteBatchSize)
+		if err != nil {
+			tieredDemoteFailure.Inc(1)
+			log.Warn("Failed to find data to demote from the hot tier", "hot", ts.hot, "err", err)
+			break
+		}
+		if len(entries) == 0 {
+			break
+		}
+		for _, entry := range entries {
+			if entry.inCold {
+				continue
+			}
+			data, err := ts.hot.GetByHash(ctx, entry.key)
+			if errors.Is(err, ErrNotFound) {
+				// Already removed after a later demotion of the same data.
+				continue
+			}
+			if err == nil {
+				err = ts.cold.Put(ctx, data, entry.expiration)
+			}
+			if err != nil {
+				tieredDemoteFailure.Inc(1)
+				log.Warn("Failed to demote data to the cold tier", "key", pretty.PrettyHash(entry.key), "cold", ts.cold, "err", err)
+				break batches
+			}
+		}
+		if err := ts.hot.removeHot(ctx, entries); err != nil {
+			tieredDemoteFailure.Inc(1)
+			log.Warn("Failed to remove demoted data from the hot tier", "hot", ts.hot, "err", err)
+			break
+		}
+		demoted += len(entries)
+		tieredDemotedCounter.Inc(int64(len(entries)))
+		if len(entries) < ts.config.DemoteBatchSize {
+			break
+		}
+	}
+	tieredDemoteDurationHistogram.Update(time.Since(start).Milliseconds())
+	if demoted > 0 {
+		log.Info("Demoted data to the cold tier", "entries", demoted, "elapsed", time.Since(start))
+	}
+}
+
+func (ts *TieredStorageService) GetByHash(ctx context.Context, key common.Hash) ([]byte, error) {
+	log.Trace("das.TieredStorageService.GetByHash", "key", pretty.PrettyHash(key), "this", ts)
+	data, err := ts.hot.GetByHash(ctx, key)
+	if err == nil {
+		tieredHotHitCounter.Inc(1)
+		return data, nil
+	}
+	if !errors.Is(err, ErrNotFound) {
+		log.Warn("Failed to read from the hot tier, trying the cold tier", "key", pretty.PrettyHash(key), "err", err)
+	}
+	data, err = ts.cold.GetByHash(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	tieredColdHitCounter.Inc(1)
+	if ts.shouldPromote(key) {
+		entry := hotTierEntry{
+			storedAt:   uint64(time.Now().UnixMilli()),
+			key:        key,
+			expiration: math.MaxUint64,
+			inCold:     true,
+		}
+		if err := ts.hot.putHot(ctx, entry, data); err != nil {
+			log.Warn("Failed to promote data to the hot tier", "key", pretty.PrettyHash(key), "err", err)
+		} else {
+			tieredPromotedCounter.Inc(1)
+		}
+	}
+	return data, nil
+}
+
+func (ts *TieredStorageService) shouldPromote(key common.Hash) bool {
+	if ts.config.PromoteAfterReads <= 0 {
+		return false
+	}
+	ts.readCountsMutex.Lock()
+	defer ts.readCountsMutex.Unlock()
+	count, _ := ts.readCounts.Get(key)
+	count++
+	if count >= ts.config.PromoteAfterReads {
+		ts.readCounts.Remove(key)
+		return true
+	}
+	ts.readCounts.Add(key, count)
+	return false
+}
+
+func (ts *TieredStorageService) Put(ctx context.Context, data []byte, expiration uint64) error {
+	logPut("das.TieredStorageService.Put", data, expiration, ts)
+	entry := hotTierEntry{
+		storedAt:   uint64(time.Now().UnixMilli()),
+		key:        dastree.Hash(data),
+		expiration: expiration,
+	}
+	return ts.hot.putHot(ctx, entry, data)
+}
+
+func (ts *TieredStorageService) Sync(ctx context.Context) error {
+	if err := ts.hot.Sync(ctx); err != nil {
+		return err
+	}
+	return ts.cold.Sync(ctx)
+}
+
+// Close stops demotion and closes the hot tier. The cold tier is owned by the caller.
+func (ts *TieredStorageService) Close(ctx context.Context) error {
+	if err := ts.StopAndWait(); err != nil {
+		return err
+	}
+	return ts.hot.Close(ctx)
+}
+
+func (ts *TieredStorageService) ExpirationPolicy(ctx context.Context) (fogstate.ExpirationPolicy, error) {
+	return ts.cold.ExpirationPolicy(ctx)
+}
+
+func (ts *TieredStorageService) String() string {
+	return fmt.Sprintf("TieredStorageService(hot:%v,cold:%v)", ts.hot, ts.cold)
+}
+
+func (ts *TieredStorageService) HealthCheck(ctx context.Context) error {
+	if err := ts.hot.HealthCheck(ctx); err != nil {
+		return err
+	}
+	return ts.cold.HealthCheck(ctx)
+}
+
+const (
+	redisHotTierDataPrefix = "tiered_data_"
+	redisHotTierIndexKey   = "tiered_index"
+	redisHotTierMetaKey    = "tiered_meta"
+)
+
+// redisHotTier keeps the hot tier in Redis. Data is stored without a Redis expiration, since it
+// must stay until it's demoted, and signed like the Redis cache. Store times are kept in a sorted set.
+type redisHotTier struct {
+	signer *RedisStorageService
+	client redis.UniversalClient
+}
+
+func newRedisHotTier(url string, keyConfig string) (*redisHotTier, error) {
+	client, err := redisutil.RedisClientFromURL(url)
+	if err != nil {
+		return nil, err
+	}
+	signingKey := common.HexToHash(keyConfig)
+	if signingKey == (common.Hash{}) {
+		return nil, errors.New("tiered storage redis-key-config is not 32 bytes of hex")
+	}
+	signer := &RedisStorageService{redisConfig: RedisConfig{RedisUrl: url}, signingKey: signingKey, client: client}
+	return &redisHotTier{signer, client}, nil
+}
+
+func (r *redisHotTier) GetByHash(ctx context.Context, key common.Hash) ([]byte, error) {
+	data, err := r.client.Get(ctx, redisHotTierDataPrefix+string(key.Bytes())).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return r.signer.verifyMessageSignature(data)
+}
+
+func (r *redisHotTier) Put(ctx context.Context, data []byte, expiration uint64) error {
+	return r.putHot(ctx, hotTierEntry{storedAt: uint64(time.Now().UnixMilli()), key: dastree.Hash(data), expiration: expiration}, data)
+}
+
+func (r *redisHotTier) putHot(ctx context.Context, entry hotTierEntry, data []byte) error {
+	member := string(entry.key.Bytes())
+	meta := make([]byte, 9)
+	binary.BigEndian.PutUint64(meta, entry.expiration)
+	if entry.inCold {
+		meta[8] = 1
+	}
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, redisHotTierDataPrefix+member, r.signer.signMessage(data), 0)
+		pipe.HSet(ctx, redisHotTierMetaKey, member, meta)
+		pipe.ZAdd(ctx, redisHotTierIndexKey, &redis.Z{Score: float64(entry.storedAt), Member: member})
+		return nil
+	})
+	return err
+}
+
+func (r *redisHotTier) demotionCandidates(ctx context.Context, cutoff uint64, limit int) ([]hotTierEntry, error) {
+	members, err := r.client.ZRangeByScoreWithScores(ctx, redisHotTierIndexKey, &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   strconv.FormatUint(cutoff, 10),
+		Count: int64(limit),
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]hotTierEntry, 0, len(members))
+	for _, member := range members {
+		keyBytes, ok := member.Member.(string)
+		if !ok || len(keyBytes) != 32 {
+			log.Warn("Unexpected member in DAS tier index", "member", member.Member)
+			continue
+		}
+		entry := hotTierEntry{storedAt: uint64(member.Score), key: common.BytesToHash([]byte(keyBytes))}
+		meta, err := r.client.HGet(ctx, redisHotTierMetaKey, keyBytes).Bytes()
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return nil, err
+		}
+		if len(meta) == 9 {
+			entry.expiration = binary.BigEndian.Uint64(meta)
+			entry.inCold = meta[8] == 1
+		} else {
+			entry.expiration = math.MaxUint64
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func (r *redisHotTier) removeHot(ctx context.Context, entries []hotTierEntry) error {
+	for _, entry := range entries {
+		member := string(entry.key.Bytes())
+		err := r.client.Watch(ctx, func(tx *redis.Tx) error {
+			storedAt, err := tx.ZScore(ctx, redisHotTierIndexKey, member).Result()
+			if errors.Is(err, redis.Nil) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if uint64(storedAt) > entry.storedAt {
+				// Stored again since it was found, so it will be demoted later.
+				return nil
+			}
+			_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				pipe.Del(ctx, redisHotTierDataPrefix+member)
+				pipe.HDel(ctx, redisHotTierMetaKey, member)
+				pipe.ZRem(ctx, redisHotTierIndexKey, member)
+				return nil
+			})
+			return err
+		}, redisHotTierIndexKey)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *redisHotTier) Sync(ctx context.Context) error {
+	return nil
+}
+
+func (r *redisHotTier) Close(ctx context.Context) error {
+	return r.client.Close()
+}
+
+func (r *redisHotTier) ExpirationPolicy(ctx context.Context) (fogstate.ExpirationPolicy, error) {
+	return fogstate.KeepForever, nil
+}
+
+func (r *redisHotTier) String() string {
+	return "RedisHotTier(" + r.signer.redisConfig.RedisUrl + ")"
+}
+
+func (r *redisHotTier) HealthCheck(ctx context.Context) error {
+	if err := r.client.Ping(ctx).Err(); err != nil {
+		return err
+	}
+	testData := []byte("Test-Data")
+	testKey := dastree.Hash(testData)
+	if err := r.client.Set(ctx, redisHotTierDataPrefix+string(testKey.Bytes()), r.signer.signMessage(testData), time.Minute).Err(); err != nil {
+		return err
+	}
+	res, err := r.GetByHash(ctx, testKey)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(res, testData) {
+		return errors.New("invalid GetByHash result")
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+// Copyright 2022, Offchain Labs, Inc.
+// For license information, see https://github.com/fogr/blob/master/LICENSE
+
+package das
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/FOGRCC/fogr/das/dastree"
+)
+
+func TestTieredStorageService(t *testing.T) {
+	ctx := context.Background()
+	hotStorage, err := NewDBStorageService(ctx, LocalDBStorageConfig{Enable: true, DataDir: t.TempDir()})
+	Require(t, err)
+	hot := hotStorage.(*DBStorageService)
+	cold := NewMemoryBackedStorageService(ctx)
+
+	config := DefaultTieredStorageConfig
+	config.Enable = true
+	config.DemoteAfter = time.Hour
+	config.DemoteInterval = time.Hour
+	config.PromoteAfterReads = 2
+	ts, err := newTieredStorageService(ctx, config, hot, cold)
+	Require(t, err)
+	defer func() {
+		Require(t, ts.Close(ctx))
+	}()
+
+	requireData := func(s StorageService, data []byte, present bool, msg string) {
+		t.Helper()
+		res, err := s.GetByHash(ctx, dastree.Hash(data))
+		if !present {
+			if !errors.Is(err, ErrNotFound) {
+				Fail(t, msg, "expected the data to be missing, got", err)
+			}
+			return
+		}
+		Require(t, err, msg)
+		if !bytes.Equal(res, data) {
+			Fail(t, msg, "got the wrong data")
+		}
+	}
+
+	data := []byte("recently posted batch")
+	Require(t, ts.Put(ctx, data, uint64(time.Now().Add(time.Hour).Unix())))
+	requireData(hot, data, true, "new data in the hot tier")
+	requireData(cold, data, false, "new data in the cold tier")
+	requireData(ts, data, true, "new data")
+
+	ts.demote(ctx, time.Now().Add(2*time.Hour))
+	requireData(hot, data, false, "demoted data in the hot tier")
+	requireData(cold, data, true, "demoted data in the cold tier")
+	requireData(ts, data, true, "demoted data")
+
+	// The second read from the cold tier promotes the data.
+	requireData(hot, data, false, "data read once in the hot tier")
+	requireData(ts, data, true, "promoted data")
+	requireData(hot, data, true, "promoted data in the hot tier")
+
+	ts.demote(ctx, time.Now().Add(2*time.Hour))
+	requireData(hot, data, false, "data demoted again in the hot tier")
+	requireData(cold, data, true, "data demoted again in the cold tier")
+}
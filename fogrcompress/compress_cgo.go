@@ -11,6 +11,46 @@ package fogcompress
 #cgo LDFLAGS: ${SRCDIR}/../target/lib/libbrotlidec-static.a ${SRCDIR}/../target/lib/libbrotlienc-static.a ${SRCDIR}/../target/lib/libbrotlicommon-static.a -lm
 #include "brotli/encode.h"
 #include "brotli/decode.h"
+
+static BROTLI_BOOL compressWithDictionary(int level, int window, size_t dictSize, const uint8_t* dict, size_t inSize, const uint8_t* in, size_t* outSize, uint8_t* out) {
+	BrotliEncoderPreparedDictionary* prepared = BrotliEncoderPrepareDictionary(BROTLI_SHARED_DICTIONARY_RAW, dictSize, dict, BROTLI_MAX_QUALITY, NULL, NULL, NULL);
+	if (prepared == NULL) {
+		return BROTLI_FALSE;
+	}
+	BrotliEncoderState* state = BrotliEncoderCreateInstance(NULL, NULL, NULL);
+	BROTLI_BOOL ok = state != NULL &&
+		BrotliEncoderSetParameter(state, BROTLI_PARAM_QUALITY, level) &&
+		BrotliEncoderSetParameter(state, BROTLI_PARAM_LGWIN, window) &&
+		BrotliEncoderAttachPreparedDictionary(state, prepared);
+	if (ok) {
+		size_t availIn = inSize;
+		size_t availOut = *outSize;
+		ok = BrotliEncoderCompressStream(state, BROTLI_OPERATION_FINISH, &availIn, &in, &availOut, &out, NULL) &&
+			BrotliEncoderIsFinished(state);
+		*outSize -= availOut;
+	}
+	if (state != NULL) {
+		BrotliEncoderDestroyInstance(state);
+	}
+	BrotliEncoderDestroyPreparedDictionary(prepared);
+	return ok;
+}
+
+static BrotliDecoderResult decompressWithDictionary(size_t dictSize, const uint8_t* dict, size_t inSize, const uint8_t* in, size_t* outSize, uint8_t* out) {
+	BrotliDecoderState* state = BrotliDecoderCreateInstance(NULL, NULL, NULL);
+	if (state == NULL) {
+		return BROTLI_DECODER_RESULT_ERROR;
+	}
+	BrotliDecoderResult res = BROTLI_DECODER_RESULT_ERROR;
+	if (BrotliDecoderAttachDictionary(state, BROTLI_SHARED_DICTIONARY_RAW, dictSize, dict)) {
+		size_t availIn = inSize;
+		size_t availOut = *outSize;
+		res = BrotliDecoderDecompressStream(state, &availIn, &in, &availOut, &out, NULL);
+		*outSize -= availOut;
+	}
+	BrotliDecoderDestroyInstance(state);
+	return res;
+}
 */
 import "C"
 import (
@@ -53,3 +93,33 @@ func compressLevel(input []byte, level int) ([]byte, error) {
 func CompressWell(input []byte) ([]byte, error) {
 	return compressLevel(input, LEVEL_WELL)
 }
+
+func compressLevelWithDictionary(input []byte, level int, dictionary []byte) ([]byte, error) {
+	maxOutSize := compressedBufferSizeFor(len(input))
+	outbuf := make([]byte, maxOutSize)
+	outSize := C.size_t(maxOutSize)
+	var inputPtr *C.uint8_t
+	if len(input) > 0 {
+		inputPtr = (*C.uint8_t)(&input[0])
+	}
+	res := C.compressWithDictionary(C.int(level), C.BROTLI_DEFAULT_WINDOW, C.size_t(len(dictionary)), (*C.uint8_t)(&dictionary[0]),
+		C.size_t(len(input)), inputPtr, &outSize, (*C.uint8_t)(&outbuf[0]))
+	if res != 1 {
+		return nil, fmt.Errorf("failed compression with dictionary: %d", res)
+	}
+	return outbuf[:outSize], nil
+}
+
+func decompressWithDictionary(input []byte, maxSize int, dictionary []byte) ([]byte, error) {
+	outbuf := make([]byte, maxSize)
+	outsize := C.size_t(maxSize)
+	var ptr *C.uint8_t
+	if len(input) > 0 {
+		ptr = (*C.uint8_t)(&input[0])
+	}
+	res := C.decompressWithDictionary(C.size_t(len(dictionary)), (*C.uint8_t)(&dictionary[0]), C.size_t(len(input)), ptr, &outsize, (*C.uint8_t)(&outbuf[0]))
+	if res != C.BROTLI_DECODER_RESULT_SUCCESS {
+		return nil, fmt.Errorf("failed decompression with dictionary: %d", res)
+	}
+	return outbuf[:outsize], nil
+}
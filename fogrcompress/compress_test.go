@@ -7,6 +7,8 @@ import (
 	"bytes"
 	"testing"
 
+	"github.com/ethereum/go-ethereum/common"
+
 	"github.com/FOGRCC/fogr/util/testhelpers"
 )
 
@@ -48,3 +50,33 @@ func TestfogCompress(t *testing.T) {
 	// test empty data:
 	testCompressDecompress(t, []byte{})
 }
+
+func TestDictionaryCompress(t *testing.T) {
+	// An ERC-20 transfer's calldata, which the batch dictionary is built around.
+	transfer := common.FromHex("a9059cbb000000000000000000000000b4c79dab8f259c7aee6e5b2aa729821864227e840000000000000000000000000000000000000000000000000de0b6b3a7640000")
+	var data []byte
+	for i := 0; i < 4; i++ {
+		data = append(data, transfer...)
+	}
+	for _, dictionary := range []Dictionary{EmptyDictionary, BatchDictionaryV1} {
+		compressed, err := CompressLevelWithDictionary(data, LEVEL_WELL, dictionary)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := DecompressWithDictionary(compressed, len(data)*2+64, dictionary)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(res, data) {
+			t.Fatal("results differ with dictionary", dictionary, res, "vs.", data)
+		}
+	}
+
+	if _, err := CompressLevelWithDictionary(data, LEVEL_WELL, Dictionary(0xff)); err == nil {
+		t.Fatal("compressed with an unknown dictionary")
+	}
+	dictionary, err := DictionaryFromName(BatchDictionaryV1.String())
+	if err != nil || dictionary != BatchDictionaryV1 {
+		t.Fatal("failed to look up dictionary by name", dictionary, err)
+	}
+}
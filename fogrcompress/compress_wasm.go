@@ -14,6 +14,10 @@ func brotliCompress(inBuf []byte, outBuf []byte, level int, windowSize int) int6
 
 func brotliDecompress(inBuf []byte, outBuf []byte) int64
 
+func brotliCompressWithDictionary(inBuf []byte, outBuf []byte, level int, windowSize int, dictBuf []byte) int64
+
+func brotliDecompressWithDictionary(inBuf []byte, outBuf []byte, dictBuf []byte) int64
+
 func Decompress(input []byte, maxSize int) ([]byte, error) {
 	outBuf := make([]byte, maxSize)
 	outLen := brotliDecompress(input, outBuf)
@@ -32,3 +36,22 @@ func compressLevel(input []byte, level int) ([]byte, error) {
 	}
 	return outBuf[:outLen], nil
 }
+
+func compressLevelWithDictionary(input []byte, level int, dictionary []byte) ([]byte, error) {
+	maxOutSize := compressedBufferSizeFor(len(input))
+	outBuf := make([]byte, maxOutSize)
+	outLen := brotliCompressWithDictionary(input, outBuf, level, WINDOW_SIZE, dictionary)
+	if outLen < 0 {
+		return nil, fmt.Errorf("failed compression with dictionary")
+	}
+	return outBuf[:outLen], nil
+}
+
+func decompressWithDictionary(input []byte, maxSize int, dictionary []byte) ([]byte, error) {
+	outBuf := make([]byte, maxSize)
+	outLen := brotliDecompressWithDictionary(input, outBuf, dictionary)
+	if outLen < 0 {
+		return nil, fmt.Errorf("failed decompression with dictionary")
+	}
+	return outBuf[:outLen], nil
+}
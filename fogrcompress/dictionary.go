@@ -0,0 +1,136 @@
+// Copyright 2021-2022, Offchain Labs, Inc.
+// For license information, see https://github.com/fogr/blob/master/LICENSE
+
+package fogcompress
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+)
+
+// Dictionary identifies a custom brotli dictionary. The contents of a dictionary must never change
+// once data compressed with it has been posted, so a new dictionary needs a new identifier.
+type Dictionary uint8
+
+const (
+	EmptyDictionary Dictionary = iota
+	// BatchDictionaryV1 is tuned for batches of L2 transactions, which are mostly ERC-20 calls.
+	BatchDictionaryV1
+)
+
+var dictionaryNames = map[Dictionary]string{
+	EmptyDictionary:   "none",
+	BatchDictionaryV1: "batch-v1",
+}
+
+func (d Dictionary) String() string {
+	if name, ok := dictionaryNames[d]; ok {
+		return name
+	}
+	return fmt.Sprintf("unknown dictionary %d", uint8(d))
+}
+
+// DictionaryFromName returns the dictionary with the given name, where an empty name means no dictionary.
+func DictionaryFromName(name string) (Dictionary, error) {
+	if name == "" {
+		return EmptyDictionary, nil
+	}
+	for dictionary, dictionaryName := range dictionaryNames {
+		if name == dictionaryName {
+			return dictionary, nil
+		}
+	}
+	return EmptyDictionary, fmt.Errorf("unknown brotli dictionary %q", name)
+}
+
+// Bytes returns the contents of the dictionary, which are nil for the empty dictionary.
+func (d Dictionary) Bytes() ([]byte, error) {
+	switch d {
+	case EmptyDictionary:
+		return nil, nil
+	case BatchDictionaryV1:
+		return batchDictionaryV1, nil
+	default:
+		return nil, fmt.Errorf("unknown brotli dictionary %d", uint8(d))
+	}
+}
+
+// batchDictionaryV1Snippets are the hex encoded pieces of BatchDictionaryV1. Brotli references
+// the end of a dictionary most cheaply, so the most common pieces come last.
+var batchDictionaryV1Snippets = []string{
+	// Uniswap-style router calls
+	"38ed1739", // swapExactTokensForTokens(uint256,uint256,address[],address,uint256)
+	"7ff36ab5", // swapExactETHForTokens(uint256,address[],address,uint256)
+	"18cbafe5", // swapExactTokensForETH(uint256,uint256,address[],address,uint256)
+	"414bf389", // exactInputSingle((address,address,uint24,address,uint256,uint256,uint256,uint160))
+	"ac9650d8", // multicall(bytes[])
+	"00000000000000000000000000000000000000000000000000000000000000a0",
+	"0000000000000000000000000000000000000000000000000000000000000080",
+	"0000000000000000000000000000000000000000000000000000000000000060",
+	"0000000000000000000000000000000000000000000000000000000000000040",
+	"0000000000000000000000000000000000000000000000000000000000000020",
+	"0000000000000000000000000000000000000000000000000000000000000002",
+	"0000000000000000000000000000000000000000000000000000000000000001",
+	// unlimited approvals
+	"ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff",
+	// ERC-20 calls followed by the padding of their first address argument
+	"095ea7b3000000000000000000000000", // approve(address,uint256)
+	"23b872dd000000000000000000000000", // transferFrom(address,address,uint256)
+	"a9059cbb000000000000000000000000", // transfer(address,uint256)
+	// the padding of an address and of a small amount
+	"000000000000000000000000",
+	"0000000000000000000000000000000000000000000000",
+	"00000000000000000000000000000000000000000000000000000000",
+	// signed L2 transaction envelopes: dynamic fee and legacy
+	"02f8b18264",
+	"02f8b0",
+	"02f8af",
+	"02f8ae",
+	"f8a9",
+	"f8aa",
+	"f8ab",
+	"808080",
+	"80c080a0",
+	"80c001a0",
+	"c080a0",
+	"c001a0",
+	"0000000000000000000000000000000000000000000000000000000000",
+}
+
+var batchDictionaryV1 = func() []byte {
+	var dictionary bytes.Buffer
+	for _, snippet := range batchDictionaryV1Snippets {
+		decoded, err := hex.DecodeString(snippet)
+		if err != nil {
+			panic(err)
+		}
+		dictionary.Write(decoded)
+	}
+	return dictionary.Bytes()
+}()
+
+// CompressLevelWithDictionary compresses input at the given brotli level using a custom dictionary.
+// Its output can only be decompressed with the same dictionary.
+func CompressLevelWithDictionary(input []byte, level int, dictionary Dictionary) ([]byte, error) {
+	dictionaryBytes, err := dictionary.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	if len(dictionaryBytes) == 0 {
+		return compressLevel(input, level)
+	}
+	return compressLevelWithDictionary(input, level, dictionaryBytes)
+}
+
+// DecompressWithDictionary decompresses input compressed by CompressLevelWithDictionary with the same dictionary.
+func DecompressWithDictionary(input []byte, maxSize int, dictionary Dictionary) ([]byte, error) {
+	dictionaryBytes, err := dictionary.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	if len(dictionaryBytes) == 0 {
+		return Decompress(input, maxSize)
+	}
+	return decompressWithDictionary(input, maxSize, dictionaryBytes)
+}
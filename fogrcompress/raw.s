@@ -14,3 +14,11 @@ TEXT ·brotliCompress(SB), NOSPLIT, $0
 TEXT ·brotliDecompress(SB), NOSPLIT, $0
   CallImport
   RET
+
+TEXT ·brotliCompressWithDictionary(SB), NOSPLIT, $0
+  CallImport
+  RET
+
+TEXT ·brotliDecompressWithDictionary(SB), NOSPLIT, $0
+  CallImport
+  RET
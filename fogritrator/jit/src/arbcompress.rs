@@ -2,6 +2,7 @@
 // For license information, see https://github.com/fogr/blob/master/LICENSE
 
 use crate::{gostack::GoStack, machine::WasmEnvMut};
+use std::{ffi::c_void, ptr};
 
 extern "C" {
     pub fn BrotliDecoderDecompress(
@@ -20,10 +21,168 @@ extern "C" {
         encoded_size: *mut usize,
         encoded_buffer: *mut u8,
     ) -> u32;
+
+    pub fn BrotliEncoderPrepareDictionary(
+        dictionary_type: u32,
+        data_size: usize,
+        data: *const u8,
+        quality: i32,
+        alloc_func: *const c_void,
+        free_func: *const c_void,
+        opaque: *mut c_void,
+    ) -> *mut c_void;
+
+    pub fn BrotliEncoderDestroyPreparedDictionary(dictionary: *mut c_void);
+
+    pub fn BrotliEncoderCreateInstance(
+        alloc_func: *const c_void,
+        free_func: *const c_void,
+        opaque: *mut c_void,
+    ) -> *mut c_void;
+
+    pub fn BrotliEncoderSetParameter(state: *mut c_void, param: u32, value: u32) -> u32;
+
+    pub fn BrotliEncoderAttachPreparedDictionary(
+        state: *mut c_void,
+        dictionary: *const c_void,
+    ) -> u32;
+
+    pub fn BrotliEncoderCompressStream(
+        state: *mut c_void,
+        op: u32,
+        available_in: *mut usize,
+        next_in: *mut *const u8,
+        available_out: *mut usize,
+        next_out: *mut *mut u8,
+        total_out: *mut usize,
+    ) -> u32;
+
+    pub fn BrotliEncoderIsFinished(state: *mut c_void) -> u32;
+
+    pub fn BrotliEncoderDestroyInstance(state: *mut c_void);
+
+    pub fn BrotliDecoderCreateInstance(
+        alloc_func: *const c_void,
+        free_func: *const c_void,
+        opaque: *mut c_void,
+    ) -> *mut c_void;
+
+    pub fn BrotliDecoderAttachDictionary(
+        state: *mut c_void,
+        dictionary_type: u32,
+        data_size: usize,
+        data: *const u8,
+    ) -> u32;
+
+    pub fn BrotliDecoderDecompressStream(
+        state: *mut c_void,
+        available_in: *mut usize,
+        next_in: *mut *const u8,
+        available_out: *mut usize,
+        next_out: *mut *mut u8,
+        total_out: *mut usize,
+    ) -> u32;
+
+    pub fn BrotliDecoderDestroyInstance(state: *mut c_void);
 }
 
 const BROTLI_MODE_GENERIC: u32 = 0;
 const BROTLI_RES_SUCCESS: u32 = 1;
+const BROTLI_SHARED_DICTIONARY_RAW: u32 = 0;
+const BROTLI_MAX_QUALITY: i32 = 11;
+const BROTLI_PARAM_QUALITY: u32 = 1;
+const BROTLI_PARAM_LGWIN: u32 = 2;
+const BROTLI_OPERATION_FINISH: u32 = 2;
+const BROTLI_DECODER_RESULT_SUCCESS: u32 = 1;
+
+/// Compresses `input` into `output` with a raw custom dictionary, returning the compressed length.
+unsafe fn compress_with_dictionary(
+    level: u32,
+    window_size: u32,
+    dictionary: &[u8],
+    input: &[u8],
+    output: &mut [u8],
+) -> Option<usize> {
+    let prepared = BrotliEncoderPrepareDictionary(
+        BROTLI_SHARED_DICTIONARY_RAW,
+        dictionary.len(),
+        dictionary.as_ptr(),
+        BROTLI_MAX_QUALITY,
+        ptr::null(),
+        ptr::null(),
+        ptr::null_mut(),
+    );
+    if prepared.is_null() {
+        return None;
+    }
+    let state = BrotliEncoderCreateInstance(ptr::null(), ptr::null(), ptr::null_mut());
+    let mut result = None;
+    if !state.is_null()
+        && BrotliEncoderSetParameter(state, BROTLI_PARAM_QUALITY, level) == BROTLI_RES_SUCCESS
+        && BrotliEncoderSetParameter(state, BROTLI_PARAM_LGWIN, window_size) == BROTLI_RES_SUCCESS
+        && BrotliEncoderAttachPreparedDictionary(state, prepared) == BROTLI_RES_SUCCESS
+    {
+        let mut available_in = input.len();
+        let mut next_in = input.as_ptr();
+        let mut available_out = output.len();
+        let mut next_out = output.as_mut_ptr();
+        let res = BrotliEncoderCompressStream(
+            state,
+            BROTLI_OPERATION_FINISH,
+            &mut available_in,
+            &mut next_in,
+            &mut available_out,
+            &mut next_out,
+            ptr::null_mut(),
+        );
+        if res == BROTLI_RES_SUCCESS && BrotliEncoderIsFinished(state) == BROTLI_RES_SUCCESS {
+            result = Some(output.len() - available_out);
+        }
+    }
+    if !state.is_null() {
+        BrotliEncoderDestroyInstance(state);
+    }
+    BrotliEncoderDestroyPreparedDictionary(prepared);
+    result
+}
+
+/// Decompresses `input` into `output` with a raw custom dictionary, returning the decompressed length.
+unsafe fn decompress_with_dictionary(
+    dictionary: &[u8],
+    input: &[u8],
+    output: &mut [u8],
+) -> Option<usize> {
+    let state = BrotliDecoderCreateInstance(ptr::null(), ptr::null(), ptr::null_mut());
+    if state.is_null() {
+        return None;
+    }
+    let mut result = None;
+    let attached = BrotliDecoderAttachDictionary(
+        state,
+        BROTLI_SHARED_DICTIONARY_RAW,
+        dictionary.len(),
+        dictionary.as_ptr(),
+    );
+    if attached == BROTLI_RES_SUCCESS {
+        let mut available_in = input.len();
+        let mut next_in = input.as_ptr();
+        let mut available_out = output.len();
+        let mut next_out = output.as_mut_ptr();
+        let res = BrotliDecoderDecompressStream(
+            state,
+            &mut available_in,
+            &mut next_in,
+            &mut available_out,
+            &mut next_out,
+            ptr::null_mut(),
+        );
+        if res == BROTLI_DECODER_RESULT_SUCCESS {
+            result = Some(output.len() - available_out);
+        }
+    }
+    BrotliDecoderDestroyInstance(state);
+    result
+}
 
 pub fn brotli_compress(mut env: WasmEnvMut, sp: u32) {
     let (sp, _) = GoStack::new(sp, &mut env);
@@ -91,3 +250,60 @@ pub fn brotli_decompress(mut env: WasmEnvMut, sp: u32) {
     sp.write_slice(out_buf_ptr, &output[..output_len]);
     sp.write_u64(output_arg, output_len as u64);
 }
+
+pub fn brotli_compress_with_dictionary(mut env: WasmEnvMut, sp: u32) {
+    let (sp, _) = GoStack::new(sp, &mut env);
+
+    //(inBuf []byte, outBuf []byte, level int, windowSize int, dictBuf []byte) int
+    let in_buf_ptr = sp.read_u64(0);
+    let in_buf_len = sp.read_u64(1);
+    let out_buf_ptr = sp.read_u64(3);
+    let out_buf_len = sp.read_u64(4);
+    let level = sp.read_u64(6) as u32;
+    let windowsize = sp.read_u64(7) as u32;
+    let dict_buf_ptr = sp.read_u64(8);
+    let dict_buf_len = sp.read_u64(9);
+    let output_arg = 11;
+
+    let in_slice = sp.read_slice(in_buf_ptr, in_buf_len);
+    let dict_slice = sp.read_slice(dict_buf_ptr, dict_buf_len);
+    let mut output = vec![0u8; out_buf_len as usize];
+
+    let res =
+        unsafe { compress_with_dictionary(level, windowsize, &dict_slice, &in_slice, &mut output) };
+
+    match res {
+        Some(output_len) => {
+            sp.write_slice(out_buf_ptr, &output[..output_len]);
+            sp.write_u64(output_arg, output_len as u64);
+        }
+        None => sp.write_u64(output_arg, u64::MAX),
+    }
+}
+
+pub fn brotli_decompress_with_dictionary(mut env: WasmEnvMut, sp: u32) {
+    let (sp, _) = GoStack::new(sp, &mut env);
+
+    //(inBuf []byte, outBuf []byte, dictBuf []byte) int
+    let in_buf_ptr = sp.read_u64(0);
+    let in_buf_len = sp.read_u64(1);
+    let out_buf_ptr = sp.read_u64(3);
+    let out_buf_len = sp.read_u64(4);
+    let dict_buf_ptr = sp.read_u64(6);
+    let dict_buf_len = sp.read_u64(7);
+    let output_arg = 9;
+
+    let in_slice = sp.read_slice(in_buf_ptr, in_buf_len);
+    let dict_slice = sp.read_slice(dict_buf_ptr, dict_buf_len);
+    let mut output = vec![0u8; out_buf_len as usize];
+
+    let res = unsafe { decompress_with_dictionary(&dict_slice, &in_slice, &mut output) };
+
+    match res {
+        Some(output_len) => {
+            sp.write_slice(out_buf_ptr, &output[..output_len]);
+            sp.write_u64(output_arg, output_len as u64);
+        }
+        None => sp.write_u64(output_arg, u64::MAX),
+    }
+}
@@ -112,6 +112,8 @@ pub fn create(opts: &Opts, env: WasmEnv) -> (Instance, FunctionEnv<WasmEnv>, Sto
 
             "github.com/FOGRCC/fogr/fogcompress.brotliCompress" => func!(fogcompress::brotli_compress),
             "github.com/FOGRCC/fogr/fogcompress.brotliDecompress" => func!(fogcompress::brotli_decompress),
+            "github.com/FOGRCC/fogr/fogcompress.brotliCompressWithDictionary" => func!(fogcompress::brotli_compress_with_dictionary),
+            "github.com/FOGRCC/fogr/fogcompress.brotliDecompressWithDictionary" => func!(fogcompress::brotli_decompress_with_dictionary),
         },
     };
 
@@ -12,6 +12,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/FOGRCC/fogr/das"
 	"github.com/FOGRCC/fogr/fogos/fogosState"
 	"github.com/FOGRCC/fogr/fogos/retryables"
 	"github.com/FOGRCC/fogr/staker"
@@ -89,6 +90,15 @@ func (a *fogAPI) CheckPublisherHealth(ctx context.Context) error {
 	return a.txPublisher.CheckHealth(ctx)
 }
 
+type DASAggregatorAPI struct {
+	agg *das.Aggregator
+}
+
+// DasSignerHealth returns the rolling Store statistics of each DAS committee member.
+func (a *DASAggregatorAPI) DasSignerHealth(ctx context.Context) ([]das.SignerHealth, error) {
+	return a.agg.SignerHealth(), nil
+}
+
 type fogDebugAPI struct {
 	blockchain        *core.BlockChain
 	blockRangeBound   uint64
@@ -11,7 +11,10 @@ import (
 )
 
 type TransactionPublisher interface {
-	PublishTransaction(ctx context.Context, tx *types.Transaction) error
+	// PublishTransaction sequences tx, if its conditions hold at inclusion when options isn't nil.
+	PublishTransaction(ctx context.Context, tx *types.Transaction, options *ConditionalOptions) error
+	// PublishBundle sequences txs consecutively in one block, where either all of them succeed or none are included.
+	PublishBundle(ctx context.Context, txs types.Transactions) error
 	CheckHealth(ctx context.Context) error
 	Initialize(context.Context) error
 	Start(context.Context) error
@@ -37,7 +40,7 @@ func (a *fogInterface) Initialize(n *Node) {
 }
 
 func (a *fogInterface) PublishTransaction(ctx context.Context, tx *types.Transaction) error {
-	return a.txPublisher.PublishTransaction(ctx, tx)
+	return a.txPublisher.PublishTransaction(ctx, tx, nil)
 }
 
 func (a *fogInterface) TransactionStreamer() *TransactionStreamer {
@@ -18,13 +18,17 @@ import (
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/rpc"
 
 	"github.com/FOGRCC/fogr/das"
+	"github.com/FOGRCC/fogr/fogcompress"
 	"github.com/FOGRCC/fogr/fognode/dataposter"
 	"github.com/FOGRCC/fogr/fogos"
+	"github.com/FOGRCC/fogr/fogos/fogosState"
 	"github.com/FOGRCC/fogr/fogstate"
 	"github.com/FOGRCC/fogr/fogutil"
 	"github.com/FOGRCC/fogr/solgen/go/bridgegen"
@@ -52,25 +56,36 @@ type BatchPoster struct {
 	seqInboxAddr common.Address
 	building     *buildingBatch
 	daWriter     das.DataAvailabilityServiceWriter
-	dataPoster   *dataposter.DataPoster[batchPosterPosition]
+	dataPoster   *dataposter.DataPoster[batchPosterPosition]   // the one currently posting batches
+	dataPosters  []*dataposter.DataPoster[batchPosterPosition] // one for each wallet
+	wallets      batchPosterWallets
 	redisLock    *SimpleRedisLock
 	firstAccErr  time.Time // first time a continuous missing accumulator occurred
+	dryRun       *batchPosterDryRun
+	postPolicy   batchPostingPolicy
+	dasFallback  dasFallback
 }
 
 type BatchPosterConfig struct {
-	Enable                             bool                        `koanf:"enable"`
-	DisableDasFallbackStoreDataOnChain bool                        `koanf:"disable-das-fallback-store-data-on-chain" reload:"hot"`
-	MaxBatchSize                       int                         `koanf:"max-size" reload:"hot"`
-	MaxBatchPostInterval               time.Duration               `koanf:"max-interval" reload:"hot"`
-	BatchPollDelay                     time.Duration               `koanf:"poll-delay" reload:"hot"`
-	PostingErrorDelay                  time.Duration               `koanf:"error-delay" reload:"hot"`
-	CompressionLevel                   int                         `koanf:"compression-level" reload:"hot"`
-	DASRetentionPeriod                 time.Duration               `koanf:"das-retention-period" reload:"hot"`
-	GasRefunderAddress                 string                      `koanf:"gas-refunder-address" reload:"hot"`
-	DataPoster                         dataposter.DataPosterConfig `koanf:"data-poster" reload:"hot"`
-	RedisUrl                           string                      `koanf:"redis-url"`
-	RedisLock                          SimpleRedisLockConfig       `koanf:"redis-lock" reload:"hot"`
-	ExtraBatchGas                      uint64                      `koanf:"extra-batch-gas" reload:"hot"`
+	Enable      bool              `koanf:"enable"`
+	DASFallback DASFallbackConfig `koanf:"das-fallback" reload:"hot"`
+	// Deprecated: use DASFallback.Enable instead
+	DisableDasFallbackStoreDataOnChain bool                          `koanf:"disable-das-fallback-store-data-on-chain" reload:"hot"`
+	MaxBatchSize                       int                           `koanf:"max-size" reload:"hot"`
+	MaxBatchPostInterval               time.Duration                 `koanf:"max-interval" reload:"hot"`
+	BatchPollDelay                     time.Duration                 `koanf:"poll-delay" reload:"hot"`
+	PostingErrorDelay                  time.Duration                 `koanf:"error-delay" reload:"hot"`
+	CompressionLevel                   int                           `koanf:"compression-level" reload:"hot"`
+	CompressionDictionary              string                        `koanf:"compression-dictionary" reload:"hot"`
+	DASRetentionPeriod                 time.Duration                 `koanf:"das-retention-period" reload:"hot"`
+	GasRefunderAddress                 string                        `koanf:"gas-refunder-address" reload:"hot"`
+	DataPoster                         dataposter.DataPosterConfig   `koanf:"data-poster" reload:"hot"`
+	RedisUrl                           string                        `koanf:"redis-url"`
+	RedisLock                          SimpleRedisLockConfig         `koanf:"redis-lock" reload:"hot"`
+	ExtraBatchGas                      uint64                        `koanf:"extra-batch-gas" reload:"hot"`
+	DryRun                             BatchPosterDryRunConfig       `koanf:"dry-run"`
+	ExtraWallets                       BatchPosterExtraWalletsConfig `koanf:"extra-wallets"`
+	PostingPolicy                      BatchPostingPolicyConfig      `koanf:"posting-policy" reload:"hot"`
 }
 
 func (c *BatchPosterConfig) Validate() error {
@@ -80,39 +95,54 @@ func (c *BatchPosterConfig) Validate() error {
 	if c.MaxBatchSize <= 40 {
 		return errors.New("MaxBatchSize too small")
 	}
-	return nil
+	if _, err := fogcompress.DictionaryFromName(c.CompressionDictionary); err != nil {
+		return err
+	}
+	if err := c.DASFallback.Validate(); err != nil {
+		return err
+	}
+	return c.PostingPolicy.Validate()
 }
 
 type BatchPosterConfigFetcher func() *BatchPosterConfig
 
 func BatchPosterConfigAddOptions(prefix string, f *flag.FlagSet) {
 	f.Bool(prefix+".enable", DefaultBatchPosterConfig.Enable, "enable posting batches to l1")
-	f.Bool(prefix+".disable-das-fallback-store-data-on-chain", DefaultBatchPosterConfig.DisableDasFallbackStoreDataOnChain, "If unable to batch to DAS, disable fallback storing data on chain")
+	f.Bool(prefix+".disable-das-fallback-store-data-on-chain", DefaultBatchPosterConfig.DisableDasFallbackStoreDataOnChain, "If unable to batch to DAS, disable fallback storing data on chain (deprecated, please use "+prefix+".das-fallback.enable)")
 	f.Int(prefix+".max-size", DefaultBatchPosterConfig.MaxBatchSize, "maximum batch size")
 	f.Duration(prefix+".max-interval", DefaultBatchPosterConfig.MaxBatchPostInterval, "maximum batch posting interval")
 	f.Duration(prefix+".poll-delay", DefaultBatchPosterConfig.BatchPollDelay, "how long to delay after successfully posting batch")
 	f.Duration(prefix+".error-delay", DefaultBatchPosterConfig.PostingErrorDelay, "how long to delay after error posting batch")
 	f.Int(prefix+".compression-level", DefaultBatchPosterConfig.CompressionLevel, "batch compression level")
+	f.String(prefix+".compression-dictionary", DefaultBatchPosterConfig.CompressionDictionary, "if set, the brotli dictionary (\"batch-v1\") to compress batches with when that makes them smaller; only used once the chain has upgraded to a fogOS version whose module root reads such batches")
 	f.Duration(prefix+".das-retention-period", DefaultBatchPosterConfig.DASRetentionPeriod, "In AnyTrust mode, the period which DASes are requested to retain the stored batches.")
 	f.String(prefix+".gas-refunder-address", DefaultBatchPosterConfig.GasRefunderAddress, "The gas refunder contract address (optional)")
 	f.Uint64(prefix+".extra-batch-gas", DefaultBatchPosterConfig.ExtraBatchGas, "use this much more gas than estimation says is necessary to post batches")
 	f.String(prefix+".redis-url", DefaultBatchPosterConfig.RedisUrl, "if non-empty, the Redis URL to store queued transactions in")
 	RedisLockConfigAddOptions(prefix+".redis-lock", f)
 	dataposter.DataPosterConfigAddOptions(prefix+".data-poster", f)
+	BatchPosterDryRunConfigAddOptions(prefix+".dry-run", f)
+	BatchPosterExtraWalletsConfigAddOptions(prefix+".extra-wallets", f)
+	BatchPostingPolicyConfigAddOptions(prefix+".posting-policy", f)
+	DASFallbackConfigAddOptions(prefix+".das-fallback", f)
 }
 
 var DefaultBatchPosterConfig = BatchPosterConfig{
-	Enable:                             false,
-	DisableDasFallbackStoreDataOnChain: false,
-	MaxBatchSize:                       100000,
-	BatchPollDelay:                     time.Second * 10,
-	PostingErrorDelay:                  time.Second * 10,
-	MaxBatchPostInterval:               time.Hour,
-	CompressionLevel:                   brotli.DefaultCompression,
-	DASRetentionPeriod:                 time.Hour * 24 * 15,
-	GasRefunderAddress:                 "",
-	ExtraBatchGas:                      50_000,
-	DataPoster:                         dataposter.DefaultDataPosterConfig,
+	Enable:                false,
+	DASFallback:           DefaultDASFallbackConfig,
+	MaxBatchSize:          100000,
+	BatchPollDelay:        time.Second * 10,
+	PostingErrorDelay:     time.Second * 10,
+	MaxBatchPostInterval:  time.Hour,
+	CompressionLevel:      brotli.DefaultCompression,
+	CompressionDictionary: "",
+	DASRetentionPeriod:    time.Hour * 24 * 15,
+	GasRefunderAddress:    "",
+	ExtraBatchGas:         50_000,
+	DataPoster:            dataposter.DefaultDataPosterConfig,
+	DryRun:                DefaultBatchPosterDryRunConfig,
+	ExtraWallets:          DefaultBatchPosterExtraWalletsConfig,
+	PostingPolicy:         DefaultBatchPostingPolicyConfig,
 }
 
 var TestBatchPosterConfig = BatchPosterConfig{
@@ -126,9 +156,13 @@ var TestBatchPosterConfig = BatchPosterConfig{
 	GasRefunderAddress:   "",
 	ExtraBatchGas:        10_000,
 	DataPoster:           dataposter.TestDataPosterConfig,
+	DryRun:               DefaultBatchPosterDryRunConfig,
+	ExtraWallets:         DefaultBatchPosterExtraWalletsConfig,
+	PostingPolicy:        DefaultBatchPostingPolicyConfig,
+	DASFallback:          DefaultDASFallbackConfig,
 }
 
-func NewBatchPoster(l1Reader *headerreader.HeaderReader, inbox *InboxTracker, streamer *TransactionStreamer, syncMonitor *SyncMonitor, config BatchPosterConfigFetcher, deployInfo *RollupAddresses, transactOpts *bind.TransactOpts, daWriter das.DataAvailabilityServiceWriter) (*BatchPoster, error) {
+func NewBatchPoster(l1Reader *headerreader.HeaderReader, inbox *InboxTracker, streamer *TransactionStreamer, syncMonitor *SyncMonitor, config BatchPosterConfigFetcher, deployInfo *RollupAddresses, transactOpts *bind.TransactOpts, daWriter das.DataAvailabilityServiceWriter, dataPosterDB ethdb.Database) (*BatchPoster, error) {
 	seqInbox, err := bridgegen.NewSequencerInbox(deployInfo.SequencerInbox, l1Reader.Client())
 	if err != nil {
 		return nil, err
@@ -171,13 +205,53 @@ func NewBatchPoster(l1Reader *headerreader.HeaderReader, inbox *InboxTracker, st
 	dataPosterConfigFetcher := func() *dataposter.DataPosterConfig {
 		return &config().DataPoster
 	}
-	b.dataPoster, err = dataposter.NewDataPoster(l1Reader, transactOpts, redisClient, redisLock, dataPosterConfigFetcher, b.getBatchPosterPosition)
+	if config().DryRun.Enable {
+		b.dryRun, err = newBatchPosterDryRun(config().DryRun)
+		if err != nil {
+			return nil, err
+		}
+	}
+	// All wallets share one fee budget, kept in the database so it lasts across restarts.
+	var feeBudgetDB ethdb.Database
+	if dataPosterDB != nil {
+		feeBudgetDB = rawdb.NewTable(dataPosterDB, "data-poster.fee-budget")
+	}
+	feeBudget, err := dataposter.NewFeeBudget(feeBudgetDB)
 	if err != nil {
 		return nil, err
 	}
+	b.dataPoster, err = dataposter.NewDataPoster(l1Reader, transactOpts, redisClient, dataPosterDB, "data-poster.queue", redisLock, feeBudget, dataPosterConfigFetcher, b.getBatchPosterPosition)
+	if err != nil {
+		return nil, err
+	}
+	b.dataPosters = append(b.dataPosters, b.dataPoster)
+	extraTransactOpts, err := openExtraBatchPosterWallets(l1Reader, transactOpts.From, config().ExtraWallets.PrivateKeys)
+	if err != nil {
+		return nil, err
+	}
+	for _, opts := range extraTransactOpts {
+		dataPoster, err := dataposter.NewDataPoster(l1Reader, opts, redisClient, dataPosterDB, "data-poster."+opts.From.Hex()+".queue", redisLock, feeBudget, dataPosterConfigFetcher, b.getBatchPosterPosition)
+		if err != nil {
+			return nil, err
+		}
+		b.dataPosters = append(b.dataPosters, dataPoster)
+	}
+	for _, dataPoster := range b.dataPosters {
+		b.wallets.wallets = append(b.wallets.wallets, dataPoster)
+	}
 	return b, nil
 }
 
+// chooseWallet picks the wallet to post the next batch from.
+func (b *BatchPoster) chooseWallet(ctx context.Context) error {
+	index, err := b.wallets.choose(ctx, b.config().ExtraWallets.StuckAfter, time.Now())
+	if err != nil {
+		return err
+	}
+	b.dataPoster = b.dataPosters[index]
+	return nil
+}
+
 func (b *BatchPoster) getBatchPosterPosition(ctx context.Context, blockNum *big.Int) (batchPosterPosition, error) {
 	bigInboxBatchCount, err := b.seqInbox.BatchCount(&bind.CallOpts{Context: ctx, BlockNumber: blockNum})
 	if err != nil {
@@ -210,6 +284,7 @@ type batchSegments struct {
 	delayedMsg            uint64
 	sizeLimit             int
 	compressionLevel      int
+	dictionary            fogcompress.Dictionary
 	newUncompressedSize   int
 	totalUncompressedSize int
 	lastCompressedSize    int
@@ -223,7 +298,7 @@ type buildingBatch struct {
 	msgCount      fogutil.MessageIndex
 }
 
-func newBatchSegments(firstDelayed uint64, config *BatchPosterConfig) *batchSegments {
+func newBatchSegments(firstDelayed uint64, config *BatchPosterConfig, dictionary fogcompress.Dictionary) *batchSegments {
 	compressedBuffer := bytes.NewBuffer(make([]byte, 0, config.MaxBatchSize*2))
 	if config.MaxBatchSize <= 40 {
 		panic("MaxBatchSize too small")
@@ -233,11 +308,41 @@ func newBatchSegments(firstDelayed uint64, config *BatchPosterConfig) *batchSegm
 		compressedWriter: brotli.NewWriterLevel(compressedBuffer, config.CompressionLevel),
 		sizeLimit:        config.MaxBatchSize - 40, // TODO
 		compressionLevel: config.CompressionLevel,
+		dictionary:       dictionary,
 		rawSegments:      make([][]byte, 0, 128),
 		delayedMsg:       firstDelayed,
 	}
 }
 
+// compressionDictionary returns the configured batch compression dictionary, but no dictionary
+// until the chain has upgraded to a fogOS version that reads batches compressed with one.
+func (b *BatchPoster) compressionDictionary(config *BatchPosterConfig) fogcompress.Dictionary {
+	dictionary, err := fogcompress.DictionaryFromName(config.CompressionDictionary)
+	if err != nil {
+		log.Error("ignoring invalid batch compression dictionary", "err", err)
+		return fogcompress.EmptyDictionary
+	}
+	if dictionary == fogcompress.EmptyDictionary {
+		return dictionary
+	}
+	statedb, err := b.streamer.bc.State()
+	if err != nil {
+		log.Warn("not compressing batch with a dictionary as the fogOS version is unknown", "err", err)
+		return fogcompress.EmptyDictionary
+	}
+	version := fogosState.fogOSVersion(statedb)
+	if version < fogstate.FirstBrotliDictionaryfogosVersion {
+		log.Warn(
+			"not compressing batch with a dictionary before the fogOS upgrade that reads it",
+			"dictionary", dictionary,
+			"fogosVersion", version,
+			"requiredfogosVersion", fogstate.FirstBrotliDictionaryfogosVersion,
+		)
+		return fogcompress.EmptyDictionary
+	}
+	return dictionary
+}
+
 func (s *batchSegments) recompressAll() error {
 	s.compressedBuffer = bytes.NewBuffer(make([]byte, 0, s.sizeLimit*2))
 	s.compressedWriter = brotli.NewWriterLevel(s.compressedBuffer, s.compressionLevel)
@@ -418,12 +523,44 @@ func (s *batchSegments) CloseAndGetBytes() ([]byte, error) {
 		return nil, err
 	}
 	compressedBytes := s.compressedBuffer.Bytes()
+	if s.dictionary != fogcompress.EmptyDictionary {
+		dictionaryMsg, err := s.compressWithDictionary()
+		if err != nil {
+			return nil, err
+		}
+		if len(dictionaryMsg) < len(compressedBytes)+1 {
+			return dictionaryMsg, nil
+		}
+	}
 	fullMsg := make([]byte, 1, len(compressedBytes)+1)
 	fullMsg[0] = fogstate.BrotliMessageHeaderByte
 	fullMsg = append(fullMsg, compressedBytes...)
 	return fullMsg, nil
 }
 
+// compressWithDictionary compresses the closed batch's segments with its custom dictionary.
+// The streaming writer used while building the batch can't use a dictionary, so its output
+// serves as an upper bound on the size and this is only done once the batch is closed.
+func (s *batchSegments) compressWithDictionary() ([]byte, error) {
+	var rawBytes []byte
+	for _, segment := range s.rawSegments {
+		encoded, err := rlp.EncodeToBytes(segment)
+		if err != nil {
+			return nil, err
+		}
+		rawBytes = append(rawBytes, encoded...)
+	}
+	compressedBytes, err := fogcompress.CompressLevelWithDictionary(rawBytes, s.compressionLevel, s.dictionary)
+	if err != nil {
+		return nil, err
+	}
+	fullMsg := make([]byte, 2, len(compressedBytes)+2)
+	fullMsg[0] = fogstate.BrotliDictionaryMessageHeaderByte
+	fullMsg[1] = byte(s.dictionary)
+	fullMsg = append(fullMsg, compressedBytes...)
+	return fullMsg, nil
+}
+
 func (b *BatchPoster) encodeAddBatch(seqNum *big.Int, prevMsgNum fogutil.MessageIndex, newMsgNum fogutil.MessageIndex, message []byte, delayedMsg uint64) ([]byte, error) {
 	method, ok := b.seqInboxABI.Methods["addSequencerL2BatchFromOrigin0"]
 	if !ok {
@@ -503,14 +640,33 @@ func (b *BatchPoster) estimateGas(ctx context.Context, sequencerMessage []byte,
 }
 
 func (b *BatchPoster) maybePostSequencerBatch(ctx context.Context) (bool, error) {
-	nonce, batchPosition, err := b.dataPoster.GetNextNonceAndMeta(ctx)
-	if err != nil {
-		return false, err
+	var nonce uint64
+	var batchPosition batchPosterPosition
+	var err error
+	if b.dryRun != nil {
+		// Nothing gets posted in dry run mode, so carry on from the last simulated batch.
+		if b.dryRun.position == nil {
+			batchPosition, err = b.getBatchPosterPosition(ctx, nil)
+			if err != nil {
+				return false, err
+			}
+			b.dryRun.position = &batchPosition
+		}
+		batchPosition = *b.dryRun.position
+	} else {
+		err = b.chooseWallet(ctx)
+		if err != nil {
+			return false, err
+		}
+		nonce, batchPosition, err = b.dataPoster.GetNextNonceAndMeta(ctx)
+		if err != nil {
+			return false, err
+		}
 	}
 
 	if b.building == nil || b.building.startMsgCount != batchPosition.MessageCount {
 		b.building = &buildingBatch{
-			segments:      newBatchSegments(batchPosition.DelayedMessageCount, b.config()),
+			segments:      newBatchSegments(batchPosition.DelayedMessageCount, b.config(), b.compressionDictionary(b.config())),
 			msgCount:      batchPosition.MessageCount,
 			startMsgCount: batchPosition.MessageCount,
 		}
@@ -530,10 +686,12 @@ func (b *BatchPoster) maybePostSequencerBatch(ctx context.Context) (bool, error)
 	nextMessageTime := time.Unix(int64(firstMsg.Message.Header.Timestamp), 0)
 
 	config := b.config()
-	forcePostBatch := time.Since(nextMessageTime) >= config.MaxBatchPostInterval
-	haveUsefulMessage := false
+	// A full batch may have been held back by the posting policy, in which case it's already closed.
+	batchFull := b.building.segments.IsDone()
+	forcePostBatch := batchFull || time.Since(nextMessageTime) >= config.MaxBatchPostInterval
+	haveUsefulMessage := batchFull
 
-	for b.building.msgCount < msgCount {
+	for !batchFull && b.building.msgCount < msgCount {
 		msg, err := b.streamer.GetMessage(b.building.msgCount)
 		if err != nil {
 			log.Error("error getting message from streamer", "error", err)
@@ -548,6 +706,7 @@ func (b *BatchPoster) maybePostSequencerBatch(ctx context.Context) (bool, error)
 		if !success {
 			// this batch is full
 			forcePostBatch = true
+			batchFull = true
 			haveUsefulMessage = true
 			break
 		}
@@ -557,6 +716,12 @@ func (b *BatchPoster) maybePostSequencerBatch(ctx context.Context) (bool, error)
 		b.building.msgCount++
 	}
 
+	if config.PostingPolicy.Enable && haveUsefulMessage {
+		forcePostBatch, err = b.policyShouldPost(ctx, firstMsg, uint64(msgCount-batchPosition.MessageCount), batchFull)
+		if err != nil {
+			return false, err
+		}
+	}
 	if !forcePostBatch || !haveUsefulMessage {
 		// the batch isn't full yet and we've posted a batch recently
 		// don't post anything for now
@@ -572,17 +737,33 @@ func (b *BatchPoster) maybePostSequencerBatch(ctx context.Context) (bool, error)
 		return false, nil
 	}
 
+	if b.dryRun != nil {
+		err = b.simulateBatch(ctx, batchPosition, sequencerMsg, batchFull)
+		if err != nil {
+			return false, err
+		}
+		b.building = nil
+		return true, nil
+	}
+
+	storeOnChain := false
 	if b.daWriter != nil {
-		cert, err := b.daWriter.Store(ctx, sequencerMsg, uint64(time.Now().Add(config.DASRetentionPeriod).Unix()), []byte{}) // b.daWriter will append signature if enabled
-		if errors.Is(err, das.BatchToDasFailed) {
-			if config.DisableDasFallbackStoreDataOnChain {
-				return false, errors.New("Unable to batch to DAS and fallback storing data on chain is disabled")
+		if b.dasFallback.shouldTryDAS(&config.DASFallback, time.Now()) {
+			cert, err := b.daWriter.Store(ctx, sequencerMsg, uint64(time.Now().Add(config.DASRetentionPeriod).Unix()), []byte{}) // b.daWriter will append signature if enabled
+			if errors.Is(err, das.BatchToDasFailed) {
+				if !b.dasFallback.recordDASFailure(&config.DASFallback, time.Now(), err) {
+					return false, fmt.Errorf("not falling back to storing data on chain: %w", err)
+				}
+				log.Warn("Falling back to storing data on chain", "err", err)
+				storeOnChain = true
+			} else if err != nil {
+				return false, err
+			} else {
+				b.dasFallback.recordDASSuccess()
+				sequencerMsg = das.Serialize(cert)
 			}
-			log.Warn("Falling back to storing data on chain", "err", err)
-		} else if err != nil {
-			return false, err
 		} else {
-			sequencerMsg = das.Serialize(cert)
+			storeOnChain = true
 		}
 	}
 
@@ -590,6 +771,17 @@ func (b *BatchPoster) maybePostSequencerBatch(ctx context.Context) (bool, error)
 	if err != nil {
 		return false, err
 	}
+	var fallbackCost *big.Int
+	if storeOnChain {
+		fallbackCost, err = b.dasFallbackCost(ctx, gasLimit)
+		if err != nil {
+			return false, err
+		}
+		err = b.dasFallback.checkSpend(&config.DASFallback, time.Now(), fallbackCost)
+		if err != nil {
+			return false, err
+		}
+	}
 	data, err := b.encodeAddBatch(new(big.Int).SetUint64(batchPosition.NextSeqNum), batchPosition.MessageCount, b.building.msgCount, sequencerMsg, b.building.segments.delayedMsg)
 	if err != nil {
 		return false, err
@@ -603,6 +795,9 @@ func (b *BatchPoster) maybePostSequencerBatch(ctx context.Context) (bool, error)
 	if err != nil {
 		return false, err
 	}
+	if storeOnChain {
+		b.dasFallback.recordSpend(time.Now(), fallbackCost)
+	}
 	log.Info(
 		"BatchPoster: batch sent",
 		"sequence nr.", batchPosition.NextSeqNum,
@@ -617,11 +812,16 @@ func (b *BatchPoster) maybePostSequencerBatch(ctx context.Context) (bool, error)
 }
 
 func (b *BatchPoster) Start(ctxIn context.Context) {
-	b.dataPoster.Start(ctxIn)
-	b.redisLock.Start(ctxIn)
+	if b.dryRun == nil {
+		for _, dataPoster := range b.dataPosters {
+			dataPoster.Start(ctxIn)
+		}
+		b.redisLock.Start(ctxIn)
+	}
 	b.StopWaiter.Start(ctxIn, b)
 	b.CallIteratively(func(ctx context.Context) time.Duration {
-		if !b.redisLock.AttemptLock(ctx) {
+		// A dry run doesn't post anything, so it doesn't need to hold the lock.
+		if b.dryRun == nil && !b.redisLock.AttemptLock(ctx) {
 			b.building = nil
 			return b.config().BatchPollDelay
 		}
@@ -653,6 +853,15 @@ func (b *BatchPoster) Start(ctxIn context.Context) {
 
 func (b *BatchPoster) StopAndWait() {
 	b.StopWaiter.StopAndWait()
-	b.dataPoster.StopAndWait()
+	if b.dryRun != nil {
+		err := b.dryRun.Close()
+		if err != nil {
+			log.Warn("failed to close batch poster dry run output", "err", err)
+		}
+		return
+	}
+	for _, dataPoster := range b.dataPosters {
+		dataPoster.StopAndWait()
+	}
 	b.redisLock.StopAndWait()
 }
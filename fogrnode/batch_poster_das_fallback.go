@@ -0,0 +1,161 @@
+// Copyright 2021-2022, Offchain Labs, Inc.
+// For license information, see https://github.com/fogr/blob/master/LICENSE
+
+package fognode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+var (
+	batchPosterDASFallbackActiveGauge      = metrics.NewRegisteredGauge("fogr/batchposter/dasfallback/active", nil)
+	batchPosterDASFailureCounter           = metrics.NewRegisteredCounter("fogr/batchposter/dasfallback/dasfailures", nil)
+	batchPosterDASFallbackBatchCounter     = metrics.NewRegisteredCounter("fogr/batchposter/dasfallback/batches", nil)
+	batchPosterDASFallbackSpendGauge       = metrics.NewRegisteredGauge("fogr/batchposter/dasfallback/hourlyspendgwei", nil)
+	batchPosterDASFallbackSpendCapCounter  = metrics.NewRegisteredCounter("fogr/batchposter/dasfallback/spendcapped", nil)
+	batchPosterDASFallbackActivatedCounter = metrics.NewRegisteredCounter("fogr/batchposter/dasfallback/activated", nil)
+)
+
+type DASFallbackConfig struct {
+	Enable              bool          `koanf:"enable" reload:"hot"`
+	ConsecutiveFailures uint64        `koanf:"consecutive-failures" reload:"hot"`
+	MaxHourlySpendEth   float64       `koanf:"max-hourly-spend-eth" reload:"hot"`
+	ProbeInterval       time.Duration `koanf:"probe-interval" reload:"hot"`
+}
+
+func DASFallbackConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Bool(prefix+".enable", DefaultDASFallbackConfig.Enable, "in AnyTrust mode, fall back to storing batch data on chain when DAS keeps failing to store batches")
+	f.Uint64(prefix+".consecutive-failures", DefaultDASFallbackConfig.ConsecutiveFailures, "the number of consecutive DAS store failures before falling back to storing data on chain")
+	f.Float64(prefix+".max-hourly-spend-eth", DefaultDASFallbackConfig.MaxHourlySpendEth, "the most ETH to spend posting batch data on chain in any hour while falling back, estimated from the L1 base fee (0 = unlimited)")
+	f.Duration(prefix+".probe-interval", DefaultDASFallbackConfig.ProbeInterval, "how often to try storing a batch with DAS again while falling back")
+}
+
+var DefaultDASFallbackConfig = DASFallbackConfig{
+	Enable:              true,
+	ConsecutiveFailures: 3,
+	MaxHourlySpendEth:   0,
+	ProbeInterval:       5 * time.Minute,
+}
+
+func (c *DASFallbackConfig) Validate() error {
+	if c.ConsecutiveFailures < 1 {
+		return errors.New("das-fallback consecutive-failures must be at least 1")
+	}
+	return nil
+}
+
+func (c *DASFallbackConfig) maxHourlySpend() *big.Int {
+	if c.MaxHourlySpendEth <= 0 {
+		return nil
+	}
+	maxSpend, _ := new(big.Float).Mul(big.NewFloat(c.MaxHourlySpendEth), big.NewFloat(params.Ether)).Int(nil)
+	return maxSpend
+}
+
+type dasFallbackSpend struct {
+	time time.Time
+	cost *big.Int
+}
+
+// dasFallback tracks whether the batch poster has fallen back from DAS to storing data on chain.
+// It's only used from the batch poster's posting loop.
+type dasFallback struct {
+	consecutiveFailures uint64
+	active              bool
+	lastProbe           time.Time
+	// the on-chain batches posted while falling back in the last hour, oldest first
+	spends []dasFallbackSpend
+}
+
+// shouldTryDAS returns whether the next batch should be stored with DAS.
+// While falling back, DAS is only tried once every probe interval.
+func (f *dasFallback) shouldTryDAS(config *DASFallbackConfig, now time.Time) bool {
+	if !f.active || !config.Enable {
+		return true
+	}
+	return now.Sub(f.lastProbe) >= config.ProbeInterval
+}
+
+func (f *dasFallback) recordDASSuccess() {
+	f.consecutiveFailures = 0
+	if f.active {
+		log.Info("BatchPoster: DAS is storing batches again, no longer falling back to storing data on chain")
+		f.active = false
+		batchPosterDASFallbackActiveGauge.Update(0)
+	}
+}
+
+// recordDASFailure returns whether the batch should be posted on chain instead.
+func (f *dasFallback) recordDASFailure(config *DASFallbackConfig, now time.Time, err error) bool {
+	batchPosterDASFailureCounter.Inc(1)
+	f.consecutiveFailures++
+	if !config.Enable {
+		return false
+	}
+	if f.active {
+		f.lastProbe = now
+		return true
+	}
+	if f.consecutiveFailures < config.ConsecutiveFailures {
+		log.Warn("BatchPoster: DAS failed to store batch", "err", err, "consecutiveFailures", f.consecutiveFailures, "fallbackAfter", config.ConsecutiveFailures)
+		return false
+	}
+	log.Error("BatchPoster: DAS keeps failing to store batches, falling back to storing data on chain", "err", err, "consecutiveFailures", f.consecutiveFailures)
+	f.active = true
+	f.lastProbe = now
+	batchPosterDASFallbackActiveGauge.Update(1)
+	batchPosterDASFallbackActivatedCounter.Inc(1)
+	return true
+}
+
+// hourlySpend returns how much was spent on fallback batches in the hour before now.
+func (f *dasFallback) hourlySpend(now time.Time) *big.Int {
+	for len(f.spends) > 0 && now.Sub(f.spends[0].time) >= time.Hour {
+		f.spends = f.spends[1:]
+	}
+	total := new(big.Int)
+	for _, spend := range f.spends {
+		total.Add(total, spend.cost)
+	}
+	return total
+}
+
+// checkSpend returns an error if posting a fallback batch costing cost would exceed the hourly cap.
+func (f *dasFallback) checkSpend(config *DASFallbackConfig, now time.Time, cost *big.Int) error {
+	spent := f.hourlySpend(now)
+	batchPosterDASFallbackSpendGauge.Update(new(big.Int).Div(spent, big.NewInt(params.GWei)).Int64())
+	maxSpend := config.maxHourlySpend()
+	if maxSpend == nil || new(big.Int).Add(spent, cost).Cmp(maxSpend) <= 0 {
+		return nil
+	}
+	batchPosterDASFallbackSpendCapCounter.Inc(1)
+	log.Error("BatchPoster: DAS fallback hourly spend cap reached, not posting batch data on chain", "spentWei", spent, "batchCostWei", cost, "maxHourlySpendEth", config.MaxHourlySpendEth)
+	return fmt.Errorf("storing batch data on chain would exceed the DAS fallback hourly spend cap of %v ETH", config.MaxHourlySpendEth)
+}
+
+func (f *dasFallback) recordSpend(now time.Time, cost *big.Int) {
+	f.spends = append(f.spends, dasFallbackSpend{time: now, cost: cost})
+	batchPosterDASFallbackBatchCounter.Inc(1)
+}
+
+// dasFallbackCost estimates what posting a batch with the given gas limit on chain costs at the current L1 base fee.
+func (b *BatchPoster) dasFallbackCost(ctx context.Context, gasLimit uint64) (*big.Int, error) {
+	header, err := b.l1Reader.LastHeader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if header.BaseFee == nil {
+		return new(big.Int), nil
+	}
+	return new(big.Int).Mul(header.BaseFee, new(big.Int).SetUint64(gasLimit)), nil
+}
@@ -0,0 +1,87 @@
+// Copyright 2021-2022, Offchain Labs, Inc.
+// For license information, see https://github.com/fogr/blob/master/LICENSE
+
+package fognode
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func TestDASFallback(t *testing.T) {
+	config := DefaultDASFallbackConfig
+	config.ConsecutiveFailures = 3
+	var fallback dasFallback
+	now := time.Now()
+	dasErr := errors.New("committee unavailable")
+
+	// A blip shorter than the failure threshold doesn't fall back.
+	for i := 0; i < 2; i++ {
+		if !fallback.shouldTryDAS(&config, now) || fallback.recordDASFailure(&config, now, dasErr) {
+			Fail(t, "fell back after", i+1, "failures")
+		}
+	}
+	fallback.recordDASSuccess()
+	for i := 0; i < 2; i++ {
+		if fallback.recordDASFailure(&config, now, dasErr) {
+			Fail(t, "failure count wasn't reset by a success")
+		}
+	}
+	if !fallback.recordDASFailure(&config, now, dasErr) || !fallback.active {
+		Fail(t, "didn't fall back after", config.ConsecutiveFailures, "failures")
+	}
+
+	// DAS is only probed once per probe interval while falling back.
+	if fallback.shouldTryDAS(&config, now.Add(config.ProbeInterval/2)) {
+		Fail(t, "probed DAS before the probe interval passed")
+	}
+	now = now.Add(config.ProbeInterval)
+	if !fallback.shouldTryDAS(&config, now) {
+		Fail(t, "didn't probe DAS after the probe interval")
+	}
+	if !fallback.recordDASFailure(&config, now, dasErr) || fallback.shouldTryDAS(&config, now) {
+		Fail(t, "failed probe didn't keep falling back")
+	}
+	fallback.recordDASSuccess()
+	if fallback.active || !fallback.shouldTryDAS(&config, now) {
+		Fail(t, "successful DAS store didn't end the fallback")
+	}
+
+	disabled := config
+	disabled.Enable = false
+	for i := 0; i < 5; i++ {
+		if fallback.recordDASFailure(&disabled, now, dasErr) {
+			Fail(t, "fell back with the fallback disabled")
+		}
+	}
+}
+
+func TestDASFallbackSpendCap(t *testing.T) {
+	config := DefaultDASFallbackConfig
+	config.MaxHourlySpendEth = 1
+	var fallback dasFallback
+	now := time.Now()
+	halfEth := new(big.Int).Div(big.NewInt(params.Ether), big.NewInt(2))
+
+	for i := 0; i < 2; i++ {
+		Require(t, fallback.checkSpend(&config, now, halfEth))
+		fallback.recordSpend(now, halfEth)
+		now = now.Add(10 * time.Minute)
+	}
+	if fallback.checkSpend(&config, now, big.NewInt(1)) == nil {
+		Fail(t, "spend cap wasn't enforced")
+	}
+	// The first spend drops out of the hourly window.
+	now = now.Add(40 * time.Minute)
+	Require(t, fallback.checkSpend(&config, now, halfEth))
+	if fallback.hourlySpend(now).Cmp(halfEth) != 0 {
+		Fail(t, "unexpected hourly spend", fallback.hourlySpend(now))
+	}
+
+	config.MaxHourlySpendEth = 0
+	Require(t, fallback.checkSpend(&config, now, big.NewInt(params.Ether)))
+}
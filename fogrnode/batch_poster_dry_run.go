@@ -0,0 +1,203 @@
+// Copyright 2021-2022, Offchain Labs, Inc.
+// For license information, see https://github.com/fogr/blob/master/LICENSE
+
+package fognode
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	flag "github.com/spf13/pflag"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+
+	"github.com/FOGRCC/fogr/fogutil"
+)
+
+type BatchPosterDryRunConfig struct {
+	Enable     bool   `koanf:"enable"`
+	OutputFile string `koanf:"output-file"`
+	MaxResults int    `koanf:"max-results"`
+}
+
+func BatchPosterDryRunConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Bool(prefix+".enable", DefaultBatchPosterDryRunConfig.Enable, "build batches as usual but only record what posting them would cost instead of posting them (gas is estimated for posting the batch data on chain)")
+	f.String(prefix+".output-file", DefaultBatchPosterDryRunConfig.OutputFile, "if non-empty, the file to append a JSON line to for each batch that would have been posted")
+	f.Int(prefix+".max-results", DefaultBatchPosterDryRunConfig.MaxResults, "the number of recent dry run batches to keep for the fogr_batchPosterDryRunResults RPC method")
+}
+
+var DefaultBatchPosterDryRunConfig = BatchPosterDryRunConfig{
+	Enable:     false,
+	OutputFile: "",
+	MaxResults: 1000,
+}
+
+// DryRunBatch describes a batch the batch poster would have posted in dry run mode.
+type DryRunBatch struct {
+	Time             time.Time            `json:"time"`
+	SequenceNumber   uint64               `json:"sequenceNumber"`
+	FirstMessage     fogutil.MessageIndex `json:"firstMessage"`
+	MessageCount     fogutil.MessageIndex `json:"messageCount"`
+	DelayedMessages  uint64               `json:"delayedMessages"`
+	Segments         int                  `json:"segments"`
+	Full             bool                 `json:"full"`
+	UncompressedSize int                  `json:"uncompressedSize"`
+	CompressedSize   int                  `json:"compressedSize"`
+	CompressionRatio float64              `json:"compressionRatio"`
+	Gas              uint64               `json:"gas"`
+	L1BaseFee        *big.Int             `json:"l1BaseFee"`
+	L1TipCap         *big.Int             `json:"l1TipCap"`
+	CostWei          *big.Int             `json:"costWei"`
+	CostEth          float64              `json:"costEth"`
+
+	// The configuration the batch was built with, to compare runs with different settings.
+	MaxBatchSize         int           `json:"maxBatchSize"`
+	MaxBatchPostInterval time.Duration `json:"maxBatchPostInterval"`
+	CompressionLevel     int           `json:"compressionLevel"`
+	Dictionary           string        `json:"dictionary"`
+}
+
+type batchPosterDryRun struct {
+	config BatchPosterDryRunConfig
+	// the position after the last batch that would have been posted, or nil before the first
+	position *batchPosterPosition
+
+	mutex   sync.Mutex
+	output  *os.File
+	results []DryRunBatch
+}
+
+func newBatchPosterDryRun(config BatchPosterDryRunConfig) (*batchPosterDryRun, error) {
+	d := &batchPosterDryRun{config: config}
+	if config.OutputFile != "" {
+		output, err := os.OpenFile(config.OutputFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to open batch poster dry run output file")
+		}
+		d.output = output
+	}
+	return d, nil
+}
+
+func (d *batchPosterDryRun) record(batch DryRunBatch) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.results = append(d.results, batch)
+	if d.config.MaxResults > 0 && len(d.results) > d.config.MaxResults {
+		d.results = d.results[len(d.results)-d.config.MaxResults:]
+	}
+	if d.output != nil {
+		line, err := json.Marshal(batch)
+		if err == nil {
+			_, err = d.output.Write(append(line, '\n'))
+		}
+		if err != nil {
+			log.Warn("failed to write batch poster dry run result", "err", err)
+		}
+	}
+}
+
+// Results returns up to count of the most recent dry run batches, oldest first.
+func (d *batchPosterDryRun) Results(count int) []DryRunBatch {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	start := 0
+	if count > 0 && count < len(d.results) {
+		start = len(d.results) - count
+	}
+	return append([]DryRunBatch{}, d.results[start:]...)
+}
+
+func (d *batchPosterDryRun) Close() error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if d.output == nil {
+		return nil
+	}
+	err := d.output.Close()
+	d.output = nil
+	return err
+}
+
+// simulateBatch records what posting the closed batch being built would have cost,
+// and moves the dry run position past it.
+func (b *BatchPoster) simulateBatch(ctx context.Context, batchPosition batchPosterPosition, sequencerMsg []byte, full bool) error {
+	config := b.config()
+	segments := b.building.segments
+	gas, err := b.estimateGas(ctx, sequencerMsg, segments.delayedMsg)
+	if err != nil {
+		return err
+	}
+	header, err := b.l1Reader.LastHeader(ctx)
+	if err != nil {
+		return err
+	}
+	tipCap, err := b.l1Reader.Client().SuggestGasTipCap(ctx)
+	if err != nil {
+		return err
+	}
+	gasPrice := new(big.Int).Add(header.BaseFee, tipCap)
+	cost := new(big.Int).Mul(gasPrice, new(big.Int).SetUint64(gas))
+	costEth, _ := new(big.Float).Quo(new(big.Float).SetInt(cost), big.NewFloat(params.Ether)).Float64()
+	batch := DryRunBatch{
+		Time:                 time.Now(),
+		SequenceNumber:       batchPosition.NextSeqNum,
+		FirstMessage:         batchPosition.MessageCount,
+		MessageCount:         b.building.msgCount - batchPosition.MessageCount,
+		DelayedMessages:      segments.delayedMsg - batchPosition.DelayedMessageCount,
+		Segments:             len(segments.rawSegments),
+		Full:                 full,
+		UncompressedSize:     segments.totalUncompressedSize,
+		CompressedSize:       len(sequencerMsg),
+		CompressionRatio:     float64(segments.totalUncompressedSize) / float64(len(sequencerMsg)),
+		Gas:                  gas,
+		L1BaseFee:            header.BaseFee,
+		L1TipCap:             tipCap,
+		CostWei:              cost,
+		CostEth:              costEth,
+		MaxBatchSize:         config.MaxBatchSize,
+		MaxBatchPostInterval: config.MaxBatchPostInterval,
+		CompressionLevel:     config.CompressionLevel,
+		Dictionary:           segments.dictionary.String(),
+	}
+	b.dryRun.record(batch)
+	b.dryRun.position = &batchPosterPosition{
+		MessageCount:        b.building.msgCount,
+		DelayedMessageCount: segments.delayedMsg,
+		NextSeqNum:          batchPosition.NextSeqNum + 1,
+	}
+	log.Info(
+		"BatchPoster: dry run batch",
+		"sequence nr.", batch.SequenceNumber,
+		"from", batchPosition.MessageCount,
+		"to", b.building.msgCount,
+		"size", batch.CompressedSize,
+		"compressionRatio", batch.CompressionRatio,
+		"gas", gas,
+		"costEth", costEth,
+	)
+	return nil
+}
+
+type BatchPosterAPI struct {
+	batchPoster *BatchPoster
+}
+
+// BatchPosterDryRunResults returns the most recent batches the batch poster would have posted
+// in dry run mode, oldest first. If count is omitted or zero, all kept results are returned.
+func (a *BatchPosterAPI) BatchPosterDryRunResults(ctx context.Context, count *int) ([]DryRunBatch, error) {
+	if a.batchPoster.dryRun == nil {
+		return nil, errors.New("batch poster dry run mode isn't enabled")
+	}
+	n := 0
+	if count != nil {
+		n = *count
+	}
+	return a.batchPoster.dryRun.Results(n), nil
+}
@@ -0,0 +1,54 @@
+// Copyright 2021-2022, Offchain Labs, Inc.
+// For license information, see https://github.com/fogr/blob/master/LICENSE
+
+package fognode
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBatchPosterDryRunResults(t *testing.T) {
+	outputFile := filepath.Join(t.TempDir(), "dry-run.jsonl")
+	config := DefaultBatchPosterDryRunConfig
+	config.Enable = true
+	config.OutputFile = outputFile
+	config.MaxResults = 3
+	dryRun, err := newBatchPosterDryRun(config)
+	Require(t, err)
+
+	for i := uint64(0); i < 5; i++ {
+		dryRun.record(DryRunBatch{SequenceNumber: i, CompressedSize: 100, UncompressedSize: 250, CompressionRatio: 2.5})
+	}
+	results := dryRun.Results(0)
+	if len(results) != 3 || results[0].SequenceNumber != 2 || results[2].SequenceNumber != 4 {
+		Fail(t, "unexpected kept results", results)
+	}
+	results = dryRun.Results(1)
+	if len(results) != 1 || results[0].SequenceNumber != 4 {
+		Fail(t, "unexpected latest result", results)
+	}
+	Require(t, dryRun.Close())
+
+	// Every batch is written to the output file, not only the kept ones.
+	file, err := os.Open(outputFile)
+	Require(t, err)
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	var lines uint64
+	for scanner.Scan() {
+		var batch DryRunBatch
+		Require(t, json.Unmarshal(scanner.Bytes(), &batch))
+		if batch.SequenceNumber != lines || batch.CompressionRatio != 2.5 {
+			Fail(t, "unexpected batch in output file", batch)
+		}
+		lines++
+	}
+	Require(t, scanner.Err())
+	if lines != 5 {
+		Fail(t, "expected 5 lines in output file, got", lines)
+	}
+}
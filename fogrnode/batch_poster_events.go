@@ -0,0 +1,83 @@
+// Copyright 2021-2022, Offchain Labs, Inc.
+// For license information, see https://github.com/fogr/blob/master/LICENSE
+
+package fognode
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/FOGRCC/fogr/fognode/dataposter"
+)
+
+const transactionEventsBufferSize = 256
+
+type BatchPosterTransactionEvent = dataposter.TransactionEvent[batchPosterPosition]
+
+// SubscribeTransactionEvents subscribes to the transaction lifecycle events of all of the batch poster's wallets.
+// The channel is closed when the batch poster stops or the returned function is called.
+func (b *BatchPoster) SubscribeTransactionEvents() (<-chan BatchPosterTransactionEvent, func()) {
+	merged := make(chan BatchPosterTransactionEvent, transactionEventsBufferSize)
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	var unsubscribes []func()
+	for _, dataPoster := range b.dataPosters {
+		events, unsubscribe := dataPoster.SubscribeTransactionEvents(transactionEventsBufferSize)
+		unsubscribes = append(unsubscribes, unsubscribe)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for event := range events {
+				select {
+				case merged <- event:
+				case <-done:
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(merged)
+	}()
+	var once sync.Once
+	return merged, func() {
+		once.Do(func() {
+			close(done)
+			for _, unsubscribe := range unsubscribes {
+				unsubscribe()
+			}
+		})
+	}
+}
+
+// TransactionEvents streams the lifecycle events of the batch poster's L1 transactions.
+// Subscribe with fogr_subscribe("transactionEvents").
+func (a *BatchPosterAPI) TransactionEvents(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return nil, rpc.ErrNotificationsUnsupported
+	}
+	subscription := notifier.CreateSubscription()
+	events, unsubscribe := a.batchPoster.SubscribeTransactionEvents()
+	go func() {
+		defer unsubscribe()
+		for {
+			select {
+			case event, ok := <-events:
+				if !ok {
+					return
+				}
+				err := notifier.Notify(subscription.ID, event)
+				if err != nil {
+					return
+				}
+			case <-subscription.Err():
+				return
+			}
+		}
+	}()
+	return subscription, nil
+}
@@ -0,0 +1,265 @@
+// Copyright 2021-2022, Offchain Labs, Inc.
+// For license information, see https://github.com/fogr/blob/master/LICENSE
+
+package fognode
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"time"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/params"
+
+	"github.com/FOGRCC/fogr/fogos"
+	"github.com/FOGRCC/fogr/fogstate"
+)
+
+var (
+	batchPosterPolicyDelayedCounter = metrics.NewRegisteredCounter("fogr/batchposter/policy/delayed", nil)
+	batchPosterL1ExpensiveGauge     = metrics.NewRegisteredGauge("fogr/batchposter/policy/l1expensive", nil)
+	batchPosterDeadlineGauge        = metrics.NewRegisteredGauge("fogr/batchposter/policy/deadline", nil)
+)
+
+type BatchPostingPolicyConfig struct {
+	Enable                bool          `koanf:"enable" reload:"hot"`
+	BacklogMessages       uint64        `koanf:"backlog-messages" reload:"hot"`
+	BacklogInterval       time.Duration `koanf:"backlog-interval" reload:"hot"`
+	BaseFeeSamples        int           `koanf:"base-fee-samples" reload:"hot"`
+	ExpensiveBaseFeeRatio float64       `koanf:"expensive-base-fee-ratio" reload:"hot"`
+	ExpensiveBaseFeeGwei  float64       `koanf:"expensive-base-fee-gwei" reload:"hot"`
+	ExpensiveExtraDelay   time.Duration `koanf:"expensive-extra-delay" reload:"hot"`
+	DeadlineMargin        time.Duration `koanf:"deadline-margin" reload:"hot"`
+}
+
+func BatchPostingPolicyConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Bool(prefix+".enable", DefaultBatchPostingPolicyConfig.Enable, "decide when to post batches based on the L1 base fee, the unposted message backlog and the sequencer inbox deadline, instead of only when a batch is full or max-interval has passed")
+	f.Uint64(prefix+".backlog-messages", DefaultBatchPostingPolicyConfig.BacklogMessages, "post after backlog-interval instead of max-interval once this many messages are waiting to be posted (0 to disable)")
+	f.Duration(prefix+".backlog-interval", DefaultBatchPostingPolicyConfig.BacklogInterval, "the posting interval used while the backlog is at least backlog-messages")
+	f.Int(prefix+".base-fee-samples", DefaultBatchPostingPolicyConfig.BaseFeeSamples, "the number of recent L1 headers whose base fees make up the base fee trend")
+	f.Float64(prefix+".expensive-base-fee-ratio", DefaultBatchPostingPolicyConfig.ExpensiveBaseFeeRatio, "L1 is considered expensive when its base fee is at least this multiple of the recent average (0 to disable)")
+	f.Float64(prefix+".expensive-base-fee-gwei", DefaultBatchPostingPolicyConfig.ExpensiveBaseFeeGwei, "L1 is considered expensive when its base fee is at least this many gwei (0 to disable)")
+	f.Duration(prefix+".expensive-extra-delay", DefaultBatchPostingPolicyConfig.ExpensiveExtraDelay, "how much longer to wait before posting while L1 is expensive, even if the batch is full")
+	f.Duration(prefix+".deadline-margin", DefaultBatchPostingPolicyConfig.DeadlineMargin, "post regardless of L1 fees once the oldest unposted message is this close to falling outside the sequencer inbox's max time variation")
+}
+
+var DefaultBatchPostingPolicyConfig = BatchPostingPolicyConfig{
+	Enable:                false,
+	BacklogMessages:       1000,
+	BacklogInterval:       5 * time.Minute,
+	BaseFeeSamples:        100,
+	ExpensiveBaseFeeRatio: 1.5,
+	ExpensiveBaseFeeGwei:  0,
+	ExpensiveExtraDelay:   time.Hour,
+	DeadlineMargin:        2 * time.Hour,
+}
+
+func (c *BatchPostingPolicyConfig) Validate() error {
+	if c.ExpensiveBaseFeeRatio != 0 && c.ExpensiveBaseFeeRatio < 1 {
+		return errors.New("posting policy expensive-base-fee-ratio must be at least 1")
+	}
+	if c.BaseFeeSamples < 1 {
+		return errors.New("posting policy base-fee-samples must be at least 1")
+	}
+	return nil
+}
+
+// batchPostingPolicyInputs is what the posting policy decides on.
+type batchPostingPolicyInputs struct {
+	now              time.Time
+	firstMessageTime time.Time
+	unpostedMessages uint64
+	batchFull        bool
+	l1BaseFee        *big.Int
+	averageL1BaseFee *big.Int
+	// deadline is when the oldest unposted message falls outside the sequencer inbox's
+	// max time variation, or the zero time if it isn't known.
+	deadline time.Time
+}
+
+func (c *BatchPostingPolicyConfig) isL1Expensive(inputs *batchPostingPolicyInputs) bool {
+	if inputs.l1BaseFee == nil {
+		return false
+	}
+	if c.ExpensiveBaseFeeGwei > 0 {
+		threshold, _ := new(big.Float).Mul(big.NewFloat(c.ExpensiveBaseFeeGwei), big.NewFloat(params.GWei)).Int(nil)
+		if inputs.l1BaseFee.Cmp(threshold) >= 0 {
+			return true
+		}
+	}
+	if c.ExpensiveBaseFeeRatio > 0 && inputs.averageL1BaseFee != nil && inputs.averageL1BaseFee.Sign() > 0 {
+		threshold, _ := new(big.Float).Mul(new(big.Float).SetInt(inputs.averageL1BaseFee), big.NewFloat(c.ExpensiveBaseFeeRatio)).Int(nil)
+		if inputs.l1BaseFee.Cmp(threshold) >= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// shouldPost decides whether the batch being built should be posted now, and why.
+// Full batches and the backlog shorten the wait, an expensive L1 lengthens it,
+// and nothing is delayed past the deadline margin.
+func (c *BatchPostingPolicyConfig) shouldPost(maxInterval time.Duration, inputs *batchPostingPolicyInputs) (bool, string) {
+	wait := maxInterval
+	reason := "max interval"
+	if c.BacklogMessages > 0 && inputs.unpostedMessages >= c.BacklogMessages && c.BacklogInterval < wait {
+		wait = c.BacklogInterval
+		reason = "backlog"
+	}
+	if inputs.batchFull {
+		wait = 0
+		reason = "batch full"
+	}
+	if c.isL1Expensive(inputs) {
+		wait += c.ExpensiveExtraDelay
+	}
+	if !inputs.deadline.IsZero() {
+		untilMargin := inputs.deadline.Add(-c.DeadlineMargin).Sub(inputs.firstMessageTime)
+		if untilMargin < wait {
+			wait = untilMargin
+			reason = "deadline"
+		}
+	}
+	return inputs.now.Sub(inputs.firstMessageTime) >= wait, reason
+}
+
+type l1HeaderSample struct {
+	number  uint64
+	time    uint64
+	baseFee *big.Int
+}
+
+const maxTimeVariationRefreshInterval = 10 * time.Minute
+
+// batchPostingPolicy keeps the L1 state the posting policy looks at.
+// It's only used from the batch poster's posting loop.
+type batchPostingPolicy struct {
+	samples                 []l1HeaderSample
+	delayBlocks             uint64
+	delaySeconds            uint64
+	maxTimeVariationUpdated time.Time
+}
+
+func (p *batchPostingPolicy) recordHeader(header *types.Header, maxSamples int) {
+	if header.BaseFee == nil {
+		return
+	}
+	if len(p.samples) > 0 && p.samples[len(p.samples)-1].number >= header.Number.Uint64() {
+		return
+	}
+	p.samples = append(p.samples, l1HeaderSample{
+		number:  header.Number.Uint64(),
+		time:    header.Time,
+		baseFee: header.BaseFee,
+	})
+	if len(p.samples) > maxSamples {
+		p.samples = p.samples[len(p.samples)-maxSamples:]
+	}
+}
+
+func (p *batchPostingPolicy) averageBaseFee() *big.Int {
+	if len(p.samples) == 0 {
+		return nil
+	}
+	sum := new(big.Int)
+	for _, sample := range p.samples {
+		sum.Add(sum, sample.baseFee)
+	}
+	return sum.Div(sum, big.NewInt(int64(len(p.samples))))
+}
+
+// averageBlockTime returns the average time between the sampled L1 blocks, or zero if unknown.
+func (p *batchPostingPolicy) averageBlockTime() time.Duration {
+	if len(p.samples) < 2 {
+		return 0
+	}
+	first := p.samples[0]
+	last := p.samples[len(p.samples)-1]
+	if last.number <= first.number || last.time <= first.time {
+		return 0
+	}
+	return time.Duration(last.time-first.time) * time.Second / time.Duration(last.number-first.number)
+}
+
+// deadline returns when a message with the given L1 block and timestamp falls outside the
+// sequencer inbox's max time variation, estimating the time of future L1 blocks from the samples.
+func (p *batchPostingPolicy) deadline(header *types.Header, msgHeader *fogos.L1IncomingMessageHeader) time.Time {
+	if p.maxTimeVariationUpdated.IsZero() {
+		return time.Time{}
+	}
+	deadline := time.Unix(int64(msgHeader.Timestamp+p.delaySeconds), 0)
+	if blockTime := p.averageBlockTime(); blockTime > 0 {
+		deadlineBlock := msgHeader.BlockNumber + p.delayBlocks
+		current := header.Number.Uint64()
+		var remainingBlocks uint64
+		if deadlineBlock > current {
+			remainingBlocks = deadlineBlock - current
+		}
+		blockDeadline := time.Unix(int64(header.Time), 0).Add(time.Duration(remainingBlocks) * blockTime)
+		if blockDeadline.Before(deadline) {
+			deadline = blockDeadline
+		}
+	}
+	return deadline
+}
+
+func (b *BatchPoster) updateMaxTimeVariation(ctx context.Context) error {
+	if time.Since(b.postPolicy.maxTimeVariationUpdated) < maxTimeVariationRefreshInterval {
+		return nil
+	}
+	maxTimeVariation, err := b.seqInbox.MaxTimeVariation(&bind.CallOpts{Context: ctx})
+	if err != nil {
+		return err
+	}
+	b.postPolicy.delayBlocks = maxTimeVariation.DelayBlocks.Uint64()
+	b.postPolicy.delaySeconds = maxTimeVariation.DelaySeconds.Uint64()
+	b.postPolicy.maxTimeVariationUpdated = time.Now()
+	return nil
+}
+
+// policyShouldPost applies the posting policy to the batch being built, whose first message is firstMsg.
+func (b *BatchPoster) policyShouldPost(ctx context.Context, firstMsg *fogstate.MessageWithMetadata, unpostedMessages uint64, batchFull bool) (bool, error) {
+	config := b.config()
+	policyConfig := &config.PostingPolicy
+	header, err := b.l1Reader.LastHeader(ctx)
+	if err != nil {
+		return false, err
+	}
+	err = b.updateMaxTimeVariation(ctx)
+	if err != nil {
+		return false, err
+	}
+	b.postPolicy.recordHeader(header, policyConfig.BaseFeeSamples)
+	inputs := &batchPostingPolicyInputs{
+		now:              time.Now(),
+		firstMessageTime: time.Unix(int64(firstMsg.Message.Header.Timestamp), 0),
+		unpostedMessages: unpostedMessages,
+		batchFull:        batchFull,
+		l1BaseFee:        header.BaseFee,
+		averageL1BaseFee: b.postPolicy.averageBaseFee(),
+		deadline:         b.postPolicy.deadline(header, firstMsg.Message.Header),
+	}
+	expensive := policyConfig.isL1Expensive(inputs)
+	if expensive {
+		batchPosterL1ExpensiveGauge.Update(1)
+	} else {
+		batchPosterL1ExpensiveGauge.Update(0)
+	}
+	if !inputs.deadline.IsZero() {
+		batchPosterDeadlineGauge.Update(int64(time.Until(inputs.deadline).Seconds()))
+	}
+	post, reason := policyConfig.shouldPost(config.MaxBatchPostInterval, inputs)
+	if post {
+		log.Info("BatchPoster: posting policy posting batch", "reason", reason, "l1Expensive", expensive, "backlog", unpostedMessages)
+	} else if batchFull || expensive {
+		batchPosterPolicyDelayedCounter.Inc(1)
+		log.Debug("BatchPoster: posting policy delaying batch", "reason", reason, "l1Expensive", expensive, "backlog", unpostedMessages, "deadline", inputs.deadline)
+	}
+	return post, nil
+}
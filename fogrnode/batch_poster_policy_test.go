@@ -0,0 +1,106 @@
+// Copyright 2021-2022, Offchain Labs, Inc.
+// For license information, see https://github.com/fogr/blob/master/LICENSE
+
+package fognode
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+
+	"github.com/FOGRCC/fogr/fogos"
+)
+
+func TestBatchPostingPolicy(t *testing.T) {
+	config := DefaultBatchPostingPolicyConfig
+	config.Enable = true
+	maxInterval := time.Hour
+	now := time.Now()
+	gwei := func(n int64) *big.Int {
+		return new(big.Int).Mul(big.NewInt(n), big.NewInt(params.GWei))
+	}
+	inputs := func(age time.Duration) *batchPostingPolicyInputs {
+		return &batchPostingPolicyInputs{
+			now:              now,
+			firstMessageTime: now.Add(-age),
+			unpostedMessages: 10,
+			l1BaseFee:        gwei(20),
+			averageL1BaseFee: gwei(20),
+		}
+	}
+	expect := func(in *batchPostingPolicyInputs, expectPost bool, expectReason string) {
+		t.Helper()
+		post, reason := config.shouldPost(maxInterval, in)
+		if post != expectPost || reason != expectReason {
+			Fail(t, "expected post", expectPost, "for", expectReason, "but got", post, "for", reason)
+		}
+	}
+
+	expect(inputs(30*time.Minute), false, "max interval")
+	expect(inputs(time.Hour), true, "max interval")
+
+	in := inputs(10 * time.Minute)
+	in.unpostedMessages = config.BacklogMessages
+	expect(in, true, "backlog")
+
+	in = inputs(time.Second)
+	in.batchFull = true
+	expect(in, true, "batch full")
+
+	// An expensive L1 holds back even full batches, until the deadline margin.
+	in.l1BaseFee = gwei(40)
+	expect(in, false, "batch full")
+	in.firstMessageTime = now.Add(-config.ExpensiveExtraDelay)
+	expect(in, true, "batch full")
+	in = inputs(time.Hour)
+	in.l1BaseFee = gwei(40)
+	expect(in, false, "max interval")
+	in.deadline = now.Add(config.DeadlineMargin)
+	expect(in, true, "deadline")
+	in.deadline = now.Add(config.DeadlineMargin + time.Minute)
+	expect(in, false, "deadline")
+
+	config.ExpensiveBaseFeeRatio = 0
+	expect(in, true, "max interval")
+	config.ExpensiveBaseFeeGwei = 30
+	expect(in, false, "deadline")
+}
+
+func TestBatchPostingPolicyDeadline(t *testing.T) {
+	policy := &batchPostingPolicy{
+		delayBlocks:             100,
+		delaySeconds:            2000,
+		maxTimeVariationUpdated: time.Now(),
+	}
+	const baseTime = 1_000_000
+	var header *types.Header
+	for i := uint64(0); i < 10; i++ {
+		header = &types.Header{
+			Number:  new(big.Int).SetUint64(1000 + i),
+			Time:    baseTime + i*12,
+			BaseFee: new(big.Int).SetUint64(i),
+		}
+		policy.recordHeader(header, 5)
+	}
+	if len(policy.samples) != 5 || policy.averageBaseFee().Uint64() != 7 {
+		Fail(t, "unexpected samples", policy.samples)
+	}
+	if policy.averageBlockTime() != 12*time.Second {
+		Fail(t, "unexpected average block time", policy.averageBlockTime())
+	}
+
+	// The block deadline is 50 blocks, or about 600 seconds, after the latest header.
+	msgHeader := &fogos.L1IncomingMessageHeader{BlockNumber: 959, Timestamp: baseTime}
+	deadline := policy.deadline(header, msgHeader)
+	if deadline.Unix() != int64(header.Time)+50*12 {
+		Fail(t, "unexpected block deadline", deadline.Unix()-int64(header.Time))
+	}
+	msgHeader.BlockNumber = 1100
+	deadline = policy.deadline(header, msgHeader)
+	if deadline.Unix() != baseTime+2000 {
+		Fail(t, "unexpected timestamp deadline", deadline.Unix()-baseTime)
+	}
+}
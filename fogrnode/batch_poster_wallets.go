@@ -0,0 +1,304 @@
+// Copyright 2021-2022, Offchain Labs, Inc.
+// For license information, see https://github.com/fogr/blob/master/LICENSE
+
+package fognode
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	flag "github.com/spf13/pflag"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/FOGRCC/fogr/fognode/dataposter"
+	"github.com/FOGRCC/fogr/util/headerreader"
+)
+
+type BatchPosterExtraWalletsConfig struct {
+	PrivateKeys []string      `koanf:"private-keys"`
+	StuckAfter  time.Duration `koanf:"stuck-after"`
+}
+
+func BatchPosterExtraWalletsConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.StringSlice(prefix+".private-keys", DefaultBatchPosterExtraWalletsConfig.PrivateKeys, "private keys of additional wallets to post batches from, each of which must be allowed to post batches and gets its own nonce queue; batches move to the next wallet whenever all those posted before are confirmed")
+	f.Duration(prefix+".stuck-after", DefaultBatchPosterExtraWalletsConfig.StuckAfter, "how long a batch may go unconfirmed before it and those after it are posted again from another wallet, and the stuck wallet's transactions are canceled")
+}
+
+var DefaultBatchPosterExtraWalletsConfig = BatchPosterExtraWalletsConfig{
+	PrivateKeys: nil,
+	StuckAfter:  20 * time.Minute,
+}
+
+// parseExtraBatchPosterKeys parses the extra wallets' private keys, which must belong to distinct
+// wallets other than the main one, as wallets sharing a nonce queue would replace each other's batches.
+func parseExtraBatchPosterKeys(mainWallet common.Address, privateKeys []string) ([]*ecdsa.PrivateKey, error) {
+	seen := map[common.Address]bool{mainWallet: true}
+	var keys []*ecdsa.PrivateKey
+	for i, key := range privateKeys {
+		privateKey, err := crypto.HexToECDSA(strings.TrimPrefix(key, "0x"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid extra batch poster private key %v: %w", i, err)
+		}
+		wallet := crypto.PubkeyToAddress(privateKey.PublicKey)
+		if wallet == mainWallet {
+			return nil, fmt.Errorf("extra batch poster private key %v is for the main batch poster wallet %v", i, wallet)
+		}
+		if seen[wallet] {
+			return nil, fmt.Errorf("extra batch poster private key %v is for wallet %v, which has another key", i, wallet)
+		}
+		seen[wallet] = true
+		keys = append(keys, privateKey)
+	}
+	return keys, nil
+}
+
+func openExtraBatchPosterWallets(l1Reader *headerreader.HeaderReader, mainWallet common.Address, privateKeys []string) ([]*bind.TransactOpts, error) {
+	keys, err := parseExtraBatchPosterKeys(mainWallet, privateKeys)
+	if err != nil || len(keys) == 0 {
+		return nil, err
+	}
+	chainIdReader, ok := l1Reader.Client().(interface {
+		ChainID(ctx context.Context) (*big.Int, error)
+	})
+	if !ok {
+		return nil, errors.New("extra batch poster wallets need an L1 client that can report its chain id")
+	}
+	chainId, err := chainIdReader.ChainID(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	var transactOpts []*bind.TransactOpts
+	for _, key := range keys {
+		opts, err := bind.NewKeyedTransactorWithChainID(key, chainId)
+		if err != nil {
+			return nil, err
+		}
+		transactOpts = append(transactOpts, opts)
+	}
+	return transactOpts, nil
+}
+
+// batchPosterWallet is the part of a wallet's DataPoster that batchPosterWallets needs.
+type batchPosterWallet interface {
+	From() common.Address
+	PendingTransactions(ctx context.Context) ([]dataposter.PendingTransaction[batchPosterPosition], error)
+	GetNextNonceAndMeta(ctx context.Context) (uint64, batchPosterPosition, error)
+	PostTransaction(ctx context.Context, dataCreatedAt time.Time, nonce uint64, meta batchPosterPosition, to common.Address, calldata []byte, gasLimit uint64) error
+	CancelTransactions(ctx context.Context) error
+}
+
+// batchPosterWallets rotates batch posting across wallets, each with its own nonce queue.
+//
+// Batches must land in order, which the nonces of a single wallet guarantee, so a batch goes to the
+// same wallet as the batches still pending before it. Once every batch posted is final, the next
+// one goes to the next idle wallet. If a wallet's oldest unconfirmed batch has been stuck for too
+// long, its unconfirmed batches are posted again, exactly as they are, from another wallet, which
+// then posts the following batches, and the stuck wallet's transactions are canceled. Whichever copy
+// of a batch lands first, the batches after it still follow on, and the other copy reverts for
+// having the wrong sequence number.
+//
+// It keeps no state other than which wallet posted last, so it picks up where it left off after a
+// restart or an error part way through reposting.
+type batchPosterWallets struct {
+	wallets []batchPosterWallet
+	current int
+}
+
+type pendingBatches = []dataposter.PendingTransaction[batchPosterPosition]
+
+func lastBatchSeqNum(pending pendingBatches) (uint64, bool) {
+	var last uint64
+	found := false
+	for _, tx := range pending {
+		if !tx.Canceled && (!found || tx.Meta.NextSeqNum > last) {
+			last = tx.Meta.NextSeqNum
+			found = true
+		}
+	}
+	return last, found
+}
+
+func hasCanceled(pending pendingBatches) bool {
+	for _, tx := range pending {
+		if tx.Canceled {
+			return true
+		}
+	}
+	return false
+}
+
+// unconfirmedBatches returns the pending batches that aren't in the latest L1 block.
+func unconfirmedBatches(pending pendingBatches) pendingBatches {
+	var batches pendingBatches
+	for _, tx := range pending {
+		if !tx.Confirmed && !tx.Canceled {
+			batches = append(batches, tx)
+		}
+	}
+	return batches
+}
+
+// leadingWallet returns the wallet with the latest pending batch, which the next batch must follow
+// on from. A wallet whose transactions were canceled only leads if no other has posted as far.
+func leadingWallet(pending []pendingBatches) (int, bool) {
+	lead := -1
+	var leadSeqNum uint64
+	for i, walletPending := range pending {
+		seqNum, ok := lastBatchSeqNum(walletPending)
+		if !ok {
+			continue
+		}
+		if lead < 0 || seqNum > leadSeqNum || (seqNum == leadSeqNum && hasCanceled(pending[lead]) && !hasCanceled(walletPending)) {
+			lead = i
+			leadSeqNum = seqNum
+		}
+	}
+	return lead, lead >= 0
+}
+
+// batchesToRepost returns which of the stuck batches the candidate still has to post, or false if
+// the candidate can't take them over because it has unconfirmed transactions of its own.
+func batchesToRepost(stuck pendingBatches, candidate pendingBatches) (pendingBatches, bool) {
+	stuckMetas := make(map[batchPosterPosition]bool)
+	for _, tx := range stuck {
+		stuckMetas[tx.Meta] = true
+	}
+	var reposted uint64
+	for _, tx := range candidate {
+		if !tx.Confirmed && (tx.Canceled || !stuckMetas[tx.Meta]) {
+			return nil, false
+		}
+		if stuckMetas[tx.Meta] && tx.Meta.NextSeqNum > reposted {
+			reposted = tx.Meta.NextSeqNum
+		}
+	}
+	var toRepost pendingBatches
+	for _, tx := range stuck {
+		if tx.Meta.NextSeqNum > reposted {
+			toRepost = append(toRepost, tx)
+		}
+	}
+	return toRepost, true
+}
+
+// choose picks the wallet to post the next batch from, and returns its index.
+func (w *batchPosterWallets) choose(ctx context.Context, stuckAfter time.Duration, now time.Time) (int, error) {
+	if len(w.wallets) < 2 {
+		return w.current, nil
+	}
+	pending := make([]pendingBatches, len(w.wallets))
+	for i, wallet := range w.wallets {
+		var err error
+		pending[i], err = wallet.PendingTransactions(ctx)
+		if err != nil {
+			return 0, fmt.Errorf("error checking batch poster wallet %v: %w", wallet.From(), err)
+		}
+	}
+
+	lead, ok := leadingWallet(pending)
+	if !ok {
+		// Every batch posted is final, so the next one can't land before them from any wallet.
+		for offset := 1; offset <= len(w.wallets); offset++ {
+			next := (w.current + offset) % len(w.wallets)
+			if len(pending[next]) == 0 {
+				w.current = next
+				break
+			}
+		}
+		return w.current, nil
+	}
+
+	stuck := unconfirmedBatches(pending[lead])
+	if stuckAfter > 0 && len(stuck) > 0 && now.Sub(stuck[0].FirstPosted) >= stuckAfter {
+		newLead, err := w.repostStuckBatches(ctx, lead, stuck, pending, now)
+		if err != nil {
+			return 0, err
+		}
+		lead = newLead
+	}
+	w.current = lead
+
+	// Batches left unconfirmed on other wallets have been posted again from this one, so stop them
+	// from being replaced by fee and landing late.
+	leadMetas := make(map[batchPosterPosition]bool)
+	for _, tx := range pending[lead] {
+		if !tx.Canceled {
+			leadMetas[tx.Meta] = true
+		}
+	}
+	for i, wallet := range w.wallets {
+		if i == lead {
+			continue
+		}
+		stale := unconfirmedBatches(pending[i])
+		if len(stale) == 0 {
+			continue
+		}
+		covered := true
+		for _, tx := range stale {
+			covered = covered && leadMetas[tx.Meta]
+		}
+		if !covered {
+			log.Warn("batch poster wallet has unconfirmed batches that aren't on the leading wallet", "wallet", wallet.From(), "leadingWallet", w.wallets[lead].From())
+			continue
+		}
+		err := wallet.CancelTransactions(ctx)
+		if err != nil {
+			log.Warn("failed to cancel batches posted again from another wallet", "wallet", wallet.From(), "err", err)
+		}
+	}
+	return w.current, nil
+}
+
+// repostStuckBatches posts the stuck batches of the lead wallet again from another wallet, and
+// returns that wallet's index. If no other wallet can take them over, it returns the lead.
+func (w *batchPosterWallets) repostStuckBatches(ctx context.Context, lead int, stuck pendingBatches, pending []pendingBatches, now time.Time) (int, error) {
+	candidate := -1
+	var toRepost pendingBatches
+	for offset := 1; offset < len(w.wallets); offset++ {
+		i := (lead + offset) % len(w.wallets)
+		remaining, ok := batchesToRepost(stuck, pending[i])
+		if !ok {
+			continue
+		}
+		// Prefer a wallet that has already taken over some of the batches.
+		if candidate < 0 || (len(remaining) < len(stuck) && len(toRepost) == len(stuck)) {
+			candidate = i
+			toRepost = remaining
+		}
+	}
+	if candidate < 0 {
+		log.Warn("batch poster wallet is stuck but no other wallet is idle", "wallet", w.wallets[lead].From(), "stuckNonce", stuck[0].Nonce)
+		return lead, nil
+	}
+	wallet := w.wallets[candidate]
+	log.Warn(
+		"batch poster wallet is stuck, switching wallets",
+		"stuckWallet", w.wallets[lead].From(),
+		"stuckNonce", stuck[0].Nonce,
+		"stuckFor", now.Sub(stuck[0].FirstPosted),
+		"newWallet", wallet.From(),
+		"batchesToRepost", len(toRepost),
+	)
+	for _, tx := range toRepost {
+		nonce, _, err := wallet.GetNextNonceAndMeta(ctx)
+		if err != nil {
+			return 0, err
+		}
+		err = wallet.PostTransaction(ctx, tx.Created, nonce, tx.Meta, tx.To, tx.Data, tx.Gas)
+		if err != nil {
+			return 0, fmt.Errorf("error reposting batch %v from wallet %v: %w", tx.Meta.NextSeqNum-1, wallet.From(), err)
+		}
+		pending[candidate] = append(pending[candidate], tx)
+	}
+	return candidate, nil
+}
@@ -0,0 +1,215 @@
+// Copyright 2021-2022, Offchain Labs, Inc.
+// For license information, see https://github.com/fogr/blob/master/LICENSE
+
+package fognode
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/FOGRCC/fogr/fognode/dataposter"
+)
+
+type testBatchPosterWallet struct {
+	from     common.Address
+	pending  []dataposter.PendingTransaction[batchPosterPosition]
+	nonce    uint64
+	postsOk  int // the number of posts to allow before failing, or -1 for any number
+	canceled bool
+}
+
+func (w *testBatchPosterWallet) From() common.Address {
+	return w.from
+}
+
+func (w *testBatchPosterWallet) PendingTransactions(ctx context.Context) ([]dataposter.PendingTransaction[batchPosterPosition], error) {
+	return append([]dataposter.PendingTransaction[batchPosterPosition]{}, w.pending...), nil
+}
+
+func (w *testBatchPosterWallet) GetNextNonceAndMeta(ctx context.Context) (uint64, batchPosterPosition, error) {
+	return w.nonce, batchPosterPosition{}, nil
+}
+
+func (w *testBatchPosterWallet) PostTransaction(ctx context.Context, dataCreatedAt time.Time, nonce uint64, meta batchPosterPosition, to common.Address, calldata []byte, gasLimit uint64) error {
+	if w.postsOk == 0 {
+		return errors.New("test post failure")
+	}
+	if w.postsOk > 0 {
+		w.postsOk--
+	}
+	w.pending = append(w.pending, dataposter.PendingTransaction[batchPosterPosition]{
+		Nonce:       nonce,
+		Meta:        meta,
+		To:          to,
+		Data:        calldata,
+		Gas:         gasLimit,
+		Created:     dataCreatedAt,
+		FirstPosted: time.Now(),
+	})
+	w.nonce++
+	return nil
+}
+
+func (w *testBatchPosterWallet) CancelTransactions(ctx context.Context) error {
+	w.canceled = true
+	for i := range w.pending {
+		if !w.pending[i].Confirmed {
+			w.pending[i].Canceled = true
+		}
+	}
+	return nil
+}
+
+func (w *testBatchPosterWallet) postBatches(seqNums []uint64, firstPosted time.Time) {
+	for _, seqNum := range seqNums {
+		w.pending = append(w.pending, dataposter.PendingTransaction[batchPosterPosition]{
+			Nonce:       w.nonce,
+			Meta:        batchPosterPosition{NextSeqNum: seqNum + 1},
+			Data:        []byte{byte(seqNum)},
+			FirstPosted: firstPosted,
+		})
+		w.nonce++
+	}
+}
+
+func testBatchPosterWallets(count int) (*batchPosterWallets, []*testBatchPosterWallet) {
+	wallets := &batchPosterWallets{}
+	var testWallets []*testBatchPosterWallet
+	for i := 0; i < count; i++ {
+		wallet := &testBatchPosterWallet{postsOk: -1}
+		wallet.from[0] = byte(i + 1)
+		wallets.wallets = append(wallets.wallets, wallet)
+		testWallets = append(testWallets, wallet)
+	}
+	return wallets, testWallets
+}
+
+func TestBatchPosterWalletRotation(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	stuckAfter := 20 * time.Minute
+	wallets, testWallets := testBatchPosterWallets(3)
+	expectWallet := func(expected int) {
+		t.Helper()
+		chosen, err := wallets.choose(ctx, stuckAfter, now)
+		Require(t, err)
+		if chosen != expected {
+			Fail(t, "chose wallet", chosen, "instead of", expected)
+		}
+	}
+
+	// Batches follow on from the wallet with the latest pending batch, even if it's confirmed.
+	testWallets[2].postBatches([]uint64{5}, now)
+	testWallets[2].pending[0].Confirmed = true
+	expectWallet(2)
+
+	// Once every batch is final, the next idle wallet takes over.
+	testWallets[2].pending = nil
+	expectWallet(0)
+	testWallets[0].pending = nil
+	expectWallet(1)
+
+	// A wallet that's still busy, such as with canceled transactions, is skipped.
+	testWallets[1].pending = nil
+	testWallets[2].postBatches([]uint64{4}, now)
+	testWallets[2].pending[0].Canceled = true
+	expectWallet(0)
+}
+
+func TestBatchPosterWalletSwitch(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	stuckAfter := 20 * time.Minute
+	wallets, testWallets := testBatchPosterWallets(3)
+	stuck := testWallets[0]
+	stuck.postBatches([]uint64{10}, now.Add(-time.Hour))
+	stuck.pending[0].Confirmed = true
+	stuck.postBatches([]uint64{11, 12, 13}, now.Add(-time.Hour))
+	// The next wallet is still canceling transactions of its own, so it can't take over.
+	testWallets[1].postBatches([]uint64{9}, now)
+	testWallets[1].pending[0].Canceled = true
+	// Reposting fails part way through.
+	testWallets[2].postsOk = 1
+
+	_, err := wallets.choose(ctx, stuckAfter, now)
+	if err == nil {
+		Fail(t, "reposting didn't fail")
+	}
+	if stuck.canceled {
+		Fail(t, "canceled the stuck wallet before its batches were reposted")
+	}
+	if len(testWallets[1].pending) != 1 || len(testWallets[2].pending) != 1 {
+		Fail(t, "unexpected reposts", len(testWallets[1].pending), len(testWallets[2].pending))
+	}
+
+	// The next attempt picks up where the last one left off.
+	testWallets[2].postsOk = -1
+	chosen, err := wallets.choose(ctx, stuckAfter, now)
+	Require(t, err)
+	if chosen != 2 {
+		Fail(t, "switched to wallet", chosen, "instead of 2")
+	}
+	reposted := testWallets[2].pending
+	if len(reposted) != 3 {
+		Fail(t, "reposted", len(reposted), "batches instead of 3")
+	}
+	for i, tx := range reposted {
+		expected := stuck.pending[i+1]
+		if tx.Meta != expected.Meta || string(tx.Data) != string(expected.Data) || tx.Nonce != uint64(i) {
+			Fail(t, "reposted batch", i, "doesn't match the stuck one", tx, expected)
+		}
+	}
+	if !stuck.canceled {
+		Fail(t, "didn't cancel the stuck wallet's transactions")
+	}
+	if testWallets[1].canceled {
+		Fail(t, "canceled the transactions of an unrelated wallet")
+	}
+
+	// Further batches stay on the new wallet.
+	chosen, err = wallets.choose(ctx, stuckAfter, now)
+	Require(t, err)
+	if chosen != 2 {
+		Fail(t, "moved on from wallet 2 to", chosen)
+	}
+
+	// Without another wallet to take over, the stuck one carries on.
+	wallets, testWallets = testBatchPosterWallets(2)
+	testWallets[0].postBatches([]uint64{1}, now.Add(-time.Hour))
+	testWallets[1].postBatches([]uint64{0}, now)
+	testWallets[1].pending[0].Canceled = true
+	chosen, err = wallets.choose(ctx, stuckAfter, now)
+	Require(t, err)
+	if chosen != 0 || testWallets[0].canceled {
+		Fail(t, "unexpected choice", chosen, testWallets[0].canceled)
+	}
+}
+
+func TestParseExtraBatchPosterKeys(t *testing.T) {
+	mainKey, err := crypto.GenerateKey()
+	Require(t, err)
+	extraKey, err := crypto.GenerateKey()
+	Require(t, err)
+	mainWallet := crypto.PubkeyToAddress(mainKey.PublicKey)
+	hexKey := func(key *ecdsa.PrivateKey) string {
+		return common.Bytes2Hex(crypto.FromECDSA(key))
+	}
+
+	keys, err := parseExtraBatchPosterKeys(mainWallet, []string{hexKey(extraKey)})
+	Require(t, err)
+	if len(keys) != 1 {
+		Fail(t, "parsed", len(keys), "keys")
+	}
+	if _, err := parseExtraBatchPosterKeys(mainWallet, []string{hexKey(mainKey)}); err == nil {
+		Fail(t, "accepted the main wallet's key")
+	}
+	if _, err := parseExtraBatchPosterKeys(mainWallet, []string{hexKey(extraKey), "0x" + hexKey(extraKey)}); err == nil {
+		Fail(t, "accepted the same key twice")
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright 2021-2022, Offchain Labs, Inc.
+// For license information, see https://github.com/fogr/blob/master/LICENSE
+
+package fognode
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// MaxConditionalKnownAccountEntries is the most storage roots and slots a transaction's conditions may check in total.
+const MaxConditionalKnownAccountEntries = 1000
+
+var ErrConditionFailed = errors.New("transaction condition failed")
+
+// RootHashOrSlots is the storage an account is expected to have: either its whole storage root,
+// or the values of some of its slots. In JSON, it's either a hash or an object of slots to values.
+type RootHashOrSlots struct {
+	RootHash  *common.Hash
+	SlotValue map[common.Hash]common.Hash
+}
+
+func (r *RootHashOrSlots) UnmarshalJSON(data []byte) error {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		r.RootHash = nil
+		return json.Unmarshal(data, &r.SlotValue)
+	}
+	var rootHash common.Hash
+	if err := json.Unmarshal(data, &rootHash); err != nil {
+		return err
+	}
+	r.RootHash = &rootHash
+	r.SlotValue = nil
+	return nil
+}
+
+func (r RootHashOrSlots) MarshalJSON() ([]byte, error) {
+	if r.RootHash != nil {
+		return json.Marshal(r.RootHash)
+	}
+	return json.Marshal(r.SlotValue)
+}
+
+// ConditionalOptions are preconditions a transaction is only sequenced under,
+// checked against the block and state it would be included in.
+type ConditionalOptions struct {
+	KnownAccounts  map[common.Address]RootHashOrSlots `json:"knownAccounts"`
+	BlockNumberMin *hexutil.Uint64                    `json:"blockNumberMin,omitempty"`
+	BlockNumberMax *hexutil.Uint64                    `json:"blockNumberMax,omitempty"`
+	TimestampMin   *hexutil.Uint64                    `json:"timestampMin,omitempty"`
+	TimestampMax   *hexutil.Uint64                    `json:"timestampMax,omitempty"`
+}
+
+func (o *ConditionalOptions) Validate() error {
+	var entries int
+	for _, expected := range o.KnownAccounts {
+		if expected.RootHash != nil {
+			entries++
+		} else {
+			entries += len(expected.SlotValue)
+		}
+	}
+	if entries > MaxConditionalKnownAccountEntries {
+		return fmt.Errorf("conditions check %v storage roots and slots, more than the limit of %v", entries, MaxConditionalKnownAccountEntries)
+	}
+	return nil
+}
+
+// Check returns an error wrapping ErrConditionFailed unless the conditions hold for a transaction
+// included in the block with the given header, on top of statedb.
+// Storage roots, like slots, are checked against the live state, including the storage changes
+// of earlier transactions in the same block.
+func (o *ConditionalOptions) Check(header *types.Header, statedb *state.StateDB) error {
+	blockNumber := header.Number.Uint64()
+	if o.BlockNumberMin != nil && blockNumber < uint64(*o.BlockNumberMin) {
+		return fmt.Errorf("%w: block number %v is below the minimum of %v", ErrConditionFailed, blockNumber, uint64(*o.BlockNumberMin))
+	}
+	if o.BlockNumberMax != nil && blockNumber > uint64(*o.BlockNumberMax) {
+		return fmt.Errorf("%w: block number %v is above the maximum of %v", ErrConditionFailed, blockNumber, uint64(*o.BlockNumberMax))
+	}
+	if o.TimestampMin != nil && header.Time < uint64(*o.TimestampMin) {
+		return fmt.Errorf("%w: timestamp %v is below the minimum of %v", ErrConditionFailed, header.Time, uint64(*o.TimestampMin))
+	}
+	if o.TimestampMax != nil && header.Time > uint64(*o.TimestampMax) {
+		return fmt.Errorf("%w: timestamp %v is above the maximum of %v", ErrConditionFailed, header.Time, uint64(*o.TimestampMax))
+	}
+	for address, expected := range o.KnownAccounts {
+		if expected.RootHash != nil {
+			// StorageTrie applies the account's pending storage to a copy of its trie, which shares the trie's nodes.
+			root := types.EmptyRootHash
+			if trie := statedb.StorageTrie(address); trie != nil {
+				root = trie.Hash()
+			}
+			if err := statedb.Error(); err != nil {
+				return err
+			}
+			if root != *expected.RootHash {
+				return fmt.Errorf("%w: storage root of %v is %v, not %v", ErrConditionFailed, address, root, *expected.RootHash)
+			}
+			continue
+		}
+		for slot, value := range expected.SlotValue {
+			actual := statedb.GetState(address, slot)
+			if err := statedb.Error(); err != nil {
+				return err
+			}
+			if actual != value {
+				return fmt.Errorf("%w: storage slot %v of %v is %v, not %v", ErrConditionFailed, slot, address, actual, value)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+// Copyright 2021-2022, Offchain Labs, Inc.
+// For license information, see https://github.com/fogr/blob/master/LICENSE
+
+package fognode
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func requireConditionFailed(t *testing.T, options *ConditionalOptions, header *types.Header, statedb *state.StateDB) {
+	t.Helper()
+	err := options.Check(header, statedb)
+	if !errors.Is(err, ErrConditionFailed) {
+		Fail(t, "expected the condition to fail but got", err)
+	}
+}
+
+func TestConditionalOptionsCheck(t *testing.T) {
+	statedb, err := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	Require(t, err)
+	account := common.HexToAddress("0x1234")
+	empty := common.HexToAddress("0x5678")
+	slot := common.HexToHash("0x1")
+	value := common.HexToHash("0xabcd")
+	statedb.SetState(account, slot, value)
+	statedb.IntermediateRoot(true)
+	root := statedb.StorageTrie(account).Hash()
+	header := &types.Header{Number: big.NewInt(100), Time: 1000}
+
+	bound := func(x uint64) *hexutil.Uint64 {
+		return (*hexutil.Uint64)(&x)
+	}
+	options := &ConditionalOptions{
+		KnownAccounts: map[common.Address]RootHashOrSlots{
+			account: {SlotValue: map[common.Hash]common.Hash{slot: value}},
+			empty:   {RootHash: &types.EmptyRootHash},
+		},
+		BlockNumberMin: bound(100),
+		BlockNumberMax: bound(100),
+		TimestampMin:   bound(1000),
+		TimestampMax:   bound(1000),
+	}
+	Require(t, options.Check(header, statedb))
+
+	requireConditionFailed(t, options, &types.Header{Number: big.NewInt(99), Time: 1000}, statedb)
+	requireConditionFailed(t, options, &types.Header{Number: big.NewInt(101), Time: 1000}, statedb)
+	requireConditionFailed(t, options, &types.Header{Number: big.NewInt(100), Time: 999}, statedb)
+	requireConditionFailed(t, options, &types.Header{Number: big.NewInt(100), Time: 1001}, statedb)
+
+	options.KnownAccounts[account] = RootHashOrSlots{RootHash: &root}
+	Require(t, options.Check(header, statedb))
+	// An earlier transaction in the block changes the storage, without the block's root being computed yet.
+	statedb.SetState(account, slot, common.Hash{})
+	statedb.Finalise(true)
+	requireConditionFailed(t, options, header, statedb)
+	options.KnownAccounts[account] = RootHashOrSlots{SlotValue: map[common.Hash]common.Hash{slot: value}}
+	requireConditionFailed(t, options, header, statedb)
+}
+
+func TestConditionalOptionsPrecheckTimestamp(t *testing.T) {
+	statedb, err := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	Require(t, err)
+	lastHeader := &types.Header{Number: big.NewInt(100), Time: 1000}
+	now := time.Unix(1010, 0)
+	minimum := hexutil.Uint64(1005)
+	options := &ConditionalOptions{TimestampMin: &minimum}
+
+	// The minimum is after the last block but not after now, so the next block can meet it.
+	nextHeader := precheckConditionalHeader(lastHeader, now)
+	if nextHeader.Number.Uint64() != 101 || nextHeader.Time != 1010 {
+		Fail(t, "unexpected precheck header", nextHeader.Number, nextHeader.Time)
+	}
+	Require(t, options.Check(nextHeader, statedb))
+
+	// The next block can't be timestamped before the last one, even if the clock is behind.
+	maximum := hexutil.Uint64(999)
+	options = &ConditionalOptions{TimestampMax: &maximum}
+	requireConditionFailed(t, options, precheckConditionalHeader(lastHeader, time.Unix(900, 0)), statedb)
+}
+
+func TestConditionalOptionsJSON(t *testing.T) {
+	account := common.HexToAddress("0x1234")
+	other := common.HexToAddress("0x5678")
+	slot := common.HexToHash("0x1")
+	root := common.HexToHash("0x2")
+	encoded := `{
+		"knownAccounts": {
+			"` + account.Hex() + `": "` + root.Hex() + `",
+			"` + other.Hex() + `": {"` + slot.Hex() + `": "` + root.Hex() + `"}
+		},
+		"blockNumberMax": "0x10"
+	}`
+	var options ConditionalOptions
+	Require(t, json.Unmarshal([]byte(encoded), &options))
+	if options.KnownAccounts[account].RootHash == nil || *options.KnownAccounts[account].RootHash != root {
+		Fail(t, "unexpected storage root condition", options.KnownAccounts[account])
+	}
+	if options.KnownAccounts[other].SlotValue[slot] != root {
+		Fail(t, "unexpected storage slot condition", options.KnownAccounts[other])
+	}
+	if options.BlockNumberMin != nil || options.BlockNumberMax == nil || *options.BlockNumberMax != 16 {
+		Fail(t, "unexpected block number bounds", options.BlockNumberMin, options.BlockNumberMax)
+	}
+
+	reencoded, err := json.Marshal(&options)
+	Require(t, err)
+	var decoded ConditionalOptions
+	Require(t, json.Unmarshal(reencoded, &decoded))
+	if *decoded.KnownAccounts[account].RootHash != root || decoded.KnownAccounts[other].SlotValue[slot] != root {
+		Fail(t, "conditions changed when re-encoded", string(reencoded))
+	}
+}
@@ -19,7 +19,9 @@ import (
 	"github.com/FOGRCC/fogr/util/stopwaiter"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rpc"
@@ -43,40 +45,64 @@ type QueueStorage[Item any] interface {
 }
 
 type DataPosterConfig struct {
-	RedisSigner           signature.SimpleHmacConfig `koanf:"redis-signer"`
-	ReplacementTimes      string                     `koanf:"replacement-times"`
-	WaitForL1Finality     bool                       `koanf:"wait-for-l1-finality" reload:"hot"`
-	MaxQueuedTransactions uint64                     `koanf:"max-queued-transactions" reload:"hot"`
-	TargetPriceGwei       float64                    `koanf:"target-price-gwei" reload:"hot"`
-	UrgencyGwei           float64                    `koanf:"urgency-gwei" reload:"hot"`
+	RedisSigner           signature.SimpleHmacConfig  `koanf:"redis-signer"`
+	UseDBStorage          bool                        `koanf:"use-db-storage"`
+	ReplacementTimes      string                      `koanf:"replacement-times"`
+	WaitForL1Finality     bool                        `koanf:"wait-for-l1-finality" reload:"hot"`
+	MaxQueuedTransactions uint64                      `koanf:"max-queued-transactions" reload:"hot"`
+	TargetPriceGwei       float64                     `koanf:"target-price-gwei" reload:"hot"`
+	UrgencyGwei           float64                     `koanf:"urgency-gwei" reload:"hot"`
+	FeeStrategy           string                      `koanf:"fee-strategy"`
+	RecentBaseFeeBlocks   uint64                      `koanf:"recent-base-fee-blocks" reload:"hot"`
+	PercentileFeeStrategy PercentileFeeStrategyConfig `koanf:"percentile-fee-strategy" reload:"hot"`
+	BudgetFeeStrategy     BudgetFeeStrategyConfig     `koanf:"budget-fee-strategy" reload:"hot"`
+	Webhook               TransactionWebhookConfig    `koanf:"webhook" reload:"hot"`
 }
 
 type DataPosterConfigFetcher func() *DataPosterConfig
 
 func DataPosterConfigAddOptions(prefix string, f *flag.FlagSet) {
 	f.String(prefix+".replacement-times", DefaultDataPosterConfig.ReplacementTimes, "comma-separated list of durations since first posting to attempt a replace-by-fee")
+	f.Bool(prefix+".use-db-storage", DefaultDataPosterConfig.UseDBStorage, "when redis isn't used, keep the transaction queue in the node's database (signed with the redis-signer key) so it survives restarts")
 	f.Bool(prefix+".wait-for-l1-finality", DefaultDataPosterConfig.WaitForL1Finality, "only treat a transaction as confirmed after L1 finality has been achieved (recommended)")
 	f.Uint64(prefix+".max-queued-transactions", DefaultDataPosterConfig.MaxQueuedTransactions, "the maximum number of transactions to have queued in the mempool at once (0 = unlimited)")
 	f.Float64(prefix+".target-price-gwei", DefaultDataPosterConfig.TargetPriceGwei, "the target price to use for maximum fee cap calculation")
 	f.Float64(prefix+".urgency-gwei", DefaultDataPosterConfig.UrgencyGwei, "the urgency to use for maximum fee cap calculation")
+	f.String(prefix+".fee-strategy", DefaultDataPosterConfig.FeeStrategy, "the strategy to choose fee caps with, one of target-price, percentile or budget")
+	f.Uint64(prefix+".recent-base-fee-blocks", DefaultDataPosterConfig.RecentBaseFeeBlocks, "the number of recent L1 blocks whose base fees are given to the fee strategy")
+	PercentileFeeStrategyConfigAddOptions(prefix+".percentile-fee-strategy", f)
+	BudgetFeeStrategyConfigAddOptions(prefix+".budget-fee-strategy", f)
+	TransactionWebhookConfigAddOptions(prefix+".webhook", f)
 	signature.SimpleHmacConfigAddOptions(prefix+".redis-signer", f)
 }
 
 var DefaultDataPosterConfig = DataPosterConfig{
 	ReplacementTimes:      "5m,10m,20m,30m,1h,2h,4h,6h,8h,12h,16h,18h,20h,22h",
+	UseDBStorage:          false,
 	WaitForL1Finality:     true,
 	TargetPriceGwei:       60.,
 	UrgencyGwei:           2.,
 	MaxQueuedTransactions: 64,
+	FeeStrategy:           TargetPriceFeeStrategyName,
+	RecentBaseFeeBlocks:   20,
+	PercentileFeeStrategy: DefaultPercentileFeeStrategyConfig,
+	BudgetFeeStrategy:     DefaultBudgetFeeStrategyConfig,
+	Webhook:               DefaultTransactionWebhookConfig,
 }
 
 var TestDataPosterConfig = DataPosterConfig{
 	ReplacementTimes:      "1s,2s,5s,10s,20s,30s,1m,5m",
 	RedisSigner:           signature.TestSimpleHmacConfig,
+	UseDBStorage:          false,
 	WaitForL1Finality:     false,
 	TargetPriceGwei:       60.,
 	UrgencyGwei:           2.,
 	MaxQueuedTransactions: 64,
+	FeeStrategy:           TargetPriceFeeStrategyName,
+	RecentBaseFeeBlocks:   20,
+	PercentileFeeStrategy: DefaultPercentileFeeStrategyConfig,
+	BudgetFeeStrategy:     DefaultBudgetFeeStrategyConfig,
+	Webhook:               DefaultTransactionWebhookConfig,
 }
 
 // DataPoster must be RLP serializable and deserializable
@@ -89,6 +115,8 @@ type DataPoster[Meta any] struct {
 	config            DataPosterConfigFetcher
 	replacementTimes  []time.Duration
 	metadataRetriever func(ctx context.Context, blockNum *big.Int) (Meta, error)
+	feeStrategy       FeeStrategy
+	events            transactionEventFeed[Meta]
 
 	// these fields are protected by the mutex
 	mutex      sync.Mutex
@@ -97,13 +125,20 @@ type DataPoster[Meta any] struct {
 	nonce      uint64
 	queue      QueueStorage[queuedTransaction[Meta]]
 	errorCount map[uint64]int // number of consecutive intermittent errors rbf-ing or sending, per nonce
+	// when each unconfirmed nonce was first posted by this instance
+	firstPosted map[uint64]time.Time
+	// base fees of recent L1 blocks, oldest first, ending with block recentBaseFeesEnd
+	recentBaseFees    []*big.Int
+	recentBaseFeesEnd uint64
 }
 
 type AttemptLocker interface {
 	AttemptLock(context.Context) bool
 }
 
-func NewDataPoster[Meta any](headerReader *headerreader.HeaderReader, auth *bind.TransactOpts, redisClient redis.UniversalClient, redisLock AttemptLocker, config DataPosterConfigFetcher, metadataRetriever func(ctx context.Context, blockNum *big.Int) (Meta, error)) (*DataPoster[Meta], error) {
+// queueName names the transaction queue in redis or the database, and must be unique to the signer.
+// feeBudget is shared by the DataPosters of all wallets for the budget fee strategy, and may be nil.
+func NewDataPoster[Meta any](headerReader *headerreader.HeaderReader, auth *bind.TransactOpts, redisClient redis.UniversalClient, db ethdb.Database, queueName string, redisLock AttemptLocker, feeBudget *FeeBudget, config DataPosterConfigFetcher, metadataRetriever func(ctx context.Context, blockNum *big.Int) (Meta, error)) (*DataPoster[Meta], error) {
 	var replacementTimes []time.Duration
 	var lastReplacementTime time.Duration
 	for _, s := range strings.Split(config().ReplacementTimes, ",") {
@@ -123,14 +158,24 @@ func NewDataPoster[Meta any](headerReader *headerreader.HeaderReader, auth *bind
 	// To avoid special casing "don't replace again", replace in 10 years
 	replacementTimes = append(replacementTimes, time.Hour*24*365*10)
 	var queue QueueStorage[queuedTransaction[Meta]]
-	if redisClient == nil {
-		queue = NewSliceStorage[queuedTransaction[Meta]]()
-	} else {
+	if redisClient != nil {
+		var err error
+		queue, err = NewRedisStorage[queuedTransaction[Meta]](redisClient, queueName, &config().RedisSigner)
+		if err != nil {
+			return nil, err
+		}
+	} else if config().UseDBStorage && db != nil {
 		var err error
-		queue, err = NewRedisStorage[queuedTransaction[Meta]](redisClient, "data-poster.queue", &config().RedisSigner)
+		queue, err = NewDBStorage[queuedTransaction[Meta]](rawdb.NewTable(db, queueName), &config().RedisSigner)
 		if err != nil {
 			return nil, err
 		}
+	} else {
+		queue = NewSliceStorage[queuedTransaction[Meta]]()
+	}
+	feeStrategy, err := NewFeeStrategy(config().FeeStrategy, config, feeBudget)
+	if err != nil {
+		return nil, err
 	}
 	return &DataPoster[Meta]{
 		headerReader:      headerReader,
@@ -139,9 +184,11 @@ func NewDataPoster[Meta any](headerReader *headerreader.HeaderReader, auth *bind
 		config:            config,
 		replacementTimes:  replacementTimes,
 		metadataRetriever: metadataRetriever,
+		feeStrategy:       feeStrategy,
 		queue:             queue,
 		redisLock:         redisLock,
 		errorCount:        make(map[uint64]int),
+		firstPosted:       make(map[uint64]time.Time),
 	}, nil
 }
 
@@ -175,38 +222,71 @@ func (p *DataPoster[Meta]) GetNextNonceAndMeta(ctx context.Context) (uint64, Met
 
 const minRbfIncrease = fogmath.OneInBips * 11 / 10
 
-func (p *DataPoster[Meta]) getFeeAndTipCaps(ctx context.Context, gasLimit uint64, lastTipCap *big.Int, dataCreatedAt time.Time, backlogOfBatches uint64) (*big.Int, *big.Int, error) {
+// the mutex must be held by the caller
+func (p *DataPoster[Meta]) updateRecentBaseFees(ctx context.Context, latestHeader *types.Header) error {
+	maxBlocks := p.config().RecentBaseFeeBlocks
+	if maxBlocks == 0 || !latestHeader.Number.IsUint64() {
+		p.recentBaseFees = nil
+		return nil
+	}
+	latest := latestHeader.Number.Uint64()
+	if len(p.recentBaseFees) > 0 && latest <= p.recentBaseFeesEnd {
+		if latest < p.recentBaseFeesEnd {
+			// L1 reorged to a shorter chain, so start over
+			p.recentBaseFees = nil
+		} else {
+			return nil
+		}
+	}
+	first := p.recentBaseFeesEnd + 1
+	if len(p.recentBaseFees) == 0 || latest-p.recentBaseFeesEnd > maxBlocks {
+		p.recentBaseFees = nil
+		first = latest - fogmath.MinInt(latest, maxBlocks-1)
+	}
+	for number := first; number < latest; number++ {
+		header, err := p.client.HeaderByNumber(ctx, new(big.Int).SetUint64(number))
+		if err != nil {
+			return err
+		}
+		p.recentBaseFees = append(p.recentBaseFees, header.BaseFee)
+	}
+	p.recentBaseFees = append(p.recentBaseFees, latestHeader.BaseFee)
+	p.recentBaseFeesEnd = latest
+	if uint64(len(p.recentBaseFees)) > maxBlocks {
+		p.recentBaseFees = p.recentBaseFees[uint64(len(p.recentBaseFees))-maxBlocks:]
+	}
+	return nil
+}
+
+// the mutex must be held by the caller
+func (p *DataPoster[Meta]) getFeeAndTipCaps(ctx context.Context, nonce uint64, gasLimit uint64, lastFeeCap *big.Int, lastTipCap *big.Int, dataCreatedAt time.Time, backlogOfBatches uint64) (*big.Int, *big.Int, error) {
 	latestHeader, err := p.headerReader.LastHeader(ctx)
 	if err != nil {
 		return nil, nil, err
 	}
-	newTipCap, err := p.client.SuggestGasTipCap(ctx)
+	suggestedTipCap, err := p.client.SuggestGasTipCap(ctx)
 	if err != nil {
 		return nil, nil, err
 	}
-	if lastTipCap != nil {
-		newTipCap = fogmath.BigMax(newTipCap, fogmath.BigMulByBips(lastTipCap, minRbfIncrease))
-	}
-	newFeeCap := new(big.Int).Mul(latestHeader.BaseFee, big.NewInt(2))
-	newFeeCap.Add(newFeeCap, newTipCap)
-
-	elapsed := time.Since(dataCreatedAt)
-	config := p.config()
-	// MaxFeeCap = (BacklogOfBatches^2 * UrgencyGWei^2 + TargetPriceGWei) * GWei
-	maxFeeCap :=
-		fogmath.FloatToBig(
-			(float64(fogmath.SquareUint(backlogOfBatches))*
-				fogmath.SquareFloat(config.UrgencyGwei) +
-				config.TargetPriceGwei) *
-				params.GWei)
-	if fogmath.BigGreaterThan(newFeeCap, maxFeeCap) {
-		log.Warn(
-			"reducing proposed fee cap to current maximum",
-			"proposedFeeCap", newFeeCap,
-			"maxFeeCap", maxFeeCap,
-			"elapsed", elapsed,
-		)
-		newFeeCap = maxFeeCap
+	err = p.updateRecentBaseFees(ctx, latestHeader)
+	if err != nil {
+		return nil, nil, err
+	}
+	newFeeCap, newTipCap, err := p.feeStrategy.FeeAndTipCaps(ctx, &FeeStrategyInputs{
+		From:            p.auth.From,
+		Nonce:           nonce,
+		GasLimit:        gasLimit,
+		LastFeeCap:      lastFeeCap,
+		LastTipCap:      lastTipCap,
+		DataCreatedAt:   dataCreatedAt,
+		Backlog:         backlogOfBatches,
+		Balance:         p.balance,
+		LatestHeader:    latestHeader,
+		SuggestedTipCap: suggestedTipCap,
+		RecentBaseFees:  p.recentBaseFees,
+	})
+	if err != nil {
+		return nil, nil, err
 	}
 
 	balanceFeeCap := new(big.Int).Div(p.balance, new(big.Int).SetUint64(gasLimit))
@@ -236,7 +316,7 @@ func (p *DataPoster[Meta]) getFeeAndTipCaps(ctx context.Context, gasLimit uint64
 func (p *DataPoster[Meta]) PostTransaction(ctx context.Context, dataCreatedAt time.Time, nonce uint64, meta Meta, to common.Address, calldata []byte, gasLimit uint64) error {
 	p.mutex.Lock()
 	defer p.mutex.Unlock()
-	feeCap, tipCap, err := p.getFeeAndTipCaps(ctx, gasLimit, nil, dataCreatedAt, 0)
+	feeCap, tipCap, err := p.getFeeAndTipCaps(ctx, nonce, gasLimit, nil, nil, dataCreatedAt, 0)
 	if err != nil {
 		return err
 	}
@@ -261,7 +341,131 @@ func (p *DataPoster[Meta]) PostTransaction(ctx context.Context, dataCreatedAt ti
 		Created:         dataCreatedAt,
 		NextReplacement: time.Now().Add(p.replacementTimes[0]),
 	}
-	return p.sendTx(ctx, nil, &queuedTx)
+	err = p.sendTx(ctx, nil, &queuedTx)
+	if err == nil {
+		p.firstPosted[nonce] = time.Now()
+	} else {
+		p.sendEvent(TransactionFailed, &queuedTx, nil, err)
+	}
+	return err
+}
+
+// PendingTransaction is a transaction in the queue that isn't final yet.
+type PendingTransaction[Meta any] struct {
+	Nonce       uint64
+	Meta        Meta
+	To          common.Address
+	Data        []byte
+	Gas         uint64
+	Created     time.Time
+	FirstPosted time.Time
+	// Confirmed is whether the transaction is in the latest L1 block.
+	Confirmed bool
+	// Canceled is whether the transaction was replaced by an empty one with CancelTransactions.
+	Canceled bool
+}
+
+func (p *DataPoster[Meta]) isCanceled(tx *queuedTransaction[Meta]) bool {
+	return tx.Data.To != nil && *tx.Data.To == p.auth.From && len(tx.Data.Data) == 0
+}
+
+// PendingTransactions returns the queued transactions that aren't final yet, in nonce order.
+// Transactions queued before this instance started are treated as first posted when it first sees them.
+func (p *DataPoster[Meta]) PendingTransactions(ctx context.Context) ([]PendingTransaction[Meta], error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	err := p.updateState(ctx)
+	if err != nil {
+		return nil, err
+	}
+	unconfirmedNonce, err := p.client.NonceAt(ctx, p.auth.From, nil)
+	if err != nil {
+		return nil, err
+	}
+	maxResults := p.config().MaxQueuedTransactions
+	if maxResults == 0 {
+		maxResults = 512
+	}
+	queueContents, err := p.queue.GetContents(ctx, p.nonce, maxResults)
+	if err != nil {
+		return nil, err
+	}
+	var txs []PendingTransaction[Meta]
+	for _, tx := range queueContents {
+		if tx.Data.Nonce < p.nonce || tx.Data.To == nil {
+			continue
+		}
+		firstPosted, ok := p.firstPosted[tx.Data.Nonce]
+		if !ok {
+			firstPosted = time.Now()
+			p.firstPosted[tx.Data.Nonce] = firstPosted
+		}
+		txs = append(txs, PendingTransaction[Meta]{
+			Nonce:       tx.Data.Nonce,
+			Meta:        tx.Meta,
+			To:          *tx.Data.To,
+			Data:        tx.Data.Data,
+			Gas:         tx.Data.Gas,
+			Created:     tx.Created,
+			FirstPosted: firstPosted,
+			Confirmed:   tx.Data.Nonce < unconfirmedNonce,
+			Canceled:    p.isCanceled(tx),
+		})
+	}
+	return txs, nil
+}
+
+// CancelTransactions replaces the queued transactions that aren't in the latest L1 block with
+// empty transfers to the signer, so that they don't land and the nonces can be used again.
+// The replacements are then replaced by fee like any other transaction until they're confirmed.
+func (p *DataPoster[Meta]) CancelTransactions(ctx context.Context) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	unconfirmedNonce, err := p.client.NonceAt(ctx, p.auth.From, nil)
+	if err != nil {
+		return err
+	}
+	maxResults := p.config().MaxQueuedTransactions
+	if maxResults == 0 {
+		maxResults = 512
+	}
+	queueContents, err := p.queue.GetContents(ctx, unconfirmedNonce, maxResults)
+	if err != nil {
+		return err
+	}
+	for _, prevTx := range queueContents {
+		if prevTx.Data.Nonce < unconfirmedNonce || p.isCanceled(prevTx) {
+			continue
+		}
+		feeCap, tipCap, err := p.getFeeAndTipCaps(ctx, prevTx.Data.Nonce, params.TxGas, prevTx.Data.GasFeeCap, prevTx.Data.GasTipCap, prevTx.Created, 0)
+		if err != nil {
+			return err
+		}
+		// The cancellation only costs a transfer's gas, so it can always outbid the transaction it replaces.
+		feeCap = fogmath.BigMax(feeCap, fogmath.BigMulByBips(prevTx.Data.GasFeeCap, minRbfIncrease))
+		tipCap = fogmath.BigMax(tipCap, fogmath.BigMulByBips(prevTx.Data.GasTipCap, minRbfIncrease))
+		tipCap = fogmath.BigMin(tipCap, feeCap)
+		to := p.auth.From
+		newTx := *prevTx
+		newTx.Sent = false
+		newTx.NextReplacement = time.Now().Add(p.replacementTimes[0])
+		newTx.Data.GasFeeCap = feeCap
+		newTx.Data.GasTipCap = tipCap
+		newTx.Data.Gas = params.TxGas
+		newTx.Data.To = &to
+		newTx.Data.Value = new(big.Int)
+		newTx.Data.Data = nil
+		newTx.FullTx, err = p.auth.Signer(p.auth.From, types.NewTx(&newTx.Data))
+		if err != nil {
+			return err
+		}
+		log.Warn("canceling data poster transaction", "from", p.auth.From, "nonce", prevTx.Data.Nonce, "feeCap", feeCap)
+		err = p.sendTx(ctx, prevTx, &newTx)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // the mutex must be held by the caller
@@ -278,6 +482,9 @@ func (p *DataPoster[Meta]) sendTx(ctx context.Context, prevTx *queuedTransaction
 		if err != nil {
 			return err
 		}
+		if prevTx == nil {
+			p.sendEvent(TransactionQueued, newTx, nil, nil)
+		}
 	}
 	err := p.client.SendTransaction(ctx, newTx.FullTx)
 	if err != nil {
@@ -291,6 +498,11 @@ func (p *DataPoster[Meta]) sendTx(ctx context.Context, prevTx *queuedTransaction
 	} else {
 		log.Info("DataPoster sent transaction", "nonce", newTx.FullTx.Nonce(), "hash", newTx.FullTx.Hash(), "feeCap", newTx.FullTx.GasFeeCap())
 	}
+	if prevTx != nil && prevTx.FullTx.Hash() != newTx.FullTx.Hash() {
+		p.sendEvent(TransactionReplaced, newTx, prevTx, nil)
+	} else {
+		p.sendEvent(TransactionSent, newTx, nil, nil)
+	}
 	newerTx := *newTx
 	newerTx.Sent = true
 	return p.saveTx(ctx, newTx, &newerTx)
@@ -298,7 +510,7 @@ func (p *DataPoster[Meta]) sendTx(ctx context.Context, prevTx *queuedTransaction
 
 // the mutex must be held by the caller
 func (p *DataPoster[Meta]) replaceTx(ctx context.Context, prevTx *queuedTransaction[Meta], backlogOfBatches uint64) error {
-	newFeeCap, newTipCap, err := p.getFeeAndTipCaps(ctx, prevTx.Data.Gas, prevTx.Data.GasTipCap, prevTx.Created, backlogOfBatches)
+	newFeeCap, newTipCap, err := p.getFeeAndTipCaps(ctx, prevTx.Data.Nonce, prevTx.Data.Gas, prevTx.Data.GasFeeCap, prevTx.Data.GasTipCap, prevTx.Created, backlogOfBatches)
 	if err != nil {
 		return err
 	}
@@ -351,11 +563,25 @@ func (p *DataPoster[Meta]) updateState(ctx context.Context) error {
 				delete(p.errorCount, x)
 			}
 		}
-		err := p.queue.Prune(ctx, nonce)
+		for x := range p.firstPosted {
+			if x < nonce {
+				delete(p.firstPosted, x)
+			}
+		}
+		confirmedTxs, err := p.queue.GetContents(ctx, p.nonce, nonce-p.nonce)
+		if err != nil {
+			return err
+		}
+		err = p.queue.Prune(ctx, nonce)
 		if err != nil {
 			return err
 		}
 		p.nonce = nonce
+		for _, tx := range confirmedTxs {
+			if tx.Data.Nonce < nonce {
+				p.sendEvent(TransactionConfirmed, tx, nil, nil)
+			}
+		}
 	}
 	// Use the pending (representated as -1) balance because we're looking at batches we'd post,
 	// so we want to see how much gas we could afford with our pending state.
@@ -385,12 +611,18 @@ func (p *DataPoster[Meta]) maybeLogError(err error, tx *queuedTransaction[Meta],
 		delete(p.errorCount, nonce)
 	}
 	log.Error(msg, "err", err, "nonce", nonce)
+	p.sendEvent(TransactionFailed, tx, nil, err)
 }
 
 const minWait = time.Second * 10
 
 func (p *DataPoster[Meta]) Start(ctxIn context.Context) {
 	p.StopWaiter.Start(ctxIn, p)
+	webhookEvents, unsubscribe := p.SubscribeTransactionEvents(p.config().Webhook.BufferSize)
+	p.LaunchThread(func(ctx context.Context) {
+		defer unsubscribe()
+		runTransactionWebhook(ctx, func() *TransactionWebhookConfig { return &p.config().Webhook }, webhookEvents)
+	})
 	p.CallIteratively(func(ctx context.Context) time.Duration {
 		p.mutex.Lock()
 		defer p.mutex.Unlock()
@@ -450,3 +682,8 @@ func (p *DataPoster[Meta]) Start(ctxIn context.Context) {
 		return wait
 	})
 }
+
+func (p *DataPoster[Meta]) StopAndWait() {
+	p.StopWaiter.StopAndWait()
+	p.events.closeAll()
+}
@@ -0,0 +1,139 @@
+// Copyright 2021-2022, Offchain Labs, Inc.
+// For license information, see https://github.com/fogr/blob/master/LICENSE
+
+package dataposter
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"sync"
+
+	"github.com/FOGRCC/fogr/util/signature"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// DBStorage keeps the queue in a local database, so a single data poster can pick up where it
+// left off after a restart. Items are stored signed like in RedisStorage, keyed by their index.
+// DBStorage requires that Item is RLP encodable/decodable.
+type DBStorage[Item any] struct {
+	db     ethdb.Database
+	signer *signature.SimpleHmac
+	mutex  sync.Mutex
+}
+
+// NewDBStorage takes a database of its own, such as a table of the node's database.
+func NewDBStorage[Item any](db ethdb.Database, signerConf *signature.SimpleHmacConfig) (*DBStorage[Item], error) {
+	signer, err := signature.NewSimpleHmac(signerConf)
+	if err != nil {
+		return nil, err
+	}
+	return &DBStorage[Item]{db: db, signer: signer}, nil
+}
+
+func dbStorageKey(index uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, index)
+	return key
+}
+
+func (s *DBStorage[Item]) GetContents(ctx context.Context, startingIndex uint64, maxResults uint64) ([]*Item, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	iter := s.db.NewIterator(nil, dbStorageKey(startingIndex))
+	defer iter.Release()
+	var items []*Item
+	for uint64(len(items)) < maxResults && iter.Next() {
+		item, err := decodeSignedItem[Item](s.signer, iter.Value())
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, iter.Error()
+}
+
+func (s *DBStorage[Item]) GetLast(ctx context.Context) (*Item, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	// The queue is pruned as transactions are confirmed, so it only ever holds a few items.
+	iter := s.db.NewIterator(nil, nil)
+	defer iter.Release()
+	var last []byte
+	for iter.Next() {
+		last = iter.Value()
+	}
+	if err := iter.Error(); err != nil {
+		return nil, err
+	}
+	if last == nil {
+		return nil, nil
+	}
+	return decodeSignedItem[Item](s.signer, last)
+}
+
+func (s *DBStorage[Item]) Prune(ctx context.Context, keepStartingAt uint64) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	iter := s.db.NewIterator(nil, nil)
+	defer iter.Release()
+	batch := s.db.NewBatch()
+	end := dbStorageKey(keepStartingAt)
+	for iter.Next() {
+		if bytes.Compare(iter.Key(), end) >= 0 {
+			break
+		}
+		err := batch.Delete(iter.Key())
+		if err != nil {
+			return err
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return err
+	}
+	return batch.Write()
+}
+
+func (s *DBStorage[Item]) Put(ctx context.Context, index uint64, prevItem *Item, newItem *Item) error {
+	if newItem == nil {
+		return fmt.Errorf("tried to insert nil item at index %v", index)
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	key := dbStorageKey(index)
+	has, err := s.db.Has(key)
+	if err != nil {
+		return err
+	}
+	if !has {
+		if prevItem != nil {
+			return fmt.Errorf("%w: tried to replace item at index %v but no item exists there", ErrStorageRace, index)
+		}
+	} else {
+		if prevItem == nil {
+			return fmt.Errorf("%w: tried to insert new item at index %v but an item exists there", ErrStorageRace, index)
+		}
+		haveItem, err := s.db.Get(key)
+		if err != nil {
+			return err
+		}
+		verifiedItem, err := peelVerifySignature(s.signer, haveItem)
+		if err != nil {
+			return fmt.Errorf("failed to validate item already in database at index %v: %w", index, err)
+		}
+		prevItemEncoded, err := rlp.EncodeToBytes(prevItem)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(verifiedItem, prevItemEncoded) {
+			return fmt.Errorf("%w: replacing different item than expected at index %v", ErrStorageRace, index)
+		}
+	}
+	signedItem, err := signItem(s.signer, newItem)
+	if err != nil {
+		return err
+	}
+	return s.db.Put(key, signedItem)
+}
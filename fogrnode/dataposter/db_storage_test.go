@@ -0,0 +1,68 @@
+// Copyright 2021-2022, Offchain Labs, Inc.
+// For license information, see https://github.com/fogr/blob/master/LICENSE
+
+package dataposter
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/FOGRCC/fogr/util/signature"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+)
+
+type testQueueItem struct {
+	Nonce uint64
+	Data  []byte
+}
+
+func TestDBStorage(t *testing.T) {
+	ctx := context.Background()
+	db := rawdb.NewMemoryDatabase()
+	storage, err := NewDBStorage[testQueueItem](db, &signature.TestSimpleHmacConfig)
+	Require(t, err)
+
+	last, err := storage.GetLast(ctx)
+	Require(t, err)
+	if last != nil {
+		Fail(t, "empty storage has a last item", last)
+	}
+	for nonce := uint64(3); nonce < 8; nonce++ {
+		Require(t, storage.Put(ctx, nonce, nil, &testQueueItem{Nonce: nonce}))
+	}
+	err = storage.Put(ctx, 5, nil, &testQueueItem{Nonce: 5})
+	if !errors.Is(err, ErrStorageRace) {
+		Fail(t, "inserting over an existing item didn't race, got", err)
+	}
+	err = storage.Put(ctx, 5, &testQueueItem{Nonce: 5, Data: []byte{1}}, &testQueueItem{Nonce: 5, Data: []byte{2}})
+	if !errors.Is(err, ErrStorageRace) {
+		Fail(t, "replacing a different item didn't race, got", err)
+	}
+	Require(t, storage.Put(ctx, 5, &testQueueItem{Nonce: 5}, &testQueueItem{Nonce: 5, Data: []byte{2}}))
+
+	// A new DBStorage on the same database picks up the queue, as after a restart.
+	storage, err = NewDBStorage[testQueueItem](db, &signature.TestSimpleHmacConfig)
+	Require(t, err)
+	Require(t, storage.Prune(ctx, 4))
+	items, err := storage.GetContents(ctx, 0, 3)
+	Require(t, err)
+	if len(items) != 3 || items[0].Nonce != 4 || items[2].Nonce != 6 || len(items[1].Data) != 1 || items[1].Data[0] != 2 {
+		Fail(t, "unexpected queue contents", items)
+	}
+	last, err = storage.GetLast(ctx)
+	Require(t, err)
+	if last == nil || last.Nonce != 7 {
+		Fail(t, "unexpected last item", last)
+	}
+
+	// Items signed with another key are rejected.
+	otherConfig := signature.TestSimpleHmacConfig
+	otherConfig.SigningKey = "0000000000000000000000000000000000000000000000000000000000000001"
+	otherStorage, err := NewDBStorage[testQueueItem](db, &otherConfig)
+	Require(t, err)
+	_, err = otherStorage.GetLast(ctx)
+	if err == nil {
+		Fail(t, "read an item signed with another key")
+	}
+}
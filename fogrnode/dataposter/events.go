@@ -0,0 +1,193 @@
+// Copyright 2021-2022, Offchain Labs, Inc.
+// For license information, see https://github.com/fogr/blob/master/LICENSE
+
+package dataposter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+	flag "github.com/spf13/pflag"
+)
+
+type TransactionEventKind string
+
+const (
+	// TransactionQueued is sent when a new transaction is added to the queue.
+	TransactionQueued TransactionEventKind = "queued"
+	// TransactionSent is sent when a transaction is sent, or sent again, to the L1 node.
+	TransactionSent TransactionEventKind = "sent"
+	// TransactionReplaced is sent when a transaction is replaced by one with higher fees.
+	TransactionReplaced TransactionEventKind = "replaced"
+	// TransactionConfirmed is sent when a transaction's nonce is confirmed on L1.
+	TransactionConfirmed TransactionEventKind = "confirmed"
+	// TransactionFailed is sent when posting, sending or replacing a transaction fails.
+	TransactionFailed TransactionEventKind = "failed"
+)
+
+// TransactionEvent is a step in the life of a transaction posted by a DataPoster.
+// The Old fields are only set for TransactionReplaced, and Error only for TransactionFailed.
+type TransactionEvent[Meta any] struct {
+	Kind      TransactionEventKind `json:"kind"`
+	Time      time.Time            `json:"time"`
+	From      common.Address       `json:"from"`
+	Nonce     uint64               `json:"nonce"`
+	Hash      common.Hash          `json:"hash"`
+	FeeCap    *big.Int             `json:"feeCap"`
+	TipCap    *big.Int             `json:"tipCap"`
+	OldHash   *common.Hash         `json:"oldHash,omitempty"`
+	OldFeeCap *big.Int             `json:"oldFeeCap,omitempty"`
+	OldTipCap *big.Int             `json:"oldTipCap,omitempty"`
+	Error     string               `json:"error,omitempty"`
+	Meta      Meta                 `json:"meta"`
+}
+
+type TransactionWebhookConfig struct {
+	URL        string        `koanf:"url" reload:"hot"`
+	Timeout    time.Duration `koanf:"timeout" reload:"hot"`
+	BufferSize int           `koanf:"buffer-size"`
+}
+
+func TransactionWebhookConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.String(prefix+".url", DefaultTransactionWebhookConfig.URL, "if non-empty, the URL to POST each transaction lifecycle event to as JSON")
+	f.Duration(prefix+".timeout", DefaultTransactionWebhookConfig.Timeout, "timeout for each webhook request")
+	f.Int(prefix+".buffer-size", DefaultTransactionWebhookConfig.BufferSize, "the number of events to buffer for the webhook before dropping them")
+}
+
+var DefaultTransactionWebhookConfig = TransactionWebhookConfig{
+	URL:        "",
+	Timeout:    5 * time.Second,
+	BufferSize: 256,
+}
+
+type transactionEventFeed[Meta any] struct {
+	mutex       sync.Mutex
+	subscribers map[chan<- TransactionEvent[Meta]]struct{}
+}
+
+func (f *transactionEventFeed[Meta]) subscribe(bufferSize int) (<-chan TransactionEvent[Meta], func()) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.subscribers == nil {
+		f.subscribers = make(map[chan<- TransactionEvent[Meta]]struct{})
+	}
+	result := make(chan TransactionEvent[Meta], bufferSize)
+	outchannel := (chan<- TransactionEvent[Meta])(result)
+	f.subscribers[outchannel] = struct{}{}
+	unsubscribeFunc := func() {
+		f.mutex.Lock()
+		defer f.mutex.Unlock()
+		if _, ok := f.subscribers[outchannel]; ok {
+			delete(f.subscribers, outchannel)
+			close(outchannel)
+		}
+	}
+	return result, unsubscribeFunc
+}
+
+// send never blocks, so the data poster isn't held up by slow subscribers.
+func (f *transactionEventFeed[Meta]) send(event TransactionEvent[Meta]) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	for ch := range f.subscribers {
+		select {
+		case ch <- event:
+		default:
+			log.Warn("dropping data poster transaction event for subscriber that's behind", "kind", event.Kind, "nonce", event.Nonce)
+		}
+	}
+}
+
+func (f *transactionEventFeed[Meta]) closeAll() {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	for ch := range f.subscribers {
+		delete(f.subscribers, ch)
+		close(ch)
+	}
+}
+
+// SubscribeTransactionEvents subscribes to the lifecycle events of the data poster's transactions.
+// Events are dropped rather than waited on once bufferSize events are waiting to be read.
+// The channel is closed when the data poster stops or the returned function is called.
+func (p *DataPoster[Meta]) SubscribeTransactionEvents(bufferSize int) (<-chan TransactionEvent[Meta], func()) {
+	return p.events.subscribe(bufferSize)
+}
+
+func (p *DataPoster[Meta]) sendEvent(kind TransactionEventKind, tx *queuedTransaction[Meta], prevTx *queuedTransaction[Meta], err error) {
+	event := TransactionEvent[Meta]{
+		Kind:   kind,
+		Time:   time.Now(),
+		From:   p.auth.From,
+		Nonce:  tx.Data.Nonce,
+		FeeCap: tx.Data.GasFeeCap,
+		TipCap: tx.Data.GasTipCap,
+		Meta:   tx.Meta,
+	}
+	if tx.FullTx != nil {
+		event.Hash = tx.FullTx.Hash()
+	}
+	if prevTx != nil && prevTx.FullTx != nil {
+		oldHash := prevTx.FullTx.Hash()
+		event.OldHash = &oldHash
+		event.OldFeeCap = prevTx.Data.GasFeeCap
+		event.OldTipCap = prevTx.Data.GasTipCap
+	}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	p.events.send(event)
+}
+
+// runTransactionWebhook POSTs each event to the configured webhook URL until events is closed.
+func runTransactionWebhook[Meta any](ctx context.Context, config func() *TransactionWebhookConfig, events <-chan TransactionEvent[Meta]) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			webhookConfig := config()
+			if webhookConfig.URL == "" {
+				continue
+			}
+			err := postTransactionEvent(ctx, webhookConfig, event)
+			if err != nil {
+				log.Warn("failed to send data poster transaction event to webhook", "err", err, "kind", event.Kind, "nonce", event.Nonce)
+			}
+		}
+	}
+}
+
+func postTransactionEvent[Meta any](ctx context.Context, config *TransactionWebhookConfig, event TransactionEvent[Meta]) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, config.Timeout)
+	defer cancel()
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, config.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %v", response.Status)
+	}
+	return nil
+}
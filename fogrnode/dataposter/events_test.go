@@ -0,0 +1,73 @@
+// Copyright 2022, Offchain Labs, Inc.
+// For license information, see https://github.com/fogr/blob/master/LICENSE
+
+package dataposter
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTransactionEventFeed(t *testing.T) {
+	var feed transactionEventFeed[uint64]
+	events, unsubscribe := feed.subscribe(2)
+	for nonce := uint64(0); nonce < 3; nonce++ {
+		feed.send(TransactionEvent[uint64]{Kind: TransactionSent, Nonce: nonce})
+	}
+	// The third event is dropped rather than blocking the sender.
+	for nonce := uint64(0); nonce < 2; nonce++ {
+		event := <-events
+		if event.Nonce != nonce {
+			Fail(t, "expected nonce", nonce, "but got", event.Nonce)
+		}
+	}
+	select {
+	case event := <-events:
+		Fail(t, "unexpected event", event)
+	default:
+	}
+	unsubscribe()
+	if _, ok := <-events; ok {
+		Fail(t, "expected channel to be closed after unsubscribing")
+	}
+	unsubscribe()
+	feed.send(TransactionEvent[uint64]{Kind: TransactionSent})
+}
+
+func TestTransactionWebhook(t *testing.T) {
+	received := make(chan TransactionEvent[uint64], 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event TransactionEvent[uint64]
+		err := json.NewDecoder(r.Body).Decode(&event)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received <- event
+	}))
+	defer server.Close()
+
+	config := DefaultTransactionWebhookConfig
+	config.URL = server.URL
+	events := make(chan TransactionEvent[uint64], 1)
+	done := make(chan struct{})
+	go func() {
+		runTransactionWebhook(context.Background(), func() *TransactionWebhookConfig { return &config }, events)
+		close(done)
+	}()
+	events <- TransactionEvent[uint64]{Kind: TransactionConfirmed, Nonce: 7, Meta: 42}
+	select {
+	case event := <-received:
+		if event.Kind != TransactionConfirmed || event.Nonce != 7 || event.Meta != 42 {
+			Fail(t, "unexpected event", event)
+		}
+	case <-time.After(10 * time.Second):
+		Fail(t, "timed out waiting for webhook")
+	}
+	close(events)
+	<-done
+}
@@ -0,0 +1,153 @@
+// Copyright 2022, Offchain Labs, Inc.
+// For license information, see https://github.com/fogr/blob/master/LICENSE
+
+package dataposter
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const feeBudgetWindow = 24 * time.Hour
+
+type feeReservationKey struct {
+	wallet common.Address
+	nonce  uint64
+}
+
+type feeReservation struct {
+	firstPosted time.Time
+	cost        *big.Int
+}
+
+// FeeBudget records the most each recently posted transaction may cost, for the budget fee strategy.
+// A single FeeBudget is shared by the DataPosters of all of a node's wallets, so the daily budget
+// caps their spending together. With a database, the reservations also survive restarts.
+type FeeBudget struct {
+	db  ethdb.Database
+	now func() time.Time
+
+	mutex        sync.Mutex
+	reservations map[feeReservationKey]feeReservation
+}
+
+// NewFeeBudget loads the reservations from db, which is a database of its own such as a table of
+// the node's database. If db is nil, the reservations are only kept in memory.
+func NewFeeBudget(db ethdb.Database) (*FeeBudget, error) {
+	b := &FeeBudget{
+		db:           db,
+		now:          time.Now,
+		reservations: make(map[feeReservationKey]feeReservation),
+	}
+	if db == nil {
+		return b, nil
+	}
+	iter := db.NewIterator(nil, nil)
+	defer iter.Release()
+	for iter.Next() {
+		key, err := decodeFeeReservationKey(iter.Key())
+		if err != nil {
+			return nil, err
+		}
+		reservation, err := decodeFeeReservation(iter.Value())
+		if err != nil {
+			return nil, err
+		}
+		b.reservations[key] = reservation
+	}
+	return b, iter.Error()
+}
+
+func (k feeReservationKey) encode() []byte {
+	key := make([]byte, common.AddressLength+8)
+	copy(key, k.wallet.Bytes())
+	binary.BigEndian.PutUint64(key[common.AddressLength:], k.nonce)
+	return key
+}
+
+func decodeFeeReservationKey(key []byte) (feeReservationKey, error) {
+	if len(key) != common.AddressLength+8 {
+		return feeReservationKey{}, fmt.Errorf("fee reservation key %x has length %v", key, len(key))
+	}
+	return feeReservationKey{
+		wallet: common.BytesToAddress(key[:common.AddressLength]),
+		nonce:  binary.BigEndian.Uint64(key[common.AddressLength:]),
+	}, nil
+}
+
+func (r feeReservation) encode() []byte {
+	value := make([]byte, 8)
+	binary.BigEndian.PutUint64(value, uint64(r.firstPosted.Unix()))
+	return append(value, r.cost.Bytes()...)
+}
+
+func decodeFeeReservation(value []byte) (feeReservation, error) {
+	if len(value) < 8 {
+		return feeReservation{}, fmt.Errorf("fee reservation %x is too short", value)
+	}
+	return feeReservation{
+		firstPosted: time.Unix(int64(binary.BigEndian.Uint64(value[:8])), 0),
+		cost:        new(big.Int).SetBytes(value[8:]),
+	}, nil
+}
+
+// reserve calls capCost with how much the other transactions of the last day may cost, and then
+// reserves the cost it returns for the wallet's transaction with the given nonce.
+// Reservations older than a day are dropped.
+func (b *FeeBudget) reserve(wallet common.Address, nonce uint64, capCost func(spent *big.Int) (*big.Int, error)) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	var batch ethdb.Batch
+	if b.db != nil {
+		batch = b.db.NewBatch()
+	}
+	now := b.now()
+	key := feeReservationKey{wallet, nonce}
+	spent := new(big.Int)
+	for otherKey, reservation := range b.reservations {
+		if now.Sub(reservation.firstPosted) >= feeBudgetWindow {
+			delete(b.reservations, otherKey)
+			if batch != nil {
+				if err := batch.Delete(otherKey.encode()); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if otherKey != key {
+			spent.Add(spent, reservation.cost)
+		}
+	}
+	cost, err := capCost(spent)
+	if err != nil {
+		if batch != nil {
+			// Still drop the expired reservations from the database.
+			if writeErr := batch.Write(); writeErr != nil {
+				log.Warn("error pruning the fee budget", "err", writeErr)
+			}
+		}
+		return err
+	}
+	reservation, ok := b.reservations[key]
+	if !ok {
+		reservation.firstPosted = now
+	}
+	reservation.cost = cost
+	if batch != nil {
+		if err := batch.Put(key.encode(), reservation.encode()); err != nil {
+			return err
+		}
+		if err := batch.Write(); err != nil {
+			return err
+		}
+	}
+	b.reservations[key] = reservation
+	return nil
+}
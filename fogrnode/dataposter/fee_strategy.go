@@ -0,0 +1,263 @@
+// Copyright 2022, Offchain Labs, Inc.
+// For license information, see https://github.com/fogr/blob/master/LICENSE
+
+package dataposter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+	flag "github.com/spf13/pflag"
+
+	"github.com/FOGRCC/fogr/util/fogmath"
+)
+
+// FeeStrategyInputs is what a FeeStrategy bases the fees of a transaction on.
+type FeeStrategyInputs struct {
+	From     common.Address
+	Nonce    uint64
+	GasLimit uint64
+	// LastFeeCap and LastTipCap are the caps the transaction was last sent with, or nil if it's new.
+	LastFeeCap    *big.Int
+	LastTipCap    *big.Int
+	DataCreatedAt time.Time
+	// Backlog is the number of transactions queued after this one.
+	Backlog         uint64
+	Balance         *big.Int
+	LatestHeader    *types.Header
+	SuggestedTipCap *big.Int
+	// RecentBaseFees are the base fees of recent L1 blocks, oldest first and ending with LatestHeader's.
+	RecentBaseFees []*big.Int
+}
+
+// A FeeStrategy decides the fee cap and tip cap to post or replace a transaction with.
+// The DataPoster then lowers them if needed to fit its balance.
+type FeeStrategy interface {
+	FeeAndTipCaps(ctx context.Context, inputs *FeeStrategyInputs) (feeCap *big.Int, tipCap *big.Int, err error)
+}
+
+const (
+	TargetPriceFeeStrategyName = "target-price"
+	PercentileFeeStrategyName  = "percentile"
+	BudgetFeeStrategyName      = "budget"
+)
+
+type PercentileFeeStrategyConfig struct {
+	Percentile        float64 `koanf:"percentile" reload:"hot"`
+	BaseFeeMultiplier float64 `koanf:"base-fee-multiplier" reload:"hot"`
+	UrgencyMultiplier float64 `koanf:"urgency-multiplier" reload:"hot"`
+	MaxFeeCapGwei     float64 `koanf:"max-fee-cap-gwei" reload:"hot"`
+}
+
+var DefaultPercentileFeeStrategyConfig = PercentileFeeStrategyConfig{
+	Percentile:        90,
+	BaseFeeMultiplier: 1.25,
+	UrgencyMultiplier: 0.1,
+	MaxFeeCapGwei:     0,
+}
+
+func PercentileFeeStrategyConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Float64(prefix+".percentile", DefaultPercentileFeeStrategyConfig.Percentile, "percentile of recent L1 base fees to expect the base fee to stay under")
+	f.Float64(prefix+".base-fee-multiplier", DefaultPercentileFeeStrategyConfig.BaseFeeMultiplier, "multiple of the percentile base fee to use as the base of the fee cap")
+	f.Float64(prefix+".urgency-multiplier", DefaultPercentileFeeStrategyConfig.UrgencyMultiplier, "fraction the fee cap is raised by for each transaction queued after this one")
+	f.Float64(prefix+".max-fee-cap-gwei", DefaultPercentileFeeStrategyConfig.MaxFeeCapGwei, "the maximum fee cap to use (0 = unlimited)")
+}
+
+type BudgetFeeStrategyConfig struct {
+	DailyBudgetEth float64 `koanf:"daily-budget-eth" reload:"hot"`
+	BaseStrategy   string  `koanf:"base-strategy" reload:"hot"`
+}
+
+var DefaultBudgetFeeStrategyConfig = BudgetFeeStrategyConfig{
+	DailyBudgetEth: 1,
+	BaseStrategy:   TargetPriceFeeStrategyName,
+}
+
+func BudgetFeeStrategyConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Float64(prefix+".daily-budget-eth", DefaultBudgetFeeStrategyConfig.DailyBudgetEth, "the most ETH the transactions posted in any 24 hours may cost, assuming each pays its full fee cap")
+	f.String(prefix+".base-strategy", DefaultBudgetFeeStrategyConfig.BaseStrategy, "the fee strategy to cap to the budget, either target-price or percentile")
+}
+
+// NewFeeStrategy makes the named strategy. The budget is only used by the budget fee strategy, and
+// should be shared by all DataPosters; if it's nil, the strategy keeps a budget of its own in memory.
+func NewFeeStrategy(name string, config DataPosterConfigFetcher, budget *FeeBudget) (FeeStrategy, error) {
+	switch name {
+	case TargetPriceFeeStrategyName, "":
+		return &TargetPriceFeeStrategy{config}, nil
+	case PercentileFeeStrategyName:
+		return &PercentileFeeStrategy{config}, nil
+	case BudgetFeeStrategyName:
+		if budget == nil {
+			var err error
+			budget, err = NewFeeBudget(nil)
+			if err != nil {
+				return nil, err
+			}
+		}
+		strategy := NewBudgetFeeStrategy(config, budget)
+		if _, err := strategy.baseStrategy(); err != nil {
+			return nil, err
+		}
+		return strategy, nil
+	default:
+		return nil, fmt.Errorf("unknown fee strategy %q, must be %v, %v or %v", name, TargetPriceFeeStrategyName, PercentileFeeStrategyName, BudgetFeeStrategyName)
+	}
+}
+
+// TargetPriceFeeStrategy offers twice the latest base fee, up to a maximum that grows with the
+// square of the backlog:
+// MaxFeeCap = (BacklogOfBatches^2 * UrgencyGWei^2 + TargetPriceGWei) * GWei
+type TargetPriceFeeStrategy struct {
+	config DataPosterConfigFetcher
+}
+
+func (s *TargetPriceFeeStrategy) FeeAndTipCaps(ctx context.Context, inputs *FeeStrategyInputs) (*big.Int, *big.Int, error) {
+	newTipCap := inputs.SuggestedTipCap
+	if inputs.LastTipCap != nil {
+		newTipCap = fogmath.BigMax(newTipCap, fogmath.BigMulByBips(inputs.LastTipCap, minRbfIncrease))
+	}
+	newFeeCap := new(big.Int).Mul(inputs.LatestHeader.BaseFee, big.NewInt(2))
+	newFeeCap.Add(newFeeCap, newTipCap)
+
+	config := s.config()
+	maxFeeCap :=
+		fogmath.FloatToBig(
+			(float64(fogmath.SquareUint(inputs.Backlog))*
+				fogmath.SquareFloat(config.UrgencyGwei) +
+				config.TargetPriceGwei) *
+				params.GWei)
+	if fogmath.BigGreaterThan(newFeeCap, maxFeeCap) {
+		log.Warn(
+			"reducing proposed fee cap to current maximum",
+			"proposedFeeCap", newFeeCap,
+			"maxFeeCap", maxFeeCap,
+			"elapsed", time.Since(inputs.DataCreatedAt),
+		)
+		newFeeCap = maxFeeCap
+	}
+	return newFeeCap, newTipCap, nil
+}
+
+// PercentileFeeStrategy bases the fee cap on a percentile of recent base fees rather than only
+// the latest one, which rides out short spikes better, and raises it linearly with the backlog.
+type PercentileFeeStrategy struct {
+	config DataPosterConfigFetcher
+}
+
+func baseFeePercentile(baseFees []*big.Int, percentile float64) *big.Int {
+	if len(baseFees) == 0 {
+		return new(big.Int)
+	}
+	sorted := make([]*big.Int, len(baseFees))
+	copy(sorted, baseFees)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Cmp(sorted[j]) < 0 })
+	index := int(float64(len(sorted)-1) * percentile / 100)
+	if index < 0 {
+		index = 0
+	}
+	if index >= len(sorted) {
+		index = len(sorted) - 1
+	}
+	return sorted[index]
+}
+
+func (s *PercentileFeeStrategy) FeeAndTipCaps(ctx context.Context, inputs *FeeStrategyInputs) (*big.Int, *big.Int, error) {
+	config := s.config().PercentileFeeStrategy
+	newTipCap := inputs.SuggestedTipCap
+	if inputs.LastTipCap != nil {
+		newTipCap = fogmath.BigMax(newTipCap, fogmath.BigMulByBips(inputs.LastTipCap, minRbfIncrease))
+	}
+	baseFee := fogmath.BigMax(baseFeePercentile(inputs.RecentBaseFees, config.Percentile), inputs.LatestHeader.BaseFee)
+	multiplier := config.BaseFeeMultiplier * (1 + config.UrgencyMultiplier*float64(inputs.Backlog))
+	newFeeCap, _ := new(big.Float).Mul(new(big.Float).SetInt(baseFee), big.NewFloat(multiplier)).Int(nil)
+	newFeeCap.Add(newFeeCap, newTipCap)
+	if config.MaxFeeCapGwei > 0 {
+		maxFeeCap := fogmath.FloatToBig(config.MaxFeeCapGwei * params.GWei)
+		if fogmath.BigGreaterThan(newFeeCap, maxFeeCap) {
+			log.Warn("reducing proposed fee cap to configured maximum", "proposedFeeCap", newFeeCap, "maxFeeCap", maxFeeCap)
+			newFeeCap = maxFeeCap
+		}
+	}
+	return newFeeCap, newTipCap, nil
+}
+
+var ErrFeeBudgetExhausted = errors.New("daily fee budget exhausted")
+
+// BudgetFeeStrategy caps the fees of another strategy so that the transactions posted in any
+// 24 hours can't cost more than the daily budget, even if each pays its full fee cap.
+// The base strategy is looked up in the config for each transaction.
+type BudgetFeeStrategy struct {
+	config         DataPosterConfigFetcher
+	budget         *FeeBudget
+	baseStrategies map[string]FeeStrategy
+}
+
+func NewBudgetFeeStrategy(config DataPosterConfigFetcher, budget *FeeBudget) *BudgetFeeStrategy {
+	return &BudgetFeeStrategy{
+		config: config,
+		budget: budget,
+		baseStrategies: map[string]FeeStrategy{
+			TargetPriceFeeStrategyName: &TargetPriceFeeStrategy{config},
+			PercentileFeeStrategyName:  &PercentileFeeStrategy{config},
+		},
+	}
+}
+
+func (s *BudgetFeeStrategy) baseStrategy() (FeeStrategy, error) {
+	name := s.config().BudgetFeeStrategy.BaseStrategy
+	if name == "" {
+		name = TargetPriceFeeStrategyName
+	}
+	base, ok := s.baseStrategies[name]
+	if !ok {
+		return nil, fmt.Errorf("invalid base strategy %q for the budget fee strategy, must be %v or %v", name, TargetPriceFeeStrategyName, PercentileFeeStrategyName)
+	}
+	return base, nil
+}
+
+func (s *BudgetFeeStrategy) FeeAndTipCaps(ctx context.Context, inputs *FeeStrategyInputs) (*big.Int, *big.Int, error) {
+	base, err := s.baseStrategy()
+	if err != nil {
+		return nil, nil, err
+	}
+	feeCap, tipCap, err := base.FeeAndTipCaps(ctx, inputs)
+	if err != nil {
+		return nil, nil, err
+	}
+	budget, _ := new(big.Float).Mul(big.NewFloat(s.config().BudgetFeeStrategy.DailyBudgetEth), big.NewFloat(params.Ether)).Int(nil)
+	gasLimit := new(big.Int).SetUint64(inputs.GasLimit)
+	err = s.budget.reserve(inputs.From, inputs.Nonce, func(spent *big.Int) (*big.Int, error) {
+		remaining := new(big.Int).Sub(budget, spent)
+		if inputs.GasLimit > 0 {
+			maxFeeCap := new(big.Int).Div(remaining, gasLimit)
+			if fogmath.BigGreaterThan(feeCap, maxFeeCap) {
+				log.Warn("reducing proposed fee cap to fit the daily budget", "proposedFeeCap", feeCap, "maxFeeCap", maxFeeCap, "spent", spent, "budget", budget)
+				feeCap = maxFeeCap
+			}
+		}
+		if feeCap.Sign() <= 0 || (inputs.LatestHeader != nil && fogmath.BigLessThan(feeCap, inputs.LatestHeader.BaseFee)) {
+			return nil, fmt.Errorf("%w: %v of %v wei spent", ErrFeeBudgetExhausted, spent, budget)
+		}
+		// If the new fee cap isn't enough of an increase, the DataPoster keeps the last transaction.
+		reservedFeeCap := feeCap
+		if inputs.LastFeeCap != nil {
+			reservedFeeCap = fogmath.BigMax(feeCap, inputs.LastFeeCap)
+		}
+		return new(big.Int).Mul(reservedFeeCap, gasLimit), nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if fogmath.BigGreaterThan(tipCap, feeCap) {
+		tipCap = new(big.Int).Set(feeCap)
+	}
+	return feeCap, tipCap, nil
+}
@@ -0,0 +1,207 @@
+// Copyright 2022, Offchain Labs, Inc.
+// For license information, see https://github.com/fogr/blob/master/LICENSE
+
+package dataposter
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+
+	"github.com/FOGRCC/fogr/util/testhelpers"
+)
+
+func Require(t *testing.T, err error, printables ...interface{}) {
+	t.Helper()
+	testhelpers.RequireImpl(t, err, printables...)
+}
+
+func Fail(t *testing.T, printables ...interface{}) {
+	t.Helper()
+	testhelpers.FailImpl(t, printables...)
+}
+
+func gwei(amount int64) *big.Int {
+	return new(big.Int).Mul(big.NewInt(amount), big.NewInt(params.GWei))
+}
+
+func testFeeInputs(baseFees ...int64) *FeeStrategyInputs {
+	inputs := &FeeStrategyInputs{
+		GasLimit:        1_000_000,
+		DataCreatedAt:   time.Now(),
+		Balance:         new(big.Int).Mul(big.NewInt(100), big.NewInt(params.Ether)),
+		SuggestedTipCap: gwei(1),
+	}
+	for _, fee := range baseFees {
+		inputs.RecentBaseFees = append(inputs.RecentBaseFees, gwei(fee))
+	}
+	inputs.LatestHeader = &types.Header{BaseFee: inputs.RecentBaseFees[len(inputs.RecentBaseFees)-1]}
+	return inputs
+}
+
+func testFeeConfig() DataPosterConfigFetcher {
+	config := DefaultDataPosterConfig
+	return func() *DataPosterConfig { return &config }
+}
+
+func TestTargetPriceFeeStrategy(t *testing.T) {
+	ctx := context.Background()
+	strategy, err := NewFeeStrategy(TargetPriceFeeStrategyName, testFeeConfig(), nil)
+	Require(t, err)
+
+	feeCap, tipCap, err := strategy.FeeAndTipCaps(ctx, testFeeInputs(10))
+	Require(t, err)
+	if feeCap.Cmp(gwei(21)) != 0 || tipCap.Cmp(gwei(1)) != 0 {
+		Fail(t, "unexpected caps", feeCap, tipCap)
+	}
+
+	// The fee cap is limited by the target price.
+	feeCap, _, err = strategy.FeeAndTipCaps(ctx, testFeeInputs(100))
+	Require(t, err)
+	if feeCap.Cmp(gwei(60)) != 0 {
+		Fail(t, "fee cap", feeCap, "wasn't limited to the target price")
+	}
+
+	// A replacement bumps the tip by at least 10%.
+	inputs := testFeeInputs(10)
+	inputs.LastTipCap = gwei(2)
+	_, tipCap, err = strategy.FeeAndTipCaps(ctx, inputs)
+	Require(t, err)
+	if tipCap.Cmp(new(big.Int).Div(gwei(22), big.NewInt(10))) != 0 {
+		Fail(t, "replacement tip cap", tipCap, "wasn't bumped")
+	}
+}
+
+func TestPercentileFeeStrategy(t *testing.T) {
+	ctx := context.Background()
+	strategy, err := NewFeeStrategy(PercentileFeeStrategyName, testFeeConfig(), nil)
+	Require(t, err)
+
+	// The spike to 40 gwei is above the 90th percentile of these 11 blocks.
+	feeCap, _, err := strategy.FeeAndTipCaps(ctx, testFeeInputs(10, 12, 14, 40, 16, 18, 20, 10, 10, 10, 8))
+	Require(t, err)
+	// 1.25 * 20 + 1
+	if feeCap.Cmp(gwei(26)) != 0 {
+		Fail(t, "unexpected fee cap", feeCap)
+	}
+
+	// The fee cap is never below the latest base fee.
+	feeCap, _, err = strategy.FeeAndTipCaps(ctx, testFeeInputs(10, 10, 10, 50))
+	Require(t, err)
+	if feeCap.Cmp(gwei(50)) <= 0 {
+		Fail(t, "fee cap", feeCap, "is below the latest base fee")
+	}
+}
+
+func TestBudgetFeeStrategy(t *testing.T) {
+	ctx := context.Background()
+	config := DefaultDataPosterConfig
+	// Enough for 2 transactions of 1M gas at 21 gwei, but not a third.
+	config.BudgetFeeStrategy.DailyBudgetEth = 0.05
+	fetcher := func() *DataPosterConfig { return &config }
+	feeBudget, err := NewFeeBudget(nil)
+	Require(t, err)
+	now := time.Now()
+	feeBudget.now = func() time.Time { return now }
+	strategy, err := NewFeeStrategy(BudgetFeeStrategyName, fetcher, feeBudget)
+	Require(t, err)
+	budget := strategy.(*BudgetFeeStrategy)
+
+	for nonce := uint64(0); nonce < 2; nonce++ {
+		inputs := testFeeInputs(10)
+		inputs.Nonce = nonce
+		feeCap, _, err := budget.FeeAndTipCaps(ctx, inputs)
+		Require(t, err)
+		if feeCap.Cmp(gwei(21)) != 0 {
+			Fail(t, "fee cap", feeCap, "of nonce", nonce, "was reduced within the budget")
+		}
+	}
+
+	// The third transaction only gets what's left of the budget.
+	inputs := testFeeInputs(5)
+	inputs.Nonce = 2
+	feeCap, tipCap, err := budget.FeeAndTipCaps(ctx, inputs)
+	Require(t, err)
+	if feeCap.Cmp(gwei(8)) != 0 || tipCap.Cmp(gwei(1)) != 0 {
+		Fail(t, "unexpected caps over the budget", feeCap, tipCap)
+	}
+
+	inputs = testFeeInputs(10)
+	inputs.Nonce = 3
+	_, _, err = budget.FeeAndTipCaps(ctx, inputs)
+	if !errors.Is(err, ErrFeeBudgetExhausted) {
+		Fail(t, "expected the budget to be exhausted, got", err)
+	}
+
+	// Replacing a transaction doesn't count its previous fee cap against it.
+	inputs = testFeeInputs(10)
+	inputs.Nonce = 1
+	inputs.LastFeeCap = gwei(21)
+	feeCap, _, err = budget.FeeAndTipCaps(ctx, inputs)
+	Require(t, err)
+	if feeCap.Cmp(gwei(21)) < 0 {
+		Fail(t, "replacement fee cap", feeCap, "was reduced by its own reservation")
+	}
+
+	now = now.Add(25 * time.Hour)
+	inputs = testFeeInputs(10)
+	inputs.Nonce = 3
+	_, _, err = budget.FeeAndTipCaps(ctx, inputs)
+	Require(t, err, "the budget wasn't renewed after a day")
+}
+
+func TestFeeBudgetSharedAndPersisted(t *testing.T) {
+	ctx := context.Background()
+	config := DefaultDataPosterConfig
+	config.BudgetFeeStrategy.DailyBudgetEth = 0.05
+	fetcher := func() *DataPosterConfig { return &config }
+	db := rawdb.NewMemoryDatabase()
+	feeBudget, err := NewFeeBudget(db)
+	Require(t, err)
+	first, err := NewFeeStrategy(BudgetFeeStrategyName, fetcher, feeBudget)
+	Require(t, err)
+	second, err := NewFeeStrategy(BudgetFeeStrategyName, fetcher, feeBudget)
+	Require(t, err)
+
+	// Two wallets with the same nonce each take a share of the one budget.
+	for i, strategy := range []FeeStrategy{first, second} {
+		inputs := testFeeInputs(10)
+		inputs.From = common.BigToAddress(big.NewInt(int64(i + 1)))
+		_, _, err := strategy.FeeAndTipCaps(ctx, inputs)
+		Require(t, err)
+	}
+
+	// After a restart, the reservations still count against the budget.
+	feeBudget, err = NewFeeBudget(db)
+	Require(t, err)
+	third, err := NewFeeStrategy(BudgetFeeStrategyName, fetcher, feeBudget)
+	Require(t, err)
+	inputs := testFeeInputs(10)
+	inputs.From = common.BigToAddress(big.NewInt(3))
+	_, _, err = third.FeeAndTipCaps(ctx, inputs)
+	if !errors.Is(err, ErrFeeBudgetExhausted) {
+		Fail(t, "expected the shared budget to be exhausted, got", err)
+	}
+
+	// The base strategy can be changed without making a new strategy.
+	config.BudgetFeeStrategy.DailyBudgetEth = 1
+	config.BudgetFeeStrategy.BaseStrategy = PercentileFeeStrategyName
+	config.PercentileFeeStrategy.MaxFeeCapGwei = 7
+	feeCap, _, err := third.FeeAndTipCaps(ctx, testFeeInputs(5))
+	Require(t, err)
+	if feeCap.Cmp(gwei(7)) != 0 {
+		Fail(t, "fee cap", feeCap, "didn't come from the new base strategy")
+	}
+	config.BudgetFeeStrategy.BaseStrategy = BudgetFeeStrategyName
+	_, _, err = third.FeeAndTipCaps(ctx, testFeeInputs(5))
+	if err == nil {
+		Fail(t, "the budget fee strategy accepted itself as its base strategy")
+	}
+}
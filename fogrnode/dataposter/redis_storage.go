@@ -35,18 +35,49 @@ func joinHmacMsg(msg []byte, sig []byte) ([]byte, error) {
 	return append(sig, msg...), nil
 }
 
-func (s *RedisStorage[Item]) peelVerifySignature(data []byte) ([]byte, error) {
+func peelVerifySignature(signer *signature.SimpleHmac, data []byte) ([]byte, error) {
 	if len(data) < 32 {
 		return nil, errors.New("data is too short to contain message signature")
 	}
 
-	err := s.signer.VerifySignature(data[:32], data[32:])
+	err := signer.VerifySignature(data[:32], data[32:])
 	if err != nil {
 		return nil, err
 	}
 	return data[32:], nil
 }
 
+// signItem RLP encodes an item and prefixes it with its signature.
+func signItem[Item any](signer *signature.SimpleHmac, item *Item) ([]byte, error) {
+	encoded, err := rlp.EncodeToBytes(*item)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := signer.SignMessage(encoded)
+	if err != nil {
+		return nil, err
+	}
+	return joinHmacMsg(encoded, sig)
+}
+
+// decodeSignedItem verifies an item signed by signItem and decodes it.
+func decodeSignedItem[Item any](signer *signature.SimpleHmac, data []byte) (*Item, error) {
+	verified, err := peelVerifySignature(signer, data)
+	if err != nil {
+		return nil, err
+	}
+	var item Item
+	err = rlp.DecodeBytes(verified, &item)
+	if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+func (s *RedisStorage[Item]) peelVerifySignature(data []byte) ([]byte, error) {
+	return peelVerifySignature(s.signer, data)
+}
+
 func (s *RedisStorage[Item]) GetContents(ctx context.Context, startingIndex uint64, maxResults uint64) ([]*Item, error) {
 	query := redis.ZRangeArgs{
 		Key:     s.key,
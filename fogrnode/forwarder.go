@@ -16,6 +16,7 @@ import (
 	"github.com/pkg/errors"
 	flag "github.com/spf13/pflag"
 
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/ethereum/go-ethereum/log"
@@ -114,13 +115,37 @@ func (f *TxForwarder) ctxWithTimeout(inctx context.Context) (context.Context, co
 	return context.WithTimeout(inctx, f.timeout)
 }
 
-func (f *TxForwarder) PublishTransaction(inctx context.Context, tx *types.Transaction) error {
+func (f *TxForwarder) PublishTransaction(inctx context.Context, tx *types.Transaction, options *ConditionalOptions) error {
 	if atomic.LoadInt32(&f.enabled) == 0 {
 		return ErrNoSequencer
 	}
 	ctx, cancelFunc := f.ctxWithTimeout(inctx)
 	defer cancelFunc()
-	return f.ethClient.SendTransaction(ctx, tx)
+	if options == nil {
+		return f.ethClient.SendTransaction(ctx, tx)
+	}
+	data, err := tx.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	return f.rpcClient.CallContext(ctx, nil, "fog_sendRawTransactionConditional", hexutil.Bytes(data), options)
+}
+
+func (f *TxForwarder) PublishBundle(inctx context.Context, txs types.Transactions) error {
+	if atomic.LoadInt32(&f.enabled) == 0 {
+		return ErrNoSequencer
+	}
+	encodedTxs := make([]hexutil.Bytes, 0, len(txs))
+	for _, tx := range txs {
+		data, err := tx.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		encodedTxs = append(encodedTxs, data)
+	}
+	ctx, cancelFunc := f.ctxWithTimeout(inctx)
+	defer cancelFunc()
+	return f.rpcClient.CallContext(ctx, nil, "fog_sendBundle", encodedTxs)
 }
 
 const cacheUpstreamHealth = 2 * time.Second
@@ -189,7 +214,11 @@ func NewTxDropper() *TxDropper {
 
 var txDropperErr = errors.New("publishing transactions not supported by this endpoint")
 
-func (f *TxDropper) PublishTransaction(ctx context.Context, tx *types.Transaction) error {
+func (f *TxDropper) PublishTransaction(ctx context.Context, tx *types.Transaction, options *ConditionalOptions) error {
+	return txDropperErr
+}
+
+func (f *TxDropper) PublishBundle(ctx context.Context, txs types.Transactions) error {
 	return txDropperErr
 }
 
@@ -228,12 +257,20 @@ func NewRedisTxForwarder(fallbackTarget string, config *ForwarderConfig) *RedisT
 	}
 }
 
-func (f *RedisTxForwarder) PublishTransaction(ctx context.Context, tx *types.Transaction) error {
+func (f *RedisTxForwarder) PublishTransaction(ctx context.Context, tx *types.Transaction, options *ConditionalOptions) error {
+	forwarder := f.getForwarder()
+	if forwarder == nil {
+		return ErrNoSequencer
+	}
+	return forwarder.PublishTransaction(ctx, tx, options)
+}
+
+func (f *RedisTxForwarder) PublishBundle(ctx context.Context, txs types.Transactions) error {
 	forwarder := f.getForwarder()
 	if forwarder == nil {
 		return ErrNoSequencer
 	}
-	return forwarder.PublishTransaction(ctx, tx)
+	return forwarder.PublishBundle(ctx, txs)
 }
 
 func (f *RedisTxForwarder) CheckHealth(ctx context.Context) error {
@@ -17,6 +17,7 @@ import (
 	"github.com/FOGRCC/fogr/staker"
 	"github.com/FOGRCC/fogr/util/containers"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/rlp"
@@ -460,6 +461,10 @@ type multiplexerBackend struct {
 	batches               []*SequencerInboxBatch
 	positionWithinMessage uint64
 
+	// the first batch being added, and the number of messages before it
+	startBatchSeqNum  uint64
+	startMessageCount fogutil.MessageIndex
+
 	ctx    context.Context
 	client fogutil.L1Interface
 	inbox  *InboxTracker
@@ -498,7 +503,34 @@ func (b *multiplexerBackend) ReadDelayedInbox(seqNum uint64) (*fogos.L1IncomingM
 	return b.inbox.GetDelayedMessage(seqNum)
 }
 
+func (b *multiplexerBackend) GetBatchStartfogosVersion() (uint64, error) {
+	if b.batchSeqNum != b.startBatchSeqNum {
+		// The batch's predecessors are being added with it, so their blocks haven't been made yet.
+		return 0, errBatchStartfogosVersionUnknown
+	}
+	return b.inbox.fogosVersionAfterMessages(b.startMessageCount)
+}
+
 var delayedMessagesMismatch = errors.New("sequencer batch delayed messages missing or different")
+var errBatchStartfogosVersionUnknown = errors.New("fogOS version at the start of the sequencer batch isn't known until its previous block is made")
+
+// fogosVersionAfterMessages returns the fogOS version after the block of the last of the given count of messages.
+func (t *InboxTracker) fogosVersionAfterMessages(count fogutil.MessageIndex) (uint64, error) {
+	if count == 0 {
+		return 0, nil
+	}
+	bc := t.txStreamer.bc
+	blockNum := fogutil.MessageCountToBlockNumber(count, bc.Config().FOGChainParams.GenesisBlockNum)
+	header := bc.GetHeaderByNumber(uint64(blockNum))
+	if header == nil {
+		return 0, errBatchStartfogosVersionUnknown
+	}
+	extraInfo, err := types.DeserializeHeaderExtraInformation(header)
+	if err != nil {
+		return 0, err
+	}
+	return extraInfo.fogOSFormatVersion, nil
+}
 
 func (t *InboxTracker) AddSequencerBatches(ctx context.Context, client fogutil.L1Interface, batches []*SequencerInboxBatch) error {
 	if len(batches) == 0 {
@@ -558,6 +590,9 @@ func (t *InboxTracker) AddSequencerBatches(ctx context.Context, client fogutil.L
 		batchSeqNum: batches[0].SequenceNumber,
 		batches:     batches,
 
+		startBatchSeqNum:  batches[0].SequenceNumber,
+		startMessageCount: prevbatchmeta.MessageCount,
+
 		inbox:  t,
 		ctx:    ctx,
 		client: client,
@@ -565,12 +600,20 @@ func (t *InboxTracker) AddSequencerBatches(ctx context.Context, client fogutil.L
 	multiplexer := fogstate.NewInboxMultiplexer(backend, prevbatchmeta.DelayedMessageCount, t.das, fogstate.KeysetValidate)
 	batchMessageCounts := make(map[uint64]fogutil.MessageIndex)
 	currentpos := prevbatchmeta.MessageCount + 1
+	var deferredErr error
 	for {
 		if len(backend.batches) == 0 {
 			break
 		}
 		batchSeqNum := backend.batches[0].SequenceNumber
 		msg, err := multiplexer.Pop(ctx)
+		if errors.Is(err, errBatchStartfogosVersionUnknown) && batchSeqNum > startPos {
+			// Add the batches before this one, then read this one once their blocks are made.
+			batches = batches[:batchSeqNum-startPos]
+			pos = batchSeqNum
+			deferredErr = err
+			break
+		}
 		if err != nil {
 			return err
 		}
@@ -686,7 +729,7 @@ func (t *InboxTracker) AddSequencerBatches(ctx context.Context, client fogutil.L
 		}
 	}
 
-	return nil
+	return deferredErr
 }
 
 func (t *InboxTracker) ReorgDelayedTo(count uint64, canReorgBatches bool) error {
@@ -643,6 +643,7 @@ type Node struct {
 	SeqCoordinator          *SeqCoordinator
 	MaintenanceRunner       *MaintenanceRunner
 	DASLifecycleManager     *das.LifecycleManager
+	DASAggregator           *das.Aggregator
 	ClassicOutboxRetriever  *ClassicOutboxRetriever
 	SyncMonitor             *SyncMonitor
 	configFetcher           ConfigFetcher
@@ -886,6 +887,7 @@ func createNodeImpl(
 			coordinator,
 			maintenanceRunner,
 			nil,
+			nil,
 			classicOutbox,
 			syncMonitor,
 			configFetcher,
@@ -908,9 +910,10 @@ func createNodeImpl(
 	var daWriter das.DataAvailabilityServiceWriter
 	var daReader das.DataAvailabilityServiceReader
 	var dasLifecycleManager *das.LifecycleManager
+	var dasAggregator *das.Aggregator
 	if config.DataAvailability.Enable {
 		if config.BatchPoster.Enable {
-			daWriter, daReader, dasLifecycleManager, err = das.CreateBatchPosterDAS(ctx, &config.DataAvailability, dataSigner, l1client, deployInfo.SequencerInbox)
+			daWriter, dasAggregator, daReader, dasLifecycleManager, err = das.CreateBatchPosterDAS(ctx, &config.DataAvailability, dataSigner, l1client, deployInfo.SequencerInbox)
 			if err != nil {
 				return nil, err
 			}
@@ -1035,7 +1038,7 @@ func createNodeImpl(
 		if txOpts == nil {
 			return nil, errors.New("batchposter, but no TxOpts")
 		}
-		batchPoster, err = NewBatchPoster(l1Reader, inboxTracker, txStreamer, syncMonitor, func() *BatchPosterConfig { return &configFetcher.Get().BatchPoster }, deployInfo, txOpts, daWriter)
+		batchPoster, err = NewBatchPoster(l1Reader, inboxTracker, txStreamer, syncMonitor, func() *BatchPosterConfig { return &configFetcher.Get().BatchPoster }, deployInfo, txOpts, daWriter, rawdb.NewTable(fogDb, dataPosterPrefix))
 		if err != nil {
 			return nil, err
 		}
@@ -1069,6 +1072,7 @@ func createNodeImpl(
 		coordinator,
 		maintenanceRunner,
 		dasLifecycleManager,
+		dasAggregator,
 		classicOutbox,
 		syncMonitor,
 		configFetcher,
@@ -1119,12 +1123,41 @@ func CreateNode(
 		})
 	}
 
+	if currentNode.DASAggregator != nil {
+		apis = append(apis, rpc.API{
+			Namespace: "fogr",
+			Version:   "1.0",
+			Service:   &DASAggregatorAPI{agg: currentNode.DASAggregator},
+			Public:    false,
+		})
+	}
+
+	if currentNode.BatchPoster != nil {
+		apis = append(apis, rpc.API{
+			Namespace: "fogr",
+			Version:   "1.0",
+			Service:   &BatchPosterAPI{batchPoster: currentNode.BatchPoster},
+			Public:    false,
+		})
+	}
+
 	apis = append(apis, rpc.API{
 		Namespace: "fogr",
 		Version:   "1.0",
 		Service:   &fogAPI{currentNode.TxPublisher},
 		Public:    false,
 	})
+	publisher := currentNode.TxPublisher
+	if preChecker, ok := publisher.(*TxPreChecker); ok {
+		publisher = preChecker.TransactionPublisher
+	}
+	sequencer, _ := publisher.(*Sequencer)
+	apis = append(apis, rpc.API{
+		Namespace: "fog",
+		Version:   "1.0",
+		Service:   &SequencerAPI{currentNode.TxPublisher, sequencer},
+		Public:    false,
+	})
 	config := configFetcher.Get()
 	apis = append(apis, rpc.API{
 		Namespace: "fogdebug",
@@ -28,6 +28,7 @@ import (
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/ethereum/go-ethereum/params"
@@ -44,6 +45,8 @@ var (
 	nonceFailureCacheOverflowCounter = metrics.NewRegisteredGauge("fogr/sequencer/noncefailurecache/overflow", nil)
 	blockCreationTimer               = metrics.NewRegisteredTimer("fogr/sequencer/block/creation", nil)
 	successfulBlocksCounter          = metrics.NewRegisteredCounter("fogr/sequencer/block/successful", nil)
+	conditionalTxRejectedCounter     = metrics.NewRegisteredCounter("fogr/sequencer/conditionaltx/rejected", nil)
+	conditionalTxAcceptedCounter     = metrics.NewRegisteredCounter("fogr/sequencer/conditionaltx/accepted", nil)
 )
 
 type SequencerConfig struct {
@@ -59,6 +62,12 @@ type SequencerConfig struct {
 	MaxTxDataSize               int                      `koanf:"max-tx-data-size" reload:"hot"`
 	NonceFailureCacheSize       int                      `koanf:"nonce-failure-cache-size" reload:"hot"`
 	NonceFailureCacheExpiry     time.Duration            `koanf:"nonce-failure-cache-expiry" reload:"hot"`
+	Ordering                    string                   `koanf:"ordering" reload:"hot"`
+	OrderingWindow              time.Duration            `koanf:"ordering-window" reload:"hot"`
+	MaxBundleSize               int                      `koanf:"max-bundle-size" reload:"hot"`
+	MaxFailedBundles            int                      `koanf:"max-failed-bundles" reload:"hot"`
+	FailedBundleInterval        time.Duration            `koanf:"failed-bundle-interval" reload:"hot"`
+	TxAdmission                 TxAdmissionConfig        `koanf:"tx-admission" reload:"hot"`
 	Dangerous                   DangerousSequencerConfig `koanf:"dangerous"`
 }
 
@@ -72,7 +81,16 @@ func (c *SequencerConfig) Validate() error {
 			return fmt.Errorf("sequencer sender whitelist entry \"%v\" is not a valid address", address)
 		}
 	}
-	return nil
+	if _, err := NewTxOrderingPolicy(c); err != nil {
+		return err
+	}
+	if c.MaxBundleSize < 0 {
+		return errors.New("sequencer max-bundle-size must not be negative")
+	}
+	if c.MaxBundleSize > 0 && (c.MaxFailedBundles <= 0 || c.FailedBundleInterval <= 0) {
+		return errors.New("sequencer bundles require a positive max-failed-bundles and failed-bundle-interval")
+	}
+	return c.TxAdmission.Validate()
 }
 
 type SequencerConfigFetcher func() *SequencerConfig
@@ -91,6 +109,12 @@ var DefaultSequencerConfig = SequencerConfig{
 	MaxTxDataSize:           95000,
 	NonceFailureCacheSize:   1024,
 	NonceFailureCacheExpiry: time.Second,
+	Ordering:                FCFSOrderingName,
+	OrderingWindow:          time.Millisecond * 50,
+	MaxBundleSize:           0,
+	MaxFailedBundles:        3,
+	FailedBundleInterval:    time.Minute,
+	TxAdmission:             DefaultTxAdmissionConfig,
 }
 
 var TestSequencerConfig = SequencerConfig{
@@ -107,6 +131,12 @@ var TestSequencerConfig = SequencerConfig{
 	MaxTxDataSize:               95000,
 	NonceFailureCacheSize:       1024,
 	NonceFailureCacheExpiry:     time.Second,
+	Ordering:                    FCFSOrderingName,
+	OrderingWindow:              time.Millisecond * 10,
+	MaxBundleSize:               16,
+	MaxFailedBundles:            3,
+	FailedBundleInterval:        time.Minute,
+	TxAdmission:                 DefaultTxAdmissionConfig,
 }
 
 func SequencerConfigAddOptions(prefix string, f *flag.FlagSet) {
@@ -122,6 +152,12 @@ func SequencerConfigAddOptions(prefix string, f *flag.FlagSet) {
 	f.Int(prefix+".max-tx-data-size", DefaultSequencerConfig.MaxTxDataSize, "maximum transaction size the sequencer will accept")
 	f.Int(prefix+".nonce-failure-cache-size", DefaultSequencerConfig.NonceFailureCacheSize, "number of transactions with too high of a nonce to keep in memory while waiting for their predecessor")
 	f.Duration(prefix+".nonce-failure-cache-expiry", DefaultSequencerConfig.NonceFailureCacheExpiry, "maximum amount of time to wait for a predecessor before rejecting a tx with nonce too high")
+	f.String(prefix+".ordering", DefaultSequencerConfig.Ordering, "how to order the transactions in each block, either fcfs (first come, first served) or priority-fee (by effective tip, then arrival)")
+	f.Duration(prefix+".ordering-window", DefaultSequencerConfig.OrderingWindow, "with priority-fee ordering, how long to collect transactions for a block before ordering them")
+	f.Int(prefix+".max-bundle-size", DefaultSequencerConfig.MaxBundleSize, "maximum number of transactions in a bundle (0 = bundles disabled)")
+	f.Int(prefix+".max-failed-bundles", DefaultSequencerConfig.MaxFailedBundles, "the most failed bundles to accept from each sender per failed bundle interval, after which their bundles are rejected (must be positive if bundles are enabled)")
+	f.Duration(prefix+".failed-bundle-interval", DefaultSequencerConfig.FailedBundleInterval, "the interval the failed bundle limit applies to")
+	TxAdmissionConfigAddOptions(prefix+".tx-admission", f)
 	DangerousSequencerConfigAddOptions(prefix+".dangerous", f)
 }
 
@@ -130,6 +166,8 @@ type txQueueItem struct {
 	resultChan     chan<- error
 	returnedResult bool
 	ctx            context.Context
+	arrival        time.Time
+	options        *ConditionalOptions
 }
 
 func (i *txQueueItem) returnResult(err error) {
@@ -239,6 +277,8 @@ type Sequencer struct {
 
 	txStreamer      *TransactionStreamer
 	txQueue         chan txQueueItem
+	bundleQueue     chan bundleQueueItem
+	nextBundle      *bundleQueueItem // a bundle received while buffering a block, to sequence right after it
 	txRetryQueue    containers.Queue[txQueueItem]
 	l1Reader        *headerreader.HeaderReader
 	config          SequencerConfigFetcher
@@ -246,6 +286,15 @@ type Sequencer struct {
 	nonceCache      *nonceCache
 	nonceFailures   *containers.LruCache[addressAndNonce, *nonceFailure]
 	onForwarderSet  chan struct{}
+	queuedTxs       *queuedTxTracker
+	failedBundles   *failedBundleTracker
+
+	admissionPolicies *txAdmissionPolicies
+	// the state the current transaction is executed on, for the admission policies' post-execution hooks
+	admissionStateDB *state.StateDB
+	// the senders' next nonces after the transactions in the block being built,
+	// whose nonce failures are revived once the block is committed
+	blockNonces []addressAndNonce
 
 	L1BlockAndTimeMutex sync.Mutex
 	l1BlockNumber       uint64
@@ -275,6 +324,7 @@ func NewSequencer(txStreamer *TransactionStreamer, l1Reader *headerreader.Header
 	s := &Sequencer{
 		txStreamer:      txStreamer,
 		txQueue:         make(chan txQueueItem, config.QueueSize),
+		bundleQueue:     make(chan bundleQueueItem, config.QueueSize),
 		l1Reader:        l1Reader,
 		config:          configFetcher,
 		senderWhitelist: senderWhitelist,
@@ -283,8 +333,11 @@ func NewSequencer(txStreamer *TransactionStreamer, l1Reader *headerreader.Header
 		l1Timestamp:     0,
 		pauseChan:       nil,
 		onForwarderSet:  make(chan struct{}, 1),
+		queuedTxs:       newQueuedTxTracker(),
+		failedBundles:   newFailedBundleTracker(),
 	}
 	s.nonceFailures = containers.NewLruCacheWithOnEvict(config.NonceCacheSize, s.onNonceFailureEvict)
+	s.admissionPolicies = newTxAdmissionPolicies(func() *TxAdmissionConfig { return &configFetcher().TxAdmission })
 	txStreamer.EnableReorgSequencing()
 	return s, nil
 }
@@ -309,7 +362,7 @@ func (s *Sequencer) onNonceFailureEvict(_ addressAndNonce, failure *nonceFailure
 		//   - We don't need the context because queueItem has its own.
 		//   - The RPC handler is on a separate StopWaiter anyways -- we should respect its context.
 		s.LaunchUntrackedThread(func() {
-			err = forwarder.PublishTransaction(queueItem.ctx, queueItem.tx)
+			err = forwarder.PublishTransaction(queueItem.ctx, queueItem.tx, queueItem.options)
 			queueItem.returnResult(err)
 		})
 	} else {
@@ -327,18 +380,7 @@ func (s *Sequencer) ctxWithQueueTimeout(inctx context.Context) (context.Context,
 	return context.WithTimeout(inctx, timeout)
 }
 
-func (s *Sequencer) PublishTransaction(parentCtx context.Context, tx *types.Transaction) error {
-	sequencerBacklogGauge.Inc(1)
-	defer sequencerBacklogGauge.Dec(1)
-
-	_, forwarder := s.GetPauseAndForwarder()
-	if forwarder != nil {
-		err := forwarder.PublishTransaction(parentCtx, tx)
-		if !errors.Is(err, ErrNoSequencer) {
-			return err
-		}
-	}
-
+func (s *Sequencer) checkPublishable(tx *types.Transaction) error {
 	if len(s.senderWhitelist) > 0 {
 		signer := types.LatestSigner(s.txStreamer.bc.Config())
 		sender, err := types.Sender(signer, tx)
@@ -354,6 +396,29 @@ func (s *Sequencer) PublishTransaction(parentCtx context.Context, tx *types.Tran
 		// Should be unreachable due to UnmarshalBinary not accepting FOGR internal txs
 		return types.ErrTxTypeNotSupported
 	}
+	return nil
+}
+
+func (s *Sequencer) PublishTransaction(parentCtx context.Context, tx *types.Transaction, options *ConditionalOptions) error {
+	sequencerBacklogGauge.Inc(1)
+	defer sequencerBacklogGauge.Dec(1)
+
+	_, forwarder := s.GetPauseAndForwarder()
+	if forwarder != nil {
+		err := forwarder.PublishTransaction(parentCtx, tx, options)
+		if !errors.Is(err, ErrNoSequencer) {
+			return err
+		}
+	}
+
+	if err := s.checkPublishable(tx); err != nil {
+		return err
+	}
+	if options != nil {
+		if err := options.Validate(); err != nil {
+			return err
+		}
+	}
 
 	ctx, cancelFunc := s.ctxWithQueueTimeout(parentCtx)
 	defer cancelFunc()
@@ -364,7 +429,11 @@ func (s *Sequencer) PublishTransaction(parentCtx context.Context, tx *types.Tran
 		resultChan,
 		false,
 		ctx,
+		time.Now(),
+		options,
 	}
+	s.queuedTxs.add(&queueItem)
+	defer s.queuedTxs.remove(tx)
 	select {
 	case s.txQueue <- queueItem:
 	case <-ctx.Done():
@@ -379,7 +448,7 @@ func (s *Sequencer) PublishTransaction(parentCtx context.Context, tx *types.Tran
 	}
 }
 
-func (s *Sequencer) preTxFilter(_ *params.ChainConfig, header *types.Header, statedb *state.StateDB, _ *fogosState.fogosState, tx *types.Transaction, sender common.Address) error {
+func (s *Sequencer) preTxFilter(_ *params.ChainConfig, header *types.Header, statedb *state.StateDB, _ *fogosState.fogosState, tx *types.Transaction, options *ConditionalOptions, sender common.Address) error {
 	if s.nonceCache.Caching() {
 		stateNonce := s.nonceCache.Get(header, statedb, sender)
 		err := MakeNonceError(sender, tx.Nonce(), stateNonce)
@@ -388,18 +457,52 @@ func (s *Sequencer) preTxFilter(_ *params.ChainConfig, header *types.Header, sta
 			return err
 		}
 	}
-	return nil
+	if options != nil {
+		err := options.Check(header, statedb)
+		if err != nil {
+			conditionalTxRejectedCounter.Inc(1)
+			return err
+		}
+		conditionalTxAcceptedCounter.Inc(1)
+	}
+	s.admissionStateDB = statedb
+	return s.admissionPolicies.preTxFilter(s.config().TxAdmission.Policies, header, statedb, tx, sender)
 }
 
 func (s *Sequencer) postTxFilter(header *types.Header, _ *fogosState.fogosState, tx *types.Transaction, sender common.Address, dataGas uint64, result *core.ExecutionResult) error {
 	if result.Err != nil && result.UsedGas > dataGas && result.UsedGas-dataGas <= s.config().MaxRevertGasReject {
 		return FOGR.NewRevertReason(result)
 	}
+	policies := s.config().TxAdmission.Policies
+	err := s.admissionPolicies.postTxFilter(policies, header, s.admissionStateDB, tx, sender, result)
+	if err != nil {
+		return err
+	}
+	s.admissionPolicies.txSequenced(policies, header, tx, sender)
 	newNonce := tx.Nonce() + 1
 	s.nonceCache.Update(header, sender, newNonce)
-	newAddrAndNonce := addressAndNonce{sender, newNonce}
-	nonceFailure, haveNonceFailure := s.nonceFailures.Get(newAddrAndNonce)
-	if haveNonceFailure {
+	s.blockNonces = append(s.blockNonces, addressAndNonce{sender, newNonce})
+	return nil
+}
+
+// startBlock is called before each block is built. Nothing is kept from a block that was discarded.
+func (s *Sequencer) startBlock(config *SequencerConfig) {
+	s.nonceCache.Resize(config.NonceCacheSize) // Would probably be better in a config hook but this is basically free
+	s.nonceCache.BeginNewBlock()
+	s.admissionPolicies.startBlock()
+	s.blockNonces = s.blockNonces[:0]
+}
+
+// commitBlock is called once the block being built has been committed.
+func (s *Sequencer) commitBlock(block *types.Block) {
+	successfulBlocksCounter.Inc(1)
+	s.nonceCache.Finalize(block)
+	s.admissionPolicies.commitBlock()
+	for _, newAddrAndNonce := range s.blockNonces {
+		nonceFailure, haveNonceFailure := s.nonceFailures.Get(newAddrAndNonce)
+		if !haveNonceFailure {
+			continue
+		}
 		nonceFailure.revived = true // prevent the expiry hook from taking effect
 		s.nonceFailures.Remove(newAddrAndNonce)
 		// Immediately check if the transaction submission has been canceled
@@ -408,10 +511,10 @@ func (s *Sequencer) postTxFilter(header *types.Header, _ *fogosState.fogosState,
 			nonceFailure.queueItem.returnResult(err)
 		} else {
 			// Add this transaction (whose nonce is now correct) back into the queue
-			s.txRetryQueue.Push(nonceFailure.queueItem)
+			s.pushRetry(nonceFailure.queueItem, "predecessor was sequenced")
 		}
 	}
-	return nil
+	s.blockNonces = s.blockNonces[:0]
 }
 
 func (s *Sequencer) CheckHealth(ctx context.Context) error {
@@ -500,31 +603,38 @@ func (s *Sequencer) GetPauseAndForwarder() (chan struct{}, *TxForwarder) {
 	return s.pauseChan, s.forwarder
 }
 
-// only called from createBlock, may be paused
-func (s *Sequencer) handleInactive(ctx context.Context, queueItems []txQueueItem) bool {
-	var forwarder *TxForwarder
+// waitUntilUnpaused returns the forwarder to use if the sequencer is inactive once it's unpaused,
+// or stopped if the context was canceled while it was paused.
+func (s *Sequencer) waitUntilUnpaused(ctx context.Context) (forwarder *TxForwarder, stopped bool) {
 	for {
 		var pause chan struct{}
 		pause, forwarder = s.GetPauseAndForwarder()
 		if pause == nil {
-			if forwarder == nil {
-				return false
-			}
-			// if forwarding: jump to next loop
-			break
+			return forwarder, false
 		}
 		// if paused: wait till unpaused
 		select {
 		case <-ctx.Done():
-			return true
+			return nil, true
 		case <-pause:
 		}
 	}
+}
+
+// only called from createBlock, may be paused
+func (s *Sequencer) handleInactive(ctx context.Context, queueItems []txQueueItem) bool {
+	forwarder, stopped := s.waitUntilUnpaused(ctx)
+	if stopped {
+		return true
+	}
+	if forwarder == nil {
+		return false
+	}
 	publishResults := make(chan *txQueueItem, len(queueItems))
 	for _, item := range queueItems {
 		item := item
 		go func() {
-			res := forwarder.PublishTransaction(item.ctx, item.tx)
+			res := forwarder.PublishTransaction(item.ctx, item.tx, item.options)
 			if errors.Is(res, ErrNoSequencer) {
 				publishResults <- &item
 			} else {
@@ -536,7 +646,7 @@ func (s *Sequencer) handleInactive(ctx context.Context, queueItems []txQueueItem
 	for range queueItems {
 		remainingItem := <-publishResults
 		if remainingItem != nil {
-			s.txRetryQueue.Push(*remainingItem)
+			s.pushRetry(*remainingItem, "no sequencer to forward to")
 		}
 	}
 	// Evict any leftover nonce failures, forwarding them
@@ -546,12 +656,24 @@ func (s *Sequencer) handleInactive(ctx context.Context, queueItems []txQueueItem
 
 var sequencerInternalError = errors.New("sequencer internal error")
 
-func (s *Sequencer) makeSequencingHooks() *fogos.SequencingHooks {
+// makeSequencingHooks returns the hooks to sequence the queue items' transactions with.
+func (s *Sequencer) makeSequencingHooks(queueItems []txQueueItem) *fogos.SequencingHooks {
+	conditions := make(map[*types.Transaction]*ConditionalOptions)
+	for _, queueItem := range queueItems {
+		if queueItem.options != nil {
+			conditions[queueItem.tx] = queueItem.options
+		}
+	}
 	return &fogos.SequencingHooks{
-		PreTxFilter:            s.preTxFilter,
+		PreTxFilter: func(chainConfig *params.ChainConfig, header *types.Header, statedb *state.StateDB, state *fogosState.fogosState, tx *types.Transaction, sender common.Address) error {
+			return s.preTxFilter(chainConfig, header, statedb, state, tx, conditions[tx], sender)
+		},
 		PostTxFilter:           s.postTxFilter,
 		DiscardInvalidTxsEarly: true,
 		TxErrors:               []error{},
+		TxTracer: func(tx *types.Transaction, sender common.Address) vm.EVMLogger {
+			return s.admissionPolicies.txTracer(s.config().TxAdmission.Policies, tx, sender)
+		},
 	}
 }
 
@@ -570,6 +692,20 @@ func (s *Sequencer) expireNonceFailures() *time.Timer {
 	}
 }
 
+// dropExpiredQueueItems returns the queue items whose submissions haven't been canceled or timed out,
+// which they may have been while the ordering policy buffered them.
+func (s *Sequencer) dropExpiredQueueItems(queueItems []txQueueItem) []txQueueItem {
+	outputQueueItems := queueItems[:0]
+	for _, queueItem := range queueItems {
+		if err := queueItem.ctx.Err(); err != nil {
+			queueItem.returnResult(err)
+			continue
+		}
+		outputQueueItems = append(outputQueueItems, queueItem)
+	}
+	return outputQueueItems
+}
+
 // There's no guarantee that returned tx nonces will be correct
 func (s *Sequencer) precheckNonces(queueItems []txQueueItem) []txQueueItem {
 	bc := s.txStreamer.bc
@@ -623,6 +759,7 @@ func (s *Sequencer) precheckNonces(queueItems []txQueueItem) []txQueueItem {
 					revivingFailure.queueItem.returnResult(err)
 				} else {
 					nextQueueItem = &revivingFailure.queueItem
+					s.queuedTxs.setStatus(nextQueueItem.tx, QueuedTxStatusSequencing, "")
 				}
 			}
 		} else {
@@ -633,6 +770,7 @@ func (s *Sequencer) precheckNonces(queueItems []txQueueItem) []txQueueItem {
 				if exists {
 					queueItem.returnResult(err)
 				} else {
+					s.queuedTxs.setStatus(tx, QueuedTxStatusNonceFailure, err.Error())
 					evicted := s.nonceFailures.Add(key, &nonceFailure{
 						queueItem: queueItem,
 						nonceErr:  err,
@@ -654,8 +792,38 @@ func (s *Sequencer) precheckNonces(queueItems []txQueueItem) []txQueueItem {
 	return outputQueueItems
 }
 
+// nextMessageHeader returns the header for the next sequenced message,
+// or nil if the L1 block is unknown or too far from the local clock to sequence.
+func (s *Sequencer) nextMessageHeader(config *SequencerConfig) *fogos.L1IncomingMessageHeader {
+	timestamp := time.Now().Unix()
+	s.L1BlockAndTimeMutex.Lock()
+	l1Block := s.l1BlockNumber
+	l1Timestamp := s.l1Timestamp
+	s.L1BlockAndTimeMutex.Unlock()
+
+	if s.l1Reader != nil && (l1Block == 0 || math.Abs(float64(l1Timestamp)-float64(timestamp)) > config.MaxAcceptableTimestampDelta.Seconds()) {
+		log.Error(
+			"cannot sequence: unknown L1 block or L1 timestamp too far from local clock time",
+			"l1Block", l1Block,
+			"l1Timestamp", time.Unix(int64(l1Timestamp), 0),
+			"localTimestamp", time.Unix(int64(timestamp), 0),
+		)
+		return nil
+	}
+
+	return &fogos.L1IncomingMessageHeader{
+		Kind:        fogos.L1MessageType_L2Message,
+		Poster:      l1pricing.BatchPosterAddress,
+		BlockNumber: l1Block,
+		Timestamp:   uint64(timestamp),
+		RequestId:   nil,
+		L1BaseFee:   nil,
+	}
+}
+
 func (s *Sequencer) createBlock(ctx context.Context) (returnValue bool) {
 	var queueItems []txQueueItem
+	var bundle *bundleQueueItem
 	var totalBatchSize int
 
 	defer func() {
@@ -668,6 +836,9 @@ func (s *Sequencer) createBlock(ctx context.Context) (returnValue bool) {
 					item.returnResult(sequencerInternalError)
 				}
 			}
+			if bundle != nil && !bundle.returnedResult {
+				bundle.returnResult(sequencerInternalError)
+			}
 			// Wait for the MaxBlockSpeed until attempting to create a block again
 			returnValue = true
 		}
@@ -675,6 +846,11 @@ func (s *Sequencer) createBlock(ctx context.Context) (returnValue bool) {
 	defer nonceFailureCacheSizeGauge.Update(int64(s.nonceFailures.Len()))
 
 	config := s.config()
+	orderingPolicy, err := NewTxOrderingPolicy(config)
+	if err != nil {
+		log.Error("invalid sequencer ordering, using first come first served", "err", err)
+		orderingPolicy = fcfsOrdering{}
+	}
 
 	// Clear out old nonceFailures
 	s.nonceFailures.Resize(config.NonceFailureCacheSize)
@@ -686,6 +862,15 @@ func (s *Sequencer) createBlock(ctx context.Context) (returnValue bool) {
 		}
 	}()
 
+	if s.nextBundle != nil {
+		// A bundle arrived while the last block's transactions were buffered
+		bundle = s.nextBundle
+		s.nextBundle = nil
+		return s.createBundleBlock(ctx, config, bundle)
+	}
+
+	// when to stop waiting for more transactions to order, if the ordering policy buffers them
+	var bufferUntil time.Time
 	for {
 		var queueItem txQueueItem
 		if s.txRetryQueue.Len() > 0 {
@@ -697,6 +882,10 @@ func (s *Sequencer) createBlock(ctx context.Context) (returnValue bool) {
 			}
 			select {
 			case queueItem = <-s.txQueue:
+			case item := <-s.bundleQueue:
+				// Bundles are sequenced in blocks of their own
+				bundle = &item
+				return s.createBundleBlock(ctx, config, bundle)
 			case <-nextNonceExpiryChan:
 				// No need to stop the previous timer since it already elapsed
 				nextNonceExpiryTimer = s.expireNonceFailures()
@@ -711,6 +900,44 @@ func (s *Sequencer) createBlock(ctx context.Context) (returnValue bool) {
 			case <-ctx.Done():
 				return false
 			}
+		} else if wait := time.Until(bufferUntil); wait > 0 {
+			var nextNonceExpiryChan <-chan time.Time
+			if nextNonceExpiryTimer != nil {
+				nextNonceExpiryChan = nextNonceExpiryTimer.C
+			}
+			// Only one bundle is held back for after this block, the rest wait in the bundle queue
+			var bundleQueue <-chan bundleQueueItem
+			if s.nextBundle == nil {
+				bundleQueue = s.bundleQueue
+			}
+			done := false
+			received := false
+			bufferTimer := time.NewTimer(wait)
+			select {
+			case queueItem = <-s.txQueue:
+				received = true
+			case item := <-bundleQueue:
+				// Bundles are sequenced in blocks of their own, so this one goes right after this block
+				s.nextBundle = &item
+			case <-nextNonceExpiryChan:
+				nextNonceExpiryTimer = s.expireNonceFailures()
+			case <-bufferTimer.C:
+				done = true
+			case <-ctx.Done():
+				bufferTimer.Stop()
+				// Hand the buffered transactions back, for StopAndWait to forward or return
+				for _, item := range queueItems {
+					s.pushRetry(item, "sequencer stopping")
+				}
+				return false
+			}
+			bufferTimer.Stop()
+			if done {
+				break
+			}
+			if !received {
+				continue
+			}
 		} else {
 			done := false
 			select {
@@ -722,6 +949,7 @@ func (s *Sequencer) createBlock(ctx context.Context) (returnValue bool) {
 				break
 			}
 		}
+		s.queuedTxs.setStatus(queueItem.tx, QueuedTxStatusSequencing, "")
 		err := queueItem.ctx.Err()
 		if err != nil {
 			queueItem.returnResult(err)
@@ -739,17 +967,21 @@ func (s *Sequencer) createBlock(ctx context.Context) (returnValue bool) {
 		}
 		if totalBatchSize+len(txBytes) > config.MaxTxDataSize {
 			// This tx would be too large to add to this batch
-			s.txRetryQueue.Push(queueItem)
+			s.pushRetry(queueItem, "block full")
 			// End the batch here to put this tx in the next one
 			break
 		}
 		totalBatchSize += len(txBytes)
 		queueItems = append(queueItems, queueItem)
+		if len(queueItems) == 1 {
+			bufferUntil = queueItem.arrival.Add(orderingPolicy.BufferWindow())
+		}
 	}
 
-	s.nonceCache.Resize(config.NonceCacheSize) // Would probably be better in a config hook but this is basically free
-	s.nonceCache.BeginNewBlock()
+	s.startBlock(config)
+	queueItems = s.dropExpiredQueueItems(queueItems)
 	queueItems = s.precheckNonces(queueItems)
+	queueItems = s.orderQueueItems(orderingPolicy, queueItems)
 	txes := make([]*types.Transaction, len(queueItems))
 	for i, queueItem := range queueItems {
 		txes[i] = queueItem.tx
@@ -759,32 +991,12 @@ func (s *Sequencer) createBlock(ctx context.Context) (returnValue bool) {
 		return false
 	}
 
-	timestamp := time.Now().Unix()
-	s.L1BlockAndTimeMutex.Lock()
-	l1Block := s.l1BlockNumber
-	l1Timestamp := s.l1Timestamp
-	s.L1BlockAndTimeMutex.Unlock()
-
-	if s.l1Reader != nil && (l1Block == 0 || math.Abs(float64(l1Timestamp)-float64(timestamp)) > config.MaxAcceptableTimestampDelta.Seconds()) {
-		log.Error(
-			"cannot sequence: unknown L1 block or L1 timestamp too far from local clock time",
-			"l1Block", l1Block,
-			"l1Timestamp", time.Unix(int64(l1Timestamp), 0),
-			"localTimestamp", time.Unix(int64(timestamp), 0),
-		)
+	header := s.nextMessageHeader(config)
+	if header == nil {
 		return false
 	}
 
-	header := &fogos.L1IncomingMessageHeader{
-		Kind:        fogos.L1MessageType_L2Message,
-		Poster:      l1pricing.BatchPosterAddress,
-		BlockNumber: l1Block,
-		Timestamp:   uint64(timestamp),
-		RequestId:   nil,
-		L1BaseFee:   nil,
-	}
-
-	hooks := s.makeSequencingHooks()
+	hooks := s.makeSequencingHooks(queueItems)
 	start := time.Now()
 	block, err := s.txStreamer.SequenceTransactions(header, txes, hooks)
 	elapsed := time.Since(start)
@@ -808,7 +1020,7 @@ func (s *Sequencer) createBlock(ctx context.Context) (returnValue bool) {
 		}
 		// try to add back to queue otherwise
 		for _, item := range queueItems {
-			s.txRetryQueue.Push(item)
+			s.pushRetry(item, "sequencer changed roles")
 		}
 		return false
 	}
@@ -816,7 +1028,7 @@ func (s *Sequencer) createBlock(ctx context.Context) (returnValue bool) {
 		if errors.Is(err, context.Canceled) {
 			// thread closed. We'll later try to forward these messages.
 			for _, item := range queueItems {
-				s.txRetryQueue.Push(item)
+				s.pushRetry(item, "sequencer stopping")
 			}
 			return true // don't return failure to avoid retrying immediately
 		}
@@ -828,8 +1040,7 @@ func (s *Sequencer) createBlock(ctx context.Context) (returnValue bool) {
 	}
 
 	if block != nil {
-		successfulBlocksCounter.Inc(1)
-		s.nonceCache.Finalize(block)
+		s.commitBlock(block)
 	}
 
 	madeBlock := false
@@ -842,7 +1053,7 @@ func (s *Sequencer) createBlock(ctx context.Context) (returnValue bool) {
 			// There's not enough gas left in the block for this tx.
 			if madeBlock {
 				// There was already an earlier tx in the block; retry in a fresh block.
-				s.txRetryQueue.Push(queueItem)
+				s.pushRetry(queueItem, "block gas limit reached")
 				continue
 			}
 		}
@@ -863,6 +1074,7 @@ func (s *Sequencer) createBlock(ctx context.Context) (returnValue bool) {
 				expiry:    time.Now().Add(config.NonceFailureCacheExpiry),
 				revived:   false,
 			}
+			s.queuedTxs.setStatus(queueItem.tx, QueuedTxStatusNonceFailure, err.Error())
 			if s.nonceFailures.Add(key, value) {
 				nonceFailureCacheOverflowCounter.Inc(1)
 			}
@@ -939,6 +1151,10 @@ func (s *Sequencer) Start(ctxIn context.Context) error {
 
 func (s *Sequencer) StopAndWait() {
 	s.StopWaiter.StopAndWait()
+	if s.nextBundle != nil {
+		s.nextBundle.returnResult(ErrNoSequencer)
+		s.nextBundle = nil
+	}
 	if s.txRetryQueue.Len() == 0 && len(s.txQueue) == 0 {
 		return
 	}
@@ -970,7 +1186,7 @@ func (s *Sequencer) StopAndWait() {
 			wg.Add(1)
 			go func() {
 				defer wg.Done()
-				err := forwarder.PublishTransaction(item.ctx, item.tx)
+				err := forwarder.PublishTransaction(item.ctx, item.tx, item.options)
 				if err != nil {
 					log.Warn("failed to forward transaction while shutting down", "source", source, "err", err)
 				}
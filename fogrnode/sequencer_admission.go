@@ -0,0 +1,230 @@
+// Copyright 2021-2022, Offchain Labs, Inc.
+// For license information, see https://github.com/fogr/blob/master/LICENSE
+
+package fognode
+
+import (
+	"fmt"
+	"sync"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// TxAdmissionPolicy decides whether the sequencer admits a transaction into a block.
+// Policies are only called from the sequencer's block creation thread.
+type TxAdmissionPolicy interface {
+	// PreTxFilter is called before the transaction is executed, and rejects it by returning an error.
+	PreTxFilter(header *types.Header, statedb *state.StateDB, tx *types.Transaction, sender common.Address) error
+	// PostTxFilter is called after the transaction is executed, and reverts and rejects it by returning an error.
+	PostTxFilter(header *types.Header, statedb *state.StateDB, tx *types.Transaction, sender common.Address, result *core.ExecutionResult) error
+}
+
+// TxAdmissionBlockPolicy is implemented by admission policies that keep track of the transactions sequenced.
+// A transaction that passes a policy's PostTxFilter can still be rejected by a later policy, or dropped
+// along with its block, so such a policy only counts a transaction once its block is committed.
+type TxAdmissionBlockPolicy interface {
+	TxAdmissionPolicy
+	// StartBlock is called before each block is built, and drops the transactions of any block that wasn't committed.
+	StartBlock()
+	// TxSequenced is called once a transaction has passed every policy and been added to the block being built.
+	TxSequenced(header *types.Header, tx *types.Transaction, sender common.Address)
+	// CommitBlock is called once the block being built has been committed.
+	CommitBlock()
+}
+
+const (
+	SenderRateLimitPolicyName  = "sender-rate-limit"
+	DestinationListPolicyName  = "destination-list"
+	CalldataLimitPolicyName    = "calldata-limit"
+	AddressBlocklistPolicyName = "address-blocklist"
+)
+
+type TxAdmissionConfig struct {
+	Policies         []string               `koanf:"policies" reload:"hot"`
+	SenderRateLimit  SenderRateLimitConfig  `koanf:"sender-rate-limit" reload:"hot"`
+	DestinationList  DestinationListConfig  `koanf:"destination-list" reload:"hot"`
+	CalldataLimit    CalldataLimitConfig    `koanf:"calldata-limit" reload:"hot"`
+	AddressBlocklist AddressBlocklistConfig `koanf:"address-blocklist" reload:"hot"`
+}
+
+func TxAdmissionConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.StringSlice(prefix+".policies", DefaultTxAdmissionConfig.Policies, "the transaction admission policies to apply, in order: any of sender-rate-limit, destination-list, calldata-limit and address-blocklist, or the name of a policy registered with RegisterTxAdmissionPolicy")
+	SenderRateLimitConfigAddOptions(prefix+".sender-rate-limit", f)
+	DestinationListConfigAddOptions(prefix+".destination-list", f)
+	CalldataLimitConfigAddOptions(prefix+".calldata-limit", f)
+	AddressBlocklistConfigAddOptions(prefix+".address-blocklist", f)
+}
+
+var DefaultTxAdmissionConfig = TxAdmissionConfig{
+	Policies:         []string{},
+	SenderRateLimit:  DefaultSenderRateLimitConfig,
+	DestinationList:  DefaultDestinationListConfig,
+	CalldataLimit:    DefaultCalldataLimitConfig,
+	AddressBlocklist: DefaultAddressBlocklistConfig,
+}
+
+// Validate checks that the policies are built in or registered, and the built in policies' config.
+func (c *TxAdmissionConfig) Validate() error {
+	for _, name := range c.Policies {
+		var err error
+		switch name {
+		case SenderRateLimitPolicyName:
+			err = c.SenderRateLimit.Validate()
+		case DestinationListPolicyName:
+			err = c.DestinationList.Validate()
+		case CalldataLimitPolicyName:
+			err = c.CalldataLimit.Validate()
+		case AddressBlocklistPolicyName:
+			err = c.AddressBlocklist.Validate()
+		default:
+			if registeredTxAdmissionPolicy(name) == nil {
+				return fmt.Errorf("unknown sequencer tx admission policy %v", name)
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("sequencer tx admission policy %v: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// txAdmissionPolicies are the admission policies the sequencer can apply, by name.
+type txAdmissionPolicies struct {
+	policies  map[string]TxAdmissionPolicy
+	rejected  map[string]metrics.Counter
+	blocklist *addressBlocklistPolicy
+}
+
+func newTxAdmissionPolicies(config func() *TxAdmissionConfig) *txAdmissionPolicies {
+	p := &txAdmissionPolicies{
+		policies: make(map[string]TxAdmissionPolicy),
+		rejected: make(map[string]metrics.Counter),
+	}
+	p.add(SenderRateLimitPolicyName, newSenderRateLimitPolicy(func() *SenderRateLimitConfig { return &config().SenderRateLimit }))
+	p.add(DestinationListPolicyName, newDestinationListPolicy(func() *DestinationListConfig { return &config().DestinationList }))
+	p.add(CalldataLimitPolicyName, newCalldataLimitPolicy(func() *CalldataLimitConfig { return &config().CalldataLimit }))
+	p.blocklist = newAddressBlocklistPolicy(func() *AddressBlocklistConfig { return &config().AddressBlocklist })
+	p.add(AddressBlocklistPolicyName, p.blocklist)
+	txAdmissionPolicyRegistryMutex.Lock()
+	defer txAdmissionPolicyRegistryMutex.Unlock()
+	for name, newPolicy := range txAdmissionPolicyRegistry {
+		p.add(name, newPolicy())
+	}
+	return p
+}
+
+func (p *txAdmissionPolicies) add(name string, policy TxAdmissionPolicy) {
+	p.policies[name] = policy
+	p.rejected[name] = metrics.GetOrRegisterCounter("fogr/sequencer/admission/"+name+"/rejected", nil)
+}
+
+func (p *txAdmissionPolicies) get(name string) (TxAdmissionPolicy, error) {
+	policy, ok := p.policies[name]
+	if !ok {
+		log.Error("unknown sequencer tx admission policy, rejecting transactions", "policy", name)
+		return nil, fmt.Errorf("unknown tx admission policy %v", name)
+	}
+	return policy, nil
+}
+
+func (p *txAdmissionPolicies) preTxFilter(names []string, header *types.Header, statedb *state.StateDB, tx *types.Transaction, sender common.Address) error {
+	for _, name := range names {
+		policy, err := p.get(name)
+		if err != nil {
+			return err
+		}
+		err = policy.PreTxFilter(header, statedb, tx, sender)
+		if err != nil {
+			p.rejected[name].Inc(1)
+			return err
+		}
+	}
+	return nil
+}
+
+// txTracer returns the tracer to execute a transaction that passed preTxFilter with,
+// or nil if none of the policies need to trace it.
+func (p *txAdmissionPolicies) txTracer(names []string, tx *types.Transaction, sender common.Address) vm.EVMLogger {
+	for _, name := range names {
+		if name == AddressBlocklistPolicyName {
+			return p.blocklist.txTracer(tx, sender)
+		}
+	}
+	return nil
+}
+
+func (p *txAdmissionPolicies) startBlock() {
+	for _, policy := range p.policies {
+		if blockPolicy, ok := policy.(TxAdmissionBlockPolicy); ok {
+			blockPolicy.StartBlock()
+		}
+	}
+}
+
+func (p *txAdmissionPolicies) postTxFilter(names []string, header *types.Header, statedb *state.StateDB, tx *types.Transaction, sender common.Address, result *core.ExecutionResult) error {
+	for _, name := range names {
+		policy, err := p.get(name)
+		if err != nil {
+			return err
+		}
+		err = policy.PostTxFilter(header, statedb, tx, sender, result)
+		if err != nil {
+			p.rejected[name].Inc(1)
+			return err
+		}
+	}
+	return nil
+}
+
+// txSequenced is called once the transaction passed every check and was added to the block being built.
+func (p *txAdmissionPolicies) txSequenced(names []string, header *types.Header, tx *types.Transaction, sender common.Address) {
+	for _, name := range names {
+		if blockPolicy, ok := p.policies[name].(TxAdmissionBlockPolicy); ok {
+			blockPolicy.TxSequenced(header, tx, sender)
+		}
+	}
+}
+
+func (p *txAdmissionPolicies) commitBlock() {
+	for _, policy := range p.policies {
+		if blockPolicy, ok := policy.(TxAdmissionBlockPolicy); ok {
+			blockPolicy.CommitBlock()
+		}
+	}
+}
+
+var (
+	txAdmissionPolicyRegistryMutex sync.Mutex
+	txAdmissionPolicyRegistry      = make(map[string]func() TxAdmissionPolicy)
+)
+
+func registeredTxAdmissionPolicy(name string) func() TxAdmissionPolicy {
+	txAdmissionPolicyRegistryMutex.Lock()
+	defer txAdmissionPolicyRegistryMutex.Unlock()
+	return txAdmissionPolicyRegistry[name]
+}
+
+// RegisterTxAdmissionPolicy makes a policy available to the sequencer's tx-admission.policies config under name,
+// with newPolicy called to create it for each sequencer.
+// It must be called before the node's config is parsed, such as from an init function.
+func RegisterTxAdmissionPolicy(name string, newPolicy func() TxAdmissionPolicy) error {
+	switch name {
+	case SenderRateLimitPolicyName, DestinationListPolicyName, CalldataLimitPolicyName, AddressBlocklistPolicyName:
+		return fmt.Errorf("tx admission policy %v is built in", name)
+	}
+	txAdmissionPolicyRegistryMutex.Lock()
+	defer txAdmissionPolicyRegistryMutex.Unlock()
+	if _, exists := txAdmissionPolicyRegistry[name]; exists {
+		return fmt.Errorf("tx admission policy %v is already registered", name)
+	}
+	txAdmissionPolicyRegistry[name] = newPolicy
+	return nil
+}
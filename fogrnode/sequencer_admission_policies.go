@@ -0,0 +1,373 @@
+// Copyright 2021-2022, Offchain Labs, Inc.
+// For license information, see https://github.com/fogr/blob/master/LICENSE
+
+package fognode
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth/tracers/logger"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+type SenderRateLimitConfig struct {
+	MaxTxs   uint64        `koanf:"max-txs" reload:"hot"`
+	Interval time.Duration `koanf:"interval" reload:"hot"`
+}
+
+func SenderRateLimitConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Uint64(prefix+".max-txs", DefaultSenderRateLimitConfig.MaxTxs, "the most transactions to sequence from each sender per interval (must be positive)")
+	f.Duration(prefix+".interval", DefaultSenderRateLimitConfig.Interval, "the interval the sender rate limit applies to")
+}
+
+var DefaultSenderRateLimitConfig = SenderRateLimitConfig{
+	MaxTxs:   10,
+	Interval: time.Second,
+}
+
+func (c *SenderRateLimitConfig) Validate() error {
+	if c.MaxTxs == 0 {
+		return errors.New("max-txs must be positive")
+	}
+	if c.Interval <= 0 {
+		return errors.New("interval must be positive")
+	}
+	return nil
+}
+
+// senderRateLimitPolicy limits how many transactions each sender gets sequenced per fixed interval.
+type senderRateLimitPolicy struct {
+	config      func() *SenderRateLimitConfig
+	windowStart time.Time
+	// the transactions in committed blocks this interval
+	counts map[common.Address]uint64
+	// the transactions in the block being built
+	blockCounts map[common.Address]uint64
+}
+
+func newSenderRateLimitPolicy(config func() *SenderRateLimitConfig) *senderRateLimitPolicy {
+	return &senderRateLimitPolicy{
+		config:      config,
+		counts:      make(map[common.Address]uint64),
+		blockCounts: make(map[common.Address]uint64),
+	}
+}
+
+func (p *senderRateLimitPolicy) PreTxFilter(_ *types.Header, _ *state.StateDB, _ *types.Transaction, sender common.Address) error {
+	config := p.config()
+	if time.Since(p.windowStart) >= config.Interval {
+		p.windowStart = time.Now()
+		p.counts = make(map[common.Address]uint64)
+	}
+	if p.counts[sender]+p.blockCounts[sender] >= config.MaxTxs {
+		return fmt.Errorf("sender %v exceeded the limit of %v transactions per %v", sender, config.MaxTxs, config.Interval)
+	}
+	return nil
+}
+
+func (p *senderRateLimitPolicy) PostTxFilter(*types.Header, *state.StateDB, *types.Transaction, common.Address, *core.ExecutionResult) error {
+	return nil
+}
+
+func (p *senderRateLimitPolicy) StartBlock() {
+	p.blockCounts = make(map[common.Address]uint64)
+}
+
+func (p *senderRateLimitPolicy) TxSequenced(_ *types.Header, _ *types.Transaction, sender common.Address) {
+	p.blockCounts[sender]++
+}
+
+func (p *senderRateLimitPolicy) CommitBlock() {
+	for sender, count := range p.blockCounts {
+		p.counts[sender] += count
+	}
+	p.blockCounts = make(map[common.Address]uint64)
+}
+
+type DestinationListConfig struct {
+	File                  string        `koanf:"file" reload:"hot"`
+	Mode                  string        `koanf:"mode" reload:"hot"`
+	AllowContractCreation bool          `koanf:"allow-contract-creation" reload:"hot"`
+	ReloadInterval        time.Duration `koanf:"reload-interval" reload:"hot"`
+}
+
+func DestinationListConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.String(prefix+".file", DefaultDestinationListConfig.File, "file listing destination addresses, one per line, which is reloaded when it changes")
+	f.String(prefix+".mode", DefaultDestinationListConfig.Mode, "whether the listed destinations are the only ones allowed (allow) or are rejected (deny)")
+	f.Bool(prefix+".allow-contract-creation", DefaultDestinationListConfig.AllowContractCreation, "in allow mode, also allow contract creations")
+	f.Duration(prefix+".reload-interval", DefaultDestinationListConfig.ReloadInterval, "how often to check the file for changes")
+}
+
+var DefaultDestinationListConfig = DestinationListConfig{
+	File:                  "",
+	Mode:                  "deny",
+	AllowContractCreation: false,
+	ReloadInterval:        10 * time.Second,
+}
+
+func (c *DestinationListConfig) Validate() error {
+	if c.File == "" {
+		return errors.New("file must be set")
+	}
+	if c.Mode != "allow" && c.Mode != "deny" {
+		return fmt.Errorf("unknown mode %q", c.Mode)
+	}
+	return nil
+}
+
+// destinationListPolicy allows or denies transactions by their destination, from a list in a file.
+type destinationListPolicy struct {
+	config func() *DestinationListConfig
+	// the file the list was loaded from
+	file    string
+	modTime time.Time
+	list    map[common.Address]struct{}
+	// the file last checked for changes
+	checkedFile string
+	lastCheck   time.Time
+}
+
+func newDestinationListPolicy(config func() *DestinationListConfig) *destinationListPolicy {
+	return &destinationListPolicy{config: config}
+}
+
+func parseAddressList(data []byte) (map[common.Address]struct{}, error) {
+	list := make(map[common.Address]struct{})
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if !common.IsHexAddress(line) {
+			return nil, fmt.Errorf("invalid address %q", line)
+		}
+		list[common.HexToAddress(line)] = struct{}{}
+	}
+	return list, scanner.Err()
+}
+
+// maybeReload reloads the list if the file changed, keeping the last good list if it can't be read.
+func (p *destinationListPolicy) maybeReload(config *DestinationListConfig) {
+	if config.File == p.checkedFile && time.Since(p.lastCheck) < config.ReloadInterval {
+		return
+	}
+	p.checkedFile = config.File
+	p.lastCheck = time.Now()
+	info, err := os.Stat(config.File)
+	if err != nil {
+		log.Error("failed to check sequencer destination list file", "file", config.File, "err", err)
+		return
+	}
+	if config.File == p.file && info.ModTime().Equal(p.modTime) {
+		return
+	}
+	data, err := os.ReadFile(config.File)
+	if err == nil {
+		var list map[common.Address]struct{}
+		list, err = parseAddressList(data)
+		if err == nil {
+			p.file = config.File
+			p.modTime = info.ModTime()
+			p.list = list
+			log.Info("loaded sequencer destination list", "file", config.File, "addresses", len(list))
+			return
+		}
+	}
+	log.Error("failed to load sequencer destination list file", "file", config.File, "err", err)
+}
+
+func (p *destinationListPolicy) PreTxFilter(_ *types.Header, _ *state.StateDB, tx *types.Transaction, _ common.Address) error {
+	config := p.config()
+	p.maybeReload(config)
+	if p.list == nil || p.file != config.File {
+		return errors.New("sequencer destination list unavailable")
+	}
+	allowMode := config.Mode == "allow"
+	if tx.To() == nil {
+		if allowMode && !config.AllowContractCreation {
+			return errors.New("contract creation is not allowed")
+		}
+		return nil
+	}
+	_, listed := p.list[*tx.To()]
+	if listed != allowMode {
+		return fmt.Errorf("destination %v is not allowed", *tx.To())
+	}
+	return nil
+}
+
+func (p *destinationListPolicy) PostTxFilter(*types.Header, *state.StateDB, *types.Transaction, common.Address, *core.ExecutionResult) error {
+	return nil
+}
+
+type CalldataLimitConfig struct {
+	DefaultLimit int      `koanf:"default-limit" reload:"hot"`
+	Selectors    []string `koanf:"selectors" reload:"hot"`
+}
+
+func CalldataLimitConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.Int(prefix+".default-limit", DefaultCalldataLimitConfig.DefaultLimit, "the most calldata bytes a transaction whose selector isn't listed may have (0 = unlimited)")
+	f.StringSlice(prefix+".selectors", DefaultCalldataLimitConfig.Selectors, "calldata size limits by function selector, each as selector:bytes (e.g. 0xa9059cbb:68)")
+}
+
+var DefaultCalldataLimitConfig = CalldataLimitConfig{
+	DefaultLimit: 0,
+	Selectors:    []string{},
+}
+
+func (c *CalldataLimitConfig) Validate() error {
+	_, err := parseSelectorLimits(c.Selectors)
+	return err
+}
+
+func parseSelectorLimits(entries []string) (map[[4]byte]int, error) {
+	limits := make(map[[4]byte]int)
+	for _, entry := range entries {
+		parts := strings.Split(entry, ":")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("calldata limit %q isn't of the form selector:bytes", entry)
+		}
+		selectorBytes := common.FromHex(parts[0])
+		if len(selectorBytes) != 4 {
+			return nil, fmt.Errorf("calldata limit %q has an invalid selector", entry)
+		}
+		limit, err := strconv.Atoi(parts[1])
+		if err != nil || limit < 0 {
+			return nil, fmt.Errorf("calldata limit %q has an invalid size", entry)
+		}
+		var selector [4]byte
+		copy(selector[:], selectorBytes)
+		limits[selector] = limit
+	}
+	return limits, nil
+}
+
+// calldataLimitPolicy limits the calldata size of transactions by their function selector.
+type calldataLimitPolicy struct {
+	config func() *CalldataLimitConfig
+	// the selector limits parsed from parsedEntries
+	parsedEntries []string
+	limits        map[[4]byte]int
+}
+
+func newCalldataLimitPolicy(config func() *CalldataLimitConfig) *calldataLimitPolicy {
+	return &calldataLimitPolicy{config: config}
+}
+
+func (p *calldataLimitPolicy) PreTxFilter(_ *types.Header, _ *state.StateDB, tx *types.Transaction, _ common.Address) error {
+	config := p.config()
+	if p.limits == nil || strings.Join(config.Selectors, ",") != strings.Join(p.parsedEntries, ",") {
+		limits, err := parseSelectorLimits(config.Selectors)
+		if err != nil {
+			return err
+		}
+		p.limits = limits
+		p.parsedEntries = config.Selectors
+	}
+	data := tx.Data()
+	limit := config.DefaultLimit
+	if len(data) >= 4 {
+		var selector [4]byte
+		copy(selector[:], data)
+		if selectorLimit, ok := p.limits[selector]; ok {
+			limit = selectorLimit
+		}
+	}
+	if limit > 0 && len(data) > limit {
+		return fmt.Errorf("calldata of %v bytes exceeds the limit of %v bytes", len(data), limit)
+	}
+	return nil
+}
+
+func (p *calldataLimitPolicy) PostTxFilter(*types.Header, *state.StateDB, *types.Transaction, common.Address, *core.ExecutionResult) error {
+	return nil
+}
+
+type AddressBlocklistConfig struct {
+	Addresses []string `koanf:"addresses" reload:"hot"`
+}
+
+func AddressBlocklistConfigAddOptions(prefix string, f *flag.FlagSet) {
+	f.StringSlice(prefix+".addresses", DefaultAddressBlocklistConfig.Addresses, "addresses that transactions may not be sent from, sent to or touch while executing")
+}
+
+var DefaultAddressBlocklistConfig = AddressBlocklistConfig{
+	Addresses: []string{},
+}
+
+func (c *AddressBlocklistConfig) Validate() error {
+	for _, address := range c.Addresses {
+		if !common.IsHexAddress(address) {
+			return fmt.Errorf("invalid address %q", address)
+		}
+	}
+	return nil
+}
+
+// addressBlocklistPolicy rejects transactions that involve a blocked address, as their sender,
+// destination, or any account or contract their execution accessed.
+type addressBlocklistPolicy struct {
+	config func() *AddressBlocklistConfig
+	// records the accounts and contracts the transaction being executed accessed
+	tracer *logger.AccessListTracer
+}
+
+func newAddressBlocklistPolicy(config func() *AddressBlocklistConfig) *addressBlocklistPolicy {
+	return &addressBlocklistPolicy{config: config}
+}
+
+func (p *addressBlocklistPolicy) PreTxFilter(_ *types.Header, _ *state.StateDB, tx *types.Transaction, sender common.Address) error {
+	for _, entry := range p.config().Addresses {
+		address := common.HexToAddress(entry)
+		if sender == address || (tx.To() != nil && *tx.To() == address) {
+			return fmt.Errorf("transaction involves blocked address %v", address)
+		}
+	}
+	return nil
+}
+
+// txTracer returns the tracer to execute the transaction with, which records the addresses its execution
+// called or accessed. Unlike the statedb's access list, that doesn't include the addresses it only declared.
+func (p *addressBlocklistPolicy) txTracer(tx *types.Transaction, sender common.Address) vm.EVMLogger {
+	var to common.Address
+	if tx.To() != nil {
+		to = *tx.To()
+	}
+	// The sender and destination are checked before the transaction is executed.
+	p.tracer = logger.NewAccessListTracer(nil, sender, to, nil)
+	return p.tracer
+}
+
+func (p *addressBlocklistPolicy) PostTxFilter(*types.Header, *state.StateDB, *types.Transaction, common.Address, *core.ExecutionResult) error {
+	tracer := p.tracer
+	p.tracer = nil
+	if tracer == nil {
+		return errors.New("transaction wasn't traced for the address blocklist")
+	}
+	accessed := make(map[common.Address]struct{})
+	for _, tuple := range tracer.AccessList() {
+		accessed[tuple.Address] = struct{}{}
+	}
+	for _, entry := range p.config().Addresses {
+		address := common.HexToAddress(entry)
+		if _, ok := accessed[address]; ok {
+			return fmt.Errorf("transaction touched blocked address %v", address)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+// Copyright 2021-2022, Offchain Labs, Inc.
+// For license information, see https://github.com/fogr/blob/master/LICENSE
+
+package fognode
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/holiman/uint256"
+
+	"github.com/FOGRCC/fogr/fogos/util"
+)
+
+func txTo(to *common.Address, data []byte) *types.Transaction {
+	return types.NewTx(&types.LegacyTx{To: to, Data: data})
+}
+
+func TestSenderRateLimitPolicy(t *testing.T) {
+	config := DefaultSenderRateLimitConfig
+	config.MaxTxs = 2
+	config.Interval = time.Hour
+	policy := newSenderRateLimitPolicy(func() *SenderRateLimitConfig { return &config })
+	alice := common.HexToAddress("0x1")
+	bob := common.HexToAddress("0x2")
+	tx := txTo(&bob, nil)
+	sequence := func() {
+		t.Helper()
+		Require(t, policy.PreTxFilter(nil, nil, tx, alice))
+		Require(t, policy.PostTxFilter(nil, nil, tx, alice, nil))
+		policy.TxSequenced(nil, tx, alice)
+	}
+
+	// Transactions only count once their block is committed.
+	policy.StartBlock()
+	sequence()
+	sequence()
+	if policy.PreTxFilter(nil, nil, tx, alice) == nil {
+		Fail(t, "sender exceeded the rate limit within a block")
+	}
+	policy.StartBlock()
+	sequence()
+	policy.CommitBlock()
+	policy.StartBlock()
+	sequence()
+	policy.CommitBlock()
+	if policy.PreTxFilter(nil, nil, tx, alice) == nil {
+		Fail(t, "sender exceeded the rate limit")
+	}
+	Require(t, policy.PreTxFilter(nil, nil, tx, bob))
+	config.Interval = 0
+	Require(t, policy.PreTxFilter(nil, nil, tx, alice))
+}
+
+func TestDestinationListPolicy(t *testing.T) {
+	listed := common.HexToAddress("0x1234")
+	other := common.HexToAddress("0x5678")
+	file := filepath.Join(t.TempDir(), "destinations")
+	config := DefaultDestinationListConfig
+	config.File = file
+	config.ReloadInterval = 0
+	policy := newDestinationListPolicy(func() *DestinationListConfig { return &config })
+	sender := common.Address{}
+
+	if policy.PreTxFilter(nil, nil, txTo(&other, nil), sender) == nil {
+		Fail(t, "accepted a transaction without a destination list")
+	}
+	Require(t, os.WriteFile(file, []byte("# denied\n"+listed.Hex()+"\n"), 0600))
+	if policy.PreTxFilter(nil, nil, txTo(&listed, nil), sender) == nil {
+		Fail(t, "accepted a denied destination")
+	}
+	Require(t, policy.PreTxFilter(nil, nil, txTo(&other, nil), sender))
+	Require(t, policy.PreTxFilter(nil, nil, txTo(nil, nil), sender))
+
+	config.Mode = "allow"
+	Require(t, policy.PreTxFilter(nil, nil, txTo(&listed, nil), sender))
+	if policy.PreTxFilter(nil, nil, txTo(&other, nil), sender) == nil {
+		Fail(t, "accepted a destination that isn't allowed")
+	}
+	if policy.PreTxFilter(nil, nil, txTo(nil, nil), sender) == nil {
+		Fail(t, "accepted a contract creation")
+	}
+
+	// The file is reloaded when it changes, and a bad file keeps the last list.
+	Require(t, os.WriteFile(file, []byte(other.Hex()+"\n"), 0600))
+	Require(t, os.Chtimes(file, time.Now(), time.Now().Add(time.Minute)))
+	Require(t, policy.PreTxFilter(nil, nil, txTo(&other, nil), sender))
+	Require(t, os.WriteFile(file, []byte("not an address\n"), 0600))
+	Require(t, os.Chtimes(file, time.Now(), time.Now().Add(2*time.Minute)))
+	Require(t, policy.PreTxFilter(nil, nil, txTo(&other, nil), sender))
+}
+
+func TestCalldataLimitPolicy(t *testing.T) {
+	config := DefaultCalldataLimitConfig
+	config.DefaultLimit = 100
+	config.Selectors = []string{"0xa9059cbb:68"}
+	Require(t, config.Validate())
+	policy := newCalldataLimitPolicy(func() *CalldataLimitConfig { return &config })
+	to := common.HexToAddress("0x1")
+	transfer := append(common.FromHex("0xa9059cbb"), make([]byte, 64)...)
+	Require(t, policy.PreTxFilter(nil, nil, txTo(&to, transfer), common.Address{}))
+	if policy.PreTxFilter(nil, nil, txTo(&to, append(transfer, 0)), common.Address{}) == nil {
+		Fail(t, "accepted calldata over its selector's limit")
+	}
+	Require(t, policy.PreTxFilter(nil, nil, txTo(&to, make([]byte, 100)), common.Address{}))
+	if policy.PreTxFilter(nil, nil, txTo(&to, make([]byte, 101)), common.Address{}) == nil {
+		Fail(t, "accepted calldata over the default limit")
+	}
+
+	config.Selectors = []string{"0xa9059cbb"}
+	if config.Validate() == nil {
+		Fail(t, "accepted a limit without a size")
+	}
+}
+
+func TestAddressBlocklistPolicy(t *testing.T) {
+	blocked := common.HexToAddress("0xbad")
+	other := common.HexToAddress("0x1")
+	config := AddressBlocklistConfig{Addresses: []string{blocked.Hex()}}
+	Require(t, config.Validate())
+	policy := newAddressBlocklistPolicy(func() *AddressBlocklistConfig { return &config })
+
+	if policy.PreTxFilter(nil, nil, txTo(&blocked, nil), other) == nil {
+		Fail(t, "accepted a transaction to a blocked address")
+	}
+	if policy.PreTxFilter(nil, nil, txTo(&other, nil), blocked) == nil {
+		Fail(t, "accepted a transaction from a blocked address")
+	}
+	Require(t, policy.PreTxFilter(nil, nil, txTo(&other, nil), other))
+
+	// execute has the transaction's execution call the target.
+	execute := func(target common.Address) {
+		tracer := policy.txTracer(txTo(&other, nil), other)
+		stack := util.TracingStackFromArgs(
+			*uint256.NewInt(0),                          // gas
+			*uint256.NewInt(0).SetBytes(target.Bytes()), // to address
+			*uint256.NewInt(0),                          // call value
+			*uint256.NewInt(0),                          // memory offset
+			*uint256.NewInt(0),                          // memory length
+			*uint256.NewInt(0),                          // return offset
+			*uint256.NewInt(0),                          // return size
+		)
+		tracer.CaptureState(0, vm.CALL, 0, 0, &vm.ScopeContext{Memory: vm.NewMemory(), Stack: stack}, []byte{}, 1, nil)
+	}
+
+	// Declaring the blocked address in the transaction's access list doesn't touch it.
+	statedb, err := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	Require(t, err)
+	statedb.AddAddressToAccessList(blocked)
+	execute(common.HexToAddress("0x2"))
+	Require(t, policy.PostTxFilter(nil, statedb, txTo(&other, nil), other, nil))
+	execute(blocked)
+	if policy.PostTxFilter(nil, statedb, txTo(&other, nil), other, nil) == nil {
+		Fail(t, "accepted a transaction that called a blocked address")
+	}
+}
+
+func TestTxAdmissionConfigValidate(t *testing.T) {
+	config := DefaultTxAdmissionConfig
+	config.Policies = []string{SenderRateLimitPolicyName}
+	config.SenderRateLimit.MaxTxs = 0
+	if config.Validate() == nil {
+		Fail(t, "accepted a sender rate limit of no transactions")
+	}
+	config.SenderRateLimit = DefaultSenderRateLimitConfig
+	config.Policies = []string{CalldataLimitPolicyName, "test-admission-policy"}
+	if config.Validate() == nil {
+		Fail(t, "accepted an unknown policy")
+	}
+	Require(t, RegisterTxAdmissionPolicy("test-admission-policy", func() TxAdmissionPolicy {
+		return newCalldataLimitPolicy(func() *CalldataLimitConfig { return &DefaultCalldataLimitConfig })
+	}))
+	Require(t, config.Validate())
+	if RegisterTxAdmissionPolicy(SenderRateLimitPolicyName, nil) == nil {
+		Fail(t, "registered a policy with a built in name")
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright 2021-2022, Offchain Labs, Inc.
+// For license information, see https://github.com/fogr/blob/master/LICENSE
+
+package fognode
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+var errNotSequencer = errors.New("this node doesn't run the sequencer")
+
+// SequencerAPI serves the sequencer's methods from every node.
+// Nodes that don't run the sequencer forward bundles and conditional transactions to it, but can't report its queue.
+type SequencerAPI struct {
+	txPublisher TransactionPublisher
+	sequencer   *Sequencer
+}
+
+// SendBundle sequences the signed transactions consecutively in one block, where either all of them succeed or none are included.
+// It returns the transactions' hashes once they're sequenced.
+func (a *SequencerAPI) SendBundle(ctx context.Context, encodedTxs []hexutil.Bytes) ([]common.Hash, error) {
+	txs := make(types.Transactions, 0, len(encodedTxs))
+	hashes := make([]common.Hash, 0, len(encodedTxs))
+	for _, encodedTx := range encodedTxs {
+		tx := new(types.Transaction)
+		if err := tx.UnmarshalBinary(encodedTx); err != nil {
+			return nil, err
+		}
+		txs = append(txs, tx)
+		hashes = append(hashes, tx.Hash())
+	}
+	if err := a.txPublisher.PublishBundle(ctx, txs); err != nil {
+		return nil, err
+	}
+	return hashes, nil
+}
+
+// SendRawTransactionConditional sequences the signed transaction only if its conditions hold
+// in the block and state it would be included in. It returns the transaction's hash once it's sequenced.
+func (a *SequencerAPI) SendRawTransactionConditional(ctx context.Context, encodedTx hexutil.Bytes, options ConditionalOptions) (common.Hash, error) {
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(encodedTx); err != nil {
+		return common.Hash{}, err
+	}
+	if err := a.txPublisher.PublishTransaction(ctx, tx, &options); err != nil {
+		return common.Hash{}, err
+	}
+	return tx.Hash(), nil
+}
+
+// PendingTransactions returns the transactions waiting in the sequencer, oldest first,
+// with where each is waiting: the tx queue, the retry queue, the nonce failure cache or a bundle.
+func (a *SequencerAPI) PendingTransactions(ctx context.Context) ([]PendingTransaction, error) {
+	if a.sequencer == nil {
+		return nil, errNotSequencer
+	}
+	return a.sequencer.PendingTransactions(), nil
+}
+
+// SequencerQueueStatus returns how many transactions are waiting in the sequencer, by where they're waiting.
+func (a *SequencerAPI) SequencerQueueStatus(ctx context.Context) (SequencerQueueStatus, error) {
+	if a.sequencer == nil {
+		return SequencerQueueStatus{}, errNotSequencer
+	}
+	return a.sequencer.QueueStatus(), nil
+}
@@ -0,0 +1,252 @@
+// Copyright 2021-2022, Offchain Labs, Inc.
+// For license information, see https://github.com/fogr/blob/master/LICENSE
+
+package fognode
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/FOGRCC/fogr/fogos/fogosState"
+	"github.com/ethereum/go-ethereum/FOGR"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+var (
+	successfulBundlesCounter = metrics.NewRegisteredCounter("fogr/sequencer/bundle/successful", nil)
+	failedBundlesCounter     = metrics.NewRegisteredCounter("fogr/sequencer/bundle/failed", nil)
+)
+
+var ErrBundleFailed = errors.New("bundle failed")
+
+// failedBundleTracker counts each sender's failed bundles per fixed interval,
+// as a failed bundle costs the sequencer a block's execution without paying any fees.
+type failedBundleTracker struct {
+	mutex       sync.Mutex
+	windowStart time.Time
+	counts      map[common.Address]int
+}
+
+func newFailedBundleTracker() *failedBundleTracker {
+	return &failedBundleTracker{
+		counts: make(map[common.Address]int),
+	}
+}
+
+// startWindow starts a new interval if the current one is over. The mutex must be held.
+func (t *failedBundleTracker) startWindow(config *SequencerConfig) {
+	if time.Since(t.windowStart) >= config.FailedBundleInterval {
+		t.windowStart = time.Now()
+		t.counts = make(map[common.Address]int)
+	}
+}
+
+func (t *failedBundleTracker) check(config *SequencerConfig, senders []common.Address) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.startWindow(config)
+	for _, sender := range senders {
+		if t.counts[sender] >= config.MaxFailedBundles {
+			return fmt.Errorf("sender %v exceeded the limit of %v failed bundles per %v", sender, config.MaxFailedBundles, config.FailedBundleInterval)
+		}
+	}
+	return nil
+}
+
+func (t *failedBundleTracker) record(config *SequencerConfig, sender common.Address) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.startWindow(config)
+	t.counts[sender]++
+}
+
+type bundleQueueItem struct {
+	txs            types.Transactions
+	resultChan     chan<- error
+	returnedResult bool
+	ctx            context.Context
+}
+
+func (i *bundleQueueItem) returnResult(err error) {
+	if i.returnedResult {
+		log.Error("attempting to return result to already finished bundle", "err", err)
+		return
+	}
+	i.returnedResult = true
+	i.resultChan <- err
+	close(i.resultChan)
+}
+
+// PublishBundle sequences the transactions consecutively, in order, in a block of their own.
+// The block is only made if every transaction succeeds without reverting, so either all of them are included or none are.
+func (s *Sequencer) PublishBundle(parentCtx context.Context, txs types.Transactions) error {
+	sequencerBacklogGauge.Inc(1)
+	defer sequencerBacklogGauge.Dec(1)
+
+	config := s.config()
+	if config.MaxBundleSize == 0 {
+		return errors.New("bundles are disabled")
+	}
+	if len(txs) == 0 {
+		return errors.New("empty bundle")
+	}
+	if len(txs) > config.MaxBundleSize {
+		return fmt.Errorf("bundle of %v transactions exceeds the limit of %v", len(txs), config.MaxBundleSize)
+	}
+
+	_, forwarder := s.GetPauseAndForwarder()
+	if forwarder != nil {
+		err := forwarder.PublishBundle(parentCtx, txs)
+		if !errors.Is(err, ErrNoSequencer) {
+			return err
+		}
+	}
+
+	signer := types.LatestSigner(s.txStreamer.bc.Config())
+	senders := make([]common.Address, 0, len(txs))
+	var totalSize int
+	for _, tx := range txs {
+		if err := s.checkPublishable(tx); err != nil {
+			return err
+		}
+		txBytes, err := tx.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		totalSize += len(txBytes)
+		sender, err := types.Sender(signer, tx)
+		if err != nil {
+			return err
+		}
+		senders = append(senders, sender)
+	}
+	if totalSize > config.MaxTxDataSize {
+		return core.ErrOversizedData
+	}
+	if err := s.failedBundles.check(config, senders); err != nil {
+		return err
+	}
+
+	ctx, cancelFunc := s.ctxWithQueueTimeout(parentCtx)
+	defer cancelFunc()
+
+	resultChan := make(chan error, 1)
+	queueItem := bundleQueueItem{
+		txs,
+		resultChan,
+		false,
+		ctx,
+	}
+	s.queuedTxs.addBundle(txs, time.Now())
+	defer func() {
+		for _, tx := range txs {
+			s.queuedTxs.remove(tx)
+		}
+	}()
+	select {
+	case s.bundleQueue <- queueItem:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case res := <-resultChan:
+		return res
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// bundlePostTxFilter rejects any bundle transaction that reverts, however much gas it used.
+// The side effects of postTxFilter only take effect once the bundle's block is committed.
+func (s *Sequencer) bundlePostTxFilter(header *types.Header, state *fogosState.fogosState, tx *types.Transaction, sender common.Address, dataGas uint64, result *core.ExecutionResult) error {
+	if result.Err != nil {
+		return FOGR.NewRevertReason(result)
+	}
+	return s.postTxFilter(header, state, tx, sender, dataGas, result)
+}
+
+// bundleBlockFilter discards a bundle's block unless all of its transactions succeeded.
+func bundleBlockFilter(_ *types.Header, txes types.Transactions, txErrors []error) error {
+	for i, err := range txErrors {
+		if err != nil {
+			return fmt.Errorf("%w: transaction %v (%v): %v", ErrBundleFailed, i, txes[i].Hash(), err)
+		}
+	}
+	return nil
+}
+
+// chargeFailedBundle counts the failed bundle against the sender of its first failing transaction.
+func (s *Sequencer) chargeFailedBundle(config *SequencerConfig, txs types.Transactions, txErrors []error) {
+	signer := types.LatestSigner(s.txStreamer.bc.Config())
+	for i, err := range txErrors {
+		if err == nil || i >= len(txs) {
+			continue
+		}
+		sender, err := types.Sender(signer, txs[i])
+		if err != nil {
+			log.Warn("failed to recover sender of failed bundle transaction", "tx", txs[i].Hash(), "err", err)
+			return
+		}
+		s.failedBundles.record(config, sender)
+		return
+	}
+}
+
+func (s *Sequencer) createBundleBlock(ctx context.Context, config *SequencerConfig, bundle *bundleQueueItem) bool {
+	if err := bundle.ctx.Err(); err != nil {
+		bundle.returnResult(err)
+		return false
+	}
+
+	forwarder, stopped := s.waitUntilUnpaused(ctx)
+	if stopped {
+		bundle.returnResult(ErrRetrySequencer)
+		return false
+	}
+	if forwarder != nil {
+		s.LaunchUntrackedThread(func() {
+			bundle.returnResult(forwarder.PublishBundle(bundle.ctx, bundle.txs))
+		})
+		return false
+	}
+
+	s.startBlock(config)
+	header := s.nextMessageHeader(config)
+	if header == nil {
+		bundle.returnResult(ErrRetrySequencer)
+		return false
+	}
+
+	hooks := s.makeSequencingHooks(nil)
+	hooks.PostTxFilter = s.bundlePostTxFilter
+	hooks.BlockFilter = bundleBlockFilter
+	start := time.Now()
+	block, err := s.txStreamer.SequenceTransactions(header, bundle.txs, hooks)
+	blockCreationTimer.Update(time.Since(start))
+	if err != nil {
+		if errors.Is(err, ErrBundleFailed) {
+			failedBundlesCounter.Inc(1)
+			s.chargeFailedBundle(config, bundle.txs, hooks.TxErrors)
+		} else if !errors.Is(err, ErrRetrySequencer) && !errors.Is(err, context.Canceled) {
+			log.Error("error sequencing bundle", "err", err)
+		}
+		bundle.returnResult(err)
+		return false
+	}
+
+	if block != nil {
+		successfulBundlesCounter.Inc(1)
+		s.commitBlock(block)
+	}
+	bundle.returnResult(nil)
+	return block != nil
+}
@@ -237,3 +237,32 @@ func (i *SequencerInbox) LookupBatchesInRange(ctx context.Context, from, to *big
 	}
 	return messages, nil
 }
+
+// LookupBatch finds the batch with the given sequence number, searching the L1 blocks from from to to.
+// A nil to searches up to the latest block.
+func (i *SequencerInbox) LookupBatch(ctx context.Context, seqNum uint64, from, to *big.Int) (*SequencerInboxBatch, error) {
+	query := ethereum.FilterQuery{
+		FromBlock: from,
+		ToBlock:   to,
+		Addresses: []common.Address{i.address},
+		Topics:    [][]common.Hash{{batchDeliveredID}, {common.BigToHash(new(big.Int).SetUint64(seqNum))}},
+	}
+	logs, err := i.client.FilterLogs(ctx, query)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if len(logs) == 0 {
+		return nil, fmt.Errorf("sequencer batch %v not found between L1 blocks %v and %v", seqNum, from, to)
+	}
+	blockNum := new(big.Int).SetUint64(logs[0].BlockNumber)
+	batches, err := i.LookupBatchesInRange(ctx, blockNum, blockNum)
+	if err != nil {
+		return nil, err
+	}
+	for _, batch := range batches {
+		if batch.SequenceNumber == seqNum {
+			return batch, nil
+		}
+	}
+	return nil, fmt.Errorf("sequencer batch %v not found in L1 block %v", seqNum, blockNum)
+}
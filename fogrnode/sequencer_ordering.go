@@ -0,0 +1,180 @@
+// Copyright 2021-2022, Offchain Labs, Inc.
+// For license information, see https://github.com/fogr/blob/master/LICENSE
+
+package fognode
+
+import (
+	"container/heap"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/FOGRCC/fogr/util/fogmath"
+)
+
+const (
+	FCFSOrderingName        = "fcfs"
+	PriorityFeeOrderingName = "priority-fee"
+)
+
+// OrderedTx is a transaction the sequencer has collected for a block, as seen by a TxOrderingPolicy.
+type OrderedTx struct {
+	Tx      *types.Transaction
+	Sender  common.Address
+	Arrival time.Time
+	// EffectiveTip is the tip the transaction pays per gas at the latest block's base fee,
+	// which is negative if its fee cap is below the base fee.
+	EffectiveTip *big.Int
+}
+
+// TxOrderingPolicy decides the order of the transactions the sequencer collects for a block.
+// Whatever the policy, each sender's transactions stay in nonce order.
+type TxOrderingPolicy interface {
+	// BufferWindow is how long to keep collecting transactions for a block after the first one arrives.
+	BufferWindow() time.Duration
+	// Before returns whether a should be sequenced before b.
+	// Transactions neither is before keep the order they arrived in.
+	Before(a, b *OrderedTx) bool
+}
+
+func NewTxOrderingPolicy(config *SequencerConfig) (TxOrderingPolicy, error) {
+	switch config.Ordering {
+	case FCFSOrderingName, "":
+		return fcfsOrdering{}, nil
+	case PriorityFeeOrderingName:
+		return priorityFeeOrdering{window: config.OrderingWindow}, nil
+	default:
+		return nil, fmt.Errorf("unknown sequencer ordering %q", config.Ordering)
+	}
+}
+
+// fcfsOrdering sequences transactions in the order they arrived in, without buffering them.
+type fcfsOrdering struct{}
+
+func (fcfsOrdering) BufferWindow() time.Duration {
+	return 0
+}
+
+func (fcfsOrdering) Before(a, b *OrderedTx) bool {
+	return false
+}
+
+// priorityFeeOrdering buffers transactions for a window and sequences them by effective tip,
+// with ties going to the transaction that arrived first.
+type priorityFeeOrdering struct {
+	window time.Duration
+}
+
+func (o priorityFeeOrdering) BufferWindow() time.Duration {
+	return o.window
+}
+
+func (priorityFeeOrdering) Before(a, b *OrderedTx) bool {
+	cmp := a.EffectiveTip.Cmp(b.EffectiveTip)
+	if cmp != 0 {
+		return cmp > 0
+	}
+	return a.Arrival.Before(b.Arrival)
+}
+
+// senderQueues is a heap of each sender's transactions that haven't been ordered yet,
+// keyed on the first of them.
+type senderQueues struct {
+	policy TxOrderingPolicy
+	txs    []*OrderedTx
+	queues [][]int
+}
+
+func (h *senderQueues) Len() int {
+	return len(h.queues)
+}
+
+func (h *senderQueues) Less(i, j int) bool {
+	a, b := h.queues[i][0], h.queues[j][0]
+	if h.policy.Before(h.txs[a], h.txs[b]) {
+		return true
+	}
+	if h.policy.Before(h.txs[b], h.txs[a]) {
+		return false
+	}
+	return a < b
+}
+
+func (h *senderQueues) Swap(i, j int) {
+	h.queues[i], h.queues[j] = h.queues[j], h.queues[i]
+}
+
+func (h *senderQueues) Push(x interface{}) {
+	h.queues = append(h.queues, x.([]int))
+}
+
+func (h *senderQueues) Pop() interface{} {
+	last := h.queues[len(h.queues)-1]
+	h.queues = h.queues[:len(h.queues)-1]
+	return last
+}
+
+// orderTxs returns the indices of txs in the order the policy sequences them in.
+// Each sender's transactions must be given in nonce order, and stay in it.
+func orderTxs(policy TxOrderingPolicy, txs []*OrderedTx) []int {
+	h := &senderQueues{policy: policy, txs: txs}
+	senderQueueIndex := make(map[common.Address]int)
+	for i, tx := range txs {
+		index, ok := senderQueueIndex[tx.Sender]
+		if !ok {
+			index = len(h.queues)
+			senderQueueIndex[tx.Sender] = index
+			h.queues = append(h.queues, nil)
+		}
+		h.queues[index] = append(h.queues[index], i)
+	}
+	heap.Init(h)
+	order := make([]int, 0, len(txs))
+	for h.Len() > 0 {
+		queue := h.queues[0]
+		order = append(order, queue[0])
+		if len(queue) > 1 {
+			h.queues[0] = queue[1:]
+			heap.Fix(h, 0)
+		} else {
+			heap.Pop(h)
+		}
+	}
+	return order
+}
+
+// orderQueueItems puts the queue items, which have passed precheckNonces, in the policy's order.
+// With first come first served ordering, they're already in order, so no senders are recovered.
+func (s *Sequencer) orderQueueItems(policy TxOrderingPolicy, queueItems []txQueueItem) []txQueueItem {
+	if _, fcfs := policy.(fcfsOrdering); fcfs || len(queueItems) < 2 {
+		return queueItems
+	}
+	bc := s.txStreamer.bc
+	latestHeader := bc.CurrentBlock().Header()
+	signer := types.MakeSigner(bc.Config(), fogmath.BigAdd(latestHeader.Number, common.Big1))
+	txs := make([]*OrderedTx, 0, len(queueItems))
+	for _, queueItem := range queueItems {
+		sender, err := types.Sender(signer, queueItem.tx)
+		if err != nil {
+			log.Warn("failed to get sender to order sequencer queue, keeping arrival order", "err", err)
+			return queueItems
+		}
+		// An error here means the fee cap is below the base fee, which the negative tip reflects.
+		tip, _ := queueItem.tx.EffectiveGasTip(latestHeader.BaseFee)
+		txs = append(txs, &OrderedTx{
+			Tx:           queueItem.tx,
+			Sender:       sender,
+			Arrival:      queueItem.arrival,
+			EffectiveTip: tip,
+		})
+	}
+	ordered := make([]txQueueItem, 0, len(queueItems))
+	for _, i := range orderTxs(policy, txs) {
+		ordered = append(ordered, queueItems[i])
+	}
+	return ordered
+}
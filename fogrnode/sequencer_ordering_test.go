@@ -0,0 +1,63 @@
+// Copyright 2021-2022, Offchain Labs, Inc.
+// For license information, see https://github.com/fogr/blob/master/LICENSE
+
+package fognode
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestTxOrdering(t *testing.T) {
+	alice := common.HexToAddress("0x1")
+	bob := common.HexToAddress("0x2")
+	carol := common.HexToAddress("0x3")
+	start := time.Now()
+	// Given in arrival order, and in nonce order for each sender.
+	txs := []*OrderedTx{
+		{Sender: alice, EffectiveTip: big.NewInt(1)},
+		{Sender: bob, EffectiveTip: big.NewInt(5)},
+		{Sender: alice, EffectiveTip: big.NewInt(10)},
+		{Sender: carol, EffectiveTip: big.NewInt(5)},
+		{Sender: bob, EffectiveTip: big.NewInt(-1)},
+		{Sender: carol, EffectiveTip: big.NewInt(7)},
+	}
+	for i, tx := range txs {
+		tx.Arrival = start.Add(time.Duration(i) * time.Millisecond)
+	}
+	expect := func(policy TxOrderingPolicy, expected []int) {
+		t.Helper()
+		order := orderTxs(policy, txs)
+		if len(order) != len(expected) {
+			Fail(t, "expected order", expected, "but got", order)
+		}
+		for i := range order {
+			if order[i] != expected[i] {
+				Fail(t, "expected order", expected, "but got", order)
+			}
+		}
+	}
+
+	fcfs, err := NewTxOrderingPolicy(&DefaultSequencerConfig)
+	Require(t, err)
+	expect(fcfs, []int{0, 1, 2, 3, 4, 5})
+
+	config := DefaultSequencerConfig
+	config.Ordering = PriorityFeeOrderingName
+	priority, err := NewTxOrderingPolicy(&config)
+	Require(t, err)
+	if priority.BufferWindow() != config.OrderingWindow {
+		Fail(t, "unexpected buffer window", priority.BufferWindow())
+	}
+	// Bob and carol tie on the first tip, so bob's earlier arrival goes first. Alice's second
+	// tx has the highest tip, but waits for her first, which has the lowest non-negative tip.
+	expect(priority, []int{1, 3, 5, 0, 2, 4})
+
+	config.Ordering = "random"
+	if config.Validate() == nil {
+		Fail(t, "unknown ordering was accepted")
+	}
+}
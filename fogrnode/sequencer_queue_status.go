@@ -0,0 +1,172 @@
+// Copyright 2021-2022, Offchain Labs, Inc.
+// For license information, see https://github.com/fogr/blob/master/LICENSE
+
+package fognode
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Where a transaction the sequencer is holding is waiting.
+const (
+	QueuedTxStatusQueued       = "queued"
+	QueuedTxStatusSequencing   = "sequencing"
+	QueuedTxStatusRetry        = "retry"
+	QueuedTxStatusNonceFailure = "nonce-failure"
+	QueuedTxStatusBundle       = "bundle"
+)
+
+type trackedTx struct {
+	arrival time.Time
+	status  string
+	reason  string
+}
+
+// queuedTxTracker records where each transaction submitted to the sequencer is waiting,
+// as the tx queue, retry queue and nonce failure cache can't be inspected outside of block creation.
+type queuedTxTracker struct {
+	mutex sync.Mutex
+	txs   map[*types.Transaction]*trackedTx
+}
+
+func newQueuedTxTracker() *queuedTxTracker {
+	return &queuedTxTracker{
+		txs: make(map[*types.Transaction]*trackedTx),
+	}
+}
+
+func (t *queuedTxTracker) add(item *txQueueItem) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.txs[item.tx] = &trackedTx{
+		arrival: item.arrival,
+		status:  QueuedTxStatusQueued,
+	}
+}
+
+// addBundle records the bundle's transactions as waiting in the bundle queue, each with the bundle as its reason.
+func (t *queuedTxTracker) addBundle(txs types.Transactions, arrival time.Time) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	for i, tx := range txs {
+		t.txs[tx] = &trackedTx{
+			arrival: arrival,
+			status:  QueuedTxStatusBundle,
+			reason:  fmt.Sprintf("transaction %v of %v in bundle %v", i+1, len(txs), txs[0].Hash()),
+		}
+	}
+}
+
+func (t *queuedTxTracker) remove(tx *types.Transaction) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	delete(t.txs, tx)
+}
+
+func (t *queuedTxTracker) setStatus(tx *types.Transaction, status string, reason string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	tracked, ok := t.txs[tx]
+	if !ok {
+		return
+	}
+	tracked.status = status
+	tracked.reason = reason
+}
+
+type PendingTransaction struct {
+	Hash   common.Hash    `json:"hash"`
+	Sender common.Address `json:"sender"`
+	Nonce  uint64         `json:"nonce"`
+	Age    string         `json:"age"`
+	Status string         `json:"status"`
+	Reason string         `json:"reason,omitempty"`
+}
+
+// snapshot returns the tracked transactions, oldest first.
+func (t *queuedTxTracker) snapshot(signer types.Signer) []PendingTransaction {
+	t.mutex.Lock()
+	type entry struct {
+		tx *types.Transaction
+		trackedTx
+	}
+	entries := make([]entry, 0, len(t.txs))
+	for tx, tracked := range t.txs {
+		entries = append(entries, entry{tx, *tracked})
+	}
+	t.mutex.Unlock()
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].arrival.Before(entries[j].arrival)
+	})
+	now := time.Now()
+	pending := make([]PendingTransaction, 0, len(entries))
+	for _, entry := range entries {
+		// The sender was already recovered and cached when the tx was prechecked, unless it's still queued.
+		sender, _ := types.Sender(signer, entry.tx)
+		pending = append(pending, PendingTransaction{
+			Hash:   entry.tx.Hash(),
+			Sender: sender,
+			Nonce:  entry.tx.Nonce(),
+			Age:    now.Sub(entry.arrival).Round(time.Millisecond).String(),
+			Status: entry.status,
+			Reason: entry.reason,
+		})
+	}
+	return pending
+}
+
+type SequencerQueueStatus struct {
+	TxQueueSize         int            `json:"txQueueSize"`
+	TxQueueCapacity     int            `json:"txQueueCapacity"`
+	BundleQueueSize     int            `json:"bundleQueueSize"`
+	BundleQueueCapacity int            `json:"bundleQueueCapacity"`
+	Counts              map[string]int `json:"counts"`
+	OldestAge           string         `json:"oldestAge,omitempty"`
+}
+
+func (s *Sequencer) QueueStatus() SequencerQueueStatus {
+	status := SequencerQueueStatus{
+		TxQueueSize:         len(s.txQueue),
+		TxQueueCapacity:     cap(s.txQueue),
+		BundleQueueSize:     len(s.bundleQueue),
+		BundleQueueCapacity: cap(s.bundleQueue),
+		Counts: map[string]int{
+			QueuedTxStatusQueued:       0,
+			QueuedTxStatusSequencing:   0,
+			QueuedTxStatusRetry:        0,
+			QueuedTxStatusNonceFailure: 0,
+			QueuedTxStatusBundle:       0,
+		},
+	}
+	s.queuedTxs.mutex.Lock()
+	defer s.queuedTxs.mutex.Unlock()
+	var oldest time.Time
+	for _, tracked := range s.queuedTxs.txs {
+		status.Counts[tracked.status]++
+		if oldest.IsZero() || tracked.arrival.Before(oldest) {
+			oldest = tracked.arrival
+		}
+	}
+	if !oldest.IsZero() {
+		status.OldestAge = time.Since(oldest).Round(time.Millisecond).String()
+	}
+	return status
+}
+
+// PendingTransactions returns the transactions submitted to the sequencer that it hasn't returned a result for yet.
+func (s *Sequencer) PendingTransactions() []PendingTransaction {
+	return s.queuedTxs.snapshot(types.LatestSigner(s.txStreamer.bc.Config()))
+}
+
+// pushRetry puts a queue item in the retry queue, recording why it's waiting.
+func (s *Sequencer) pushRetry(item txQueueItem, reason string) {
+	s.queuedTxs.setStatus(item.tx, QueuedTxStatusRetry, reason)
+	s.txRetryQueue.Push(item)
+}
@@ -0,0 +1,97 @@
+// Copyright 2021-2022, Offchain Labs, Inc.
+// For license information, see https://github.com/fogr/blob/master/LICENSE
+
+package fognode
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestQueuedTxTracker(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	Require(t, err)
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+	signer := types.LatestSignerForChainID(big.NewInt(412346))
+	s := &Sequencer{
+		txQueue:   make(chan txQueueItem, 8),
+		queuedTxs: newQueuedTxTracker(),
+	}
+	now := time.Now()
+	var txs []*types.Transaction
+	for nonce := uint64(0); nonce < 3; nonce++ {
+		tx, err := types.SignTx(types.NewTx(&types.LegacyTx{Nonce: nonce, To: &sender}), signer, key)
+		Require(t, err)
+		txs = append(txs, tx)
+		// Added newest first, to check they're listed oldest first.
+		s.queuedTxs.add(&txQueueItem{tx: tx, arrival: now.Add(-time.Duration(nonce) * time.Second)})
+	}
+	s.queuedTxs.setStatus(txs[1], QueuedTxStatusRetry, "block full")
+	s.queuedTxs.setStatus(txs[2], QueuedTxStatusNonceFailure, "nonce too high")
+
+	pending := s.queuedTxs.snapshot(signer)
+	if len(pending) != 3 {
+		Fail(t, "expected 3 pending transactions but got", len(pending))
+	}
+	expected := []struct {
+		nonce  uint64
+		status string
+		reason string
+	}{
+		{2, QueuedTxStatusNonceFailure, "nonce too high"},
+		{1, QueuedTxStatusRetry, "block full"},
+		{0, QueuedTxStatusQueued, ""},
+	}
+	for i, entry := range pending {
+		if entry.Nonce != expected[i].nonce || entry.Status != expected[i].status || entry.Reason != expected[i].reason {
+			Fail(t, "unexpected pending transaction", i, entry)
+		}
+		if entry.Sender != sender || entry.Hash != txs[entry.Nonce].Hash() {
+			Fail(t, "unexpected pending transaction sender or hash", entry)
+		}
+	}
+
+	s.queuedTxs.remove(txs[2])
+	status := s.QueueStatus()
+	if status.TxQueueCapacity != 8 || status.Counts[QueuedTxStatusRetry] != 1 || status.Counts[QueuedTxStatusQueued] != 1 || status.Counts[QueuedTxStatusNonceFailure] != 0 {
+		Fail(t, "unexpected queue status", status)
+	}
+}
+
+func TestQueuedTxTrackerBundle(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	Require(t, err)
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+	signer := types.LatestSignerForChainID(big.NewInt(412346))
+	s := &Sequencer{
+		txQueue:     make(chan txQueueItem, 8),
+		bundleQueue: make(chan bundleQueueItem, 8),
+		queuedTxs:   newQueuedTxTracker(),
+	}
+	var txs types.Transactions
+	for nonce := uint64(0); nonce < 2; nonce++ {
+		tx, err := types.SignTx(types.NewTx(&types.LegacyTx{Nonce: nonce, To: &sender}), signer, key)
+		Require(t, err)
+		txs = append(txs, tx)
+	}
+	s.queuedTxs.addBundle(txs, time.Now())
+
+	pending := s.queuedTxs.snapshot(signer)
+	if len(pending) != 2 {
+		Fail(t, "expected 2 pending transactions but got", len(pending))
+	}
+	for _, entry := range pending {
+		if entry.Status != QueuedTxStatusBundle || !strings.Contains(entry.Reason, txs[0].Hash().String()) {
+			Fail(t, "bundle transaction not reported with its bundle", entry)
+		}
+	}
+	status := s.QueueStatus()
+	if status.BundleQueueCapacity != 8 || status.Counts[QueuedTxStatusBundle] != 2 {
+		Fail(t, "unexpected queue status", status)
+	}
+}
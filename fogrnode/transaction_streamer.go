@@ -908,6 +908,11 @@ func (s *TransactionStreamer) sequenceTransactionsWithInsertionMutex(header *fog
 	if len(hooks.TxErrors) != len(txes) {
 		return nil, fmt.Errorf("unexpected number of error results: %v vs number of txes %v", len(hooks.TxErrors), len(txes))
 	}
+	if hooks.BlockFilter != nil {
+		if err := hooks.BlockFilter(block.Header(), txes, hooks.TxErrors); err != nil {
+			return nil, err
+		}
+	}
 
 	if len(receipts) == 0 {
 		return nil, nil
@@ -6,6 +6,7 @@ package fognode
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/FOGRCC/fogr/fogos/fogosState"
 	"github.com/FOGRCC/fogr/fogos/l1pricing"
@@ -14,9 +15,12 @@ import (
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/ethereum/go-ethereum/params"
 )
 
+var conditionalTxRejectedByPreCheckerCounter = metrics.NewRegisteredCounter("fogr/txprechecker/conditionaltx/rejected", nil)
+
 type TxPreChecker struct {
 	TransactionPublisher
 	bc            *core.BlockChain
@@ -127,7 +131,16 @@ func PreCheckTx(chainConfig *params.ChainConfig, header *types.Header, statedb *
 	return nil
 }
 
-func (c *TxPreChecker) PublishTransaction(ctx context.Context, tx *types.Transaction) error {
+// precheckConditionalHeader returns the header a conditional transaction's block and timestamp bounds are prechecked against.
+// The transaction can be included in the next block at the earliest, which won't be timestamped before now.
+func precheckConditionalHeader(header *types.Header, now time.Time) *types.Header {
+	nextHeader := types.CopyHeader(header)
+	nextHeader.Number = fogmath.BigAdd(header.Number, common.Big1)
+	nextHeader.Time = fogmath.MaxInt(header.Time, uint64(now.Unix()))
+	return nextHeader
+}
+
+func (c *TxPreChecker) PublishTransaction(ctx context.Context, tx *types.Transaction, options *ConditionalOptions) error {
 	block := c.bc.CurrentBlock()
 	statedb, err := c.bc.StateAt(block.Root())
 	if err != nil {
@@ -141,5 +154,51 @@ func (c *TxPreChecker) PublishTransaction(ctx context.Context, tx *types.Transac
 	if err != nil {
 		return err
 	}
-	return c.TransactionPublisher.PublishTransaction(ctx, tx)
+	if options != nil {
+		if err := options.Validate(); err != nil {
+			return err
+		}
+		err = options.Check(precheckConditionalHeader(block.Header(), time.Now()), statedb)
+		if err != nil {
+			conditionalTxRejectedByPreCheckerCounter.Inc(1)
+			return err
+		}
+	}
+	return c.TransactionPublisher.PublishTransaction(ctx, tx, options)
+}
+
+// PublishBundle prechecks each transaction against the latest state, updated with the nonces
+// and transfers of the transactions before it, as the bundle is sequenced consecutively.
+func (c *TxPreChecker) PublishBundle(ctx context.Context, txs types.Transactions) error {
+	strictness := c.getStrictness()
+	if strictness < TxPreCheckerStrictnessAlwaysCompatible {
+		return c.TransactionPublisher.PublishBundle(ctx, txs)
+	}
+	block := c.bc.CurrentBlock()
+	statedb, err := c.bc.StateAt(block.Root())
+	if err != nil {
+		return err
+	}
+	fogos, err := fogosState.OpenSystemfogosState(statedb, nil, true)
+	if err != nil {
+		return err
+	}
+	header := block.Header()
+	signer := types.MakeSigner(c.bc.Config(), header.Number)
+	for i, tx := range txs {
+		err = PreCheckTx(c.bc.Config(), header, statedb, fogos, tx, strictness)
+		if err != nil {
+			return fmt.Errorf("bundle transaction %v (%v): %w", i, tx.Hash(), err)
+		}
+		sender, err := types.Sender(signer, tx)
+		if err != nil {
+			return err
+		}
+		statedb.SetNonce(sender, tx.Nonce()+1)
+		if tx.To() != nil && !fogmath.BigLessThan(statedb.GetBalance(sender), tx.Value()) {
+			statedb.SubBalance(sender, tx.Value())
+			statedb.AddBalance(*tx.To(), tx.Value())
+		}
+	}
+	return c.TransactionPublisher.PublishBundle(ctx, txs)
 }
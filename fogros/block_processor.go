@@ -83,6 +83,11 @@ type SequencingHooks struct {
 	DiscardInvalidTxsEarly bool
 	PreTxFilter            func(*params.ChainConfig, *types.Header, *state.StateDB, *fogosState.fogosState, *types.Transaction, common.Address) error
 	PostTxFilter           func(*types.Header, *fogosState.fogosState, *types.Transaction, common.Address, uint64, *core.ExecutionResult) error
+	// BlockFilter, if set, is called with the produced block's header and each tx's error before the block is written,
+	// and discards the block by returning an error.
+	BlockFilter func(*types.Header, types.Transactions, []error) error
+	// TxTracer, if set, returns the tracer to execute each tx with once it passed PreTxFilter, or nil not to trace it.
+	TxTracer func(*types.Transaction, common.Address) vm.EVMLogger
 }
 
 func NoopSequencingHooks() *SequencingHooks {
@@ -95,6 +100,8 @@ func NoopSequencingHooks() *SequencingHooks {
 		func(*types.Header, *fogosState.fogosState, *types.Transaction, common.Address, uint64, *core.ExecutionResult) error {
 			return nil
 		},
+		nil,
+		nil,
 	}
 }
 
@@ -272,6 +279,13 @@ func ProduceBlockAdvanced(
 			snap := statedb.Snapshot()
 			statedb.Prepare(tx.Hash(), len(receipts)) // the number of successful state transitions
 
+			vmConfig := vm.Config{}
+			if hooks.TxTracer != nil {
+				if tracer := hooks.TxTracer(tx, sender); tracer != nil {
+					vmConfig = vm.Config{Debug: true, Tracer: tracer}
+				}
+			}
+
 			gasPool := gethGas
 			receipt, result, err := core.ApplyTransactionWithResultFilter(
 				chainConfig,
@@ -282,7 +296,7 @@ func ProduceBlockAdvanced(
 				header,
 				tx,
 				&header.GasUsed,
-				vm.Config{},
+				vmConfig,
 				func(result *core.ExecutionResult) error {
 					return hooks.PostTxFilter(header, state, tx, sender, dataGas, result)
 				},
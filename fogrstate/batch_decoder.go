@@ -0,0 +1,218 @@
+// Copyright 2021-2022, Offchain Labs, Inc.
+// For license information, see https://github.com/fogr/blob/master/LICENSE
+
+package fogstate
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rlp"
+
+	"github.com/FOGRCC/fogr/blsSignatures"
+	"github.com/FOGRCC/fogr/fogcompress"
+	"github.com/FOGRCC/fogr/fogos"
+	"github.com/FOGRCC/fogr/fogos/l1pricing"
+)
+
+// DecodedSequencerMessage is a sequencer batch broken down for inspection.
+type DecodedSequencerMessage struct {
+	MinTimestamp         uint64                 `json:"minTimestamp"`
+	MaxTimestamp         uint64                 `json:"maxTimestamp"`
+	MinL1Block           uint64                 `json:"minL1Block"`
+	MaxL1Block           uint64                 `json:"maxL1Block"`
+	AfterDelayedMessages uint64                 `json:"afterDelayedMessages"`
+	HeaderByte           *hexutil.Uint64        `json:"headerByte,omitempty"`
+	HeaderFlags          []string               `json:"headerFlags"`
+	Dictionary           string                 `json:"dictionary,omitempty"`
+	DASCertificate       *DecodedDASCertificate `json:"dasCertificate,omitempty"`
+	Segments             []*DecodedBatchSegment `json:"segments"`
+}
+
+type DecodedDASCertificate struct {
+	Version     uint8             `json:"version"`
+	KeysetHash  common.Hash       `json:"keysetHash"`
+	DataHash    common.Hash       `json:"dataHash"`
+	Timeout     uint64            `json:"timeout"`
+	SignersMask hexutil.Uint64    `json:"signersMask"`
+	Keyset      *DecodedDASKeyset `json:"keyset,omitempty"`
+	KeysetError string            `json:"keysetError,omitempty"`
+	Error       string            `json:"error,omitempty"`
+}
+
+type DecodedDASKeyset struct {
+	AssumedHonest uint64          `json:"assumedHonest"`
+	PubKeys       []hexutil.Bytes `json:"pubKeys"`
+}
+
+// DecodedBatchSegment is one segment of a batch. Timestamp and L1BlockNumber are the values
+// messages in the segment get, after clamping them to the batch's bounds.
+type DecodedBatchSegment struct {
+	Index         int                      `json:"index"`
+	Kind          string                   `json:"kind"`
+	Advance       uint64                   `json:"advance,omitempty"`
+	Timestamp     uint64                   `json:"timestamp"`
+	L1BlockNumber uint64                   `json:"l1BlockNumber"`
+	Message       *fogos.L1IncomingMessage `json:"message,omitempty"`
+	Error         string                   `json:"error,omitempty"`
+}
+
+var batchSegmentKindNames = map[uint8]string{
+	BatchSegmentKindL2Message:            "l2-message",
+	BatchSegmentKindL2MessageBrotli:      "l2-message-brotli",
+	BatchSegmentKindDelayedMessages:      "delayed-message",
+	BatchSegmentKindAdvanceTimestamp:     "advance-timestamp",
+	BatchSegmentKindAdvanceL1BlockNumber: "advance-l1-block-number",
+}
+
+func describeHeaderByte(header byte) []string {
+	flags := []string{}
+	if IsDASMessageHeaderByte(header) {
+		flags = append(flags, "das")
+		if IsTreeDASMessageHeaderByte(header) {
+			flags = append(flags, "tree-das")
+		}
+	}
+	if header&L1AuthenticatedMessageHeaderFlag != 0 {
+		flags = append(flags, "l1-authenticated")
+	}
+	if IsZeroheavyEncodedHeaderByte(header) {
+		flags = append(flags, "zeroheavy")
+	}
+	if IsBrotliMessageHeaderByte(header) {
+		flags = append(flags, "brotli")
+	}
+	if IsBrotliDictionaryMessageHeaderByte(header) {
+		flags = append(flags, "brotli-dictionary")
+	}
+	return flags
+}
+
+// DecodeSequencerMessage decodes a serialized sequencer batch the way the inbox multiplexer reads it.
+// Problems with the batch's contents are reported in the result rather than as errors, just like the
+// multiplexer turns them into invalid or empty messages. The DAS reader may be nil.
+// The fogOS version is the chain's at the start of the batch, which decides the formats it can be read in.
+func DecodeSequencerMessage(ctx context.Context, batchNum uint64, data []byte, dasReader DataAvailabilityReader, fogosVersion uint64) (*DecodedSequencerMessage, error) {
+	parsed, err := parseSequencerMessage(ctx, batchNum, data, dasReader, KeysetDontValidate, func() (uint64, error) {
+		return fogosVersion, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	decoded := &DecodedSequencerMessage{
+		MinTimestamp:         parsed.minTimestamp,
+		MaxTimestamp:         parsed.maxTimestamp,
+		MinL1Block:           parsed.minL1Block,
+		MaxL1Block:           parsed.maxL1Block,
+		AfterDelayedMessages: parsed.afterDelayedMessages,
+		HeaderFlags:          []string{},
+		Segments:             []*DecodedBatchSegment{},
+	}
+	payload := data[40:]
+	if len(payload) > 0 {
+		header := hexutil.Uint64(payload[0])
+		decoded.HeaderByte = &header
+		decoded.HeaderFlags = describeHeaderByte(payload[0])
+		if IsBrotliDictionaryMessageHeaderByte(payload[0]) && len(payload) > 1 {
+			decoded.Dictionary = fogcompress.Dictionary(payload[1]).String()
+		}
+		if IsDASMessageHeaderByte(payload[0]) {
+			decoded.DASCertificate = decodeDASCertificate(ctx, payload, dasReader)
+		}
+	}
+
+	var timestamp, blockNumber uint64
+	for i, segment := range parsed.segments {
+		decodedSegment := &DecodedBatchSegment{Index: i}
+		decoded.Segments = append(decoded.Segments, decodedSegment)
+		if len(segment) == 0 {
+			decodedSegment.Kind = "empty"
+			continue
+		}
+		kind := segment[0]
+		if name, ok := batchSegmentKindNames[kind]; ok {
+			decodedSegment.Kind = name
+		} else {
+			decodedSegment.Kind = "unknown"
+			decodedSegment.Error = "bad sequencer message segment kind"
+		}
+		switch kind {
+		case BatchSegmentKindAdvanceTimestamp, BatchSegmentKindAdvanceL1BlockNumber:
+			advancing, err := rlp.NewStream(bytes.NewReader(segment[1:]), 16).Uint64()
+			if err != nil {
+				decodedSegment.Error = err.Error()
+				break
+			}
+			decodedSegment.Advance = advancing
+			if kind == BatchSegmentKindAdvanceTimestamp {
+				timestamp += advancing
+			} else {
+				blockNumber += advancing
+			}
+		case BatchSegmentKindL2Message, BatchSegmentKindL2MessageBrotli:
+			l2msg := segment[1:]
+			if kind == BatchSegmentKindL2MessageBrotli {
+				l2msg, err = fogcompress.Decompress(l2msg, fogos.MaxL2MessageSize)
+				if err != nil {
+					decodedSegment.Error = err.Error()
+					break
+				}
+			}
+			decodedSegment.Message = &fogos.L1IncomingMessage{
+				Header: &fogos.L1IncomingMessageHeader{
+					Kind:        fogos.L1MessageType_L2Message,
+					Poster:      l1pricing.BatchPosterAddress,
+					BlockNumber: clamp(blockNumber, parsed.minL1Block, parsed.maxL1Block),
+					Timestamp:   clamp(timestamp, parsed.minTimestamp, parsed.maxTimestamp),
+					RequestId:   nil,
+					L1BaseFee:   big.NewInt(0),
+				},
+				L2msg: l2msg,
+			}
+		}
+		decodedSegment.Timestamp = clamp(timestamp, parsed.minTimestamp, parsed.maxTimestamp)
+		decodedSegment.L1BlockNumber = clamp(blockNumber, parsed.minL1Block, parsed.maxL1Block)
+	}
+	return decoded, nil
+}
+
+func clamp(value, min, max uint64) uint64 {
+	if value < min {
+		return min
+	}
+	if value > max {
+		return max
+	}
+	return value
+}
+
+func decodeDASCertificate(ctx context.Context, payload []byte, dasReader DataAvailabilityReader) *DecodedDASCertificate {
+	cert, err := DeserializeDASCertFrom(bytes.NewReader(payload))
+	if err != nil {
+		return &DecodedDASCertificate{Error: err.Error()}
+	}
+	decoded := &DecodedDASCertificate{
+		Version:     cert.Version,
+		KeysetHash:  cert.KeysetHash,
+		DataHash:    cert.DataHash,
+		Timeout:     cert.Timeout,
+		SignersMask: hexutil.Uint64(cert.SignersMask),
+	}
+	if dasReader == nil {
+		decoded.KeysetError = "no DAS reader configured"
+		return decoded
+	}
+	keyset, err := cert.RecoverKeyset(ctx, dasReader, true)
+	if err != nil {
+		decoded.KeysetError = err.Error()
+		return decoded
+	}
+	decoded.Keyset = &DecodedDASKeyset{AssumedHonest: keyset.AssumedHonest}
+	for _, pubKey := range keyset.PubKeys {
+		decoded.Keyset.PubKeys = append(decoded.Keyset.PubKeys, blsSignatures.PublicKeyToBytes(pubKey))
+	}
+	return decoded
+}
@@ -0,0 +1,82 @@
+// Copyright 2021-2022, Offchain Labs, Inc.
+// For license information, see https://github.com/fogr/blob/master/LICENSE
+
+package fogstate
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/rlp"
+
+	"github.com/FOGRCC/fogr/fogcompress"
+)
+
+func TestDecodeSequencerMessage(t *testing.T) {
+	advance := func(kind uint8, diff uint64) []byte {
+		encoded, err := rlp.EncodeToBytes(diff)
+		Require(t, err)
+		return append([]byte{kind}, encoded...)
+	}
+	l2msg := []byte{4, 1, 2, 3}
+	compressedL2msg, err := fogcompress.CompressWell(l2msg)
+	Require(t, err)
+	segments := [][]byte{
+		advance(BatchSegmentKindAdvanceTimestamp, 1000),
+		advance(BatchSegmentKindAdvanceL1BlockNumber, 50),
+		append([]byte{BatchSegmentKindL2Message}, l2msg...),
+		{BatchSegmentKindDelayedMessages},
+		advance(BatchSegmentKindAdvanceTimestamp, 5000),
+		append([]byte{BatchSegmentKindL2MessageBrotli}, compressedL2msg...),
+	}
+	var rawSegments []byte
+	for _, segment := range segments {
+		encoded, err := rlp.EncodeToBytes(segment)
+		Require(t, err)
+		rawSegments = append(rawSegments, encoded...)
+	}
+	compressed, err := fogcompress.CompressWell(rawSegments)
+	Require(t, err)
+
+	data := make([]byte, 40)
+	bounds := []uint64{1100, 2000, 10, 100, 7}
+	for i, bound := range bounds {
+		binary.BigEndian.PutUint64(data[i*8:], bound)
+	}
+	data = append(data, BrotliMessageHeaderByte)
+	data = append(data, compressed...)
+
+	decoded, err := DecodeSequencerMessage(context.Background(), 0, data, nil, FirstBrotliDictionaryfogosVersion)
+	Require(t, err)
+	if decoded.MinTimestamp != 1100 || decoded.MaxL1Block != 100 || decoded.AfterDelayedMessages != 7 {
+		Fail(t, "unexpected batch header", decoded)
+	}
+	if len(decoded.HeaderFlags) != 1 || decoded.HeaderFlags[0] != "brotli" {
+		Fail(t, "unexpected header flags", decoded.HeaderFlags)
+	}
+	if len(decoded.Segments) != len(segments) {
+		Fail(t, "expected", len(segments), "segments but got", len(decoded.Segments))
+	}
+
+	first := decoded.Segments[2]
+	if first.Kind != "l2-message" || first.Message == nil || !bytes.Equal(first.Message.L2msg, l2msg) {
+		Fail(t, "unexpected first message segment", first)
+	}
+	// The timestamp is clamped up to the batch's minimum.
+	if first.Message.Header.Timestamp != 1100 || first.Message.Header.BlockNumber != 50 {
+		Fail(t, "unexpected first message header", first.Message.Header)
+	}
+	if decoded.Segments[3].Kind != "delayed-message" {
+		Fail(t, "unexpected delayed message segment", decoded.Segments[3])
+	}
+	second := decoded.Segments[5]
+	if second.Kind != "l2-message-brotli" || second.Message == nil || !bytes.Equal(second.Message.L2msg, l2msg) {
+		Fail(t, "unexpected second message segment", second)
+	}
+	// The timestamp is clamped down to the batch's maximum.
+	if decoded.Segments[4].Advance != 5000 || second.Message.Header.Timestamp != 2000 {
+		Fail(t, "unexpected second message timestamp", second.Message.Header.Timestamp)
+	}
+}
@@ -43,6 +43,23 @@ const ZeroheavyMessageHeaderFlag byte = 0x20
 // BrotliMessageHeaderByte indicates that the message is brotli-compressed.
 const BrotliMessageHeaderByte byte = 0
 
+// BrotliDictionaryMessageHeaderByte indicates that the message is brotli-compressed with a custom
+// dictionary, identified by the byte following the header byte.
+const BrotliDictionaryMessageHeaderByte byte = 0x01
+
+// FirstBrotliDictionaryfogosVersion is the first fogOS version whose replay binary, and so whose
+// wasm module root, reads batches compressed with a dictionary. Older module roots treat them as
+// empty, so the upgrade must go in this order:
+//  1. upgrade every node, and the validators' machines, to a release that reads them
+//  2. set the rollup's wasm module root to that release's
+//  3. schedule the upgrade to this fogOS version
+//  4. once the upgrade is active, batch posters may compress batches with a dictionary
+//
+// Batch posters check for the last step themselves, and only use a dictionary once it's active.
+// Batches compressed with a dictionary before then are still read as empty, as of the fogOS version
+// at the start of the batch, so that upgraded nodes agree with older module roots on them.
+const FirstBrotliDictionaryfogosVersion = 11
+
 func IsDASMessageHeaderByte(header byte) bool {
 	return (DASMessageHeaderFlag & header) > 0
 }
@@ -59,6 +76,10 @@ func IsBrotliMessageHeaderByte(b uint8) bool {
 	return b == BrotliMessageHeaderByte
 }
 
+func IsBrotliDictionaryMessageHeaderByte(b uint8) bool {
+	return b == BrotliDictionaryMessageHeaderByte
+}
+
 type DataAvailabilityCertificate struct {
 	KeysetHash  [32]byte
 	DataHash    [32]byte
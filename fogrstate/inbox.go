@@ -37,6 +37,10 @@ type InboxBackend interface {
 	SetPositionWithinMessage(pos uint64)
 
 	ReadDelayedInbox(seqNum uint64) (*fogos.L1IncomingMessage, error)
+
+	// GetBatchStartfogosVersion returns the fogOS version of the block before the current
+	// sequencer message's first message, which decides the formats the sequencer message may use.
+	GetBatchStartfogosVersion() (uint64, error)
 }
 
 type MessageWithMetadata struct {
@@ -86,7 +90,9 @@ const maxZeroheavyDecompressedLen = 101*MaxDecompressedLen/100 + 64
 const MaxSegmentsPerSequencerMessage = 100 * 1024
 const MinLifetimeSecondsForDataAvailabilityCert = 7 * 24 * 60 * 60 // one week
 
-func parseSequencerMessage(ctx context.Context, batchNum uint64, data []byte, dasReader DataAvailabilityReader, keysetValidationMode KeysetValidationMode) (*sequencerMessage, error) {
+// parseSequencerMessage only calls fogosVersion for formats added by a fogOS upgrade.
+// Before the upgrade, those are read as an unknown format, just like older replay binaries read them.
+func parseSequencerMessage(ctx context.Context, batchNum uint64, data []byte, dasReader DataAvailabilityReader, keysetValidationMode KeysetValidationMode, fogosVersion func() (uint64, error)) (*sequencerMessage, error) {
 	if len(data) < 40 {
 		return nil, errors.New("sequencer message missing L1 header")
 	}
@@ -124,29 +130,21 @@ func parseSequencerMessage(ctx context.Context, batchNum uint64, data []byte, da
 		payload = pl
 	}
 
+	var decompressed []byte
+	var err error
 	if len(payload) > 0 && IsBrotliMessageHeaderByte(payload[0]) {
-		decompressed, err := fogcompress.Decompress(payload[1:], MaxDecompressedLen)
-		if err == nil {
-			reader := bytes.NewReader(decompressed)
-			stream := rlp.NewStream(reader, uint64(MaxDecompressedLen))
-			for {
-				var segment []byte
-				err := stream.Decode(&segment)
-				if err != nil {
-					if !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
-						log.Warn("error parsing sequencer message segment", "err", err.Error())
-					}
-					break
-				}
-				if len(parsedMsg.segments) >= MaxSegmentsPerSequencerMessage {
-					log.Warn("too many segments in sequence batch")
-					break
-				}
-				parsedMsg.segments = append(parsedMsg.segments, segment)
-			}
-		} else {
-			log.Warn("sequencer msg decompression failed", "err", err)
+		decompressed, err = fogcompress.Decompress(payload[1:], MaxDecompressedLen)
+	} else if len(payload) > 1 && IsBrotliDictionaryMessageHeaderByte(payload[0]) {
+		var version uint64
+		version, err = fogosVersion()
+		if err != nil {
+			return nil, err
 		}
+		if version < FirstBrotliDictionaryfogosVersion {
+			log.Warn("sequencer message compressed with a dictionary before the fogOS upgrade that reads it", "fogosVersion", version)
+			return parsedMsg, nil
+		}
+		decompressed, err = fogcompress.DecompressWithDictionary(payload[2:], MaxDecompressedLen, fogcompress.Dictionary(payload[1]))
 	} else {
 		length := len(payload)
 		if length == 0 {
@@ -154,12 +152,45 @@ func parseSequencerMessage(ctx context.Context, batchNum uint64, data []byte, da
 		} else {
 			log.Warn("unknown sequencer message format", "length", length, "firstByte", payload[0])
 		}
+		return parsedMsg, nil
+	}
+	if err != nil {
+		log.Warn("sequencer msg decompression failed", "err", err)
+		return parsedMsg, nil
+	}
 
+	reader := bytes.NewReader(decompressed)
+	stream := rlp.NewStream(reader, uint64(MaxDecompressedLen))
+	for {
+		var segment []byte
+		err := stream.Decode(&segment)
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+				log.Warn("error parsing sequencer message segment", "err", err.Error())
+			}
+			break
+		}
+		if len(parsedMsg.segments) >= MaxSegmentsPerSequencerMessage {
+			log.Warn("too many segments in sequence batch")
+			break
+		}
+		parsedMsg.segments = append(parsedMsg.segments, segment)
 	}
 
 	return parsedMsg, nil
 }
 
+// ReadsBatchStartfogosVersion returns whether reading the sequencer message depends on the fogOS version
+// at the start of its batch, in which case the prover reads that version from the headers of the batch's blocks.
+func ReadsBatchStartfogosVersion(ctx context.Context, batchNum uint64, data []byte, dasReader DataAvailabilityReader) (bool, error) {
+	reads := false
+	_, err := parseSequencerMessage(ctx, batchNum, data, dasReader, KeysetDontValidate, func() (uint64, error) {
+		reads = true
+		return 0, nil
+	})
+	return reads, err
+}
+
 func RecoverPayloadFromDasBatch(
 	ctx context.Context,
 	batchNum uint64,
@@ -312,7 +343,7 @@ func (r *inboxMultiplexer) Pop(ctx context.Context) (*MessageWithMetadata, error
 		}
 		r.cachedSequencerMessageNum = r.backend.GetSequencerInboxPosition()
 		var err error
-		r.cachedSequencerMessage, err = parseSequencerMessage(ctx, r.cachedSequencerMessageNum, bytes, r.dasReader, r.keysetValidationMode)
+		r.cachedSequencerMessage, err = parseSequencerMessage(ctx, r.cachedSequencerMessageNum, bytes, r.dasReader, r.keysetValidationMode, r.backend.GetBatchStartfogosVersion)
 		if err != nil {
 			return nil, err
 		}
@@ -55,6 +55,10 @@ func (b *multiplexerBackend) ReadDelayedInbox(seqNum uint64) (*fogos.L1IncomingM
 	return msg, nil
 }
 
+func (b *multiplexerBackend) GetBatchStartfogosVersion() (uint64, error) {
+	return FirstBrotliDictionaryfogosVersion, nil
+}
+
 func FuzzInboxMultiplexer(f *testing.F) {
 	f.Fuzz(func(t *testing.T, seqMsg []byte, delayedMsg []byte) {
 		if len(seqMsg) < 40 {
@@ -0,0 +1,61 @@
+// Copyright 2022, Offchain Labs, Inc.
+// For license information, see https://github.com/fogr/blob/master/LICENSE
+
+package fogstate
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/rlp"
+
+	"github.com/FOGRCC/fogr/fogcompress"
+	"github.com/FOGRCC/fogr/util/testhelpers"
+)
+
+func TestDictionarySequencerMessage(t *testing.T) {
+	ctx := context.Background()
+	segments := [][]byte{
+		testhelpers.RandomizeSlice(make([]byte, 100)),
+		testhelpers.RandomizeSlice(make([]byte, 200)),
+	}
+	var rawSegments []byte
+	for _, segment := range segments {
+		encoded, err := rlp.EncodeToBytes(segment)
+		Require(t, err)
+		rawSegments = append(rawSegments, encoded...)
+	}
+	compressed, err := fogcompress.CompressLevelWithDictionary(rawSegments, fogcompress.LEVEL_WELL, fogcompress.BatchDictionaryV1)
+	Require(t, err)
+	seqMsg := append(make([]byte, 40), BrotliDictionaryMessageHeaderByte, byte(fogcompress.BatchDictionaryV1))
+	seqMsg = append(seqMsg, compressed...)
+
+	upgraded := func() (uint64, error) { return FirstBrotliDictionaryfogosVersion, nil }
+	parsed, err := parseSequencerMessage(ctx, 0, seqMsg, nil, KeysetValidate, upgraded)
+	Require(t, err)
+	if len(parsed.segments) != len(segments) {
+		Fail(t, "expected", len(segments), "segments but got", len(parsed.segments))
+	}
+	for i := range segments {
+		if !bytes.Equal(parsed.segments[i], segments[i]) {
+			Fail(t, "segment", i, "doesn't match")
+		}
+	}
+
+	// Before the fogOS upgrade, the batch is read as an unknown format.
+	notUpgraded := func() (uint64, error) { return FirstBrotliDictionaryfogosVersion - 1, nil }
+	parsed, err = parseSequencerMessage(ctx, 0, seqMsg, nil, KeysetValidate, notUpgraded)
+	Require(t, err)
+	if len(parsed.segments) != 0 {
+		Fail(t, "expected no segments before the fogOS upgrade, got", len(parsed.segments))
+	}
+
+	// The same data can't be read with another dictionary.
+	seqMsg[41] = byte(fogcompress.EmptyDictionary)
+	parsed, err = parseSequencerMessage(ctx, 0, seqMsg, nil, KeysetValidate, upgraded)
+	Require(t, err)
+	if len(parsed.segments) != 0 {
+		Fail(t, "expected no segments with the wrong dictionary, got", len(parsed.segments))
+	}
+}
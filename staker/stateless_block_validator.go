@@ -23,6 +23,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/pkg/errors"
 )
 
@@ -486,6 +487,28 @@ func (v *StatelessBlockValidator) ValidationEntryAddSeqMessage(ctx context.Conte
 			}
 		}
 	}
+	if startPos.PosInBatch > 0 {
+		readsVersion, err := fogstate.ReadsBatchStartfogosVersion(ctx, startPos.BatchNumber, seqMsg, v.daService)
+		if err != nil {
+			return err
+		}
+		if readsVersion {
+			// The replay binary reads the fogOS version from the block before the batch,
+			// walking back through the blocks of the batch's earlier messages.
+			header := e.PrevBlockHeader
+			for pos := startPos.PosInBatch; pos > 0 && header.ParentHash != (common.Hash{}); pos-- {
+				header = v.blockchain.GetHeaderByHash(header.ParentHash)
+				if header == nil {
+					return fmt.Errorf("missing header of an earlier block in batch %v", startPos.BatchNumber)
+				}
+				encoded, err := rlp.EncodeToBytes(header)
+				if err != nil {
+					return err
+				}
+				e.Preimages[header.Hash()] = encoded
+			}
+		}
+	}
 	e.Stage = Ready
 	return nil
 }
@@ -0,0 +1,72 @@
+// Copyright 2021-2022, Offchain Labs, Inc.
+// For license information, see https://github.com/fogr/blob/master/LICENSE
+
+package fogtest
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func encodeBundle(t *testing.T, txs ...*types.Transaction) []hexutil.Bytes {
+	var encoded []hexutil.Bytes
+	for _, tx := range txs {
+		data, err := tx.MarshalBinary()
+		Require(t, err)
+		encoded = append(encoded, data)
+	}
+	return encoded
+}
+
+func TestSequencerBundle(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	l2info, l2node, client := CreateTestL2(t, ctx)
+	defer l2node.StopAndWait()
+	l2rpc, err := l2node.Stack.Attach()
+	Require(t, err)
+
+	l2info.GenerateAccount("User")
+	l2info.GenerateAccount("User2")
+	TransferBalance(t, "Owner", "User", big.NewInt(params.Ether), l2info, client, ctx)
+
+	// User2 can't pay for its transfer, so neither transaction in the bundle is included
+	userNonce := l2info.GetInfoWithPrivKey("User").Nonce
+	toUser2 := l2info.PrepareTx("User", "User2", l2info.TransferGas, big.NewInt(params.Ether/10), nil)
+	fromUser2 := l2info.PrepareTx("User2", "User", l2info.TransferGas, big.NewInt(params.Ether), nil)
+	var hashes []common.Hash
+	err = l2rpc.CallContext(ctx, &hashes, "fog_sendBundle", encodeBundle(t, toUser2, fromUser2))
+	if err == nil {
+		Fail(t, "bundle with a failing transaction was sequenced")
+	}
+	nonce, err := client.NonceAt(ctx, l2info.GetAddress("User"), nil)
+	Require(t, err)
+	if nonce != userNonce {
+		Fail(t, "transaction from a failed bundle was included")
+	}
+	l2info.GetInfoWithPrivKey("User").Nonce = userNonce
+	l2info.GetInfoWithPrivKey("User2").Nonce = 0
+
+	// User2 is paid then pays some back, consecutively in one block
+	toUser2 = l2info.PrepareTx("User", "User2", l2info.TransferGas, big.NewInt(params.Ether/10), nil)
+	fromUser2 = l2info.PrepareTx("User2", "User", l2info.TransferGas, big.NewInt(params.Ether/100), nil)
+	err = l2rpc.CallContext(ctx, &hashes, "fog_sendBundle", encodeBundle(t, toUser2, fromUser2))
+	Require(t, err)
+	if len(hashes) != 2 || hashes[0] != toUser2.Hash() || hashes[1] != fromUser2.Hash() {
+		Fail(t, "unexpected bundle hashes", hashes)
+	}
+	receiptTo, err := EnsureTxSucceeded(ctx, client, toUser2)
+	Require(t, err)
+	receiptFrom, err := EnsureTxSucceeded(ctx, client, fromUser2)
+	Require(t, err)
+	if receiptTo.BlockHash != receiptFrom.BlockHash || receiptFrom.TransactionIndex != receiptTo.TransactionIndex+1 {
+		Fail(t, "bundle transactions weren't consecutive in one block", receiptTo.BlockNumber, receiptTo.TransactionIndex, receiptFrom.BlockNumber, receiptFrom.TransactionIndex)
+	}
+}
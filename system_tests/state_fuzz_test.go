@@ -106,6 +106,10 @@ func (b *inboxBackend) ReadDelayedInbox(seqNum uint64) (*fogos.L1IncomingMessage
 	return msg, nil
 }
 
+func (b *inboxBackend) GetBatchStartfogosVersion() (uint64, error) {
+	return fogstate.FirstBrotliDictionaryfogosVersion, nil
+}
+
 // A chain context with no information
 type noopChainContext struct{}
 